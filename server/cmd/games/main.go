@@ -6,17 +6,29 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"games_webapp/internal/clients/telegram"
 	"games_webapp/internal/config"
+	"games_webapp/internal/httpx"
+	"games_webapp/internal/lib/jwt"
+	"games_webapp/internal/lib/redact"
+	"games_webapp/internal/lifecycle"
+	"games_webapp/internal/mailer"
 	"games_webapp/internal/middleware"
+	"games_webapp/internal/repository"
 	"games_webapp/internal/routes"
-	"games_webapp/internal/storage/mariadb"
+	"games_webapp/internal/scheduler"
+	"games_webapp/internal/services"
+	dbstorage "games_webapp/internal/storage"
 	"games_webapp/internal/storage/uploads"
+	"games_webapp/internal/tracing"
 
 	_ "games_webapp/internal/controllers"
 
+	"games_webapp/internal/clients/sso/fake"
 	ssogrpc "games_webapp/internal/clients/sso/grpc"
 )
 
@@ -25,12 +37,51 @@ const (
 	envProd  = "prod"
 )
 
+// ssoAppID — идентификатор приложения в SSO
+const ssoAppID = 1
+
 func main() {
 	cfg := config.MustLoad()
 
-	log := setupLogger(cfg.Env)
+	level := new(slog.LevelVar)
+	level.Set(logLevel(cfg.Env, cfg.LogLevel))
+	log := setupLogger(cfg.Env, level)
+
+	reloader := config.NewReloader(config.Path(), cfg, log)
+	reloader.OnReload("log_level", func(c *config.Config) error {
+		level.Set(logLevel(cfg.Env, c.LogLevel))
+		return nil
+	})
 
 	log.Info("starting server", slog.String("env", cfg.Env))
+	if config.Path() == "" {
+		log.Info("config file not set, using environment only")
+	}
+	log.Debug("effective config", slog.Any("config", cfg))
+
+	shutdownTracing := tracing.Setup(cfg.Tracing, cfg.Env, log)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Tracing.Timeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+	if cfg.Tracing.Enabled {
+		log.Info("tracing enabled", slog.String("endpoint", cfg.Tracing.Endpoint), slog.Float64("sample_ratio", cfg.Tracing.SampleRatio))
+	}
+	httpx.Setup(cfg.Outbound)
+
+	if cfg.Clients.SSO.Fake {
+		ssoFake := fake.New(fake.Options{AnyLogin: true, Admins: cfg.Clients.SSO.FakeAdmins})
+		if err := ssoFake.Start("127.0.0.1:0"); err != nil {
+			log.Error("failed to start fake sso", slog.String("error", err.Error()))
+			panic("sso-err")
+		}
+		defer ssoFake.Stop()
+		cfg.Clients.SSO.Address = ssoFake.Addr()
+		log.Warn("using fake sso: any email and password log in", slog.String("address", ssoFake.Addr()))
+	}
 
 	ssoClient, err := ssogrpc.New(
 		context.Background(),
@@ -43,21 +94,62 @@ func main() {
 		log.Error("failed to create sso client", slog.String("error", err.Error()))
 		panic("sso-err")
 	}
+	if cfg.Clients.SSO.BreakerThreshold > 0 {
+		ssoClient.UseBreaker(cfg.Clients.SSO.BreakerThreshold, cfg.Clients.SSO.BreakerCooldown)
+	}
+	if cfg.Clients.SSO.CacheTTL > 0 {
+		ssoClient.UseTokenCache(cfg.Clients.SSO.CacheTTL, cfg.Clients.SSO.StaleTTL)
+	}
+	// Токены фейкового SSO не JWT, локально их не проверить
+	if cfg.Clients.SSO.LocalValidation && !cfg.Clients.SSO.Fake {
+		verifier := jwt.NewHMAC(cfg.AppSecret, ssoAppID)
+		if cfg.Clients.SSO.PublicKeyPath != "" {
+			key, err := os.ReadFile(cfg.Clients.SSO.PublicKeyPath)
+			if err == nil {
+				verifier, err = jwt.NewRSA(key, ssoAppID)
+			}
+			if err != nil {
+				log.Error("failed to load sso public key", slog.String("error", err.Error()))
+				panic("sso-key-err")
+			}
+		}
+		ssoClient.UseLocalValidation(verifier)
+	}
 
 	authMiddleware := middleware.NewAuthMiddleware(ssoClient)
 
-	storage, err := mariadb.New(cfg.Database)
+	storage, err := dbstorage.New(cfg.Database)
 	if err != nil {
 		log.Error("failed to create database", slog.String("error", err.Error()))
 		panic("db-err")
 	}
+	if cfg.Tracing.Enabled {
+		if err := storage.DB().Use(tracing.GormPlugin()); err != nil {
+			log.Error("failed to enable database tracing", slog.String("error", err.Error()))
+			panic("db-err")
+		}
+	}
 
-	uploadsStorage, err := uploads.NewUploads(cfg.UploadsPath)
+	uploadsStorage, err := uploads.NewUploads(cfg.UploadsPath, uploads.Limits{
+		MaxSize:      cfg.Images.MaxSize,
+		AllowedTypes: cfg.Images.AllowedTypes,
+		JPEGQuality:  cfg.Images.JPEGQuality,
+	})
 	if err != nil {
 		log.Error("failed to create uploads storage", slog.String("error", err.Error()))
 		panic("uploads-err")
 	}
 
+	photosStorage, err := uploads.NewUploads(cfg.Photos.Path, uploads.Limits{
+		MaxSize:      cfg.Images.MaxSize,
+		AllowedTypes: cfg.Images.AllowedTypes,
+		JPEGQuality:  cfg.Images.JPEGQuality,
+	})
+	if err != nil {
+		log.Error("failed to create photos storage", slog.String("error", err.Error()))
+		panic("photos-err")
+	}
+
 	log.Info("storage init")
 
 	defer func() {
@@ -72,18 +164,144 @@ func main() {
 		panic("table-err")
 	}
 
+	replicas, err := dbstorage.UseReplicas(storage.DB(), cfg.Database)
+	if err != nil {
+		log.Error("failed to connect read replicas", slog.String("error", err.Error()))
+		panic("replicas-err")
+	}
+	if replicas != nil {
+		defer replicas.Close()
+		log.Info("read replicas enabled", slog.Int("count", replicas.Len()))
+	}
+
 	log.Info("database init")
 
-	r := routes.SetupRouter(log, storage, uploadsStorage, authMiddleware, ssoClient, cfg)
+	lc := lifecycle.New(log)
+
+	r := routes.SetupRouter(log, storage, uploadsStorage, photosStorage, authMiddleware, ssoClient, lc, cfg, reloader)
 
 	log.Info("routes init")
 
+	jobs := scheduler.New(log)
+	if cfg.PriorityAging.Enabled {
+		gameService := services.NewGameService(repository.New(storage.DB()), log)
+		aging := cfg.PriorityAging
+		jobs.Add("priority_aging", aging.Interval, func(ctx context.Context) error {
+			aged, err := gameService.AgeBacklog(ctx, time.Now().AddDate(0, -aging.AfterMonths, 0), aging.Mode)
+			if err != nil {
+				return err
+			}
+			log.Info("priority aging", slog.Int("aged", aged), slog.String("mode", aging.Mode))
+			return nil
+		})
+	}
+	if cfg.UploadsGC.Enabled && cfg.Clients.SSO.Fake {
+		// Фейковый SSO не знает фото пользователей, и они ушли бы в мусор
+		log.Warn("uploads gc is disabled with fake sso")
+	} else if cfg.UploadsGC.Enabled {
+		gc := services.NewUploadsGCService(repository.New(storage.DB()), log, ssoClient, uploadsStorage, cfg.UploadsGC.MinAge)
+		dryRun := cfg.UploadsGC.DryRun
+		jobs.Add("uploads_gc", cfg.UploadsGC.Interval, func(ctx context.Context) error {
+			report, err := gc.Collect(ctx, dryRun)
+			if err != nil {
+				return err
+			}
+			log.Info("uploads gc",
+				slog.Bool("dry_run", dryRun),
+				slog.Int("scanned", report.Scanned),
+				slog.Int("orphaned", len(report.Orphaned)),
+				slog.Int("deleted", report.Deleted),
+				slog.Int64("bytes", report.Bytes))
+			return nil
+		})
+	}
+	if cfg.Webhooks.Enabled {
+		webhooks := services.NewWebhookService(repository.New(storage.DB()), log, cfg.Webhooks.Timeout)
+		jobs.Add("webhooks", cfg.Webhooks.Interval, func(ctx context.Context) error {
+			delivered, failed, err := webhooks.Dispatch(ctx)
+			if err != nil {
+				return err
+			}
+			if delivered > 0 || failed > 0 {
+				log.Info("webhooks", slog.Int("delivered", delivered), slog.Int("failed", failed))
+			}
+			return nil
+		})
+	}
+	if cfg.Telegram.Token != "" {
+		bot := services.NewTelegramService(repository.New(storage.DB()), log,
+			telegram.New(cfg.Telegram.Token, cfg.Telegram.Timeout), lc, cfg.Telegram.BotName)
+		jobs.Add("telegram_updates", cfg.Telegram.PollInterval, bot.PollUpdates)
+	}
+	if cfg.Digest.Enabled && cfg.Mailer.Host != "" {
+		m := mailer.New(cfg.Mailer.Host, cfg.Mailer.Port, cfg.Mailer.Username, cfg.Mailer.Password, cfg.Mailer.From)
+		digest := services.NewDigestService(repository.New(storage.DB()), log, m, ssoClient)
+		jobs.Add("weekly_digest", cfg.Digest.Interval, func(ctx context.Context) error {
+			sent, err := digest.SendDue(ctx, time.Now())
+			if err != nil {
+				return err
+			}
+			if sent > 0 {
+				log.Info("weekly digest", slog.Int("sent", sent))
+			}
+			return nil
+		})
+	}
+	if replicas != nil {
+		jobs.Add("db_replicas", cfg.Database.ReplicaCheckInterval, func(ctx context.Context) error {
+			if up := replicas.Check(ctx); up < replicas.Len() {
+				log.Warn("read replicas unavailable", slog.Int("up", up), slog.Int("total", replicas.Len()))
+			}
+			return nil
+		})
+	}
+	// Сессии старше срока жизни refresh-токена уже не обновить
+	sessions := services.NewSessionService(repository.New(storage.DB()), log)
+	jobs.Add("sessions_cleanup", 24*time.Hour, func(ctx context.Context) error {
+		return sessions.Cleanup(30 * 24 * time.Hour)
+	})
+	if cfg.ImageProxy.Enabled {
+		imageProxy, err := services.NewImageProxyService(log, cfg.ImageProxy.CacheDir, routes.ImageProxyPolicy(cfg.ImageProxy))
+		if err != nil {
+			log.Error("failed to set up image proxy cache cleanup", slog.String("error", err.Error()))
+		} else {
+			jobs.Add("image_proxy_cleanup", time.Hour, func(ctx context.Context) error {
+				removed, err := imageProxy.Cleanup()
+				if removed > 0 {
+					log.Info("image proxy cache cleaned", slog.Int("removed", removed))
+				}
+				return err
+			})
+		}
+	}
+	if cfg.LoginGuard.Enabled {
+		guard := services.NewLoginGuardService(repository.New(storage.DB()), log, routes.LoginPolicy(cfg.LoginGuard))
+		reloader.OnReload("login_guard_cleanup", func(c *config.Config) error {
+			guard.SetPolicy(routes.LoginPolicy(c.LoginGuard))
+			return nil
+		})
+		jobs.Add("login_attempts_cleanup", time.Hour, func(ctx context.Context) error {
+			return guard.Cleanup()
+		})
+	}
+	lc.Go("scheduler", func(ctx context.Context) error {
+		jobs.Start(ctx)
+		<-ctx.Done()
+		jobs.Wait()
+		return nil
+	})
+	watchConfig(log, reloader, config.Path(), cfg.ConfigWatchInterval, lc)
+
 	server := &http.Server{
-		Addr:         cfg.Address,
-		Handler:      r,
-		ReadTimeout:  cfg.Timeout,
-		WriteTimeout: cfg.Timeout,
-		IdleTimeout:  cfg.IdleTimeout,
+		Addr:              cfg.Address,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Timeout,
+		WriteTimeout:      cfg.Timeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	if cfg.TLS.Enabled() {
+		setupTLS(log, server, cfg.TLS, lc)
 	}
 
 	serverErrors := make(chan error, 1)
@@ -92,8 +310,8 @@ func main() {
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		log.Info("starting server", slog.String("address", cfg.Address))
-		serverErrors <- server.ListenAndServe()
+		log.Info("starting server", slog.String("address", cfg.Address), slog.Bool("tls", cfg.TLS.Enabled()))
+		serverErrors <- listenAndServe(server, cfg.TLS)
 	}()
 
 	select {
@@ -101,35 +319,80 @@ func main() {
 		log.Error("server error", slog.String("error", err.Error()))
 		os.Exit(1)
 
+	case <-lc.Context().Done():
+		log.Error("background worker failed, shutting down")
+		shutdownServer(log, server, lc, cfg.DrainTimeout)
+
 	case sig := <-shutdown:
 
 		log.Info("shutting down", slog.String("signal", sig.String()))
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		shutdownServer(log, server, lc, cfg.DrainTimeout)
 
-		if err := server.Shutdown(ctx); err != nil {
-			log.Error("graceful shutdown error", slog.String("error", err.Error()))
-			if err := server.Close(); err != nil {
-				log.Error("force shutdown error", slog.String("error", err.Error()))
-			}
-		}
 		close(shutdown)
 		close(serverErrors)
 	}
 	log.Info("server stopped")
 }
 
-func setupLogger(env string) *slog.Logger {
+// shutdownServer перестаёт принимать запросы и ждёт запросы и фоновую работу.
+// На всё отводится drainTimeout
+func shutdownServer(log *slog.Logger, server *http.Server, lc *lifecycle.Manager, drainTimeout time.Duration) {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("graceful shutdown error", slog.String("error", err.Error()))
+		if err := server.Close(); err != nil {
+			log.Error("force shutdown error", slog.String("error", err.Error()))
+		}
+	}
+
+	remaining := drainTimeout - time.Since(start)
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	if err := lc.Shutdown(remaining); err != nil {
+		log.Error("background work shutdown error", slog.String("error", err.Error()))
+	}
+}
+
+// setupLogger создаёт логгер для env. Уровень берётся из level, чтобы его можно
+// было поменять при перечитывании конфига
+// setupLogger создаёт логгер для env. Значения с ключами вроде password, token
+// или secret скрываются, см. redact.Attr
+func setupLogger(env string, level *slog.LevelVar) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact.Attr}
+
 	var log *slog.Logger
 	switch env {
 	case envLocal:
 		log = slog.New(
-			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+			slog.NewTextHandler(os.Stdout, opts),
 		)
 	case envProd:
 		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+			slog.NewJSONHandler(os.Stdout, opts),
 		)
 	}
 	return log
 }
+
+// logLevel возвращает уровень из log_level или, если он пуст, умолчание для env
+func logLevel(env, configured string) slog.Level {
+	switch strings.ToLower(configured) {
+	case "debug":
+		return slog.LevelDebug
+	case "info":
+		return slog.LevelInfo
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	if env == envLocal {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/lifecycle"
+)
+
+// watchConfig перечитывает конфиг по SIGHUP, а при interval > 0 — ещё и когда
+// меняется время изменения файла. Плохой конфиг только логируется: приложение
+// продолжает работать со старым
+func watchConfig(log *slog.Logger, reloader *config.Reloader, path string, interval time.Duration, lc *lifecycle.Manager) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	lc.Go("config_reload", func(ctx context.Context) error {
+		defer signal.Stop(hup)
+
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		modTime := fileModTime(path)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-hup:
+				modTime = fileModTime(path)
+				reload(log, reloader, "signal")
+			case <-tick:
+				if mt := fileModTime(path); !mt.Equal(modTime) {
+					modTime = mt
+					reload(log, reloader, "file")
+				}
+			}
+		}
+	})
+}
+
+func reload(log *slog.Logger, reloader *config.Reloader, trigger string) {
+	if err := reloader.Reload(); err != nil {
+		log.Error("config reload failed, keeping previous config", slog.String("trigger", trigger), slog.String("error", err.Error()))
+		return
+	}
+	log.Info("config reloaded", slog.String("trigger", trigger))
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/lifecycle"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// setupTLS переводит server на HTTPS по настройкам cfg. HTTP/2 net/http включает
+// для TLS сам. Если задан redirect_address, рядом запускается HTTP-сервер,
+// который перенаправляет на HTTPS и отвечает на проверки Let's Encrypt
+func setupTLS(log *slog.Logger, server *http.Server, cfg config.TLS, lc *lifecycle.Manager) {
+	redirect := redirectToHTTPS(server.Addr)
+
+	if len(cfg.AutocertDomains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		server.TLSConfig = manager.TLSConfig()
+		redirect = manager.HTTPHandler(redirect)
+	} else {
+		server.TLSConfig = &tls.Config{}
+	}
+	server.TLSConfig.MinVersion = tls.VersionTLS12
+
+	if cfg.RedirectAddress == "" {
+		return
+	}
+
+	redirectServer := &http.Server{
+		Addr:              cfg.RedirectAddress,
+		Handler:           redirect,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	lc.Go("https_redirect", func(ctx context.Context) error {
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			redirectServer.Shutdown(shutdownCtx)
+		}()
+
+		log.Info("starting https redirect", slog.String("address", cfg.RedirectAddress))
+		if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+}
+
+// listenAndServe запускает server по HTTPS, если TLS настроен, иначе по HTTP
+func listenAndServe(server *http.Server, cfg config.TLS) error {
+	if !cfg.Enabled() {
+		return server.ListenAndServe()
+	}
+	if len(cfg.AutocertDomains) > 0 {
+		// Сертификаты отдаёт autocert через TLSConfig
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+}
+
+// redirectToHTTPS перенаправляет запрос на тот же адрес по HTTPS. Порт берётся
+// из адреса HTTPS-сервера и опускается, если он стандартный
+func redirectToHTTPS(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"games_webapp/internal/clients/igdb"
+	ssogrpc "games_webapp/internal/clients/sso/grpc"
+	"games_webapp/internal/models"
+	"games_webapp/internal/routes"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage/uploads"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) migrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply database migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.storage.Migrate()
+		},
+	}
+}
+
+type recalcStep struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+func (a *app) recalcCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "recalc",
+		Short: "Recalculate derived game fields: title keys, sources, release dates, genres, companies, Steam app IDs, priority lists",
+		Long: "Statistics are computed on request, so recalc refreshes the stored fields they are built from. " +
+			"The server does the same at start; recalc lets cron do it without a restart.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			games := a.gameService()
+			steps := []struct {
+				name string
+				run  func() error
+			}{
+				{"title_keys", games.BackfillTitleKeys},
+				{"sources", games.BackfillSources},
+				{"release_dates", games.BackfillReleaseDates},
+				{"genres", games.BackfillGenres},
+				{"companies", games.BackfillCompanies},
+				{"priority_lists", games.BackfillPriorityLists},
+				{"steam_app_ids", func() error {
+					_, err := games.BackfillSteamAppIDs(cmd.Context())
+					return err
+				}},
+			}
+
+			report := make([]recalcStep, 0, len(steps))
+			failed := 0
+			for _, s := range steps {
+				step := recalcStep{Name: s.name}
+				if err := s.run(); err != nil {
+					step.Error = err.Error()
+					failed++
+				}
+				report = append(report, step)
+			}
+
+			if err := printJSON(cmd, report); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d steps failed", failed, len(steps))
+			}
+			return nil
+		},
+	}
+}
+
+func (a *app) coversCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "covers",
+		Short: "Re-download covers that are missing from the uploads folder",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := a.uploads()
+			if err != nil {
+				return err
+			}
+
+			igdbClient := igdb.New(a.log, a.cfg.TwitchClientId, a.cfg.TwitchClientSecret)
+			chain := routes.MetadataChain(a.log, a.cfg, igdbClient)
+			covers := services.NewCoverService(a.store(), a.log, chain, files, a.gameService())
+
+			report, err := covers.Refetch(cmd.Context(), dryRun)
+			if report != nil {
+				if err := printJSON(cmd, report); err != nil {
+					return err
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if len(report.Failed) > 0 {
+				return fmt.Errorf("%d of %d covers failed", len(report.Failed), report.Missing)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only find covers, do not download them")
+	return cmd
+}
+
+func (a *app) duplicatesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "duplicates",
+		Short: "Find and merge duplicate games",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List groups of games that look like duplicates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			groups, err := a.gameService().FindDuplicates(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if groups == nil {
+				groups = []models.DuplicateGroup{}
+			}
+			return printJSON(cmd, groups)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "merge <survivor-id> <duplicate-id>",
+		Short: "Move libraries, events and overrides of the duplicate to the survivor and delete the duplicate",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			survivorID, err := strconv.Atoi(args[0])
+			if err != nil || survivorID <= 0 {
+				return fmt.Errorf("invalid survivor id %q", args[0])
+			}
+			duplicateID, err := strconv.Atoi(args[1])
+			if err != nil || duplicateID <= 0 {
+				return fmt.Errorf("invalid duplicate id %q", args[1])
+			}
+
+			games := a.gameService()
+			survivor, orphanImage, err := games.MergeGames(cmd.Context(), survivorID, duplicateID)
+			if err != nil {
+				return err
+			}
+
+			// Игры уже объединены, лишний файл не повод возвращать ошибку
+			if orphanImage != "" && orphanImage != survivor.Image {
+				a.releaseImage(cmd, games, orphanImage)
+			}
+			return printJSON(cmd, survivor)
+		},
+	})
+
+	return cmd
+}
+
+func (a *app) uploadsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uploads",
+		Short: "Maintain the uploads folder",
+	}
+
+	var (
+		dryRun bool
+		minAge time.Duration
+	)
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete uploaded files that no game, override or user photo refers to",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := a.uploads()
+			if err != nil {
+				return err
+			}
+
+			sso := a.cfg.Clients.SSO
+			// Фейковый SSO не знает фото пользователей, и они ушли бы в мусор
+			if sso.Fake {
+				return fmt.Errorf("uploads prune needs the real sso, clients.sso.fake is set")
+			}
+			ssoClient, err := ssogrpc.New(cmd.Context(), a.log, sso.Address, sso.Timeout, sso.RetriesCount)
+			if err != nil {
+				return fmt.Errorf("sso client: %w", err)
+			}
+
+			if !cmd.Flags().Changed("min-age") {
+				minAge = a.cfg.UploadsGC.MinAge
+			}
+			gc := services.NewUploadsGCService(a.store(), a.log, ssoClient, files, minAge)
+			report, err := gc.Collect(cmd.Context(), dryRun)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd, report)
+		},
+	}
+	prune.Flags().BoolVar(&dryRun, "dry-run", false, "only list orphaned files")
+	prune.Flags().DurationVar(&minAge, "min-age", 0, "keep files younger than this (default uploads_gc.min_age)")
+
+	cmd.AddCommand(prune)
+	return cmd
+}
+
+// uploads открывает папку загрузок с ограничениями из конфига
+func (a *app) uploads() (*uploads.Uploads, error) {
+	files, err := uploads.NewUploads(a.cfg.UploadsPath, uploads.Limits{
+		MaxSize:      a.cfg.Images.MaxSize,
+		AllowedTypes: a.cfg.Images.AllowedTypes,
+		JPEGQuality:  a.cfg.Images.JPEGQuality,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open uploads: %w", err)
+	}
+	return files, nil
+}
+
+// releaseImage снимает ссылку на картинку и удаляет файл, если он больше не нужен
+func (a *app) releaseImage(cmd *cobra.Command, games *services.GameService, filename string) {
+	remove, err := games.ReleaseImage(cmd.Context(), filename)
+	if err != nil {
+		a.log.Error("failed to release image", slog.String("filename", filename), slog.String("error", err.Error()))
+		return
+	}
+	if !remove {
+		return
+	}
+
+	files, err := a.uploads()
+	if err == nil {
+		err = files.DeleteImage(filename)
+	}
+	if err != nil {
+		a.log.Error("failed to delete image", slog.String("filename", filename), slog.String("error", err.Error()))
+	}
+}
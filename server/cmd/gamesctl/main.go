@@ -0,0 +1,127 @@
+// gamesctl — обслуживание сервера без HTTP: миграции, пересчёт производных
+// полей, обложки, дубликаты, чистка загрузок и демо-данные для разработки. Читает тот же конфиг, что и
+// сервер, пишет отчёты в stdout в JSON, логи — в stderr. Подходит для cron:
+// при ошибке код выхода ненулевой
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/httpx"
+	"games_webapp/internal/lib/redact"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/services"
+	dbstorage "games_webapp/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// app — то, что нужно командам: конфиг, логгер и открытая база
+type app struct {
+	configPath string
+
+	cfg     *config.Config
+	log     *slog.Logger
+	storage dbstorage.Storage
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := newRootCmd().ExecuteContext(ctx); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	a := &app{}
+
+	root := &cobra.Command{
+		Use:          "gamesctl",
+		Short:        "Administration tasks for the games server",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return a.open()
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return a.close()
+		},
+	}
+	root.PersistentFlags().StringVar(&a.configPath, "config", os.Getenv("CONFIG_PATH"),
+		"path to config yaml file; empty - read config from environment only")
+
+	root.AddCommand(
+		a.migrateCmd(),
+		a.recalcCmd(),
+		a.coversCmd(),
+		a.duplicatesCmd(),
+		a.uploadsCmd(),
+		a.seedCmd(),
+	)
+	return root
+}
+
+// open читает конфиг и подключается к базе
+func (a *app) open() error {
+	cfg, err := config.Load(a.configPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	a.cfg = cfg
+	a.log = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level:       logLevel(cfg.LogLevel),
+		ReplaceAttr: redact.Attr,
+	}))
+	httpx.Setup(cfg.Outbound)
+
+	storage, err := dbstorage.New(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	a.storage = storage
+	return nil
+}
+
+func (a *app) close() error {
+	if a.storage == nil {
+		return nil
+	}
+	return a.storage.Close()
+}
+
+func (a *app) store() repository.Store {
+	return repository.New(a.storage.DB())
+}
+
+func (a *app) gameService() *services.GameService {
+	return services.NewGameService(a.store(), a.log)
+}
+
+// logLevel по умолчанию показывает только предупреждения, чтобы не засорять cron
+func logLevel(configured string) slog.Level {
+	switch strings.ToLower(configured) {
+	case "debug":
+		return slog.LevelDebug
+	case "info":
+		return slog.LevelInfo
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelWarn
+}
+
+// printJSON выводит отчёт команды
+func printJSON(cmd *cobra.Command, v interface{}) error {
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage/uploads"
+
+	"github.com/spf13/cobra"
+)
+
+// seedGame — демо-игра. Источники разные, чтобы у фронтенда были игры из
+// Steam, Википедии и добавленные вручную
+type seedGame struct {
+	Title     string
+	Summary   string
+	Developer string
+	Publisher string
+	Release   string // ГГГГ-ММ-ДД
+	Genre     string
+	Platforms string
+	URL       string
+	Source    models.GameSource
+	ID        string
+}
+
+var seedGames = []seedGame{
+	{"The Witcher 3: Wild Hunt", "Geralt of Rivia searches for his adopted daughter across a war-torn open world.", "CD Projekt Red", "CD Projekt", "2015-05-19", "Role-playing (RPG), Adventure", "PC (Microsoft Windows), PlayStation 4, Xbox One, Nintendo Switch", "https://store.steampowered.com/app/292030/", models.SourceSteam, "292030"},
+	{"Hollow Knight", "Descend into the ruined kingdom of Hallownest in a hand-drawn metroidvania.", "Team Cherry", "Team Cherry", "2017-02-24", "Platform, Adventure, Indie", "PC (Microsoft Windows), Mac, Linux, Nintendo Switch", "https://store.steampowered.com/app/367520/", models.SourceSteam, "367520"},
+	{"Disco Elysium", "A detective with amnesia solves a murder in the city of Revachol.", "ZA/UM", "ZA/UM", "2019-10-15", "Role-playing (RPG), Adventure", "PC (Microsoft Windows), Mac, PlayStation 5", "https://store.steampowered.com/app/632470/", models.SourceSteam, "632470"},
+	{"Hades", "Battle out of the Underworld in a rogue-like dungeon crawler.", "Supergiant Games", "Supergiant Games", "2020-09-17", "Hack and slash/Beat 'em up, Role-playing (RPG), Indie", "PC (Microsoft Windows), Mac, Nintendo Switch, PlayStation 5", "https://store.steampowered.com/app/1145360/", models.SourceSteam, "1145360"},
+	{"Celeste", "Help Madeline survive her inner demons on her journey to the top of Celeste Mountain.", "Maddy Makes Games", "Maddy Makes Games", "2018-01-25", "Platform, Indie", "PC (Microsoft Windows), Nintendo Switch, PlayStation 4", "https://store.steampowered.com/app/504230/", models.SourceSteam, "504230"},
+	{"Elden Ring", "Rise, Tarnished, and become an Elden Lord in the Lands Between.", "FromSoftware", "Bandai Namco Entertainment", "2022-02-25", "Role-playing (RPG), Adventure", "PC (Microsoft Windows), PlayStation 5, Xbox Series X|S", "https://store.steampowered.com/app/1245620/", models.SourceSteam, "1245620"},
+	{"Stardew Valley", "Inherit your grandfather's old farm plot and build a new life.", "ConcernedApe", "ConcernedApe", "2016-02-26", "Simulator, Role-playing (RPG), Indie", "PC (Microsoft Windows), Mac, Linux, Nintendo Switch", "https://store.steampowered.com/app/413150/", models.SourceSteam, "413150"},
+	{"Outer Wilds", "Explore a solar system trapped in an endless time loop.", "Mobius Digital", "Annapurna Interactive", "2019-05-28", "Adventure, Puzzle, Indie", "PC (Microsoft Windows), PlayStation 4, Xbox One", "https://store.steampowered.com/app/753640/", models.SourceSteam, "753640"},
+	{"Baldur's Gate 3", "Gather your party and return to the Forgotten Realms.", "Larian Studios", "Larian Studios", "2023-08-03", "Role-playing (RPG), Strategy, Turn-based strategy (TBS)", "PC (Microsoft Windows), Mac, PlayStation 5, Xbox Series X|S", "https://store.steampowered.com/app/1086940/", models.SourceSteam, "1086940"},
+	{"Portal 2", "Solve physics puzzles with a portal gun alongside GLaDOS and Wheatley.", "Valve", "Valve", "2011-04-18", "Puzzle, Platform", "PC (Microsoft Windows), Mac, Linux, PlayStation 3, Xbox 360", "https://store.steampowered.com/app/620/", models.SourceSteam, "620"},
+	{"Half-Life 2", "Gordon Freeman returns to a City 17 under alien occupation.", "Valve", "Valve", "2004-11-16", "Shooter", "PC (Microsoft Windows), Mac, Linux", "https://store.steampowered.com/app/220/", models.SourceSteam, "220"},
+	{"Cyberpunk 2077", "An open-world action adventure set in the megalopolis of Night City.", "CD Projekt Red", "CD Projekt", "2020-12-10", "Role-playing (RPG), Shooter", "PC (Microsoft Windows), PlayStation 5, Xbox Series X|S", "https://store.steampowered.com/app/1091500/", models.SourceSteam, "1091500"},
+	{"Sekiro: Shadows Die Twice", "Carve your own path of revenge as a shinobi in late 1500s Japan.", "FromSoftware", "Activision", "2019-03-22", "Adventure, Hack and slash/Beat 'em up", "PC (Microsoft Windows), PlayStation 4, Xbox One", "https://store.steampowered.com/app/814380/", models.SourceSteam, "814380"},
+	{"Inscryption", "A deck-building roguelike wrapped in an escape room and a psychological horror.", "Daniel Mullins Games", "Devolver Digital", "2021-10-19", "Card & Board Game, Puzzle, Indie", "PC (Microsoft Windows), Mac, Linux, PlayStation 5, Nintendo Switch", "https://store.steampowered.com/app/1092790/", models.SourceSteam, "1092790"},
+	{"Return of the Obra Dinn", "Identify the fates of sixty crew members of a lost merchant ship.", "Lucas Pope", "3909", "2018-10-18", "Puzzle, Adventure, Indie", "PC (Microsoft Windows), Mac, Nintendo Switch", "https://store.steampowered.com/app/653530/", models.SourceSteam, "653530"},
+	{"Factorio", "Build and maintain factories on an alien planet.", "Wube Software", "Wube Software", "2020-08-14", "Simulator, Strategy, Indie", "PC (Microsoft Windows), Mac, Linux, Nintendo Switch", "https://store.steampowered.com/app/427520/", models.SourceSteam, "427520"},
+	{"Slay the Spire", "Craft a unique deck and climb the ever-changing Spire.", "Mega Crit", "Humble Games", "2019-01-23", "Card & Board Game, Strategy, Indie", "PC (Microsoft Windows), Mac, Linux, Nintendo Switch", "https://store.steampowered.com/app/646570/", models.SourceSteam, "646570"},
+	{"Death Stranding", "Reconnect a fractured America as the porter Sam Bridges.", "Kojima Productions", "505 Games", "2019-11-08", "Adventure, Shooter", "PC (Microsoft Windows), PlayStation 4, PlayStation 5", "https://store.steampowered.com/app/1850570/", models.SourceSteam, "1850570"},
+	{"Dark Souls III", "Journey through the dying kingdom of Lothric.", "FromSoftware", "Bandai Namco Entertainment", "2016-03-24", "Role-playing (RPG), Adventure", "PC (Microsoft Windows), PlayStation 4, Xbox One", "https://store.steampowered.com/app/374320/", models.SourceSteam, "374320"},
+	{"Terraria", "Dig, fight, explore and build in a procedurally generated 2D world.", "Re-Logic", "Re-Logic", "2011-05-16", "Adventure, Platform, Indie", "PC (Microsoft Windows), Mac, Linux, Nintendo Switch", "https://store.steampowered.com/app/105600/", models.SourceSteam, "105600"},
+	{"Metroid Dread", "Samus Aran explores the planet ZDR while hunted by E.M.M.I. robots.", "MercurySteam", "Nintendo", "2021-10-08", "Platform, Shooter, Adventure", "Nintendo Switch", "https://en.wikipedia.org/wiki/Metroid_Dread", models.SourceWiki, ""},
+	{"The Legend of Zelda: Breath of the Wild", "Link awakens to explore a ruined Hyrule and defeat Calamity Ganon.", "Nintendo EPD", "Nintendo", "2017-03-03", "Adventure, Role-playing (RPG)", "Nintendo Switch, Wii U", "https://en.wikipedia.org/wiki/The_Legend_of_Zelda:_Breath_of_the_Wild", models.SourceWiki, ""},
+	{"Hollow Knight: Silksong", "Play as Hornet, princess-protector of Hallownest, in a haunted kingdom.", "Team Cherry", "Team Cherry", "2025-09-04", "Platform, Adventure, Indie", "PC (Microsoft Windows), Mac, Linux, Nintendo Switch, PlayStation 5, Xbox Series X|S", "https://store.steampowered.com/app/1030300/", models.SourceSteam, "1030300"},
+	{"Моя домашняя игра", "Игра, добавленная вручную, без внешнего источника.", "Инди-студия", "Инди-студия", "2024-06-01", "Indie", "PC (Microsoft Windows)", "", models.SourceManual, ""},
+}
+
+// seedEntry — как игра лежит в библиотеке демо-пользователя
+type seedEntry struct {
+	Status   models.GameStatus
+	Priority int
+	Favorite bool
+	Rating   int
+	Percent  int
+	Notes    string
+}
+
+// seedLibrary повторяется по кругу, сдвигаясь для каждого пользователя, чтобы
+// библиотеки разных пользователей отличались
+var seedLibrary = []seedEntry{
+	{Status: models.StatusFinished, Favorite: true, Rating: 10, Percent: 100, Notes: "Лучшая игра в библиотеке"},
+	{Status: models.StatusPlaying, Priority: 9, Percent: 45},
+	{Status: models.StatusPlanned, Priority: 7},
+	{Status: models.StatusFinished, Rating: 8, Percent: 100},
+	{Status: models.StatusDropped, Percent: 20, Notes: "Слишком сложные боссы"},
+	{Status: models.StatusPlanned, Priority: 3},
+	{Status: models.StatusPlaying, Priority: 5, Percent: 10},
+	{Status: models.StatusPlanned},
+}
+
+type seedReport struct {
+	Games         int `json:"games"`
+	CreatedGames  int `json:"created_games"`
+	LibraryLinks  int `json:"library_links"`
+	Users         int `json:"users"`
+	SkippedImages int `json:"skipped_images"`
+}
+
+func (a *app) seedCmd() *cobra.Command {
+	var (
+		users []int
+		force bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Fill a development database with demo games, libraries and covers",
+		Long: "Users live in SSO, so seed only links existing SSO user IDs (--user) to the demo games. " +
+			"Running seed again does not create duplicates: games are matched by title and year " +
+			"and games already in a library are left as they are.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if a.cfg.Env == "prod" && !force {
+				return fmt.Errorf("refusing to seed env %q without --force", a.cfg.Env)
+			}
+			if err := a.storage.Migrate(); err != nil {
+				return fmt.Errorf("migrate: %w", err)
+			}
+
+			report, err := a.seed(cmd.Context(), users)
+			if report != nil {
+				if err := printJSON(cmd, report); err != nil {
+					return err
+				}
+			}
+			return err
+		},
+	}
+	cmd.Flags().IntSliceVar(&users, "user", []int{1}, "SSO user ID to give a demo library; repeat for several users")
+	cmd.Flags().BoolVar(&force, "force", false, "allow seeding when env is prod")
+	return cmd
+}
+
+func (a *app) seed(ctx context.Context, users []int) (*seedReport, error) {
+	files, err := a.uploads()
+	if err != nil {
+		return nil, err
+	}
+	games := a.gameService()
+
+	report := &seedReport{Games: len(seedGames), Users: len(users)}
+	now := time.Now()
+
+	for u, userID := range users {
+		for i, sg := range seedGames {
+			entry := seedLibrary[(i+u)%len(seedLibrary)]
+
+			image, err := seedCover(ctx, files, games, sg.Title)
+			if err != nil {
+				a.log.Warn("failed to generate cover", slog.String("game", sg.Title), slog.String("error", err.Error()))
+				report.SkippedImages++
+			}
+
+			game := sg.model(image, now)
+			ug := entry.model(userID, now.AddDate(0, 0, -(i*11+u*3)))
+
+			_, isNew, err := games.CreateWithUserGame(ctx, game, ug)
+			if err != nil {
+				return report, fmt.Errorf("seed %q for user %d: %w", sg.Title, userID, err)
+			}
+			if isNew {
+				report.CreatedGames++
+			} else if image != "" {
+				// Игра уже была в базе и держит свою ссылку на картинку, наша лишняя
+				a.releaseSeedCover(ctx, files, games, image)
+			}
+			report.LibraryLinks++
+		}
+	}
+	return report, nil
+}
+
+func (a *app) releaseSeedCover(ctx context.Context, files *uploads.Uploads, games *services.GameService, image string) {
+	remove, err := games.ReleaseImage(ctx, image)
+	if err == nil && remove {
+		err = files.DeleteImage(image)
+	}
+	if err != nil {
+		a.log.Warn("failed to release image", slog.String("filename", image), slog.String("error", err.Error()))
+	}
+}
+
+func (sg seedGame) model(image string, now time.Time) *models.Game {
+	game := &models.Game{
+		Title:        sg.Title,
+		Preambula:    sg.Summary,
+		TitleEn:      sg.Title,
+		SummaryEn:    sg.Summary,
+		Image:        image,
+		Developer:    sg.Developer,
+		Publisher:    sg.Publisher,
+		Genre:        sg.Genre,
+		Platforms:    sg.Platforms,
+		URL:          sg.URL,
+		Source:       sg.Source,
+		ExternalID:   sg.ID,
+		CreatedAt:    &now,
+		UpdatedAt:    &now,
+		LastSyncedAt: &now,
+	}
+	if release, err := time.Parse("2006-01-02", sg.Release); err == nil {
+		game.ReleaseDate = &release
+		game.Year = release.Format("2006")
+	}
+	return game
+}
+
+func (e seedEntry) model(userID int, added time.Time) *models.UserGames {
+	ug := &models.UserGames{
+		UserID:            userID,
+		Status:            e.Status,
+		Priority:          e.Priority,
+		IsFavorite:        e.Favorite,
+		Rating:            e.Rating,
+		CompletionPercent: e.Percent,
+		Notes:             e.Notes,
+		Label:             models.PlaythroughFirstRun,
+		IsActive:          true,
+		CreatedAt:         &added,
+		UpdatedAt:         &added,
+	}
+	if e.Status != models.StatusPlanned {
+		started := added.AddDate(0, 0, 2)
+		ug.StartedAt = &started
+		ug.Sessions = 1 + e.Percent/10
+	}
+	if e.Status == models.StatusFinished {
+		finished := added.AddDate(0, 0, 30)
+		ug.FinishedAt = &finished
+	}
+	return ug
+}
+
+// seedCover рисует обложку 3:4 с градиентом, цвет которого зависит от названия,
+// и сохраняет её как обычную загрузку
+func seedCover(ctx context.Context, files *uploads.Uploads, images services.ImageRegistry, title string) (string, error) {
+	h := fnv.New32a()
+	h.Write([]byte(title))
+	sum := h.Sum32()
+	from := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+	to := color.RGBA{R: from.B / 3, G: from.R / 3, B: from.G / 3, A: 255}
+
+	const width, height = 300, 400
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		t := float64(y) / height
+		c := color.RGBA{
+			R: uint8(float64(from.R)*(1-t) + float64(to.R)*t),
+			G: uint8(float64(from.G)*(1-t) + float64(to.G)*t),
+			B: uint8(float64(from.B)*(1-t) + float64(to.B)*t),
+			A: 255,
+		}
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	data, contentType, err := files.ReadImage(&buf)
+	if err != nil {
+		return "", err
+	}
+	return services.SaveImage(ctx, files, images, data, contentType)
+}
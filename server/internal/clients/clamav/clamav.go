@@ -0,0 +1,120 @@
+// Package clamav — клиент демона ClamAV (clamd) по TCP. Файлы передаются
+// командой INSTREAM, без доступа clamd к папке загрузок
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var ErrScan = errors.New("clamd scan failed")
+
+// chunkSize — размер куска INSTREAM. clamd собирает куски до StreamMaxLength
+const chunkSize = 64 << 10
+
+type Client struct {
+	address string
+	timeout time.Duration
+	dialer  net.Dialer
+}
+
+func New(address string, timeout time.Duration) *Client {
+	return &Client{address: address, timeout: timeout}
+}
+
+// Scan проверяет data. Для заражённого файла возвращает имя сигнатуры, для
+// чистого — пустую строку
+func (c *Client) Scan(data []byte) (string, error) {
+	const op = "clients.clamav.Scan"
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	reply, err := c.command(ctx, "INSTREAM", func(conn net.Conn) error {
+		var size [4]byte
+		for len(data) > 0 {
+			n := min(len(data), chunkSize)
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err := conn.Write(size[:]); err != nil {
+				return err
+			}
+			if _, err := conn.Write(data[:n]); err != nil {
+				return err
+			}
+			data = data[n:]
+		}
+		// Кусок нулевой длины завершает поток
+		binary.BigEndian.PutUint32(size[:], 0)
+		_, err := conn.Write(size[:])
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Ответ: "stream: OK", "stream: <сигнатура> FOUND" или "... ERROR"
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("%s: %w: %s", op, ErrScan, reply)
+	}
+}
+
+// Ping проверяет, что clamd отвечает
+func (c *Client) Ping(ctx context.Context) error {
+	const op = "clients.clamav.Ping"
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	reply, err := c.command(ctx, "PING", nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("%s: %w: %s", op, ErrScan, reply)
+	}
+	return nil
+}
+
+// command отправляет команду в формате "z<команда>\0", при необходимости
+// дописывает тело и читает ответ до нулевого байта
+func (c *Client) command(ctx context.Context, name string, body func(conn net.Conn) error) (string, error) {
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := conn.Write([]byte("z" + name + "\x00")); err != nil {
+		return "", err
+	}
+	if body != nil {
+		if err := body(conn); err != nil {
+			return "", err
+		}
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimRight(reply, "\x00")), nil
+}
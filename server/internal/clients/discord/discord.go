@@ -0,0 +1,73 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrUnexpectedStatus = errors.New("unexpected status code")
+
+// maxContentLength — ограничение Discord на длину сообщения
+const maxContentLength = 2000
+
+// Client отправляет сообщения в канал Discord через входящий вебхук
+type Client struct {
+	webhookURL string
+	http       *http.Client
+}
+
+func New(webhookURL string, timeout time.Duration) *Client {
+	return &Client{
+		webhookURL: webhookURL,
+		http:       &http.Client{Timeout: timeout},
+	}
+}
+
+type message struct {
+	Content string `json:"content"`
+	// AllowedMentions с пустым списком не даёт тексту из библиотеки упомянуть @everyone
+	AllowedMentions struct {
+		Parse []string `json:"parse"`
+	} `json:"allowed_mentions"`
+}
+
+// Send публикует сообщение. Слишком длинный текст обрезается
+func (c *Client) Send(ctx context.Context, content string) error {
+	const op = "clients.discord.Send"
+
+	if utf8.RuneCountInString(content) > maxContentLength {
+		content = string([]rune(content)[:maxContentLength-1]) + "…"
+	}
+
+	msg := message{Content: content}
+	msg.AllowedMentions.Parse = []string{}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %w: %d", op, ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,451 @@
+package igdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"games_webapp/internal/httpx"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	twitchLoginURL = "https://id.twitch.tv/oauth2/token"
+	gamesURL       = "https://api.igdb.com/v4/games"
+)
+
+var (
+	ErrGameNotFound     = errors.New("game not found")
+	ErrUnexpectedStatus = errors.New("unexpected status code")
+)
+
+// tokenMargin — за сколько до истечения токен считается устаревшим
+const tokenMargin = time.Minute
+
+// gameFields — поля игры, которые запрашиваются у IGDB для GameInfo
+const gameFields = `
+			id,
+			name,
+			summary,
+			url,
+			cover.url,
+			involved_companies.company.name,
+			involved_companies.publisher,
+			involved_companies.developer,
+			first_release_date,
+			aggregated_rating,
+			genres.name,
+			platforms.name,
+			platforms.abbreviation`
+
+type Client struct {
+	clientID     string
+	clientSecret string
+	http         *http.Client
+	log          *slog.Logger
+
+	// Одинаковые запросы, пришедшие одновременно (например, несколько пользователей
+	// импортируют одну и ту же популярную игру), выполняются один раз
+	group singleflight.Group
+
+	// Токен Twitch живёт около двух месяцев, поэтому переиспользуется между запросами
+	mu      sync.Mutex
+	token   *Token
+	expires time.Time
+}
+
+func New(log *slog.Logger, clientID, clientSecret string) *Client {
+	return &Client{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		http:         httpx.Client(0),
+		log:          log,
+	}
+}
+
+type Token struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+type GameInfo struct {
+	ID          int
+	Name        string
+	Summary     string
+	URL         string
+	CoverURL    string
+	ReleaseDate string
+	Rating      float64
+	Genres      []string
+	Developers  []string
+	Publishers  []string
+	Platforms   []string
+}
+
+type gameResponse struct {
+	ID               int     `json:"id"`
+	Name             string  `json:"name"`
+	Summary          string  `json:"summary"`
+	FirstReleaseDate int     `json:"first_release_date"`
+	URL              string  `json:"url"`
+	AggregatedRating float64 `json:"aggregated_rating"`
+	Cover            *struct {
+		URL string `json:"url"`
+	} `json:"cover"`
+	InvolvedCompanies []struct {
+		Company *struct {
+			Name string `json:"name"`
+		} `json:"company"`
+		Publisher bool `json:"publisher"`
+		Developer bool `json:"developer"`
+	} `json:"involved_companies"`
+	Genres []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+	Platforms []struct {
+		Name         string `json:"name"`
+		Abbreviation string `json:"abbreviation"`
+	} `json:"platforms"`
+}
+
+func (g *gameResponse) toInfo() GameInfo {
+	info := GameInfo{
+		ID:      g.ID,
+		Name:    g.Name,
+		Summary: g.Summary,
+		URL:     g.URL,
+		Rating:  g.AggregatedRating,
+	}
+
+	for _, ic := range g.InvolvedCompanies {
+		if ic.Company == nil {
+			continue
+		}
+		if ic.Developer {
+			info.Developers = append(info.Developers, ic.Company.Name)
+		}
+		if ic.Publisher {
+			info.Publishers = append(info.Publishers, ic.Company.Name)
+		}
+	}
+
+	if g.FirstReleaseDate != 0 {
+		info.ReleaseDate = time.Unix(int64(g.FirstReleaseDate), 0).Format("2006-01-02")
+	}
+
+	if g.Cover != nil {
+		info.CoverURL = "https:" + strings.Replace(g.Cover.URL, "t_thumb", "t_1080p", 1)
+	}
+
+	for _, genre := range g.Genres {
+		info.Genres = append(info.Genres, genre.Name)
+	}
+
+	// Короткие названия (PC, PS5, Switch) удобнее полных, но есть не у всех платформ
+	for _, p := range g.Platforms {
+		if p.Abbreviation != "" {
+			info.Platforms = append(info.Platforms, p.Abbreviation)
+		} else {
+			info.Platforms = append(info.Platforms, p.Name)
+		}
+	}
+
+	return info
+}
+
+// Login возвращает токен Twitch. Пока полученный ранее токен не истёк,
+// новый не запрашивается
+func (c *Client) Login(ctx context.Context) (*Token, error) {
+	const op = "igdb.Login"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != nil && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	query := url.Values{}
+	query.Set("client_id", c.clientID)
+	query.Set("client_secret", c.clientSecret)
+	query.Set("grant_type", "client_credentials")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, twitchLoginURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %w: %d", op, ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	var token Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	c.token = &token
+	c.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenMargin)
+
+	return &token, nil
+}
+
+// resetToken забывает сохранённый токен, если IGDB его больше не принимает
+func (c *Client) resetToken(token *Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == token {
+		c.token = nil
+	}
+}
+
+// SearchGame ищет одну игру по названию. Одновременные запросы с одинаковым
+// названием объединяются в один запрос к IGDB
+func (c *Client) SearchGame(ctx context.Context, name string, token *Token) (*GameInfo, error) {
+	const op = "igdb.SearchGame"
+
+	key := "search:" + strings.ToLower(strings.TrimSpace(name))
+
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		// Запрос не должен отмениться из-за одного ушедшего клиента,
+		// его результат ждут и остальные
+		return c.searchGame(context.WithoutCancel(ctx), name, token)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s: %w", op, ctx.Err())
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		if res.Shared {
+			c.log.Debug("igdb search coalesced", slog.String("operation", op), slog.String("game", name))
+		}
+		info := *res.Val.(*GameInfo)
+		return &info, nil
+	}
+}
+
+func (c *Client) searchGame(ctx context.Context, name string, token *Token) (*GameInfo, error) {
+	const op = "igdb.searchGame"
+
+	body := fmt.Sprintf(`
+		search %s;
+		fields %s;
+		where version_parent = null & game_type = (0, 8, 9, 10) & (aggregated_rating != null | (aggregated_rating = null & hypes != null & hypes > 10));
+		limit 1;
+	`, strconv.Quote(name), gameFields)
+
+	var result []gameResponse
+	if err := c.query(ctx, body, token, &result); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrGameNotFound)
+	}
+
+	info := result[0].toInfo()
+	return &info, nil
+}
+
+// Search возвращает до limit игр, подходящих под запрос, чтобы пользователь
+// сам выбрал нужную. В отличие от SearchGame, малоизвестные игры не отсекаются.
+// Одинаковые одновременные запросы объединяются, как и в SearchGame
+func (c *Client) Search(ctx context.Context, query string, limit int, token *Token) ([]GameInfo, error) {
+	const op = "igdb.Search"
+
+	key := fmt.Sprintf("candidates:%d:%s", limit, strings.ToLower(strings.TrimSpace(query)))
+
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		return c.search(context.WithoutCancel(ctx), query, limit, token)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s: %w", op, ctx.Err())
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		games := res.Val.([]GameInfo)
+		return append([]GameInfo(nil), games...), nil
+	}
+}
+
+func (c *Client) search(ctx context.Context, query string, limit int, token *Token) ([]GameInfo, error) {
+	const op = "igdb.search"
+
+	body := fmt.Sprintf(`
+		search %s;
+		fields %s;
+		where version_parent = null & game_type = (0, 8, 9, 10);
+		limit %d;
+	`, strconv.Quote(query), gameFields, limit)
+
+	var result []gameResponse
+	if err := c.query(ctx, body, token, &result); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	games := make([]GameInfo, 0, len(result))
+	for i := range result {
+		games = append(games, result[i].toInfo())
+	}
+
+	return games, nil
+}
+
+// GetByIDs возвращает игры по их ID в IGDB. Ненайденные ID пропускаются
+func (c *Client) GetByIDs(ctx context.Context, ids []int, token *Token) ([]GameInfo, error) {
+	const op = "igdb.GetByIDs"
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = strconv.Itoa(id)
+	}
+
+	body := fmt.Sprintf(`
+		fields %s;
+		where id = (%s);
+		limit %d;
+	`, gameFields, strings.Join(list, ", "), len(ids))
+
+	var result []gameResponse
+	if err := c.query(ctx, body, token, &result); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	games := make([]GameInfo, 0, len(result))
+	for i := range result {
+		games = append(games, result[i].toInfo())
+	}
+
+	return games, nil
+}
+
+// GetBySlug возвращает игру по slug из ссылки igdb.com/games/<slug>
+func (c *Client) GetBySlug(ctx context.Context, slug string, token *Token) (*GameInfo, error) {
+	const op = "igdb.GetBySlug"
+
+	body := fmt.Sprintf(`
+		fields %s;
+		where slug = %s;
+		limit 1;
+	`, gameFields, strconv.Quote(slug))
+
+	var result []gameResponse
+	if err := c.query(ctx, body, token, &result); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrGameNotFound)
+	}
+
+	info := result[0].toInfo()
+	return &info, nil
+}
+
+// FindSimilar возвращает игры с высоким рейтингом, у которых совпадает жанр или разработчик
+func (c *Client) FindSimilar(ctx context.Context, genres, developers []string, minRating int, token *Token) ([]GameInfo, error) {
+	const op = "igdb.FindSimilar"
+
+	var filters []string
+	if len(genres) > 0 {
+		filters = append(filters, fmt.Sprintf("genres.name = (%s)", quoteList(genres)))
+	}
+	if len(developers) > 0 {
+		filters = append(filters, fmt.Sprintf("involved_companies.company.name = (%s)", quoteList(developers)))
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	body := fmt.Sprintf(`
+		fields %s;
+		where (%s) & aggregated_rating >= %d & version_parent = null & game_type = (0, 8, 9, 10);
+		sort aggregated_rating desc;
+		limit 100;
+	`, gameFields, strings.Join(filters, " | "), minRating)
+
+	var result []gameResponse
+	if err := c.query(ctx, body, token, &result); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	games := make([]GameInfo, 0, len(result))
+	for i := range result {
+		games = append(games, result[i].toInfo())
+	}
+
+	return games, nil
+}
+
+func (c *Client) query(ctx context.Context, body string, token *Token, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gamesURL, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Client-ID", c.clientID)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		c.resetToken(token)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	return json.Unmarshal(bodyBytes, out)
+}
+
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
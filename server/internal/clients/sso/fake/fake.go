@@ -0,0 +1,429 @@
+// Package fake — SSO в памяти для локальной разработки и интеграционных тестов.
+// Он слушает настоящий gRPC-порт, поэтому сервер ходит в него своим обычным
+// клиентом со всеми перехватчиками
+package fake
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	ssov1 "github.com/Nergous/sso_protos/gen/go/sso"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Options задают поведение фейкового SSO
+type Options struct {
+	// AnyLogin впускает с любым email и паролем: пользователь заводится при первом
+	// входе. Выданные токены принимаются и после перезапуска, пока не отозваны.
+	// Без него входят только заведённые пользователи с верным паролем
+	AnyLogin bool
+	// Admins — email пользователей с правами администратора
+	Admins []string
+}
+
+// User — пользователь фейкового SSO
+type User struct {
+	ID          uint32
+	Email       string
+	Password    string
+	SteamURL    string
+	PathToPhoto string
+	Admin       bool
+}
+
+// Server — фейковый SSO. id пользователя выводится из email, поэтому один и тот
+// же email получает тот же id и после перезапуска, и библиотека в базе не теряется
+type Server struct {
+	ssov1.UnimplementedAuthServer
+	ssov1.UnimplementedAppServer
+	ssov1.UnimplementedUserServer
+
+	anyLogin bool
+	admins   map[string]bool
+
+	server   *grpc.Server
+	listener net.Listener
+
+	mu      sync.Mutex
+	seq     int
+	users   map[uint32]*User
+	access  map[string]uint32
+	refresh map[string]uint32
+	revoked map[string]bool
+}
+
+func New(opts Options) *Server {
+	s := &Server{
+		anyLogin: opts.AnyLogin,
+		admins:   make(map[string]bool, len(opts.Admins)),
+		users:    make(map[uint32]*User),
+		access:   make(map[string]uint32),
+		refresh:  make(map[string]uint32),
+		revoked:  make(map[string]bool),
+	}
+	for _, email := range opts.Admins {
+		s.admins[normalizeEmail(email)] = true
+	}
+	return s
+}
+
+// Start начинает слушать addr, например 127.0.0.1:0 для свободного порта
+func (s *Server) Start(addr string) error {
+	const op = "fake.Start"
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.listener = lis
+	s.server = grpc.NewServer()
+	ssov1.RegisterAuthServer(s.server, s)
+	ssov1.RegisterAppServer(s.server, s)
+	ssov1.RegisterUserServer(s.server, s)
+
+	go func() { _ = s.server.Serve(lis) }()
+	return nil
+}
+
+// Addr — адрес для clients.sso.address
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Stop останавливает сервер
+func (s *Server) Stop() {
+	if s.server != nil {
+		s.server.Stop()
+	}
+}
+
+// UserID — id, который получит пользователь с этим email
+func UserID(email string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(normalizeEmail(email)))
+	// Старший бит сбрасываем, чтобы id помещался в int32 и в колонки со знаком
+	if id := h.Sum32() & 0x7fffffff; id != 0 {
+		return id
+	}
+	return 1
+}
+
+// AddUser заводит пользователя без регистрации через API
+func (s *Server) AddUser(email, password string, admin bool) (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, err := s.create(email, password)
+	if err != nil {
+		return 0, fmt.Errorf("fake.AddUser: %w", err)
+	}
+	u.Admin = u.Admin || admin
+	return u.ID, nil
+}
+
+// SetAdmin выдаёт или забирает права администратора
+func (s *Server) SetAdmin(userID uint32, admin bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[userID]; ok {
+		u.Admin = admin
+	}
+}
+
+// User возвращает копию пользователя
+func (s *Server) User(userID uint32) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return User{}, false
+	}
+	return *u, true
+}
+
+// Token выдаёт access-токен пользователю, как после входа
+func (s *Server) Token(userID uint32) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return ""
+	}
+	access, _ := s.issue(u)
+	return access
+}
+
+// RevokeTokens отзывает все токены пользователя
+func (s *Server) RevokeTokens(userID uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revoke(userID)
+}
+
+// revoke отзывает токены пользователя. Вызывается под s.mu
+func (s *Server) revoke(userID uint32) {
+	for _, tokens := range []map[string]uint32{s.access, s.refresh} {
+		for token, id := range tokens {
+			if id == userID {
+				delete(tokens, token)
+				s.revoked[token] = true
+			}
+		}
+	}
+}
+
+// create заводит пользователя. Вызывается под s.mu
+func (s *Server) create(email, password string) (*User, error) {
+	email = normalizeEmail(email)
+	id := UserID(email)
+	if u, ok := s.users[id]; ok {
+		if u.Email == email {
+			return nil, fmt.Errorf("%s already exists", email)
+		}
+		return nil, fmt.Errorf("%s has the same id as %s", email, u.Email)
+	}
+
+	u := &User{ID: id, Email: email, Password: password, Admin: s.admins[email]}
+	s.users[id] = u
+	return u, nil
+}
+
+// issue выдаёт пару токенов. Токен несёт email, чтобы в режиме AnyLogin его
+// можно было принять и после перезапуска. Вызывается под s.mu
+func (s *Server) issue(u *User) (access, refresh string) {
+	s.seq++
+	email := base64.RawURLEncoding.EncodeToString([]byte(u.Email))
+	access = fmt.Sprintf("fake-access.%s.%d", email, s.seq)
+	refresh = fmt.Sprintf("fake-refresh.%s.%d", email, s.seq)
+	s.access[access] = u.ID
+	s.refresh[refresh] = u.ID
+	return access, refresh
+}
+
+// lookup находит пользователя по токену. В режиме AnyLogin незнакомый, но
+// правильно собранный токен принимается, а его пользователь заводится заново.
+// Вызывается под s.mu
+func (s *Server) lookup(tokens map[string]uint32, prefix, token string) *User {
+	if id, ok := tokens[token]; ok {
+		return s.users[id]
+	}
+	if !s.anyLogin || s.revoked[token] {
+		return nil
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != prefix {
+		return nil
+	}
+	email, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || len(email) == 0 {
+		return nil
+	}
+
+	u, ok := s.users[UserID(string(email))]
+	if !ok {
+		if u, err = s.create(string(email), ""); err != nil {
+			return nil
+		}
+	}
+	tokens[token] = u.ID
+	return u
+}
+
+func (s *Server) byEmail(email string) *User {
+	u, ok := s.users[UserID(email)]
+	if !ok || u.Email != normalizeEmail(email) {
+		return nil
+	}
+	return u
+}
+
+func (s *Server) Register(_ context.Context, req *ssov1.RegisterRequest) (*ssov1.RegisterResponse, error) {
+	if req.GetEmail() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, err := s.create(req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	u.SteamURL = req.GetSteamUrl()
+	u.PathToPhoto = req.GetPathToPhoto()
+	return &ssov1.RegisterResponse{UserId: u.ID}, nil
+}
+
+func (s *Server) Login(_ context.Context, req *ssov1.LoginRequest) (*ssov1.LoginResponse, error) {
+	if req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.byEmail(req.GetEmail())
+	switch {
+	case u == nil && s.anyLogin:
+		var err error
+		if u, err = s.create(req.GetEmail(), req.GetPassword()); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	case u == nil, !s.anyLogin && u.Password != req.GetPassword():
+		return nil, status.Error(codes.InvalidArgument, "invalid email or password")
+	}
+
+	access, refresh := s.issue(u)
+	return &ssov1.LoginResponse{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+func (s *Server) Logout(_ context.Context, req *ssov1.LogoutRequest) (*ssov1.LogoutResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.refresh, req.GetToken())
+	s.revoked[req.GetToken()] = true
+	return &ssov1.LogoutResponse{}, nil
+}
+
+func (s *Server) Refresh(_ context.Context, req *ssov1.RefreshRequest) (*ssov1.LoginResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.lookup(s.refresh, "fake-refresh", req.GetRefreshToken())
+	if u == nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+	}
+	delete(s.refresh, req.GetRefreshToken())
+	s.revoked[req.GetRefreshToken()] = true
+
+	access, refresh := s.issue(u)
+	return &ssov1.LoginResponse{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+func (s *Server) ValidateToken(_ context.Context, req *ssov1.ValidateTokenRequest) (*ssov1.ValidateTokenResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.lookup(s.access, "fake-access", req.GetToken())
+	if u == nil {
+		return &ssov1.ValidateTokenResponse{}, nil
+	}
+	return &ssov1.ValidateTokenResponse{UserId: u.ID, Valid: true}, nil
+}
+
+func (s *Server) IsAdmin(_ context.Context, req *ssov1.IsAdminRequest) (*ssov1.IsAdminResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[req.GetUserId()]
+	return &ssov1.IsAdminResponse{IsAdmin: ok && u.Admin}, nil
+}
+
+func (s *Server) GetAllUsersForApp(_ context.Context, _ *ssov1.GetAllUsersForAppRequest) (*ssov1.GetAllUsersForAppResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &ssov1.GetAllUsersForAppResponse{}
+	for _, u := range s.sorted() {
+		resp.Users = append(resp.Users, &ssov1.AppUser{
+			Id:          u.ID,
+			Email:       u.Email,
+			SteamUrl:    u.SteamURL,
+			PathToPhoto: u.PathToPhoto,
+			IsAdmin:     u.Admin,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) UserInfo(_ context.Context, req *ssov1.UserInfoRequest) (*ssov1.UserInfoResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[req.GetUserId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &ssov1.UserInfoResponse{Email: u.Email, SteamUrl: u.SteamURL, PathToPhoto: u.PathToPhoto}, nil
+}
+
+func (s *Server) GetAllUsers(_ context.Context, _ *ssov1.GetAllUsersRequest) (*ssov1.GetAllUsersResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &ssov1.GetAllUsersResponse{}
+	for _, u := range s.sorted() {
+		resp.Users = append(resp.Users, &ssov1.UserModel{
+			Id:          u.ID,
+			Email:       u.Email,
+			SteamUrl:    u.SteamURL,
+			PathToPhoto: u.PathToPhoto,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) UpdateUser(_ context.Context, req *ssov1.UpdateUserRequest) (*ssov1.UpdateUserResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	// Смена email поменяла бы id, поэтому фейк её не поддерживает
+	if email := normalizeEmail(req.GetEmail()); email != "" && email != u.Email {
+		return nil, status.Error(codes.Unimplemented, "fake sso cannot change email")
+	}
+	if req.GetPassword() != "" {
+		u.Password = req.GetPassword()
+	}
+	if req.GetSteamUrl() != "" {
+		u.SteamURL = req.GetSteamUrl()
+	}
+	if req.GetPathToPhoto() != "" {
+		u.PathToPhoto = req.GetPathToPhoto()
+	}
+	return &ssov1.UpdateUserResponse{}, nil
+}
+
+func (s *Server) DeleteUser(_ context.Context, req *ssov1.DeleteUserRequest) (*ssov1.DeleteUserResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[req.GetId()]; !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	delete(s.users, req.GetId())
+	s.revoke(req.GetId())
+	return &ssov1.DeleteUserResponse{}, nil
+}
+
+func (s *Server) sorted() []*User {
+	users := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
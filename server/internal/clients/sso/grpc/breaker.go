@@ -0,0 +1,149 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker — автомат защиты от недоступного SSO. После threshold сбоев подряд он
+// размыкается и cooldown отвечает ошибкой сразу, не дожидаясь таймаутов. Затем
+// пропускает один пробный вызов: успех замыкает цепь, сбой размыкает снова
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	log       *slog.Logger
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration, log *slog.Logger) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, log: log}
+}
+
+// allow сообщает, можно ли сделать вызов
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		// Пока идёт пробный вызов, остальные ждут его результата
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if b.state != breakerClosed {
+		b.setState(breakerClosed)
+	}
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.setState(breakerOpen)
+	}
+}
+
+// isOpen сообщает, что вызовы сейчас не пропускаются
+func (b *breaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state == breakerOpen && time.Since(b.openedAt) < b.cooldown
+}
+
+func (b *breaker) setState(s breakerState) {
+	b.log.Warn("sso circuit breaker", slog.String("from", b.state.String()), slog.String("to", s.String()))
+	b.state = s
+}
+
+// unavailable сообщает, что ошибка говорит о недоступности SSO, а не об ответе
+// на сам запрос: только такие ошибки размыкают цепь
+func unavailable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// interceptor пропускает вызовы через breaker. Пока цепь разомкнута, вызов сразу
+// завершается с codes.Unavailable
+func (c *Client) breakerInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	b := c.breaker
+	if b == nil {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	if !b.allow() {
+		return status.Error(codes.Unavailable, "sso circuit breaker is open")
+	}
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if err != nil && unavailable(err) {
+		b.failure()
+	} else {
+		b.success()
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// maxCachedTokens ограничивает размер кэша, см. evict
+const maxCachedTokens = 10000
+
+type cachedToken struct {
+	userID    uint32
+	checkedAt time.Time
+}
+
+// tokenCache хранит результаты успешной проверки access-токенов. Свежая запись
+// (моложе ttl) отдаётся без вызова SSO; устаревшая, но моложе stale, — только
+// когда SSO недоступен. Токены хранятся по SHA-256
+type tokenCache struct {
+	ttl   time.Duration
+	stale time.Duration
+
+	mu     sync.Mutex
+	tokens map[[sha256.Size]byte]cachedToken
+}
+
+func newTokenCache(ttl, stale time.Duration) *tokenCache {
+	if stale < ttl {
+		stale = ttl
+	}
+	return &tokenCache{
+		ttl:    ttl,
+		stale:  stale,
+		tokens: make(map[[sha256.Size]byte]cachedToken),
+	}
+}
+
+// get возвращает пользователя токена, если запись моложе maxAge
+func (c *tokenCache) get(token string, maxAge time.Duration) (uint32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.tokens[sha256.Sum256([]byte(token))]
+	if !ok || time.Since(entry.checkedAt) > maxAge {
+		return 0, false
+	}
+	return entry.userID, true
+}
+
+func (c *tokenCache) put(token string, userID uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.tokens) >= maxCachedTokens {
+		c.evict()
+	}
+	c.tokens[sha256.Sum256([]byte(token))] = cachedToken{userID: userID, checkedAt: time.Now()}
+}
+
+// evict удаляет записи старше stale, а если таких нет — все
+func (c *tokenCache) evict() {
+	for key, entry := range c.tokens {
+		if time.Since(entry.checkedAt) > c.stale {
+			delete(c.tokens, key)
+		}
+	}
+	if len(c.tokens) >= maxCachedTokens {
+		c.tokens = make(map[[sha256.Size]byte]cachedToken)
+	}
+}
@@ -2,24 +2,42 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"games_webapp/internal/lib/jwt"
+	"games_webapp/internal/tracing"
+
 	ssov1 "github.com/Nergous/sso_protos/gen/go/sso"
 
 	grpclog "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrInvalidCredentials — SSO отклонил пару email и пароль
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUserExists — email уже занят другим пользователем
+	ErrUserExists = errors.New("user already exists")
 )
 
 type Client struct {
+	cc   *grpc.ClientConn
 	auth ssov1.AuthClient
 	app  ssov1.AppClient
 	user ssov1.UserClient
 	log  *slog.Logger
+
+	breaker *breaker
+	tokens  *tokenCache
+	local   *jwt.Verifier
 }
 
 func New(
@@ -41,10 +59,15 @@ func New(
 		grpclog.WithLogOnEvents(grpclog.PayloadReceived, grpclog.PayloadSent),
 	}
 
+	c := &Client{log: log}
+
+	// breaker стоит снаружи retry, чтобы все повторы одного вызова считались одним сбоем
 	cc, err := grpc.DialContext(ctx, addr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithChainUnaryInterceptor(
+			tracing.UnaryClientInterceptor,
 			grpclog.UnaryClientInterceptor(InterceptorLogger(log), logOpts...),
+			c.breakerInterceptor,
 			grpcretry.UnaryClientInterceptor(retryOpts...),
 		),
 	)
@@ -52,12 +75,56 @@ func New(
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Client{
-		auth: ssov1.NewAuthClient(cc),
-		app:  ssov1.NewAppClient(cc),
-		user: ssov1.NewUserClient(cc),
-		log:  log,
-	}, nil
+	c.cc = cc
+	c.auth = ssov1.NewAuthClient(cc)
+	c.app = ssov1.NewAppClient(cc)
+	c.user = ssov1.NewUserClient(cc)
+
+	return c, nil
+}
+
+// UseBreaker включает размыкание цепи: после threshold сбоев связи подряд вызовы
+// SSO cooldown сразу завершаются с codes.Unavailable. IsAdmin в это время
+// возвращает ошибку, и пользователи получают обычные права
+func (c *Client) UseBreaker(threshold int, cooldown time.Duration) {
+	c.breaker = newBreaker(threshold, cooldown, c.log)
+}
+
+// UseTokenCache включает кэш проверки access-токенов: ttl запись отдаётся без
+// обращения к SSO, а до stale — если SSO недоступен
+func (c *Client) UseTokenCache(ttl, stale time.Duration) {
+	c.tokens = newTokenCache(ttl, stale)
+}
+
+// UseLocalValidation включает проверку access-токенов без обращения к SSO.
+// SSO спрашивается, только если подпись не сошлась (например, сменили ключ)
+func (c *Client) UseLocalValidation(v *jwt.Verifier) {
+	c.local = v
+}
+
+// BreakerOpen сообщает, что вызовы SSO сейчас не выполняются
+func (c *Client) BreakerOpen() bool {
+	return c.breaker != nil && c.breaker.isOpen()
+}
+
+// Ping проверяет, что соединение с SSO установлено или устанавливается успешно
+func (c *Client) Ping(ctx context.Context) error {
+	const op = "grpc.Ping"
+
+	c.cc.Connect()
+	for {
+		state := c.cc.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Shutdown:
+			return fmt.Errorf("%s: connection is shut down", op)
+		}
+
+		if !c.cc.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("%s: %w (state %s)", op, ctx.Err(), state)
+		}
+	}
 }
 
 func InterceptorLogger(l *slog.Logger) grpclog.Logger {
@@ -67,12 +134,38 @@ func InterceptorLogger(l *slog.Logger) grpclog.Logger {
 }
 
 func (c *Client) ValidateToken(ctx context.Context, token string) (uint32, bool, error) {
+	if c.local != nil {
+		claims, err := c.local.Verify(token)
+		switch {
+		case err == nil:
+			return claims.UserID, true, nil
+		case errors.Is(err, jwt.ErrExpired), errors.Is(err, jwt.ErrMalformed), errors.Is(err, jwt.ErrWrongApp):
+			return 0, false, nil
+		}
+	}
+
+	if c.tokens != nil {
+		if userID, ok := c.tokens.get(token, c.tokens.ttl); ok {
+			return userID, true, nil
+		}
+	}
+
 	resp, err := c.auth.ValidateToken(ctx, &ssov1.ValidateTokenRequest{Token: token})
 	if err != nil {
+		if c.tokens != nil && unavailable(err) {
+			if userID, ok := c.tokens.get(token, c.tokens.stale); ok {
+				c.log.Warn("sso unavailable, using cached token validation", slog.String("error", err.Error()))
+				return userID, true, nil
+			}
+		}
 		c.log.Error("sso.ValidateToken failed", slog.String("error", err.Error()))
 		return 0, false, err
 	}
 
+	if c.tokens != nil && resp.GetValid() {
+		c.tokens.put(token, resp.GetUserId())
+	}
+
 	return resp.GetUserId(), resp.GetValid(), nil
 }
 
@@ -90,6 +183,10 @@ func (c *Client) Login(ctx context.Context, email, password string, appID uint32
 	resp, err := c.auth.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password, AppId: appID})
 	if err != nil {
 		c.log.Error("sso.Login failed", slog.String("error", err.Error()))
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.Unauthenticated, codes.NotFound:
+			return "", "", fmt.Errorf("%w: %s", ErrInvalidCredentials, status.Convert(err).Message())
+		}
 		return "", "", err
 	}
 
@@ -162,6 +259,9 @@ func (c *Client) UpdateUser(ctx context.Context, user *ssov1.UpdateUserRequest)
 	resp, err := c.user.UpdateUser(ctx, user)
 	if err != nil {
 		c.log.Error("sso.UpdateUser failed", slog.String("error", err.Error()))
+		if status.Code(err) == codes.AlreadyExists {
+			return nil, fmt.Errorf("%w: %s", ErrUserExists, status.Convert(err).Message())
+		}
 		return nil, err
 	}
 
@@ -0,0 +1,121 @@
+package grpc_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ssogrpc "games_webapp/internal/clients/sso/grpc"
+	"games_webapp/internal/lib/jwt"
+
+	ssov1 "github.com/Nergous/sso_protos/gen/go/sso"
+	"google.golang.org/grpc"
+)
+
+const (
+	testSecret = "test-app-secret"
+	ssoUserID  = 7
+)
+
+// authStub считает обращения к ValidateToken и принимает любой токен
+type authStub struct {
+	ssov1.UnimplementedAuthServer
+	calls atomic.Int32
+}
+
+func (s *authStub) ValidateToken(context.Context, *ssov1.ValidateTokenRequest) (*ssov1.ValidateTokenResponse, error) {
+	s.calls.Add(1)
+	return &ssov1.ValidateTokenResponse{UserId: ssoUserID, Valid: true}, nil
+}
+
+func signHS256(t *testing.T, secret, alg string, claims jwt.Claims) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signed := encode(map[string]string{"alg": alg}) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidateTokenLocal(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &authStub{}
+	server := grpc.NewServer()
+	ssov1.RegisterAuthServer(server, stub)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	client, err := ssogrpc.New(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), lis.Addr().String(), time.Second, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.UseLocalValidation(jwt.NewHMAC(testSecret, 1))
+
+	claims := func(change func(c *jwt.Claims)) jwt.Claims {
+		c := jwt.Claims{UserID: 42, AppID: 1, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+		if change != nil {
+			change(&c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		wantID    uint32
+		wantValid bool
+		wantSSO   bool
+	}{
+		{"valid", signHS256(t, testSecret, "HS256", claims(nil)), 42, true, false},
+
+		// Ответ однозначен и без SSO: 401 сразу
+		{"expired", signHS256(t, testSecret, "HS256", claims(func(c *jwt.Claims) {
+			c.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+		})), 0, false, false},
+		{"wrong app", signHS256(t, testSecret, "HS256", claims(func(c *jwt.Claims) { c.AppID = 2 })), 0, false, false},
+		{"zero uid", signHS256(t, testSecret, "HS256", claims(func(c *jwt.Claims) { c.UserID = 0 })), 0, false, false},
+		{"not a jwt", "opaque-token", 0, false, false},
+		{"empty alg", signHS256(t, testSecret, "", claims(nil)), 0, false, false},
+
+		// Подпись не сошлась или алгоритм не наш: решает SSO, например после смены ключа
+		{"rotated secret", signHS256(t, "rotated-secret", "HS256", claims(nil)), ssoUserID, true, true},
+		{"unsupported alg", signHS256(t, testSecret, "RS256", claims(nil)), ssoUserID, true, true},
+		{"alg none", signHS256(t, testSecret, "none", claims(nil)), ssoUserID, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := stub.calls.Load()
+
+			userID, valid, err := client.ValidateToken(context.Background(), tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if userID != tt.wantID || valid != tt.wantValid {
+				t.Errorf("got (%d, %v), want (%d, %v)", userID, valid, tt.wantID, tt.wantValid)
+			}
+			if asked := stub.calls.Load() != before; asked != tt.wantSSO {
+				t.Errorf("asked sso = %v, want %v", asked, tt.wantSSO)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const apiURL = "https://api.telegram.org/bot"
+
+var ErrAPI = errors.New("telegram api error")
+
+// Client — минимальный клиент Bot API: отправка сообщений и чтение обновлений
+type Client struct {
+	token string
+	http  *http.Client
+}
+
+func New(token string, timeout time.Duration) *Client {
+	return &Client{
+		token: token,
+		http:  &http.Client{Timeout: timeout},
+	}
+}
+
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+type Message struct {
+	Chat Chat   `json:"chat"`
+	Text string `json:"text"`
+}
+
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+type response struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// SendMessage отправляет текстовое сообщение в чат
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) error {
+	const op = "clients.telegram.SendMessage"
+
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.method("sendMessage"), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if _, err := c.do(req); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetUpdates возвращает входящие сообщения начиная с offset. Telegram помечает
+// прочитанными все обновления с id меньше offset
+func (c *Client) GetUpdates(ctx context.Context, offset int64) ([]Update, error) {
+	const op = "clients.telegram.GetUpdates"
+
+	query := url.Values{}
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	query.Set("allowed_updates", `["message"]`)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.method("getUpdates")+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var updates []Update
+	if err := json.Unmarshal(result, &updates); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return updates, nil
+}
+
+func (c *Client) method(name string) string {
+	return apiURL + c.token + "/" + name
+}
+
+func (c *Client) do(req *http.Request) (json.RawMessage, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// В тексте ошибки есть URL вместе с токеном бота
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return nil, urlErr.Err
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	if !r.OK {
+		return nil, fmt.Errorf("%w: %d %s", ErrAPI, resp.StatusCode, r.Description)
+	}
+
+	return r.Result, nil
+}
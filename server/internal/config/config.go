@@ -4,71 +4,568 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
+	"games_webapp/internal/lib/redact"
+
 	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Env                string `yaml:"env" env:"ENV" env-required:"true"`
-	UploadsPath        string `yaml:"uploads_path" env:"UPLOADS_PATH" env-required:"true"`
-	TwitchClientId     string `yaml:"twitch_client_id" env:"TWITCH_CLIENT_ID" env-required:"true"`
-	TwitchClientSecret string `yaml:"twitch_client_secret" env:"TWITCH_CLIENT_SECRET" env-required:"true"`
-	Database           `yaml:"database"`
-	HTTPServer         `yaml:"http_server"`
-	Clients            ClientsConfig `yaml:"clients"`
-	AppSecret          string        `yaml:"app_secret" env:"APP_SECRET" env-required:"true"`
+	Env string `yaml:"env" env:"ENV"`
+	// Уровень логов: debug, info, warn или error. Пусто — по env (local — debug, prod — info)
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL"`
+	// Как часто проверять, не изменился ли файл конфига. 0 — перечитывать только по SIGHUP
+	ConfigWatchInterval time.Duration `yaml:"config_watch_interval" env:"CONFIG_WATCH_INTERVAL" env-default:"0s"`
+	UploadsPath         string        `yaml:"uploads_path" env:"UPLOADS_PATH"`
+	TwitchClientId      string        `yaml:"twitch_client_id" env:"TWITCH_CLIENT_ID"`
+	TwitchClientSecret  string        `yaml:"twitch_client_secret" env:"TWITCH_CLIENT_SECRET"`
+	Database            `yaml:"database"`
+	HTTPServer          `yaml:"http_server"`
+	Clients             ClientsConfig `yaml:"clients"`
+	AppSecret           string        `yaml:"app_secret" env:"APP_SECRET"`
+	PriorityAging       PriorityAging `yaml:"priority_aging"`
+	Images              Images        `yaml:"images"`
+	ImageProxy          ImageProxy    `yaml:"image_proxy"`
+	UploadsGC           UploadsGC     `yaml:"uploads_gc"`
+	UploadQuota         UploadQuota   `yaml:"upload_quota"`
+	Antivirus           Antivirus     `yaml:"antivirus"`
+	Photos              Photos        `yaml:"photos"`
+	Webhooks            Webhooks      `yaml:"webhooks"`
+	Discord             Discord       `yaml:"discord"`
+	Telegram            Telegram      `yaml:"telegram"`
+	Mailer              Mailer        `yaml:"mailer"`
+	Digest              Digest        `yaml:"digest"`
+	LoginGuard          LoginGuard    `yaml:"login_guard"`
+	Metadata            Metadata      `yaml:"metadata"`
+	AccessLog           AccessLog     `yaml:"access_log"`
+	Tracing             Tracing       `yaml:"tracing"`
+	Outbound            Outbound      `yaml:"outbound"`
+	Import              Import        `yaml:"import"`
+	// Флаги возможностей по именам, см. internal/features. Перечитываются без перезапуска
+	Features map[string]Feature `yaml:"features"`
 }
 
+// Поддерживаемые СУБД для database.driver
+const (
+	DriverMariaDB  = "mariadb"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 type Database struct {
+	Driver     string `yaml:"driver" env:"DB_DRIVER" env-default:"mariadb"`
 	Host       string `yaml:"host" env:"HOST" env-default:"localhost"`
-	Port       int    `yaml:"port" env:"PORT" env-required:"true"`
-	UsernameDB string `yaml:"username-db" env:"USERNAMEDB" env-required:"true"`
+	Port       int    `yaml:"port" env:"PORT"`
+	UsernameDB string `yaml:"username-db" env:"USERNAMEDB"`
 	Password   string `yaml:"password" env:"PASSWORD"`
 	DBName     string `yaml:"dbname" env:"DBNAME" env-default:"games"`
+	Path       string `yaml:"path" env:"DB_PATH" env-default:"games.db"` // Файл базы для driver: sqlite
+	// DSN реплик для чтения тяжёлых списков (только mariadb и postgres). Пусто — реплик нет
+	Replicas []string `yaml:"replicas" env:"DB_REPLICAS" env-separator:","`
+	// Как часто проверять доступность реплик
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" env:"DB_REPLICA_CHECK_INTERVAL" env-default:"15s"`
 }
 
 type HTTPServer struct {
-	Address     string        `yaml:"address" env-default:"localhost:8080"`
-	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
-	Cors        []string      `yaml:"cors" env-default:"[http://localhost:3000]"`
+	Address     string        `yaml:"address" env:"HTTP_ADDRESS" env-default:"localhost:8080"`
+	Timeout     time.Duration `yaml:"timeout" env:"HTTP_TIMEOUT" env-default:"4s"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" env-default:"60s"`
+	Cors        []string      `yaml:"cors" env:"HTTP_CORS" env-separator:"," env-default:"http://localhost:3000"`
+	// Источники для /api/quick-add: расширения браузера и закладки-букмарклеты.
+	// Запросы идут без cookies, только с токеном в заголовке, поэтому здесь можно
+	// разрешить шире, чем в cors, вплоть до "*"
+	ExtensionCors []string `yaml:"extension_cors" env:"HTTP_EXTENSION_CORS" env-separator:"," env-default:"chrome-extension://*,moz-extension://*,safari-web-extension://*"`
+	// Сколько ждать заголовков запроса: защищает от клиентов, которые шлют их по байту
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"READ_HEADER_TIMEOUT" env-default:"5s"`
+	// Предельные размеры тела запроса в байтах: JSON и multipart/form-data с картинками
+	MaxJSONBody      int64 `yaml:"max_json_body" env:"MAX_JSON_BODY" env-default:"1048576"`
+	MaxMultipartBody int64 `yaml:"max_multipart_body" env:"MAX_MULTIPART_BODY" env-default:"12582912"`
+	// Сколько ждать завершения запросов и фоновой работы при остановке
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"DRAIN_TIMEOUT" env-default:"15s"`
+	TLS          TLS           `yaml:"tls"`
+}
+
+// TLS — HTTPS без обратного прокси. Сертификат берётся из cert_file/key_file или
+// выпускается Let's Encrypt для autocert_domains. Без них сервер работает по HTTP
+type TLS struct {
+	CertFile         string   `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile          string   `yaml:"key_file" env:"TLS_KEY_FILE"`
+	AutocertDomains  []string `yaml:"autocert_domains" env:"TLS_AUTOCERT_DOMAINS" env-separator:","`
+	AutocertEmail    string   `yaml:"autocert_email" env:"TLS_AUTOCERT_EMAIL"`
+	AutocertCacheDir string   `yaml:"autocert_cache_dir" env:"TLS_AUTOCERT_CACHE_DIR" env-default:"certs"`
+	// Адрес HTTP-сервера, который перенаправляет на HTTPS (и отвечает на проверки
+	// Let's Encrypt). Пусто — не запускается; для autocert нужен :80
+	RedirectAddress string        `yaml:"redirect_address" env:"TLS_REDIRECT_ADDRESS"`
+	HSTS            bool          `yaml:"hsts" env:"TLS_HSTS" env-default:"false"`
+	HSTSMaxAge      time.Duration `yaml:"hsts_max_age" env:"TLS_HSTS_MAX_AGE" env-default:"8760h"`
+	HSTSSubdomains  bool          `yaml:"hsts_include_subdomains" env:"TLS_HSTS_INCLUDE_SUBDOMAINS" env-default:"false"`
+}
+
+// Enabled сообщает, что сервер должен отдавать HTTPS
+func (t TLS) Enabled() bool {
+	return (t.CertFile != "" && t.KeyFile != "") || len(t.AutocertDomains) > 0
 }
 
 type Client struct {
-	Address      string        `yaml:"address" env-required:"true"`
-	Timeout      time.Duration `yaml:"timeout" env-required:"true"`
-	RetriesCount int           `yaml:"retries_count" env-required:"true"`
-	Insecure     bool          `yaml:"insecure" env-required:"true"`
+	Address      string        `yaml:"address" env:"ADDRESS"`
+	Timeout      time.Duration `yaml:"timeout" env:"TIMEOUT"`
+	RetriesCount int           `yaml:"retries_count" env:"RETRIES_COUNT" env-default:"3"`
+	Insecure     bool          `yaml:"insecure" env:"INSECURE" env-default:"false"`
+	// Кэш проверки access-токенов: cache_ttl без обращения к SSO, до stale_ttl — пока SSO
+	// недоступен. cache_ttl: 0 выключает кэш
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"CACHE_TTL" env-default:"30s"`
+	StaleTTL time.Duration `yaml:"stale_ttl" env:"STALE_TTL" env-default:"5m"`
+	// После breaker_threshold сбоев связи подряд SSO не вызывается breaker_cooldown.
+	// breaker_threshold: 0 выключает размыкание
+	BreakerThreshold int           `yaml:"breaker_threshold" env:"BREAKER_THRESHOLD" env-default:"5"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown" env:"BREAKER_COOLDOWN" env-default:"10s"`
+	// Проверять access-токены локально: RS256 открытым ключом из public_key_path или,
+	// если путь пуст, HS256 секретом app_secret. SSO спрашивается, если подпись не сошлась
+	LocalValidation bool   `yaml:"local_validation" env:"LOCAL_VALIDATION" env-default:"false"`
+	PublicKeyPath   string `yaml:"public_key_path" env:"PUBLIC_KEY_PATH"`
+	// Вместо SSO запустить встроенный фейковый (internal/clients/sso/fake): входит
+	// любой email с любым паролем, id выводится из email, администраторы — fake_admins.
+	// Только для локальной разработки, при env: prod запрещено
+	Fake       bool     `yaml:"fake" env:"FAKE" env-default:"false"`
+	FakeAdmins []string `yaml:"fake_admins" env:"FAKE_ADMINS" env-separator:","`
+}
+
+// PriorityAging — понижение приоритета запланированных игр, которые давно не трогали.
+// Применяется только к пользователям, включившим это в настройках
+type PriorityAging struct {
+	Enabled     bool          `yaml:"enabled" env:"PRIORITY_AGING_ENABLED" env-default:"false"`
+	AfterMonths int           `yaml:"after_months" env:"PRIORITY_AGING_AFTER_MONTHS" env-default:"6"`
+	Interval    time.Duration `yaml:"interval" env:"PRIORITY_AGING_INTERVAL" env-default:"24h"`
+	Mode        string        `yaml:"mode" env:"PRIORITY_AGING_MODE" env-default:"decay"` // decay — понижать приоритет, flag — только помечать stale
+}
+
+// Images — ограничения на загружаемые картинки. Тип проверяется по содержимому файла
+type Images struct {
+	MaxSize      int64    `yaml:"max_size" env:"IMAGES_MAX_SIZE" env-default:"5242880"` // в байтах
+	AllowedTypes []string `yaml:"allowed_types" env:"IMAGES_ALLOWED_TYPES" env-default:"image/jpeg,image/png,image/webp,image/gif"`
+	JPEGQuality  int      `yaml:"jpeg_quality" env:"IMAGES_JPEG_QUALITY" env-default:"85"` // все картинки перекодируются в JPEG
+}
+
+// ImageProxy — отдача внешних обложек через /api/images/proxy. Картинки берутся
+// только с allowed_hosts и их поддоменов и хранятся в cache_dir. Через cache_ttl
+// картинка скачивается заново, а ещё через cache_ttl удаляется из кэша
+type ImageProxy struct {
+	Enabled      bool          `yaml:"enabled" env:"IMAGE_PROXY_ENABLED" env-default:"true"`
+	AllowedHosts []string      `yaml:"allowed_hosts" env:"IMAGE_PROXY_ALLOWED_HOSTS" env-separator:"," env-default:"images.igdb.com,upload.wikimedia.org,steamstatic.com,images.gog-statics.com,cdn1.epicgames.com"`
+	CacheDir     string        `yaml:"cache_dir" env:"IMAGE_PROXY_CACHE_DIR" env-default:"../image_cache"`
+	CacheTTL     time.Duration `yaml:"cache_ttl" env:"IMAGE_PROXY_CACHE_TTL" env-default:"168h"`
+	MaxSize      int64         `yaml:"max_size" env:"IMAGE_PROXY_MAX_SIZE" env-default:"5242880"` // в байтах
+}
+
+// UploadsGC — периодическое удаление файлов загрузок, на которые ничего не ссылается
+type UploadsGC struct {
+	Enabled  bool          `yaml:"enabled" env:"UPLOADS_GC_ENABLED" env-default:"false"`
+	Interval time.Duration `yaml:"interval" env:"UPLOADS_GC_INTERVAL" env-default:"24h"`
+	MinAge   time.Duration `yaml:"min_age" env:"UPLOADS_GC_MIN_AGE" env-default:"1h"` // более свежие файлы не трогаются
+	DryRun   bool          `yaml:"dry_run" env:"UPLOADS_GC_DRY_RUN" env-default:"true"`
+}
+
+// UploadQuota — сколько места в загрузках может занять один пользователь:
+// загруженные им обложки и фото. Администраторов квота не касается.
+// Перечитывается без перезапуска
+type UploadQuota struct {
+	Enabled bool  `yaml:"enabled" env:"UPLOAD_QUOTA_ENABLED" env-default:"true"`
+	Bytes   int64 `yaml:"bytes" env:"UPLOAD_QUOTA_BYTES" env-default:"104857600"` // в байтах
+}
+
+// Limit — квота в байтах, 0 — без ограничения
+func (q UploadQuota) Limit() int64 {
+	if !q.Enabled {
+		return 0
+	}
+	return q.Bytes
+}
+
+// Antivirus — проверка загружаемых картинок и фото демоном ClamAV (clamd) по
+// TCP. Результат каждой проверки пишется в журнал аудита
+type Antivirus struct {
+	Enabled bool          `yaml:"enabled" env:"ANTIVIRUS_ENABLED" env-default:"false"`
+	Address string        `yaml:"address" env:"ANTIVIRUS_ADDRESS" env-default:"127.0.0.1:3310"`
+	Timeout time.Duration `yaml:"timeout" env:"ANTIVIRUS_TIMEOUT" env-default:"10s"`
+	// FailOpen сохраняет файл, если clamd не ответил. По умолчанию такой файл отклоняется
+	FailOpen bool `yaml:"fail_open" env:"ANTIVIRUS_FAIL_OPEN" env-default:"false"`
+}
+
+// Photos — фото пользователей. Хранятся отдельно от обложек и отдаются только
+// по подписанным ссылкам, которые живут TTL
+type Photos struct {
+	Path   string        `yaml:"path" env:"PHOTOS_PATH" env-default:"../photos"`
+	Secret string        `yaml:"secret" env:"PHOTOS_SECRET"`
+	TTL    time.Duration `yaml:"ttl" env:"PHOTOS_TTL" env-default:"15m"`
+}
+
+// Webhooks — фоновая отправка событий библиотеки на адреса пользователей
+type Webhooks struct {
+	Enabled  bool          `yaml:"enabled" env:"WEBHOOKS_ENABLED" env-default:"true"`
+	Interval time.Duration `yaml:"interval" env:"WEBHOOKS_INTERVAL" env-default:"30s"`
+	Timeout  time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" env-default:"10s"` // на одну попытку отправки
+}
+
+// Discord — уведомления в канал Discord о пройденных играх и импортах.
+// Пустой webhook_url отключает интеграцию; пустые шаблоны заменяются стандартными
+type Discord struct {
+	WebhookURL       string        `yaml:"webhook_url" env:"DISCORD_WEBHOOK_URL"`
+	Timeout          time.Duration `yaml:"timeout" env:"DISCORD_TIMEOUT" env-default:"10s"`
+	FinishedTemplate string        `yaml:"finished_template" env:"DISCORD_FINISHED_TEMPLATE"` // поля: .UserID, .GameID, .Game
+	ImportTemplate   string        `yaml:"import_template" env:"DISCORD_IMPORT_TEMPLATE"`     // поля: .UserID, .Provider, .Requested, .Succeeded, .Failed, .Review
+}
+
+// Telegram — личные уведомления через бота. Пустой token отключает интеграцию
+type Telegram struct {
+	Token        string        `yaml:"token" env:"TELEGRAM_BOT_TOKEN"`
+	BotName      string        `yaml:"bot_name" env:"TELEGRAM_BOT_NAME"` // имя бота без @, для ссылки привязки
+	Timeout      time.Duration `yaml:"timeout" env:"TELEGRAM_TIMEOUT" env-default:"10s"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"TELEGRAM_POLL_INTERVAL" env-default:"5s"` // как часто читать сообщения боту
+}
+
+// Mailer — SMTP-сервер для писем. Пустой host отключает отправку почты
+type Mailer struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string `yaml:"from" env:"SMTP_FROM"`
+}
+
+// Digest — еженедельные письма подписавшимся пользователям. Каждому письмо уходит
+// не чаще раза в неделю; interval — как часто проверять, кому пора
+type Digest struct {
+	Enabled  bool          `yaml:"enabled" env:"DIGEST_ENABLED" env-default:"false"`
+	Interval time.Duration `yaml:"interval" env:"DIGEST_INTERVAL" env-default:"1h"`
+}
+
+// LoginGuard — защита входа от перебора паролей. Неудачи считаются по email и по IP:
+// после free_attempts вход закрывается на base_delay с удвоением до max_delay,
+// после lockout_after — на lockout_duration. Счётчик сбрасывается через window без неудач
+type LoginGuard struct {
+	Enabled         bool          `yaml:"enabled" env:"LOGIN_GUARD_ENABLED" env-default:"true"`
+	FreeAttempts    int           `yaml:"free_attempts" env:"LOGIN_GUARD_FREE_ATTEMPTS" env-default:"3"`
+	BaseDelay       time.Duration `yaml:"base_delay" env:"LOGIN_GUARD_BASE_DELAY" env-default:"2s"`
+	MaxDelay        time.Duration `yaml:"max_delay" env:"LOGIN_GUARD_MAX_DELAY" env-default:"5m"`
+	LockoutAfter    int           `yaml:"lockout_after" env:"LOGIN_GUARD_LOCKOUT_AFTER" env-default:"10"`
+	LockoutDuration time.Duration `yaml:"lockout_duration" env:"LOGIN_GUARD_LOCKOUT_DURATION" env-default:"15m"`
+	Window          time.Duration `yaml:"window" env:"LOGIN_GUARD_WINDOW" env-default:"1h"`
+}
+
+// AccessLog — журнал HTTP-запросов. Ошибки пишутся всегда, успешные запросы —
+// с долей sample_rate. У доли body_sample_rate ошибочных ответов пишутся тела
+// запроса и ответа (до max_body_size байт) со скрытыми паролями и токенами.
+// Перечитывается без перезапуска
+type AccessLog struct {
+	Enabled        bool     `yaml:"enabled" env:"ACCESS_LOG_ENABLED" env-default:"true"`
+	SampleRate     float64  `yaml:"sample_rate" env:"ACCESS_LOG_SAMPLE_RATE" env-default:"1"`
+	BodySampleRate float64  `yaml:"body_sample_rate" env:"ACCESS_LOG_BODY_SAMPLE_RATE" env-default:"0.1"`
+	MaxBodySize    int      `yaml:"max_body_size" env:"ACCESS_LOG_MAX_BODY_SIZE" env-default:"2048"`
+	SkipPaths      []string `yaml:"skip_paths" env:"ACCESS_LOG_SKIP_PATHS" env-separator:"," env-default:"/api/health"` // успешные запросы сюда не пишутся
+}
+
+// Tracing — трассировка OpenTelemetry: входящие запросы, вызовы SSO, запросы к
+// базе и к внешним сайтам. Спаны отправляются коллектору по OTLP/HTTP (protobuf) на
+// endpoint/v1/traces. Трассируется доля sample_ratio запросов, но если вызывающий
+// уже начал трассу, сохраняется его решение
+type Tracing struct {
+	Enabled     bool              `yaml:"enabled" env:"TRACING_ENABLED" env-default:"false"`
+	Endpoint    string            `yaml:"endpoint" env:"TRACING_ENDPOINT" env-default:"http://localhost:4318"`
+	Headers     map[string]string `yaml:"headers" env:"TRACING_HEADERS"` // например, ключ API коллектора
+	ServiceName string            `yaml:"service_name" env:"TRACING_SERVICE_NAME" env-default:"games_webapp"`
+	SampleRatio float64           `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+	Timeout     time.Duration     `yaml:"timeout" env:"TRACING_TIMEOUT" env-default:"10s"` // на одну отправку спанов
+}
+
+// Outbound — общий пул соединений для запросов к внешним сайтам: обложки, IGDB,
+// Steam, Википедия. GET и HEAD повторяются при сетевых ошибках, 429 и 502–504 с
+// растущей паузой; timeout ограничивает запрос вместе с повторами
+type Outbound struct {
+	Timeout             time.Duration `yaml:"timeout" env:"OUTBOUND_TIMEOUT" env-default:"30s"`                      // если вызывающий не задал свой
+	MaxConnsPerHost     int           `yaml:"max_conns_per_host" env:"OUTBOUND_MAX_CONNS_PER_HOST" env-default:"16"` // 0 — без ограничения
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host" env:"OUTBOUND_MAX_IDLE_CONNS_PER_HOST" env-default:"4"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout" env:"OUTBOUND_IDLE_CONN_TIMEOUT" env-default:"90s"`
+	Retries             int           `yaml:"retries" env:"OUTBOUND_RETRIES" env-default:"2"`                  // повторов сверх первой попытки
+	RetryBackoff        time.Duration `yaml:"retry_backoff" env:"OUTBOUND_RETRY_BACKOFF" env-default:"500ms"`  // пауза перед первым повтором, дальше вдвое больше
+	MaxRetryWait        time.Duration `yaml:"max_retry_wait" env:"OUTBOUND_MAX_RETRY_WAIT" env-default:"10s"`  // Retry-After дольше этого — без повтора
+	UserAgent           string        `yaml:"user_agent" env:"OUTBOUND_USER_AGENT" env-default:"games_webapp"` // Википедия отклоняет запросы без него
+}
+
+// Import — импорт списка игр по названиям. Названия обрабатываются по workers
+// одновременно, на каждое даётся item_timeout. Весь импорт ограничен временем,
+// которого хватит на список волнами по workers, но не больше budget
+type Import struct {
+	Workers     int           `yaml:"workers" env:"IMPORT_WORKERS" env-default:"10"`
+	ItemTimeout time.Duration `yaml:"item_timeout" env:"IMPORT_ITEM_TIMEOUT" env-default:"10s"`
+	Budget      time.Duration `yaml:"budget" env:"IMPORT_BUDGET" env-default:"2m"`
+}
+
+// Metadata — источники сведений об играх для импорта по названию или ссылке.
+// Источники опрашиваются в порядке списка до первого совпадения. Пустой список —
+// steam, wiki, igdb, gog, epic. igdb без twitch_client_id и twitch_client_secret пропускается
+type Metadata struct {
+	Providers []MetadataProvider `yaml:"providers"`
+	WikiLang  string             `yaml:"wiki_lang" env:"METADATA_WIKI_LANG" env-default:"en"`
+}
+
+// MetadataProvider — один источник: steam, wiki, igdb, gog или epic
+type MetadataProvider struct {
+	Name    string        `yaml:"name"`
+	Enabled bool          `yaml:"enabled"`
+	Timeout time.Duration `yaml:"timeout"` // 0 — 10s
+}
+
+// Поддерживаемые источники для metadata.providers
+const (
+	ProviderSteam = "steam"
+	ProviderWiki  = "wiki"
+	ProviderIGDB  = "igdb"
+	ProviderGOG   = "gog"  // только ссылки на gog.com
+	ProviderEpic  = "epic" // только ссылки на store.epicgames.com
+)
+
+// DefaultProviderTimeout — сколько ждать источник, если timeout не задан
+const DefaultProviderTimeout = 10 * time.Second
+
+// ProviderChain возвращает включённые источники по порядку, подставляя
+// порядок и таймауты по умолчанию
+func (m Metadata) ProviderChain() []MetadataProvider {
+	providers := m.Providers
+	if len(providers) == 0 {
+		providers = []MetadataProvider{
+			{Name: ProviderSteam, Enabled: true},
+			{Name: ProviderWiki, Enabled: true},
+			{Name: ProviderIGDB, Enabled: true},
+			{Name: ProviderGOG, Enabled: true},
+			{Name: ProviderEpic, Enabled: true},
+		}
+	}
+
+	chain := make([]MetadataProvider, 0, len(providers))
+	for _, p := range providers {
+		if !p.Enabled {
+			continue
+		}
+		if p.Timeout <= 0 {
+			p.Timeout = DefaultProviderTimeout
+		}
+		chain = append(chain, p)
+	}
+	return chain
+}
+
+// Feature — настройки одного флага. Выключенный флаг выключен для всех. Включённый
+// действует на rollout процентов пользователей (0 — на всех) и всегда на users
+type Feature struct {
+	Enabled bool  `yaml:"enabled"`
+	Rollout int   `yaml:"rollout"`
+	Users   []int `yaml:"users"`
 }
 
 type ClientsConfig struct {
-	SSO Client `yaml:"sso"`
+	SSO Client `yaml:"sso" env-prefix:"SSO_"`
 }
 
+// configPath — файл, из которого загружен конфиг, см. Path
+var configPath string
+
+// MustLoad читает конфиг из файла -config или CONFIG_PATH. Если ни то, ни другое
+// не задано, конфиг целиком берётся из переменных окружения. С -print-config
+// печатает итоговый конфиг со скрытыми секретами и завершает программу
 func MustLoad() *Config {
-	configPath := flag.String("config", "", "path to config yaml file")
+	var printConfig bool
+	flag.StringVar(&configPath, "config", os.Getenv("CONFIG_PATH"), "path to config yaml file; empty - read config from environment only")
+	flag.BoolVar(&printConfig, "print-config", false, "print effective config with secrets redacted and exit")
 	flag.Parse()
-	if *configPath == "" {
-		log.Fatal("CONFIG_PATH is not set")
+
+	if configPath != "" {
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+			log.Fatalf("config file does not exist: %s", configPath)
+		}
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		if configPath == "" {
+			log.Fatalf("cannot read config from environment: %s", err)
+		}
+		log.Fatalf("cannot read config: %s - %s", configPath, err)
 	}
 
-	if _, err := os.Stat(*configPath); os.IsNotExist(err) {
-		log.Fatalf("config file does not exist: %s", *configPath)
+	if printConfig {
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			log.Fatalf("cannot print config: %s", err)
+		}
+		fmt.Print(out)
+		os.Exit(0)
 	}
 
+	return cfg
+}
+
+// Path возвращает файл, из которого MustLoad загрузил конфиг. Пусто — конфиг
+// взят из переменных окружения
+func Path() string {
+	return configPath
+}
+
+// Load читает и проверяет конфиг из файла path. Переменные окружения важнее
+// значений из файла; при пустом path читаются только они
+func Load(path string) (*Config, error) {
 	var cfg Config
 
-	if err := cleanenv.ReadConfig(*configPath, &cfg); err != nil {
-		log.Fatalf("cannot read config: %s - %s", *configPath, err)
+	read := func() error { return cleanenv.ReadConfig(path, &cfg) }
+	if path == "" {
+		read = func() error { return cleanenv.ReadEnv(&cfg) }
+	}
+	if err := read(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
-	return &cfg
+	return &cfg, nil
+}
+
+// extensionSchemes — схемы источников, допустимые в http_server.extension_cors.
+// http и https нужны букмарклетам, которые запускаются на странице магазина
+var extensionSchemes = map[string]bool{
+	"http":                 true,
+	"https":                true,
+	"chrome-extension":     true,
+	"moz-extension":        true,
+	"safari-web-extension": true,
+}
+
+// Validate проверяет обязательные значения и значения, которые можно поменять
+// без перезапуска
+func (cfg *Config) Validate() error {
+	if err := cfg.checkRequired(); err != nil {
+		return err
+	}
+
+	for _, origin := range cfg.Cors {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return fmt.Errorf("http_server.cors: invalid origin %q", origin)
+		}
+	}
+	for _, origin := range cfg.ExtensionCors {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || !extensionSchemes[u.Scheme] || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return fmt.Errorf("http_server.extension_cors: invalid origin %q", origin)
+		}
+	}
+
+	if cfg.Clients.SSO.Fake && cfg.Env == "prod" {
+		return fmt.Errorf("clients.sso.fake: fake sso must not be used with env: prod")
+	}
+
+	switch strings.ToLower(cfg.LogLevel) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log_level: unknown level %q", cfg.LogLevel)
+	}
+
+	seen := make(map[string]bool, len(cfg.Metadata.Providers))
+	for _, p := range cfg.Metadata.Providers {
+		switch p.Name {
+		case ProviderSteam, ProviderWiki, ProviderIGDB, ProviderGOG, ProviderEpic:
+		default:
+			return fmt.Errorf("metadata.providers: unknown provider %q", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("metadata.providers: %s is listed twice", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Timeout < 0 {
+			return fmt.Errorf("metadata.providers: %s: timeout must not be negative", p.Name)
+		}
+	}
+
+	for name, f := range cfg.Features {
+		if f.Rollout < 0 || f.Rollout > 100 {
+			return fmt.Errorf("features.%s.rollout: must be from 0 to 100", name)
+		}
+	}
+
+	a := cfg.AccessLog
+	if a.SampleRate < 0 || a.SampleRate > 1 || a.BodySampleRate < 0 || a.BodySampleRate > 1 || a.MaxBodySize < 0 {
+		return fmt.Errorf("access_log: sample rates must be from 0 to 1 and max_body_size must not be negative")
+	}
+
+	if t := cfg.Tracing; t.Enabled {
+		u, err := url.Parse(t.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracing.endpoint: invalid url %q", t.Endpoint)
+		}
+		if t.SampleRatio < 0 || t.SampleRatio > 1 {
+			return fmt.Errorf("tracing.sample_ratio: must be from 0 to 1")
+		}
+	}
+
+	if i := cfg.Import; i.Workers <= 0 || i.ItemTimeout <= 0 || i.Budget < i.ItemTimeout {
+		return fmt.Errorf("import: workers and item_timeout must be positive and budget at least item_timeout")
+	}
+
+	if p := cfg.ImageProxy; p.Enabled && (len(p.AllowedHosts) == 0 || p.CacheDir == "" || p.CacheTTL <= 0 || p.MaxSize <= 0) {
+		return fmt.Errorf("image_proxy: allowed_hosts and cache_dir must be set, cache_ttl and max_size positive")
+	}
+
+	if q := cfg.UploadQuota; q.Enabled && q.Bytes <= 0 {
+		return fmt.Errorf("upload_quota.bytes: must be positive")
+	}
+
+	if a := cfg.Antivirus; a.Enabled && (a.Address == "" || a.Timeout <= 0) {
+		return fmt.Errorf("antivirus: address must be set and timeout positive")
+	}
+
+	o := cfg.Outbound
+	if o.Timeout < 0 || o.MaxConnsPerHost < 0 || o.MaxIdleConnsPerHost < 0 || o.IdleConnTimeout < 0 ||
+		o.Retries < 0 || o.RetryBackoff < 0 || o.MaxRetryWait < 0 {
+		return fmt.Errorf("outbound: timeouts, limits and retries must not be negative")
+	}
+
+	g := cfg.LoginGuard
+	if g.FreeAttempts < 0 || g.LockoutAfter < 0 || g.BaseDelay < 0 || g.MaxDelay < g.BaseDelay || g.Window <= 0 {
+		return fmt.Errorf("login_guard: free_attempts and lockout_after must not be negative, max_delay must be at least base_delay and window positive")
+	}
+
+	return nil
 }
 
 func (cfg *Database) GetDSN() string {
-	dsn := fmt.Sprintf(
+	if cfg.Driver == DriverSQLite {
+		// WAL и busy_timeout, чтобы параллельные запросы ждали блокировку, а не падали с SQLITE_BUSY
+		return fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL", cfg.Path)
+	}
+
+	if cfg.Driver == DriverPostgres {
+		return fmt.Sprintf(
+			"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+			cfg.Host,
+			cfg.Port,
+			cfg.UsernameDB,
+			cfg.Password,
+			cfg.DBName,
+		)
+	}
+
+	return fmt.Sprintf(
 		"%s:%s@tcp(%s:%d)/%s?parseTime=true",
 		cfg.UsernameDB,
 		cfg.Password,
@@ -76,8 +573,102 @@ func (cfg *Database) GetDSN() string {
 		cfg.Port,
 		cfg.DBName,
 	)
+}
+
+// LogValue описывает подключение к базе для логов без пароля и DSN реплик
+func (cfg Database) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("driver", cfg.Driver),
+		slog.String("host", cfg.Host),
+		slog.Int("port", cfg.Port),
+		slog.String("user", cfg.UsernameDB),
+		slog.String("dbname", cfg.DBName),
+		slog.String("path", cfg.Path),
+		slog.Int("replicas", len(cfg.Replicas)),
+	)
+}
+
+// checkRequired перечисляет все незаполненные обязательные значения сразу, с
+// ключом YAML и переменной окружения, чтобы их можно было поправить за один раз
+func (cfg *Config) checkRequired() error {
+	var missing []string
+	require := func(set bool, key, env string) {
+		if !set {
+			missing = append(missing, fmt.Sprintf("%s (%s)", key, env))
+		}
+	}
+
+	require(cfg.Env != "", "env", "ENV")
+	require(cfg.UploadsPath != "", "uploads_path", "UPLOADS_PATH")
+	require(cfg.AppSecret != "", "app_secret", "APP_SECRET")
+	require(cfg.Photos.Secret != "", "photos.secret", "PHOTOS_SECRET")
+	require(cfg.Clients.SSO.Address != "" || cfg.Clients.SSO.Fake, "clients.sso.address", "SSO_ADDRESS")
+	require(cfg.Clients.SSO.Timeout > 0, "clients.sso.timeout", "SSO_TIMEOUT")
+
+	switch cfg.Database.Driver {
+	case DriverSQLite:
+		require(cfg.Database.Path != "", "database.path", "DB_PATH")
+	case DriverMariaDB, DriverPostgres, "":
+		require(cfg.Database.Port > 0, "database.port", "PORT")
+		require(cfg.Database.UsernameDB != "", "database.username-db", "USERNAMEDB")
+	default:
+		return fmt.Errorf("database.driver: unknown driver %q", cfg.Database.Driver)
+	}
 
-	log.Print(dsn)
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required values: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Redacted возвращает копию конфига, в которой пароли, токены и ключи заменены
+// на [redacted]. Пустые значения остаются пустыми, чтобы было видно, что не задано
+func (cfg *Config) Redacted() *Config {
+	c := *cfg
+
+	c.TwitchClientSecret = redact.String(c.TwitchClientSecret)
+	c.AppSecret = redact.String(c.AppSecret)
+	c.Database.Password = redact.String(c.Database.Password)
+	c.Photos.Secret = redact.String(c.Photos.Secret)
+	c.Discord.WebhookURL = redact.String(c.Discord.WebhookURL) // токен вебхука — часть адреса
+	c.Telegram.Token = redact.String(c.Telegram.Token)
+	c.Mailer.Password = redact.String(c.Mailer.Password)
+
+	// В заголовках коллектора обычно ключ API
+	c.Tracing.Headers = make(map[string]string, len(cfg.Tracing.Headers))
+	for key, value := range cfg.Tracing.Headers {
+		c.Tracing.Headers[key] = redact.String(value)
+	}
+
+	// В DSN реплик есть пароль
+	c.Database.Replicas = make([]string, len(cfg.Database.Replicas))
+	for i := range c.Database.Replicas {
+		c.Database.Replicas[i] = redact.Mask
+	}
 
-	return dsn
+	return &c
+}
+
+// String выводит конфиг со скрытыми секретами, так что его безопасно печатать
+// через fmt и log
+func (cfg *Config) String() string {
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		return fmt.Sprintf("config: %s", err)
+	}
+	return out
+}
+
+// LogValue позволяет передавать конфиг в slog: секреты скрыты так же, как в String
+func (cfg *Config) LogValue() slog.Value {
+	return slog.StringValue(cfg.String())
+}
+
+// YAML выводит конфиг в формате файла конфига
+func (cfg *Config) YAML() (string, error) {
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Applier применяет перечитанный конфиг к работающему приложению. Ошибка
+// означает, что новые значения применить нельзя
+type Applier func(cfg *Config) error
+
+// Reloader перечитывает конфиг без перезапуска. Применяются только части,
+// на которые подписались через OnReload (CORS, уровень логов, защита входа
+// и т.п.); остальное, как адреса и база, требует перезапуска
+type Reloader struct {
+	path string
+	log  *slog.Logger
+
+	mu       sync.Mutex
+	current  *Config
+	appliers []namedApplier
+}
+
+type namedApplier struct {
+	name  string
+	apply Applier
+}
+
+func NewReloader(path string, current *Config, log *slog.Logger) *Reloader {
+	return &Reloader{path: path, current: current, log: log}
+}
+
+// OnReload подписывает fn на перечитывание конфига
+func (r *Reloader) OnReload(name string, fn Applier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.appliers = append(r.appliers, namedApplier{name: name, apply: fn})
+}
+
+// Reload читает файл заново, проверяет его и применяет. Если файл не прошёл
+// проверку, ничего не меняется; если один из подписчиков отказался, уже
+// применённые части возвращаются к прежнему конфигу
+func (r *Reloader) Reload() error {
+	const op = "config.Reload"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := Load(r.path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i, a := range r.appliers {
+		if err := a.apply(next); err != nil {
+			for _, prev := range r.appliers[:i] {
+				if rbErr := prev.apply(r.current); rbErr != nil {
+					r.log.Error("config rollback failed", slog.String("part", prev.name), slog.String("error", rbErr.Error()))
+				}
+			}
+			return fmt.Errorf("%s: %s: %w", op, a.name, err)
+		}
+	}
+
+	r.current = next
+	return nil
+}
+
+// Current возвращает последний успешно применённый конфиг
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
@@ -4,8 +4,8 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -13,21 +13,31 @@ import (
 	"time"
 	"unicode"
 
+	"games_webapp/internal/lib/signer"
 	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
 	"games_webapp/internal/storage/uploads"
 
+	ssogrpc "games_webapp/internal/clients/sso/grpc"
+
 	ssov1 "github.com/Nergous/sso_protos/gen/go/sso"
 )
 
 type AuthController struct {
-	log     *slog.Logger
-	client  GRPCClient
-	uploads uploads.IUploads
+	log      *slog.Logger
+	client   GRPCClient
+	photos   uploads.IUploads
+	signer   *signer.Signer
+	sessions SessionRegistry
+	guard    LoginGuard
+	quota    UploadQuota
 }
 
 type GRPCClient interface {
 	Login(ctx context.Context, email, password string, appID uint32) (string, string, error)
 	Logout(ctx context.Context, token string) error
+	ValidateToken(ctx context.Context, token string) (uint32, bool, error)
 	Register(ctx context.Context, email, password, steamURL, pathToPhoto string) (uint32, error)
 	GetUserInfo(ctx context.Context, userID uint32) (email, steamURL, pathToPhoto string, err error)
 	GetUsers(ctx context.Context) (*ssov1.GetAllUsersResponse, error)
@@ -37,8 +47,71 @@ type GRPCClient interface {
 	GetUsersForApp(ctx context.Context, appID uint32) (*ssov1.GetAllUsersForAppResponse, error)
 }
 
-func NewAuthController(log *slog.Logger, client GRPCClient, uploads uploads.IUploads) *AuthController {
-	return &AuthController{log: log, client: client, uploads: uploads}
+func NewAuthController(log *slog.Logger, client GRPCClient, photos uploads.IUploads, signer *signer.Signer) *AuthController {
+	return &AuthController{log: log, client: client, photos: photos, signer: signer}
+}
+
+// SessionRegistry отслеживает входы, чтобы их можно было посмотреть и отозвать
+type SessionRegistry interface {
+	Start(userID int, refreshToken, userAgent, ip string) error
+	CheckRefresh(refreshToken string) error
+	Rotate(userID int, oldToken, newToken, userAgent, ip string) error
+	End(refreshToken string) error
+}
+
+// UseSessions включает реестр сессий. Без него вход и обновление токенов
+// работают как раньше, но сессии не видны пользователю
+func (c *AuthController) UseSessions(s SessionRegistry) {
+	c.sessions = s
+}
+
+// LoginGuard считает неудачные входы и закрывает вход при переборе
+type LoginGuard interface {
+	Check(email, ip string) (time.Time, error)
+	Fail(email, ip string) error
+	Succeed(email string) error
+}
+
+// UseLoginGuard включает защиту входа от перебора паролей
+func (c *AuthController) UseLoginGuard(g LoginGuard) {
+	c.guard = g
+}
+
+// UseUploadQuota включает учёт фото пользователей в квоте загрузок
+func (c *AuthController) UseUploadQuota(q UploadQuota) {
+	c.quota = q
+}
+
+// LoginLockedResponse — ответ 429, когда вход временно закрыт
+type LoginLockedResponse struct {
+	Error       string    `json:"error"`
+	RetryAfter  int       `json:"retry_after"` // в секундах
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (c *AuthController) writeLoginLocked(w http.ResponseWriter, op, ip string, until time.Time) {
+	retryAfter := int(time.Until(until).Seconds()) + 1
+	c.log.Warn(ErrLoginLocked.Error(), slog.String("operation", op), slog.String("ip", ip))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(LoginLockedResponse{
+		Error:       ErrLoginLocked.Error(),
+		RetryAfter:  retryAfter,
+		LockedUntil: until.UTC(),
+	})
+}
+
+// tokenUser достаёт пользователя из только что выданного access-токена
+func (c *AuthController) tokenUser(ctx context.Context, accessToken string) (int, error) {
+	userID, valid, err := c.client.ValidateToken(ctx, accessToken)
+	if err != nil {
+		return 0, err
+	}
+	if !valid || userID == 0 {
+		return 0, fmt.Errorf("sso returned invalid access token")
+	}
+	return int(userID), nil
 }
 
 type RegisterRequest struct {
@@ -75,48 +148,50 @@ func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
 		SteamURL: r.FormValue("steam_url"),
 	}
 
-	if request.Email == "" {
-		c.log.Error(ErrMissingEmail.Error(), slog.String("operation", op))
-		http.Error(w, ErrRegister.Error(), http.StatusBadRequest)
-		return
-	}
+	cleanedEmail := strings.ToLower(strings.TrimSpace(request.Email))
+	request.SteamURL = strings.TrimSpace(request.SteamURL)
 
-	if request.Password == "" {
-		c.log.Error(ErrMissingPassword.Error(), slog.String("operation", op))
-		http.Error(w, ErrRegister.Error(), http.StatusBadRequest)
-		return
+	// Проверяем все поля сразу, чтобы форма могла подсветить каждое
+	fields := make(map[string]string)
+	if err := validateEmail(cleanedEmail); err != nil {
+		fields["email"] = err.Error()
 	}
-
-	if request.SteamURL == "" {
-		c.log.Error(ErrMissingSteamURL.Error(), slog.String("operation", op))
-		http.Error(w, ErrRegister.Error(), http.StatusBadRequest)
-		return
+	if err := validatePassword(request.Password); err != nil {
+		fields["password"] = err.Error()
+	}
+	if err := validateSteamURL(request.SteamURL); err != nil {
+		fields["steam_url"] = err.Error()
 	}
-
 	file, _, err := r.FormFile("image")
 	if err != nil {
-		c.log.Error(ErrMissingImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		http.Error(w, ErrRegister.Error(), http.StatusBadRequest)
+		fields["image"] = ErrMissingImage.Error()
+	} else {
+		defer file.Close()
+	}
+
+	if len(fields) > 0 {
+		c.log.Error(ErrRegisterFields.Error(), slog.String("operation", op), slog.Any("fields", fields))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ValidationErrorResponse{Error: ErrRegisterFields.Error(), Fields: fields})
 		return
 	}
-	defer file.Close()
 
-	imageData, err := io.ReadAll(file)
-	if err != nil {
-		c.log.Error(ErrReadImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		http.Error(w, ErrRegister.Error(), http.StatusInternalServerError)
+	imageData, contentType, ok := readImage(w, c.log, op, c.photos, file, ErrRegister)
+	if !ok {
 		return
 	}
 
-	imageFilename := generatePhotoFilename(request.Email)
-	if err := c.uploads.SaveImage(imageData, imageFilename); err != nil {
+	imageFilename := generatePhotoFilename(request.Email, uploads.Extension(contentType))
+	if err := c.photos.SaveImage(imageData, imageFilename); err != nil {
+		if writeUploadError(w, c.log, op, err) {
+			return
+		}
 		c.log.Error(ErrSaveImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrRegister.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	cleanedEmail := strings.ToLower(strings.TrimSpace(request.Email))
-
 	userID, err := c.client.Register(r.Context(), cleanedEmail, request.Password, request.SteamURL, imageFilename)
 	if err != nil {
 		c.log.Error("sso.Register failed", slog.String("operation", op), slog.String("error", err.Error()))
@@ -124,6 +199,13 @@ func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Новый пользователь ничего не занимает, поэтому фото только учитывается
+	if c.quota != nil {
+		if err := c.quota.SetPhoto(r.Context(), int(userID), imageFilename, int64(len(imageData))); err != nil {
+			c.log.Error("failed to record photo size", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(userID); err != nil {
 		c.log.Error("encoding response", slog.String("operation", op), slog.String("error", err.Error()))
@@ -149,14 +231,53 @@ func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	cleanedEmail := strings.ToLower(strings.TrimSpace(req.Email))
+	ip := clientIP(r)
+
+	if c.guard != nil {
+		until, err := c.guard.Check(cleanedEmail, ip)
+		if errors.Is(err, services.ErrLoginLocked) {
+			c.writeLoginLocked(w, op, ip, until)
+			return
+		}
+		if err != nil {
+			// Недоступный счётчик не должен закрывать вход всем
+			c.log.Error("failed to check login lockout", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
 
 	accessToken, refreshToken, err := c.client.Login(r.Context(), cleanedEmail, req.Password, req.AppId)
 	if err != nil {
 		c.log.Error("sso.Login failed", slog.String("error", err.Error()), slog.String("operation", op))
+		if errors.Is(err, ssogrpc.ErrInvalidCredentials) {
+			if c.guard != nil {
+				if err := c.guard.Fail(cleanedEmail, ip); err != nil {
+					c.log.Error("failed to record failed login", slog.String("operation", op), slog.String("error", err.Error()))
+				}
+			}
+			http.Error(w, ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+			return
+		}
 		http.Error(w, ErrLogin.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if c.guard != nil {
+		if err := c.guard.Succeed(cleanedEmail); err != nil {
+			c.log.Error("failed to reset failed logins", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
+
+	if c.sessions != nil {
+		// Вход уже состоялся, поэтому сбой реестра его не отменяет
+		userID, err := c.tokenUser(r.Context(), accessToken)
+		if err == nil {
+			err = c.sessions.Start(userID, refreshToken, r.UserAgent(), ip)
+		}
+		if err != nil {
+			c.log.Error("failed to start session", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
+
 	http.SetCookie(w, &http.Cookie{
 		Name:        refreshTokenCookieName,
 		Value:       refreshToken,
@@ -185,6 +306,11 @@ func (c *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
 	refreshCookie, err := r.Cookie(refreshTokenCookieName)
 	if err == nil && refreshCookie.Value != "" {
 		c.client.Logout(r.Context(), refreshCookie.Value)
+		if c.sessions != nil {
+			if err := c.sessions.End(refreshCookie.Value); err != nil {
+				c.log.Error("failed to end session", slog.String("operation", "controllers.auth.Logout"), slog.String("error", err.Error()))
+			}
+		}
 	}
 
 	// Удаляем refresh token cookie
@@ -220,6 +346,28 @@ func (c *AuthController) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if c.sessions != nil {
+		if err := c.sessions.CheckRefresh(refreshToken); err != nil {
+			if errors.Is(err, services.ErrSessionRevoked) {
+				// Сессию завершили с другого устройства: гасим токен и в SSO
+				c.client.Logout(r.Context(), refreshToken)
+				http.SetCookie(w, &http.Cookie{
+					Name:        refreshTokenCookieName,
+					Value:       "",
+					Path:        "/",
+					MaxAge:      -1,
+					HttpOnly:    true,
+					Secure:      true,
+					SameSite:    http.SameSiteNoneMode,
+					Partitioned: true,
+				})
+				http.Error(w, ErrSessionRevoked.Error(), http.StatusUnauthorized)
+				return
+			}
+			c.log.Error("failed to check session", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
+
 	// Обновляем токены
 	accessToken, newRefreshToken, err := c.client.RefreshToken(r.Context(), refreshToken)
 	if err != nil {
@@ -241,6 +389,16 @@ func (c *AuthController) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if c.sessions != nil {
+		userID, err := c.tokenUser(r.Context(), accessToken)
+		if err == nil {
+			err = c.sessions.Rotate(userID, refreshToken, newRefreshToken, r.UserAgent(), clientIP(r))
+		}
+		if err != nil {
+			c.log.Error("failed to rotate session", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
+
 	// Устанавливаем новый refresh token в cookie
 	http.SetCookie(w, &http.Cookie{
 		Name:        refreshTokenCookieName,
@@ -267,9 +425,10 @@ func (c *AuthController) Refresh(w http.ResponseWriter, r *http.Request) {
 }
 
 type GetUserInfoResponse struct {
-	Email    string `json:"email"`
-	SteamURL string `json:"steam_url"`
-	Photo    string `json:"photo"`
+	Email    string      `json:"email"`
+	SteamURL string      `json:"steam_url"`
+	Photo    string      `json:"photo"`
+	Role     models.Role `json:"role"`
 }
 
 func (c *AuthController) GetUserInfo(w http.ResponseWriter, r *http.Request) {
@@ -288,6 +447,8 @@ func (c *AuthController) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, ErrGetUserInfo.Error(), http.StatusInternalServerError)
 		return
 	}
+	user.Photo = photoURL(c.signer, user.Photo)
+	user.Role = middleware.RoleFromContext(r.Context())
 
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(user); err != nil {
@@ -316,17 +477,6 @@ func (c *AuthController) GetUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isAdmin, ok := r.Context().Value(middleware.IsAdminKey).(bool)
-	if !ok {
-		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
-		return
-	}
-
-	if !isAdmin {
-		http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
-		return
-	}
-
 	var users GetUsersResponse
 	var err error
 
@@ -342,7 +492,7 @@ func (c *AuthController) GetUsers(w http.ResponseWriter, r *http.Request) {
 			Id:          int(user.Id),
 			Email:       user.Email,
 			SteamURL:    user.SteamUrl,
-			PathToPhoto: user.PathToPhoto,
+			PathToPhoto: photoURL(c.signer, user.PathToPhoto),
 			IsAdmin:     user.IsAdmin,
 		})
 	}
@@ -362,17 +512,6 @@ func (c *AuthController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isAdmin, ok := r.Context().Value(middleware.IsAdminKey).(bool)
-	if !ok {
-		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
-		return
-	}
-
-	if !isAdmin {
-		http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
-		return
-	}
-
 	var user *ssov1.UpdateUserRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
@@ -398,17 +537,6 @@ func (c *AuthController) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isAdmin, ok := r.Context().Value(middleware.IsAdminKey).(bool)
-	if !ok {
-		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
-		return
-	}
-
-	if !isAdmin {
-		http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
-		return
-	}
-
 	var user *ssov1.DeleteUserRequest
 
 	parts := strings.Split(r.URL.Path, "/")
@@ -444,7 +572,7 @@ func (c *AuthController) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func generatePhotoFilename(email string) string {
+func generatePhotoFilename(email, ext string) string {
 	// Удаляем все недопустимые символы из email для имени файла
 	cleanEmail := strings.Map(func(r rune) rune {
 		switch {
@@ -461,5 +589,5 @@ func generatePhotoFilename(email string) string {
 	hash := sha256.Sum256([]byte(cleanEmail + timestamp))
 	cleanEmail = fmt.Sprintf("%x", hash[:8])
 
-	return cleanEmail + ".jpg"
+	return cleanEmail + ext
 }
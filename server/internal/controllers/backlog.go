@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"games_webapp/internal/middleware"
+)
+
+// GetStaleGames возвращает запланированные игры, помеченные как залежавшиеся
+// или не обновлявшиеся дольше months месяцев (по умолчанию 6)
+func (c *GameController) GetStaleGames(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetStaleGames"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	months, _ := strconv.Atoi(r.URL.Query().Get("months"))
+	if months < 1 {
+		months = 6
+	}
+
+	games, err := c.service.GetStaleGames(r.Context(), userID, time.Now().AddDate(0, -months, 0))
+	if err != nil {
+		c.log.Error(ErrGetUserGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetUserGames.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(games); err != nil {
+		c.log.Error(ErrGetUserGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetUserGames.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetTriage отдаёт всё, что стоит почистить в библиотеке, одним ответом.
+// Залежавшимися считаются игры, не обновлявшиеся months месяцев (по умолчанию 6)
+func (c *GameController) GetTriage(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetTriage"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	months, _ := strconv.Atoi(r.URL.Query().Get("months"))
+	if months < 1 {
+		months = 6
+	}
+
+	triage, err := c.service.GetTriage(r.Context(), userID, time.Now().AddDate(0, -months, 0))
+	if err != nil {
+		c.log.Error(ErrGetTriage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetTriage.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(triage); err != nil {
+		c.log.Error(ErrGetTriage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetTriage.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -6,6 +6,8 @@ var (
 	ErrUnauthorized = errors.New("пользователь не авторизован")
 
 	ErrNotFound     = errors.New("not found")
+	ErrNoRoute      = errors.New("маршрут не найден")
+	ErrNoMethod     = errors.New("метод не поддерживается для этого маршрута")
 	ErrGameNotFound = errors.New("игра не найдена")
 
 	ErrGetGames     = errors.New("ошибка при получении игр")
@@ -20,15 +22,40 @@ var (
 	ErrInvalidURL      = errors.New("неверный url")
 	ErrInvalidID       = errors.New("неверный id")
 
+	ErrGameFields     = errors.New("проверьте поля игры")
+	ErrEmptyTitle     = errors.New("название игры не может быть пустым")
+	ErrTitleTooLong   = errors.New("название игры должно быть не длиннее 255 символов")
+	ErrInvalidYear    = errors.New("год выхода не распознан")
+	ErrYearOutOfRange = errors.New("год выхода должен быть не раньше 1950 и не позже чем через 10 лет")
+	ErrInvalidGameURL = errors.New("ссылка на игру должна быть полным http(s) адресом")
+	ErrGenreTooLong   = errors.New("жанр должен быть не длиннее 100 символов, список жанров — 1000")
+
 	ErrParsingForm    = errors.New("ошибка при парсинге формы")
 	ErrParsingJSON    = errors.New("ошибка при парсинге json")
 	ErrInvalidRequest = errors.New("неверный формат запроса")
 
+	ErrInvalidFlexField = errors.New("недопустимое поле в запросе")
+
+	ErrGameVersionRequired = errors.New("не указана версия игры: передайте If-Match или version")
+	ErrGameChanged         = errors.New("игру уже изменил кто-то другой, загрузите её заново")
+	ErrPatchField          = errors.New("поле нельзя изменить через PATCH")
+
+	ErrGetRoles    = errors.New("ошибка при получении ролей")
+	ErrSetRole     = errors.New("ошибка при назначении роли")
+	ErrInvalidRole = errors.New("назначить можно только роли moderator и user")
+
+	ErrOverrideNotFound = errors.New("личных правок для игры нет")
+	ErrGetOverride      = errors.New("ошибка при получении личных правок игры")
+	ErrSetOverride      = errors.New("ошибка при сохранении личных правок игры")
+	ErrDeleteOverride   = errors.New("ошибка при удалении личных правок игры")
+
 	ErrReadImage           = errors.New("ошибка при чтении картинки")
 	ErrSaveImage           = errors.New("ошибка при сохранении картинки")
 	ErrImageURL            = errors.New("ошибка при получении картинки")
 	ErrDownloadImage       = errors.New("ошибка при скачивании картинки")
 	ErrUnexpectedImageType = errors.New("неожиданный тип картинки")
+	ErrImageData           = errors.New("картинка должна быть ссылкой или base64")
+	ErrImageHost           = errors.New("картинку нельзя скачать с этого адреса")
 
 	ErrCreateGame     = errors.New("ошибка при создании игры")
 	ErrCreateUserGame = errors.New("ошибка при создании связки игры и пользователя")
@@ -38,11 +65,25 @@ var (
 
 	ErrDeleteGame     = errors.New("ошибка при удалении игры")
 	ErrDeleteUserGame = errors.New("ошибка при удалении связки игры и пользователя")
+	ErrGameInUse      = errors.New("игру отслеживают другие пользователи")
 
 	ErrNoGamesNames  = errors.New("пустой запрос: нет игр")
 	ErrTooManyGames  = errors.New("нельзя создать более 100 игр одновременно")
 	ErrPartialCreate = errors.New("ошибка при множественном создании игр")
 	ErrInvalidSource = errors.New("неверный источник")
+	ErrImportTimeout = errors.New("источники не ответили вовремя")
+	ErrRepeatedName  = errors.New("название повторяется в списке")
+	ErrAlreadyOwned  = errors.New("игра уже есть в библиотеке")
+
+	ErrUnsupportedGameURL  = errors.New("ссылка должна вести на страницу игры в Steam, Википедии, IGDB, GOG или Epic Games Store")
+	ErrMetadataUnavailable = errors.New("источник не ответил, попробуйте позже")
+
+	ErrImportRunNotFound = errors.New("запуск импорта не найден")
+	ErrGetImportRun      = errors.New("ошибка при получении запуска импорта")
+	ErrNothingToRetry    = errors.New("в запуске импорта нет неудачных игр для повтора")
+
+	ErrMissingTrophyFile = errors.New("отсутствует файл CSV в поле file")
+	ErrParseTrophies     = errors.New("не удалось прочитать CSV с трофеями")
 
 	ErrRegister        = errors.New("ошибка при регистрации")
 	ErrLogin           = errors.New("ошибка при логине")
@@ -50,8 +91,27 @@ var (
 	ErrMissingPassword = errors.New("отсутствует password в запросе")
 	ErrMissingSteamURL = errors.New("отсутствует steam url в запросе")
 
+	ErrRegisterFields   = errors.New("проверьте поля формы регистрации")
+	ErrInvalidEmail     = errors.New("неверный формат email")
+	ErrPasswordTooShort = errors.New("пароль должен быть не короче 8 символов")
+	ErrPasswordTooLong  = errors.New("пароль должен быть не длиннее 72 байт")
+	ErrWeakPassword     = errors.New("пароль слишком простой: добавьте заглавные буквы, цифры или символы")
+	ErrInvalidSteamURL  = errors.New("ссылка должна вести на профиль steamcommunity.com/id/... или steamcommunity.com/profiles/...")
+
+	ErrProfileFields          = errors.New("проверьте поля профиля")
+	ErrUpdateProfile          = errors.New("ошибка при обновлении профиля")
+	ErrEmptyProfileUpdate     = errors.New("нечего обновлять: передайте email, password, steam_url или image")
+	ErrMissingCurrentPassword = errors.New("для смены email или пароля укажите текущий пароль")
+	ErrWrongCurrentPassword   = errors.New("неверный текущий пароль")
+	ErrEmailTaken             = errors.New("email уже занят")
+
+	ErrInvalidCredentials = errors.New("неверный email или пароль")
+	ErrLoginLocked        = errors.New("слишком много неудачных попыток входа, попробуйте позже")
+	ErrGetLockouts        = errors.New("ошибка при получении блокировок входа")
+	ErrClearLockout       = errors.New("ошибка при снятии блокировки входа")
+	ErrMissingLockoutKey  = errors.New("укажите email или ip")
+
 	ErrGetUserInfo = errors.New("ошибка при получении информации о пользователе")
-	ErrForbidden   = errors.New("недостаточно прав")
 
 	ErrGetUsers   = errors.New("ошибка при получении пользователей")
 	ErrUpdateUser = errors.New("ошибка при обновлении пользователя")
@@ -59,4 +119,138 @@ var (
 
 	ErrLoginTwitch = errors.New("ошибка при логине через twitch")
 	ErrUnknown     = errors.New("неизвестная ошибка")
+
+	ErrGetFeed    = errors.New("ошибка при получении ленты")
+	ErrFollow     = errors.New("ошибка при подписке на пользователя")
+	ErrUnfollow   = errors.New("ошибка при отписке от пользователя")
+	ErrGetFollows = errors.New("ошибка при получении подписок")
+	ErrSelfFollow = errors.New("нельзя подписаться на самого себя")
+
+	ErrInvalidStatus      = errors.New("неверный статус")
+	ErrGetPlaythroughs    = errors.New("ошибка при получении прохождений")
+	ErrCreatePlaythrough  = errors.New("ошибка при создании прохождения")
+	ErrUpdatePlaythrough  = errors.New("ошибка при обновлении прохождения")
+	ErrDeletePlaythrough  = errors.New("ошибка при удалении прохождения")
+	ErrNoPlaythrough      = errors.New("прохождение не найдено")
+	ErrInvalidRating      = errors.New("неверная оценка, допустимо от 1 до 10 или 0 без оценки")
+	ErrInvalidDates       = errors.New("дата окончания прохождения раньше даты начала")
+	ErrInvalidPlatform    = errors.New("слишком длинное название платформы")
+	ErrInvalidReleaseDate = errors.New("неверная дата выхода, ожидается ГГГГ-ММ-ДД")
+
+	ErrBuildReport   = errors.New("ошибка при формировании отчёта")
+	ErrInvalidMonth  = errors.New("неверный месяц, ожидается формат YYYY-MM")
+	ErrInvalidFormat = errors.New("неверный формат отчёта")
+
+	ErrGetRecommendations = errors.New("ошибка при получении рекомендаций")
+
+	ErrSearchQuery = errors.New("не указан поисковый запрос")
+	ErrSearchIGDB  = errors.New("ошибка при поиске игры в IGDB")
+
+	ErrFindDuplicates = errors.New("ошибка при поиске дубликатов")
+	ErrMergeGames     = errors.New("ошибка при объединении игр")
+	ErrGetDevelopers  = errors.New("не удалось получить список разработчиков")
+	ErrBackfillSteam  = errors.New("ошибка при заполнении appid Steam")
+
+	ErrGetSettings    = errors.New("ошибка при получении настроек")
+	ErrUpdateSettings = errors.New("ошибка при обновлении настроек")
+
+	ErrGetTriage = errors.New("ошибка при проверке библиотеки")
+
+	ErrShuttingDown = errors.New("сервер останавливается, повторите запрос позже")
+
+	ErrInvalidImage     = errors.New("картинка не прошла проверку")
+	ErrImageTooLarge    = errors.New("картинка слишком большая")
+	ErrUnsupportedImage = errors.New("неподдерживаемый формат картинки")
+	ErrCorruptImage     = errors.New("картинка повреждена")
+
+	ErrCollectUploads = errors.New("ошибка при очистке загрузок")
+	ErrQuotaExceeded  = errors.New("превышена квота загрузок: удалите ненужные картинки или загрузите файл меньше")
+	ErrGetUploadUsage = errors.New("ошибка при подсчёте занятого места")
+
+	ErrInfectedImage   = errors.New("антивирус нашёл в файле угрозу, файл не сохранён")
+	ErrScanUnavailable = errors.New("не удалось проверить файл антивирусом, повторите позже")
+
+	ErrGetMissingCovers = errors.New("ошибка при поиске игр без обложек")
+	ErrRefetchCover     = errors.New("ошибка при загрузке обложки")
+	ErrCoverNotFound    = errors.New("обложка не найдена в источниках")
+
+	ErrProxyHost  = errors.New("картинки с этого сайта не отдаются")
+	ErrProxyImage = errors.New("не удалось получить картинку")
+
+	ErrCreateFlag        = errors.New("ошибка при отправке жалобы")
+	ErrGetFlags          = errors.New("ошибка при получении жалоб")
+	ErrResolveFlag       = errors.New("ошибка при закрытии жалобы")
+	ErrFlagNotFound      = errors.New("жалоба не найдена")
+	ErrInvalidFlagReason = errors.New("неверная причина: wrong_metadata, inappropriate_image, duplicate или other")
+	ErrInvalidFlagAction = errors.New("неверное действие: edit, delete или dismiss")
+	ErrInvalidFlagStatus = errors.New("неверный статус: open, resolved или dismissed")
+	ErrFlagComment       = errors.New("комментарий к жалобе слишком длинный")
+	ErrAlreadyFlagged    = errors.New("вы уже пожаловались на эту игру")
+	ErrFlagClosed        = errors.New("жалоба уже закрыта")
+
+	ErrUpdateNotes  = errors.New("ошибка при сохранении заметок")
+	ErrNotesTooLong = errors.New("заметки слишком длинные")
+	ErrNotInLibrary = errors.New("игры нет в библиотеке пользователя")
+
+	ErrToggleFavorite = errors.New("ошибка при изменении избранного")
+
+	ErrReorder          = errors.New("ошибка при изменении порядка игр")
+	ErrReorderTooMany   = errors.New("слишком много игр, приоритет можно задать не более чем 10 играм")
+	ErrReorderDuplicate = errors.New("игра указана в списке несколько раз")
+	ErrReorderStatus    = errors.New("порядок задаётся внутри одного статуса: укажите status, и все игры должны быть в нём")
+
+	ErrBulkUpdate  = errors.New("ошибка при массовом обновлении игр")
+	ErrBulkDelete  = errors.New("ошибка при массовом удалении игр")
+	ErrBulkEmpty   = errors.New("не указаны игры")
+	ErrBulkTooMany = errors.New("слишком много игр в одном запросе")
+
+	ErrInvalidCompletion = errors.New("неверный прогресс прохождения: процент от 0 до 100, выполненных достижений не больше общего числа")
+
+	ErrInvalidPhotoLink = errors.New("ссылка на фото недействительна или устарела")
+	ErrPhotoNotFound    = errors.New("фото не найдено")
+	ErrGetPhoto         = errors.New("ошибка при получении фото")
+
+	ErrCreateToken        = errors.New("ошибка при создании токена")
+	ErrGetTokens          = errors.New("ошибка при получении токенов")
+	ErrRevokeToken        = errors.New("ошибка при отзыве токена")
+	ErrInvalidTokenName   = errors.New("название токена должно быть от 1 до 100 символов")
+	ErrInvalidTokenScope  = errors.New("неверные права токена: read или read_write")
+	ErrTooManyTokens      = errors.New("достигнуто максимальное число токенов")
+	ErrTokenNotFound      = errors.New("токен не найден")
+	ErrTokenAuthForbidden = errors.New("управлять токенами можно только после входа в аккаунт")
+
+	ErrFeatureNotFound     = errors.New("флаг не найден")
+	ErrSetFeature          = errors.New("ошибка при переключении флага")
+	ErrClearFeature        = errors.New("ошибка при сбросе флага")
+	ErrMissingFeatureState = errors.New("укажите enabled: true или false")
+
+	ErrGetSessions     = errors.New("ошибка при получении сессий")
+	ErrRevokeSession   = errors.New("ошибка при завершении сессии")
+	ErrSessionNotFound = errors.New("сессия не найдена")
+	ErrSessionRevoked  = errors.New("сессия завершена, войдите заново")
+
+	ErrCreateWebhook        = errors.New("ошибка при создании вебхука")
+	ErrGetWebhooks          = errors.New("ошибка при получении вебхуков")
+	ErrDeleteWebhook        = errors.New("ошибка при удалении вебхука")
+	ErrGetDeliveries        = errors.New("ошибка при получении журнала отправок")
+	ErrInvalidWebhookURL    = errors.New("адрес вебхука должен быть ссылкой http или https")
+	ErrPrivateWebhookURL    = errors.New("адрес вебхука ведёт во внутреннюю сеть")
+	ErrInvalidWebhookEvents = errors.New("неверные события: game.created, status.changed, game.finished")
+	ErrTooManyWebhooks      = errors.New("достигнуто максимальное число вебхуков")
+	ErrWebhookNotFound      = errors.New("вебхук не найден")
+
+	ErrTelegramLink   = errors.New("ошибка при создании ссылки привязки Telegram")
+	ErrGetTelegram    = errors.New("ошибка при получении привязки Telegram")
+	ErrTelegramUnlink = errors.New("ошибка при отвязке Telegram")
+
+	ErrInvalidDefaultSort = errors.New("неверная сортировка по умолчанию: title, year, priority или favorite")
+	ErrInvalidSortOrder   = errors.New("неверное направление сортировки: asc или desc")
+	ErrInvalidPageSize    = errors.New("размер страницы должен быть от 1 до 100")
+	ErrInvalidLanguage    = errors.New("неверный язык: ru или en")
+	ErrInvalidVisibility  = errors.New("неверная видимость: followers, private или public")
+	ErrInvalidPublicSlug  = errors.New("адрес профиля: от 3 до 32 символов, латиница в нижнем регистре, цифры, - и _")
+	ErrPublicSlugTaken    = errors.New("адрес профиля уже занят")
+
+	ErrProfileNotFound = errors.New("профиль не найден")
+	ErrGetPublicFeed   = errors.New("ошибка при получении ленты профиля")
 )
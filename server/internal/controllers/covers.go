@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"games_webapp/internal/metadata"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CoverServicer interface {
+	Missing(ctx context.Context) ([]models.Game, error)
+	RefetchGame(ctx context.Context, id int) (models.CoverResult, error)
+}
+
+type CoverController struct {
+	covers CoverServicer
+	log    *slog.Logger
+}
+
+func NewCoverController(covers CoverServicer, log *slog.Logger) *CoverController {
+	return &CoverController{
+		covers: covers,
+		log:    log,
+	}
+}
+
+// GetMissingCovers возвращает игры без обложки или с обложкой, файла которой нет
+func (c *CoverController) GetMissingCovers(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.covers.GetMissingCovers"
+
+	games, err := c.covers.Missing(r.Context())
+	if err != nil {
+		c.log.Error(ErrGetMissingCovers.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetMissingCovers.Error(), http.StatusInternalServerError)
+		return
+	}
+	if games == nil {
+		games = []models.Game{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(games); err != nil {
+		c.log.Error(ErrGetMissingCovers.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+// RefetchCover заново ищет и скачивает обложку игры: сначала в источнике, из
+// которого игра импортирована, потом во всех источниках по ссылке и названию
+func (c *CoverController) RefetchCover(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.covers.RefetchCover"
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := c.covers.RefetchGame(r.Context(), id)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, ErrGameNotFound.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrNoCover), errors.Is(err, metadata.ErrNotFound):
+		http.Error(w, ErrCoverNotFound.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		c.log.Error(ErrRefetchCover.Error(), slog.String("operation", op), slog.Int("game_id", id), slog.String("error", err.Error()))
+		http.Error(w, ErrRefetchCover.Error(), http.StatusBadGateway)
+		return
+	}
+
+	c.log.Info("cover refetched", slog.Int("game_id", id), slog.String("url", result.URL), slog.String("image", result.Image))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		c.log.Error(ErrRefetchCover.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
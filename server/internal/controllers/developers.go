@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+)
+
+// GetDevelopers возвращает разработчиков игр из библиотеки пользователя с количеством его игр
+func (c *GameController) GetDevelopers(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetDevelopers"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	developers, err := c.service.GetDevelopers(r.Context(), userID)
+	if err != nil {
+		c.log.Error(ErrGetDevelopers.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetDevelopers.Error(), http.StatusInternalServerError)
+		return
+	}
+	if developers == nil {
+		developers = []models.DeveloperCount{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(developers); err != nil {
+		c.log.Error(ErrGetDevelopers.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetDevelopers.Error(), http.StatusInternalServerError)
+		return
+	}
+}
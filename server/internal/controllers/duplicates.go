@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"games_webapp/internal/models"
+)
+
+type MergeGamesRequest struct {
+	SurvivorID  int `json:"survivor_id"`
+	DuplicateID int `json:"duplicate_id"`
+}
+
+func (c *GameController) FindDuplicates(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.FindDuplicates"
+
+	groups, err := c.service.FindDuplicates(r.Context())
+	if err != nil {
+		c.log.Error(ErrFindDuplicates.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrFindDuplicates.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if groups == nil {
+		groups = []models.DuplicateGroup{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		c.log.Error(ErrFindDuplicates.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrFindDuplicates.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *GameController) MergeGames(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.MergeGames"
+
+	var request MergeGamesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.SurvivorID <= 0 || request.DuplicateID <= 0 || request.SurvivorID == request.DuplicateID {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	survivor, orphanImage, err := c.service.MergeGames(r.Context(), request.SurvivorID, request.DuplicateID)
+	if err != nil {
+		c.log.Error(ErrMergeGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrMergeGames.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if orphanImage != "" && orphanImage != survivor.Image {
+		// Игры уже объединены, лишний файл не повод возвращать ошибку
+		c.releaseImage(r.Context(), op, orphanImage)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(survivor); err != nil {
+		c.log.Error(ErrMergeGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrMergeGames.Error(), http.StatusInternalServerError)
+		return
+	}
+}
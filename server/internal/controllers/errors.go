@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ErrorResponse — тело ответа с ошибкой для маршрутов, которых нет
+type ErrorResponse struct {
+	Error  string   `json:"error"`
+	Path   string   `json:"path"`
+	Method string   `json:"method"`
+	Allow  []string `json:"allow,omitempty"`
+}
+
+// routeMethods — методы, которые проверяются при поиске разрешённых для маршрута
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// NotFound отвечает JSON-ошибкой вместо текстовой страницы chi
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, ErrorResponse{
+		Error:  ErrNoRoute.Error(),
+		Path:   r.URL.Path,
+		Method: r.Method,
+	})
+}
+
+// MethodNotAllowed возвращает обработчик 405 для маршрутов routes. Он перечисляет
+// разрешённые методы в заголовке Allow, а на OPTIONS отвечает 204 с тем же заголовком
+func MethodNotAllowed(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allow := allowedMethods(routes, r.URL.Path)
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		writeError(w, http.StatusMethodNotAllowed, ErrorResponse{
+			Error:  ErrNoMethod.Error(),
+			Path:   r.URL.Path,
+			Method: r.Method,
+			Allow:  allow,
+		})
+	}
+}
+
+// allowedMethods перебирает методы и оставляет те, для которых у пути есть обработчик.
+// HEAD доступен везде, где есть GET
+func allowedMethods(routes chi.Routes, path string) []string {
+	allow := []string{}
+	for _, method := range routeMethods {
+		if !routes.Match(chi.NewRouteContext(), method, path) {
+			continue
+		}
+		allow = append(allow, method)
+		if method == http.MethodGet {
+			allow = append(allow, http.MethodHead)
+		}
+	}
+	return append(allow, http.MethodOptions)
+}
+
+func writeError(w http.ResponseWriter, status int, resp ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"games_webapp/internal/features"
+	"games_webapp/internal/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type FeatureServicer interface {
+	ForUser(userID int) map[string]bool
+	List() []features.State
+	SetOverride(name string, enabled bool, by int) error
+	ClearOverride(name string) error
+}
+
+type FeatureController struct {
+	service FeatureServicer
+	log     *slog.Logger
+}
+
+func NewFeatureController(s FeatureServicer, log *slog.Logger) *FeatureController {
+	return &FeatureController{
+		service: s,
+		log:     log,
+	}
+}
+
+type SetFeatureRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// GetFeatures возвращает флаги, включённые или выключенные для текущего пользователя
+func (c *FeatureController) GetFeatures(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.features.GetFeatures"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c.service.ForUser(userID))
+}
+
+// GetAdminFeatures возвращает все флаги с настройками из конфига и переопределениями
+func (c *FeatureController) GetAdminFeatures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c.service.List())
+}
+
+// SetFeature включает или выключает флаг для всех поверх конфига
+func (c *FeatureController) SetFeature(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.features.SetFeature"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req SetFeatureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Enabled == nil {
+		c.log.Error(ErrMissingFeatureState.Error(), slog.String("operation", op))
+		http.Error(w, ErrMissingFeatureState.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	if err := c.service.SetOverride(name, *req.Enabled, userID); err != nil {
+		c.writeError(w, op, name, ErrSetFeature, err)
+		return
+	}
+
+	c.GetAdminFeatures(w, r)
+}
+
+// ClearFeature возвращает флаг к настройкам из конфига
+func (c *FeatureController) ClearFeature(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.features.ClearFeature"
+
+	name := chi.URLParam(r, "name")
+	if err := c.service.ClearOverride(name); err != nil {
+		c.writeError(w, op, name, ErrClearFeature, err)
+		return
+	}
+
+	c.GetAdminFeatures(w, r)
+}
+
+func (c *FeatureController) writeError(w http.ResponseWriter, op, name string, public, err error) {
+	if errors.Is(err, features.ErrUnknownFlag) {
+		c.log.Error(ErrFeatureNotFound.Error(), slog.String("operation", op), slog.String("flag", name))
+		http.Error(w, ErrFeatureNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	c.log.Error(public.Error(), slog.String("operation", op), slog.String("flag", name), slog.String("error", err.Error()))
+	http.Error(w, public.Error(), http.StatusInternalServerError)
+}
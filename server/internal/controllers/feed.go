@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type FeedServicer interface {
+	Follow(followerID, followeeID int) error
+	Unfollow(followerID, followeeID int) error
+	GetFollowing(userID int) ([]models.Follow, error)
+	GetFollowers(userID int) ([]models.Follow, error)
+	GetFeed(userID int, page, pageSize int) ([]models.FeedItem, int, error)
+}
+
+type FeedController struct {
+	service FeedServicer
+	log     *slog.Logger
+}
+
+func NewFeedController(s FeedServicer, log *slog.Logger) *FeedController {
+	return &FeedController{
+		service: s,
+		log:     log,
+	}
+}
+
+type FeedResponse struct {
+	Total   int               `json:"total"`
+	Pages   int               `json:"pages"`
+	Current int               `json:"current"`
+	Size    int               `json:"size"`
+	Data    []models.FeedItem `json:"data"`
+}
+
+func (c *FeedController) GetFeed(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.feed.GetFeed"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize < 1 {
+		if settings := middleware.SettingsFromContext(r.Context()); settings != nil {
+			pageSize = settings.DefaultPageSize
+		}
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	items, total, err := c.service.GetFeed(userID, page, pageSize)
+	if err != nil {
+		c.log.Error(ErrGetFeed.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetFeed.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := total / pageSize
+	if total%pageSize != 0 {
+		totalPages++
+	}
+
+	response := FeedResponse{
+		Total:   total,
+		Pages:   totalPages,
+		Current: page,
+		Size:    pageSize,
+		Data:    items,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		c.log.Error(ErrGetFeed.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetFeed.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *FeedController) Follow(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.feed.Follow"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	followeeID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || followeeID <= 0 {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.Follow(userID, followeeID); err != nil {
+		if errors.Is(err, services.ErrSelfFollow) {
+			c.log.Error(ErrSelfFollow.Error(), slog.String("operation", op))
+			http.Error(w, ErrSelfFollow.Error(), http.StatusBadRequest)
+			return
+		}
+		c.log.Error(ErrFollow.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrFollow.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *FeedController) Unfollow(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.feed.Unfollow"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	followeeID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || followeeID <= 0 {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.Unfollow(userID, followeeID); err != nil {
+		c.log.Error(ErrUnfollow.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUnfollow.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *FeedController) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.feed.GetFollowing"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	follows, err := c.service.GetFollowing(userID)
+	if err != nil {
+		c.log.Error(ErrGetFollows.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetFollows.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(follows); err != nil {
+		c.log.Error(ErrGetFollows.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetFollows.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *FeedController) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.feed.GetFollowers"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	follows, err := c.service.GetFollowers(userID)
+	if err != nil {
+		c.log.Error(ErrGetFollows.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetFollows.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(follows); err != nil {
+		c.log.Error(ErrGetFollows.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetFollows.Error(), http.StatusInternalServerError)
+		return
+	}
+}
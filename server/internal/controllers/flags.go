@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type FlagServicer interface {
+	Create(ctx context.Context, userID, gameID int, reason models.FlagReason, comment string) (*models.GameFlag, error)
+	List(ctx context.Context, status models.FlagStatus, page, pageSize int) ([]models.GameFlag, int, error)
+	Resolve(ctx context.Context, id, moderatorID int, action models.FlagAction, version int, patch *models.GamePatch) (*models.GameFlag, error)
+}
+
+type FlagController struct {
+	service FlagServicer
+	log     *slog.Logger
+}
+
+func NewFlagController(s FlagServicer, log *slog.Logger) *FlagController {
+	return &FlagController{
+		service: s,
+		log:     log,
+	}
+}
+
+type CreateFlagRequest struct {
+	Reason  models.FlagReason `json:"reason"`
+	Comment string            `json:"comment"`
+}
+
+// ResolveFlagRequest — решение по жалобе. Game — тело как у PATCH /api/games/{id}
+// вместе с version; нужно только для edit, без него жалобы просто закрываются
+type ResolveFlagRequest struct {
+	Action models.FlagAction          `json:"action"`
+	Game   map[string]json.RawMessage `json:"game"`
+}
+
+type FlagsResponse struct {
+	Total   int               `json:"total"`
+	Pages   int               `json:"pages"`
+	Current int               `json:"current"`
+	Size    int               `json:"size"`
+	Data    []models.GameFlag `json:"data"`
+}
+
+// CreateFlag принимает жалобу пользователя на запись игры в каталоге
+func (c *FlagController) CreateFlag(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.flags.CreateFlag"
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || gameID <= 0 {
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req CreateFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flag, err := c.service.Create(r.Context(), userID, gameID, req.Reason, req.Comment)
+	switch {
+	case errors.Is(err, services.ErrInvalidFlagReason):
+		http.Error(w, ErrInvalidFlagReason.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrFlagComment):
+		http.Error(w, ErrFlagComment.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, ErrGameNotFound.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrAlreadyFlagged):
+		http.Error(w, ErrAlreadyFlagged.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		c.log.Error(ErrCreateFlag.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrCreateFlag.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(flag); err != nil {
+		c.log.Error(ErrCreateFlag.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+// GetFlags возвращает жалобы для модераторов: по умолчанию открытые, старые первыми
+func (c *FlagController) GetFlags(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.flags.GetFlags"
+
+	query := r.URL.Query()
+
+	status := models.FlagStatus(query.Get("status"))
+	if status == "" {
+		status = models.FlagOpen
+	}
+	if !status.IsValid() {
+		http.Error(w, ErrInvalidFlagStatus.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize < 1 {
+		pageSize = 20
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	flags, total, err := c.service.List(r.Context(), status, page, pageSize)
+	if err != nil {
+		c.log.Error(ErrGetFlags.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetFlags.Error(), http.StatusInternalServerError)
+		return
+	}
+	if flags == nil {
+		flags = []models.GameFlag{}
+	}
+
+	totalPages := total / pageSize
+	if total%pageSize != 0 {
+		totalPages++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(FlagsResponse{
+		Total:   total,
+		Pages:   totalPages,
+		Current: page,
+		Size:    pageSize,
+		Data:    flags,
+	}); err != nil {
+		c.log.Error(ErrGetFlags.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+// ResolveFlag закрывает жалобу: edit правит игру и закрывает все жалобы на неё,
+// delete удаляет игру, dismiss отклоняет жалобу
+func (c *FlagController) ResolveFlag(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.flags.ResolveFlag"
+
+	moderatorID, _ := middleware.UserIDFromContext(r.Context())
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req ResolveFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var patch *models.GamePatch
+	var version int
+	if req.Action == models.FlagActionEdit && len(req.Game) > 0 {
+		p, bodyVersion, field, err := decodeGamePatch(req.Game)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%s: %s", field, err.Error()), http.StatusBadRequest)
+			return
+		}
+		if version, err = gameVersion(r, bodyVersion); err != nil {
+			http.Error(w, ErrGameVersionRequired.Error(), http.StatusPreconditionRequired)
+			return
+		}
+		patch = &p
+	}
+
+	flag, err := c.service.Resolve(r.Context(), id, moderatorID, req.Action, version, patch)
+	switch {
+	case errors.Is(err, services.ErrInvalidFlagAction):
+		http.Error(w, ErrInvalidFlagAction.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, ErrFlagNotFound.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrFlagClosed):
+		http.Error(w, ErrFlagClosed.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, storage.ErrConflict):
+		http.Error(w, ErrGameChanged.Error(), http.StatusConflict)
+		return
+	case writeGameFields(w, c.log, op, err):
+		return
+	case err != nil:
+		c.log.Error(ErrResolveFlag.Error(), slog.String("operation", op), slog.Int("flag_id", id), slog.String("error", err.Error()))
+		http.Error(w, ErrResolveFlag.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(flag); err != nil {
+		c.log.Error(ErrResolveFlag.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
@@ -1,26 +1,30 @@
 package controllers
 
 import (
-	"bytes"
 	"context"
-	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"path/filepath"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
+	"games_webapp/internal/clients/igdb"
+	"games_webapp/internal/httpx"
+	"games_webapp/internal/metadata"
 	"games_webapp/internal/middleware"
 	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage"
 	"games_webapp/internal/storage/uploads"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
 )
 
 // ======================
@@ -28,48 +32,135 @@ import (
 // ======================
 
 type GameServicer interface {
-	GetByID(id int) (*models.Game, error)
-	SearchAllGames(query string) ([]models.Game, error)
-	GetUserGames(userID int, status *models.GameStatus, search, sortBy, sortOrder string, page, pageSize int) ([]models.UserGameResponse, int, error)
-	GetUserGame(userID, gameID int) (*models.UserGames, error)
-	GetGamesPaginated(userID int, search, sortBy, sortOrder string, page, pageSize int) ([]models.UserGameResponse, int, error)
-	GetFlex(userID int, fields []string, where []models.WhereQuery, order []models.Sort, limit int, offset int) ([]models.UserGameResponse, error)
-
-	Create(game *models.Game) (*models.Game, error)
-	Update(game *models.Game) (*models.Game, error)
-	Delete(id int) error
-	GetGameByURL(url string) error
-	CreateUserGame(ug *models.UserGames) error
-	UpdateUserGame(ug *models.UserGames) error
-	DeleteUserGame(userID, gameID int) error
-	GetFinishedGames(userID int) (int, error)
-	GetPlayingGames(userID int) (int, error)
-	GetPlannedGames(userID int) (int, error)
-	GetDroppedGames(userID int) (int, error)
+	GetByID(ctx context.Context, id int) (*models.Game, error)
+	SearchAllGames(ctx context.Context, query string) ([]models.Game, error)
+	GetUserGames(ctx context.Context, userID int, status *models.GameStatus, search string, filter models.GameFilter, sortBy, sortOrder string, page, pageSize int) ([]models.UserGameResponse, int, error)
+	GetLibraryEntries(ctx context.Context, userID int, status *models.GameStatus, search string, filter models.GameFilter, sortBy, sortOrder string, page, pageSize int) ([]models.LibraryEntry, int, error)
+	GetUserGame(ctx context.Context, userID, gameID int) (*models.UserGames, error)
+	GetGamesPaginated(ctx context.Context, userID int, search string, filter models.GameFilter, sortBy, sortOrder string, page, pageSize int) ([]models.UserGameResponse, int, error)
+	GetFlex(ctx context.Context, userID int, joins []string, fields []string, where []models.WhereQuery, order []models.Sort, limit int, offset int) ([]models.UserGameResponse, error)
+	GetFlexAggregate(ctx context.Context, userID int, joins []string, where []models.WhereQuery, aggregate models.FlexAggregation, order []models.Sort, limit int, offset int) ([]map[string]interface{}, error)
+
+	Create(ctx context.Context, game *models.Game) (*models.Game, bool, error)
+	CreateWithUserGame(ctx context.Context, game *models.Game, ug *models.UserGames) (*models.Game, bool, error)
+	Update(ctx context.Context, game *models.Game) (*models.Game, error)
+	Patch(ctx context.Context, id, version int, patch models.GamePatch) (*models.Game, error)
+
+	GetOverride(ctx context.Context, userID, gameID int) (*models.GameOverride, error)
+	SetOverride(ctx context.Context, userID, gameID int, title, notes string, image *string) (*models.GameOverride, string, error)
+	DeleteOverride(ctx context.Context, userID, gameID int) (string, error)
+	Delete(ctx context.Context, id, requesterID int, force bool) (int, error)
+	GetGameByURL(ctx context.Context, url string) error
+	CreateUserGame(ctx context.Context, ug *models.UserGames) error
+	UpdateUserGame(ctx context.Context, ug *models.UserGames) error
+	UpdatePriority(ctx context.Context, ug *models.UserGames) error
+	BackfillSteamAppIDs(ctx context.Context) (int, error)
+	BulkUpdateStatus(ctx context.Context, userID int, gameIDs []int, status models.GameStatus) ([]models.BulkItem, error)
+	BulkDeleteUserGames(ctx context.Context, userID int, gameIDs []int, deleteOwned bool) ([]models.BulkItem, []models.Game, error)
+	ReorderPriorities(ctx context.Context, userID int, status models.GameStatus, gameIDs []int) error
+	UpdateNotes(ctx context.Context, userID, gameID int, notes string) (*models.UserGames, error)
+	ToggleFavorite(ctx context.Context, userID, gameID int) (*models.UserGames, error)
+	DeleteUserGame(ctx context.Context, userID, gameID int) error
+	GetStatusCounts(ctx context.Context, userID int) (*models.StatusCounts, error)
+	GetLibraryStats(ctx context.Context, userID, months int, now time.Time) (*models.LibraryStats, error)
+
+	GetPlaythroughs(ctx context.Context, userID, gameID int) ([]models.UserGames, error)
+	StartPlaythrough(ctx context.Context, ug *models.UserGames) error
+	ActivatePlaythrough(ctx context.Context, userID, gameID, playthroughID int) error
+	UpdatePlaythrough(ctx context.Context, ug *models.UserGames, notes, platform *string) error
+	DeletePlaythrough(ctx context.Context, userID, gameID, playthroughID int) error
+
+	RecordImportRun(ctx context.Context, run *models.ImportRun) error
+	RecordImportRetry(ctx context.Context, retriedID int, run *models.ImportRun) error
+	GetImportRun(ctx context.Context, id, userID int) (*models.ImportRun, error)
+	GetLibraryProfile(ctx context.Context, userID int, limit int) (*models.LibraryProfile, error)
+	GetDevelopers(ctx context.Context, userID int) ([]models.DeveloperCount, error)
+	FindDuplicates(ctx context.Context) ([]models.DuplicateGroup, error)
+	MergeGames(ctx context.Context, survivorID, duplicateID int) (*models.Game, string, error)
+	AcquireImage(ctx context.Context, hash, filename string, size int64, uploadedBy int) (string, bool, error)
+	ReleaseImage(ctx context.Context, filename string) (bool, error)
+
+	GetStaleGames(ctx context.Context, userID int, olderThan time.Time) ([]models.UserGameResponse, error)
+	GetUpcomingGames(ctx context.Context, userID int, from, to time.Time) ([]models.UserGameResponse, error)
+	GetTriage(ctx context.Context, userID int, staleBefore time.Time) (*models.Triage, error)
 }
 
 // ======================
 // CONSTRUCTOR
 // ======================
 
+type IGDBClient interface {
+	Login(ctx context.Context) (*igdb.Token, error)
+	Search(ctx context.Context, query string, limit int, token *igdb.Token) ([]igdb.GameInfo, error)
+	GetByIDs(ctx context.Context, ids []int, token *igdb.Token) ([]igdb.GameInfo, error)
+	FindSimilar(ctx context.Context, genres, developers []string, minRating int, token *igdb.Token) ([]igdb.GameInfo, error)
+}
+
+// MetadataChain — источники сведений об играх для импорта, см. metadata.Chain
+type MetadataChain interface {
+	Fetch(ctx context.Context, query string) (*metadata.Game, error)
+	FetchFrom(ctx context.Context, provider, query string) (*metadata.Game, error)
+	Has(provider string) bool
+}
+
+// Tracker учитывает фоновую работу, которую нужно дождаться при остановке сервера
+type Tracker interface {
+	Track() (done func(), ok bool)
+}
+
 type GameController struct {
-	service            GameServicer
-	log                *slog.Logger
-	uploads            uploads.IUploads
-	twitchClientId     string
-	twitchClientSecret string
+	service GameServicer
+	log     *slog.Logger
+	uploads uploads.IUploads
+	igdb    IGDBClient
+	tracker Tracker
+
+	metadata MetadataChain
+	limits   ImportLimits
+	quota    UploadQuota
+}
+
+// ImportLimits — параллельность и сроки импорта списка игр
+type ImportLimits struct {
+	Workers     int
+	ItemTimeout time.Duration // на одно название
+	Budget      time.Duration // предел для всего списка
 }
 
-func NewGameController(s GameServicer, log *slog.Logger, u uploads.IUploads, twitchClientId, twitchClientSecret string) *GameController {
+// deadline — срок импорта n названий: столько волн по ItemTimeout, сколько нужно
+// при Workers одновременно, но не больше Budget
+func (l ImportLimits) deadline(n int) time.Duration {
+	waves := (n + l.Workers - 1) / l.Workers
+	return min(time.Duration(waves)*l.ItemTimeout, l.Budget)
+}
+
+func NewGameController(s GameServicer, log *slog.Logger, u uploads.IUploads, igdbClient IGDBClient, tracker Tracker) *GameController {
 	return &GameController{
-		service:            s,
-		log:                log,
-		uploads:            u,
-		twitchClientId:     twitchClientId,
-		twitchClientSecret: twitchClientSecret,
+		service: s,
+		log:     log,
+		uploads: u,
+		igdb:    igdbClient,
+		tracker: tracker,
+		limits:  ImportLimits{Workers: 10, ItemTimeout: 10 * time.Second, Budget: 2 * time.Minute},
 	}
 }
 
+// UseMetadata подключает цепочку источников для импорта по названию. Без неё
+// игры ищутся только в IGDB
+func (c *GameController) UseMetadata(m MetadataChain) {
+	c.metadata = m
+}
+
+// UseImportLimits заменяет параллельность и сроки импорта списка
+func (c *GameController) UseImportLimits(l ImportLimits) {
+	c.limits = l
+}
+
+// UseUploadQuota включает проверку квоты загрузок для обложек от пользователей
+func (c *GameController) UseUploadQuota(q UploadQuota) {
+	c.quota = q
+}
+
 // ======================
 // GETTERS
 // ======================
@@ -82,14 +173,21 @@ type PaginationResponse struct {
 	Data    []models.UserGameResponse `json:"data"`
 }
 
+// gameFilter читает из query общие для списков игр фильтры
+func gameFilter(query url.Values) models.GameFilter {
+	return models.GameFilter{
+		Genre:     strings.TrimSpace(query.Get("genre")),
+		Developer: strings.TrimSpace(query.Get("developer")),
+		Publisher: strings.TrimSpace(query.Get("publisher")),
+		Platform:  strings.TrimSpace(query.Get("platform")),
+		PlayedOn:  strings.TrimSpace(query.Get("played_on")),
+	}
+}
+
 func (c *GameController) GetAll(w http.ResponseWriter, r *http.Request) {
 	const op = "controllers.games.GetAll"
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
-		fmt.Println("+++++++++++++++++++++++++++++++++++")
-		fmt.Println("+++++++++++++++++++++++++++++++++++")
-		fmt.Println("+++++++++++++++++++++++++++++++++++")
-		fmt.Println("+++++++++++++++++++++++++++++++++++")
 		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
 		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
 		return
@@ -97,22 +195,15 @@ func (c *GameController) GetAll(w http.ResponseWriter, r *http.Request) {
 
 	query := r.URL.Query()
 	search := strings.TrimSpace(query.Get("search"))
-	sortBy := query.Get("sort_by")
-	sortOrder := query.Get("sort_order")
+	filter := gameFilter(query)
+	sortBy, sortOrder, pageSize := listDefaults(r.Context(), query)
 
 	page, _ := strconv.Atoi(query.Get("page"))
 	if page < 1 {
 		page = 1
 	}
 
-	pageSize, _ := strconv.Atoi(query.Get("page_size"))
-	if pageSize < 1 {
-		pageSize = 10
-	} else if pageSize > 100 {
-		pageSize = 100
-	}
-
-	games, total, err := c.service.GetGamesPaginated(userID, search, sortBy, sortOrder, page, pageSize)
+	games, total, err := c.service.GetGamesPaginated(r.Context(), userID, search, filter, sortBy, sortOrder, page, pageSize)
 	if err != nil {
 		c.log.Error(ErrGetGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
@@ -124,6 +215,8 @@ func (c *GameController) GetAll(w http.ResponseWriter, r *http.Request) {
 		totalPages++
 	}
 
+	localizeGames(r.Context(), games)
+
 	response := PaginationResponse{
 		Total:   total,
 		Pages:   totalPages,
@@ -162,7 +255,7 @@ func (c *GameController) GetByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, ErrGetGames.Error(), http.StatusBadRequest)
 		return
 	}
-	res, err := c.service.GetByID(int(id_s))
+	res, err := c.service.GetByID(r.Context(), int(id_s))
 	if err != nil {
 		c.log.Error(
 			ErrGetGame.Error(),
@@ -173,7 +266,10 @@ func (c *GameController) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	localizeGame(r.Context(), res)
+
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", gameETag(res.Version))
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(res); err != nil {
 		c.log.Error(ErrGetGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
@@ -187,7 +283,6 @@ func (c *GameController) GetUserGames(w http.ResponseWriter, r *http.Request) {
 
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
-		fmt.Println(r.Context())
 		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
 		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
 		return
@@ -202,23 +297,16 @@ func (c *GameController) GetUserGames(w http.ResponseWriter, r *http.Request) {
 	}
 
 	search := strings.TrimSpace(query.Get("search"))
+	filter := gameFilter(query)
 
-	sortBy := query.Get("sort_by")
-	sortOrder := query.Get("sort_order")
+	sortBy, sortOrder, pageSize := listDefaults(r.Context(), query)
 
 	page, _ := strconv.Atoi(query.Get("page"))
 	if page < 1 {
 		page = 1
 	}
 
-	pageSize, _ := strconv.Atoi(query.Get("page_size"))
-	if pageSize < 1 {
-		pageSize = 10
-	} else if pageSize > 100 {
-		pageSize = 100
-	}
-
-	games, total, err := c.service.GetUserGames(int(userID), status, search, sortBy, sortOrder, page, pageSize)
+	games, total, err := c.service.GetUserGames(r.Context(), int(userID), status, search, filter, sortBy, sortOrder, page, pageSize)
 	if err != nil {
 		c.log.Error(ErrGetUserGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
@@ -230,6 +318,8 @@ func (c *GameController) GetUserGames(w http.ResponseWriter, r *http.Request) {
 		totalPages++
 	}
 
+	localizeGames(r.Context(), games)
+
 	response := PaginationResponse{
 		Total:   total,
 		Pages:   totalPages,
@@ -248,18 +338,36 @@ func (c *GameController) GetUserGames(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// FlexRequest — выборка по библиотеке текущего пользователя. user_games
+// присоединяется всегда, пользователь берётся из токена
 type FlexRequest struct {
-	UserID int                 `json:"user_id"`
+	Joins  []string            `json:"joins"`
 	Fields []string            `json:"fields"`
 	Where  []models.WhereQuery `json:"where"`
 	Order  []models.Sort       `json:"order"`
 	Limit  int                 `json:"limit"`
 	Offset int                 `json:"offset"`
+	// Aggregate вместо списка игр возвращает строки с группировкой и агрегатами
+	Aggregate *models.FlexAggregation `json:"aggregate"`
+}
+
+// FlexErrorResponse называет поле запроса, из-за которого он отклонён
+type FlexErrorResponse struct {
+	Error  string `json:"error"`
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
 }
 
 func (c *GameController) GetFlex(w http.ResponseWriter, r *http.Request) {
 	const op = "controllers.games.GetFlex"
 
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
 	var req FlexRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		c.log.Error(ErrInvalidRequest.Error(), slog.String("operation", op), slog.String("error", err.Error()))
@@ -267,8 +375,33 @@ func (c *GameController) GetFlex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	games, err := c.service.GetFlex(req.UserID, req.Fields, req.Where, req.Order, req.Limit, req.Offset)
+	var (
+		res interface{}
+		err error
+	)
+	if req.Aggregate != nil {
+		var rows []map[string]interface{}
+		rows, err = c.service.GetFlexAggregate(r.Context(), userID, req.Joins, req.Where, *req.Aggregate, req.Order, req.Limit, req.Offset)
+		if rows == nil {
+			rows = []map[string]interface{}{}
+		}
+		res = rows
+	} else {
+		res, err = c.service.GetFlex(r.Context(), userID, req.Joins, req.Fields, req.Where, req.Order, req.Limit, req.Offset)
+	}
 	if err != nil {
+		var fieldErr *storage.FieldError
+		if errors.As(err, &fieldErr) {
+			c.log.Error(ErrInvalidFlexField.Error(), slog.String("operation", op), slog.String("field", fieldErr.Field))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(FlexErrorResponse{
+				Error:  ErrInvalidFlexField.Error(),
+				Field:  fieldErr.Field,
+				Reason: fieldErr.Reason,
+			})
+			return
+		}
 		c.log.Error(ErrGetGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
 		return
@@ -277,7 +410,7 @@ func (c *GameController) GetFlex(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	if err := json.NewEncoder(w).Encode(games); err != nil {
+	if err := json.NewEncoder(w).Encode(res); err != nil {
 		c.log.Error(ErrGetGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
 		return
@@ -298,13 +431,17 @@ func (c *GameController) SearchAllGames(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	games, err := c.service.SearchAllGames(query)
+	games, err := c.service.SearchAllGames(r.Context(), query)
 	if err != nil {
 		c.log.Error(ErrSearching.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrSearching.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	for i := range games {
+		localizeGame(r.Context(), &games[i])
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -322,11 +459,15 @@ func (c *GameController) SearchAllGames(w http.ResponseWriter, r *http.Request)
 type CreateGameRequest struct {
 	Title     string            `json:"title"`
 	Preambula string            `json:"preambula"`
+	TitleEn   string            `json:"title_en"`
+	SummaryEn string            `json:"summary_en"`
 	Image     string            `json:"image"`
 	Developer string            `json:"developer"`
 	Publisher string            `json:"publisher"`
 	Year      string            `json:"year"`
 	Genre     string            `json:"genre"`
+	Platforms string            `json:"platforms"`
+	Release   string            `json:"release_date"`
 	Status    models.GameStatus `json:"status"`
 	URL       string            `json:"url"`
 	Priority  int               `json:"priority"`
@@ -343,15 +484,77 @@ type RequestData struct {
 }
 
 type GameError struct {
-	Name string `json:"name"`
-	Err  string `json:"error"`
+	Name     string `json:"name"`
+	Err      string `json:"error"`
+	Category string `json:"category,omitempty"`
+
+	// source — источник из запроса, чтобы повтор искал там же
+	source string
+}
+
+// Категории ошибок импорта в GameError.Category
+const (
+	ImportTimeout       = "timeout"        // источники не ответили за отведённое на игру время
+	ImportNotFound      = "not_found"      // игру не нашли ни в одном источнике
+	ImportDuplicate     = "duplicate"      // название повторяется в списке или игра уже в библиотеке
+	ImportInvalidSource = "invalid_source" // указан неизвестный источник
+	ImportInvalid       = "invalid"        // данные из источника не прошли проверку игры
+	ImportFailed        = "failed"         // остальные ошибки
+)
+
+// importError описывает ошибку импорта одного названия. ctx — контекст этого
+// названия: если его срок истёк, источники не успели ответить, какой бы ни была ошибка
+func importError(ctx context.Context, game RequestGame, err error) GameError {
+	category := ImportFailed
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		category, err = ImportTimeout, ErrImportTimeout
+	case errors.Is(err, ErrGameNotFound):
+		category = ImportNotFound
+	case errors.Is(err, ErrRepeatedName), errors.Is(err, ErrAlreadyOwned):
+		category = ImportDuplicate
+	case errors.Is(err, ErrInvalidSource):
+		category = ImportInvalidSource
+	case errors.Is(err, ErrGameFields):
+		category = ImportInvalid
+	}
+	return GameError{Name: game.Name, Err: err.Error(), Category: category, source: game.Source}
+}
+
+// CreatedGame — игра в ответе на создание. Existing = true, если такая игра
+// уже была в базе и пользователь просто привязан к ней
+type CreatedGame struct {
+	*models.Game
+	Existing bool `json:"existing"`
+
+	// owned — игра уже была в библиотеке пользователя до импорта
+	owned bool
+}
+
+// ReviewItem — название из импорта, для которого нашлось несколько подходящих
+// игр или ни одного точного совпадения. Пользователь выбирает кандидата и
+// отправляет его в POST /api/games/import/resolve
+type ReviewItem struct {
+	Name       string          `json:"name"`
+	Candidates []IGDBCandidate `json:"candidates"`
 }
 
 type MultiGameResponse struct {
-	Success []*models.Game `json:"success"`
-	Errors  []*GameError   `json:"errors"`
+	// JobID — запуск в истории импорта; неудачные названия можно повторить через
+	// POST /api/games/multi/{jobID}/retry
+	JobID       int            `json:"job_id,omitempty"`
+	Success     []*CreatedGame `json:"success"`
+	Errors      []*GameError   `json:"errors"`
+	NeedsReview []*ReviewItem  `json:"needs_review"`
 }
 
+// MaxCreateJSONBody — предел JSON-тела создания и изменения игры: картинка в
+// base64 занимает на треть больше самого файла. Роутер поднимает до него общий
+// предел JSON для POST /api/games и PUT /api/games/{id}
+const MaxCreateJSONBody = 15 << 20
+
+// Create создаёт игру из multipart-формы с файлом image или из JSON, где image —
+// ссылка на картинку или её содержимое в base64
 func (c *GameController) Create(w http.ResponseWriter, r *http.Request) {
 	const op = "controllers.games.Create"
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
@@ -361,51 +564,77 @@ func (c *GameController) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		c.log.Error(ErrParsingForm.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		http.Error(w, ErrCreateGame.Error(), http.StatusBadRequest)
-		return
-	}
+	var request CreateGameRequest
+	var image io.ReadCloser
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		r.Body = http.MaxBytesReader(w, r.Body, MaxCreateJSONBody)
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrCreateGame.Error(), http.StatusBadRequest)
+			return
+		}
+		request.Creator = userID
 
-	request := CreateGameRequest{
-		Title:     r.FormValue("title"),
-		Preambula: r.FormValue("preambula"),
-		Developer: r.FormValue("developer"),
-		Publisher: r.FormValue("publisher"),
-		Year:      r.FormValue("year"),
-		Genre:     r.FormValue("genre"),
-		URL:       r.FormValue("url"),
-		Creator:   userID,
-	}
+		var err error
+		if image, err = jsonImage(r.Context(), request.Image); err != nil {
+			c.log.Error(err.Error(), slog.String("operation", op))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			c.log.Error(ErrParsingForm.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrCreateGame.Error(), http.StatusBadRequest)
+			return
+		}
 
-	var err error
-	if request.Priority, err = strconv.Atoi(r.FormValue("priority")); err != nil {
-		request.Priority = 0
-	}
+		request = CreateGameRequest{
+			Title:     r.FormValue("title"),
+			Preambula: r.FormValue("preambula"),
+			TitleEn:   r.FormValue("title_en"),
+			SummaryEn: r.FormValue("summary_en"),
+			Developer: r.FormValue("developer"),
+			Publisher: r.FormValue("publisher"),
+			Year:      r.FormValue("year"),
+			Genre:     r.FormValue("genre"),
+			Platforms: r.FormValue("platforms"),
+			Release:   r.FormValue("release_date"),
+			URL:       r.FormValue("url"),
+			Creator:   userID,
+		}
 
-	if request.Priority > 10 {
-		c.log.Error(ErrInvalidPriority.Error(), slog.String("operation", op), slog.String("error", "priority > 10"))
-		http.Error(w, ErrCreateGame.Error(), http.StatusBadRequest)
-		return
+		var err error
+		if request.Priority, err = strconv.Atoi(r.FormValue("priority")); err != nil {
+			request.Priority = 0
+		}
+
+		file, _, err := r.FormFile("image")
+		if err != nil {
+			c.log.Error(ErrMissingImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrCreateGame.Error(), http.StatusBadRequest)
+			return
+		}
+		image = file
 	}
+	defer image.Close()
 
-	file, header, err := r.FormFile("image")
+	releaseDate, err := parseReleaseDate(request.Release)
 	if err != nil {
-		c.log.Error(ErrMissingImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		http.Error(w, ErrCreateGame.Error(), http.StatusBadRequest)
+		c.log.Error(err.Error(), slog.String("operation", op), slog.String("release_date", request.Release))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	imageData, err := io.ReadAll(file)
-	if err != nil {
-		c.log.Error(ErrReadImage.Error(), slog.String("error", err.Error()))
-		http.Error(w, ErrCreateGame.Error(), http.StatusInternalServerError)
+	imageData, contentType, ok := readImage(w, c.log, op, c.uploads, image, ErrCreateGame)
+	if !ok {
 		return
 	}
 
-	imageFilename := uuid.New().String() + filepath.Ext(header.Filename)
-	if err := c.uploads.SaveImage(imageData, imageFilename); err != nil {
+	imageFilename, err := c.storeImage(r.Context(), imageData, contentType, userID)
+	if writeUploadError(w, c.log, op, err) {
+		return
+	}
+	if err != nil {
 		c.log.Error(ErrSaveImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrCreateGame.Error(), http.StatusInternalServerError)
 		return
@@ -415,54 +644,65 @@ func (c *GameController) Create(w http.ResponseWriter, r *http.Request) {
 	game := &models.Game{
 		Title:     request.Title,
 		Preambula: request.Preambula,
+		TitleEn:   request.TitleEn,
+		SummaryEn: request.SummaryEn,
 		Image:     imageFilename,
 		Developer: request.Developer,
 		Publisher: request.Publisher,
 		Year:      request.Year,
 		Genre:     request.Genre,
+		Platforms: request.Platforms,
 		URL:       request.URL,
 		Creator:   request.Creator,
+		Source:    models.SourceManual,
 		CreatedAt: &timeNow,
 		UpdatedAt: &timeNow,
-	}
 
-	res, err := c.service.Create(game)
-	if err != nil {
-		_ = c.uploads.DeleteImage(imageFilename)
-		c.log.Error(ErrCreateGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		http.Error(w, ErrCreateGame.Error(), http.StatusInternalServerError)
-		return
+		ReleaseDate: releaseDate,
 	}
 
 	usrGame := &models.UserGames{
 		UserID:   userID,
-		GameID:   res.ID,
 		Priority: request.Priority,
 		Status:   request.Status,
 	}
 
-	if err := c.service.CreateUserGame(usrGame); err != nil {
-		c.log.Error(ErrCreateUserGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	res, created, err := c.service.CreateWithUserGame(r.Context(), game, usrGame)
+	if err != nil {
+		c.releaseImage(r.Context(), op, imageFilename)
+		if writeGameFields(w, c.log, op, err) {
+			return
+		}
+		c.log.Error(ErrCreateGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrCreateGame.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if !created {
+		// Игра уже есть в базе, загруженная картинка не понадобится
+		c.releaseImage(r.Context(), op, imageFilename)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(res); err != nil {
+	if err := json.NewEncoder(w).Encode(CreatedGame{Game: res, Existing: !created}); err != nil {
 		c.log.Error(ErrCreateGame.Error(), slog.String("error", err.Error()))
 		http.Error(w, ErrCreateGame.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
-func (c *GameController) downloadAndSaveImage(url string) (string, error) {
+func (c *GameController) downloadAndSaveImage(ctx context.Context, url string) (string, error) {
 	if url == "" {
 		return "", ErrInvalidURL
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", ErrImageURL
+	}
+
+	resp, err := httpx.Client(0).Do(req)
 	if err != nil {
 		return "", ErrImageURL
 	}
@@ -472,42 +712,110 @@ func (c *GameController) downloadAndSaveImage(url string) (string, error) {
 		return "", ErrDownloadImage
 	}
 
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.HasPrefix(contentType, "image/") {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
 		return "", ErrUnexpectedImageType
 	}
 
-	imageData, err := io.ReadAll(resp.Body)
+	imageData, contentType, err := c.uploads.ReadImage(resp.Body)
 	if err != nil {
+		if errors.Is(err, uploads.ErrImageTooLarge) || errors.Is(err, uploads.ErrUnsupportedType) {
+			return "", ErrUnexpectedImageType
+		}
 		return "", ErrReadImage
 	}
-	filename := generateImageFilename(url, contentType)
-
-	if err := c.uploads.SaveImage(imageData, filename); err != nil {
+	filename, err := c.storeImage(ctx, imageData, contentType, 0)
+	if err != nil {
 		return "", ErrSaveImage
 	}
 
 	return filename, nil
 }
 
-func generateImageFilename(url, contentType string) string {
-	// Извлекаем расширение из Content-Type
-	ext := ".jpg"
-	switch {
-	case strings.Contains(contentType, "png"):
-		ext = ".png"
-	case strings.Contains(contentType, "gif"):
-		ext = ".gif"
-	case strings.Contains(contentType, "webp"):
-		ext = ".webp"
+// FromURLRequest — ссылка на страницу игры в одном из источников
+type FromURLRequest struct {
+	URL string `json:"url"`
+}
+
+// CreateFromURL создаёт игру по ссылке на Steam, Википедию, IGDB, GOG или Epic
+// Games Store: источник определяется по сайту, обложка скачивается, а игра
+// добавляется в библиотеку пользователя
+func (c *GameController) CreateFromURL(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.CreateFromURL"
+
+	var request FromURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	done, ok := c.tracker.Track()
+	if !ok {
+		http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer done()
+
+	game, err := c.gameFromURL(r.Context(), op, request.URL)
+	if err != nil {
+		writeFromURLError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(game); err != nil {
+		c.log.Error(ErrCreateGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+// gameFromURL определяет источник по сайту ссылки, получает игру только из него
+// и добавляет её в библиотеку пользователя из ctx
+func (c *GameController) gameFromURL(ctx context.Context, op, rawURL string) (*CreatedGame, error) {
+	provider, ok := metadata.ProviderFor(rawURL)
+	if !ok {
+		c.log.Warn(ErrUnsupportedGameURL.Error(), slog.String("operation", op), slog.String("url", rawURL))
+		return nil, ErrUnsupportedGameURL
+	}
+	if c.metadata == nil || !c.metadata.Has(provider) {
+		c.log.Warn(ErrInvalidSource.Error(), slog.String("operation", op), slog.String("provider", provider))
+		return nil, ErrInvalidSource
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	found, err := c.metadata.FetchFrom(ctx, provider, strings.TrimSpace(rawURL))
+	if err != nil {
+		c.log.Warn("failed to get game metadata", slog.String("operation", op),
+			slog.String("provider", provider), slog.String("url", rawURL), slog.String("error", err.Error()))
+		switch {
+		case errors.Is(err, metadata.ErrUnsupported):
+			return nil, ErrUnsupportedGameURL
+		case errors.Is(err, metadata.ErrNotFound):
+			return nil, ErrGameNotFound
+		default:
+			return nil, ErrMetadataUnavailable
+		}
 	}
 
-	// Get current timestamp
-	unique_string := time.Now().Format("20060102150405") + url
+	return c.importMetadataGame(ctx, found)
+}
 
-	// Создаем хэш от URL для уникального имени
-	hash := sha256.Sum256([]byte(unique_string))
-	return fmt.Sprintf("%x%s", hash[:8], ext)
+// writeFromURLError отвечает на ошибку gameFromURL
+func writeFromURLError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrUnsupportedGameURL), errors.Is(err, ErrInvalidSource), errors.Is(err, ErrGameFields):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrGameNotFound):
+		http.Error(w, ErrGameNotFound.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrMetadataUnavailable):
+		http.Error(w, ErrMetadataUnavailable.Error(), http.StatusBadGateway)
+	default:
+		http.Error(w, ErrCreateGame.Error(), http.StatusInternalServerError)
+	}
 }
 
 func (c *GameController) CreateMultiGamesIGDB(w http.ResponseWriter, r *http.Request) {
@@ -533,51 +841,146 @@ func (c *GameController) CreateMultiGamesIGDB(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	access, err := c.loginTwitch()
+	// Импорт может идти дольше, чем сервер ждёт обычные запросы при остановке
+	done, ok := c.tracker.Track()
+	if !ok {
+		http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer done()
+
+	access, err := c.importAccess(r.Context(), op)
+	if err != nil {
+		c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrCreateGame.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := c.importNames(w, r, op, request.Games, access)
+	c.writeImportResponse(w, r, op, importProvider, len(request.Games), response, 0)
+}
+
+// RetryImport повторяет импорт только тех названий, которые не удалось
+// импортировать в запуске jobID. Неудачные названия повтора можно повторить снова
+func (c *GameController) RetryImport(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.RetryImport"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := strconv.Atoi(chi.URLParam(r, "jobID"))
+	if err != nil || jobID <= 0 {
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	run, err := c.service.GetImportRun(r.Context(), jobID, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, ErrImportRunNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.log.Error(ErrGetImportRun.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetImportRun.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(run.Items) == 0 {
+		http.Error(w, ErrNothingToRetry.Error(), http.StatusConflict)
+		return
+	}
+
+	done, ok := c.tracker.Track()
+	if !ok {
+		http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer done()
+
+	access, err := c.importAccess(r.Context(), op)
 	if err != nil {
 		c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrCreateGame.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	games := make([]RequestGame, 0, len(run.Items))
+	for _, item := range run.Items {
+		games = append(games, RequestGame{Name: item.Name, Source: item.Source})
+	}
+
+	response := c.importNames(w, r, op, games, access)
+	c.writeImportResponse(w, r, op, importProvider, len(games), response, run.ID)
+}
+
+// importNames ищет названия по цепочке источников, по limits.Workers одновременно
+func (c *GameController) importNames(w http.ResponseWriter, r *http.Request, op string, games []RequestGame, access *igdb.Token) MultiGameResponse {
+	limits := c.limits
+	deadline := limits.deadline(len(games))
+	// Ответ пишется после импорта, поэтому общего срока записи сервера может не хватить
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(deadline + 5*time.Second)); err != nil {
+		c.log.Debug("failed to extend write deadline", slog.String("operation", op), slog.String("error", err.Error()))
+	}
+
 	var (
-		maxWorkers  = 10
-		sem         = make(chan struct{}, maxWorkers)
+		sem         = make(chan struct{}, limits.Workers)
 		wg          sync.WaitGroup
-		errChan     = make(chan GameError, len(request.Games))
-		resultsChan = make(chan *models.Game, len(request.Games))
+		errChan     = make(chan GameError, len(games))
+		resultsChan = make(chan *CreatedGame, len(games))
+		reviewChan  = make(chan *ReviewItem, len(games))
 	)
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-
+	ctx, cancel := context.WithTimeout(r.Context(), deadline)
 	defer cancel()
 
-	for _, game := range request.Games {
+	// Повторы названия в списке не ищутся: вторая копия ничего не добавит
+	seen := make(map[string]bool, len(games))
+	for _, game := range games {
+		key := services.TitleKey(game.Name)
+		if seen[key] {
+			errChan <- importError(ctx, game, ErrRepeatedName)
+			continue
+		}
+		seen[key] = true
+
 		sem <- struct{}{}
 		wg.Add(1)
-		go func(name, source string, access *TwitchLoginResponse) {
+		go func(game RequestGame, access *igdb.Token) {
 			defer func() {
 				<-sem
 				wg.Done()
 			}()
 
-			game, err := c.createThroughIGDB(ctx, name, access)
+			itemCtx, cancel := context.WithTimeout(ctx, limits.ItemTimeout)
+			defer cancel()
+
+			created, review, err := c.createThroughProviders(itemCtx, game.Name, game.Source, access)
+			if err == nil && created != nil && created.owned {
+				err = ErrAlreadyOwned
+			}
 			if err != nil {
-				errChan <- GameError{Name: name, Err: err.Error()}
+				errChan <- importError(itemCtx, game, err)
 				return
 			}
-			resultsChan <- game
-		}(game.Name, game.Source, access)
+			if review != nil {
+				reviewChan <- review
+				return
+			}
+			resultsChan <- created
+		}(game, access)
 	}
 
 	go func() {
 		wg.Wait()
 		close(errChan)
 		close(resultsChan)
+		close(reviewChan)
 	}()
 
 	var errors []*GameError
-	var createdGames []*models.Game
+	var createdGames []*CreatedGame
 
 	for err := range errChan {
 		errors = append(errors, &err)
@@ -587,15 +990,83 @@ func (c *GameController) CreateMultiGamesIGDB(w http.ResponseWriter, r *http.Req
 		createdGames = append(createdGames, res)
 	}
 
-	response := MultiGameResponse{
-		Success: createdGames,
-		Errors:  errors,
+	var review []*ReviewItem
+	for item := range reviewChan {
+		review = append(review, item)
+	}
+
+	return MultiGameResponse{
+		Success:     createdGames,
+		Errors:      errors,
+		NeedsReview: review,
+	}
+
+}
+
+// importAccess входит в IGDB перед импортом. Без цепочки источников IGDB
+// обязателен и ошибка входа возвращается. С цепочкой импорт идёт через
+// остальные источники, но без кандидатов на выбор, и токен может быть nil
+func (c *GameController) importAccess(ctx context.Context, op string) (*igdb.Token, error) {
+	if c.metadata != nil && !c.metadata.Has(string(models.SourceIGDB)) {
+		return nil, nil
+	}
+
+	token, err := c.igdb.Login(ctx)
+	switch {
+	case err == nil:
+		return token, nil
+	case c.metadata == nil:
+		return nil, err
+	default:
+		c.log.Warn(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		return nil, nil
+	}
+}
+
+// writeImportResponse записывает запуск импорта в историю и отвечает клиенту:
+// 201 — всё создано, 207 — есть ошибки или игры, ждущие выбора, 500 — ничего не вышло.
+// retryOf — запуск, неудачные названия которого повторялись, 0 — новый импорт
+func (c *GameController) writeImportResponse(w http.ResponseWriter, r *http.Request, op, provider string, requested int, response MultiGameResponse, retryOf int) {
+	createdGames, errors, review := response.Success, response.Errors, response.NeedsReview
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	runAt := time.Now()
+	run := &models.ImportRun{
+		UserID:    userID,
+		Provider:  provider,
+		Requested: requested,
+		Succeeded: len(createdGames),
+		Failed:    len(errors),
+		Review:    len(review),
+		CreatedAt: &runAt,
+	}
+	// Повторить можно только поиск по названиям: выбранные кандидаты IGDB
+	// ищутся по id, а не по цепочке источников
+	if provider == importProvider {
+		run.Items = retryItems(errors)
+	}
+
+	var err error
+	if retryOf > 0 {
+		err = c.service.RecordImportRetry(r.Context(), retryOf, run)
+	} else {
+		err = c.service.RecordImportRun(r.Context(), run)
+	}
+	if err != nil {
+		c.log.Error("failed to record import run", slog.String("operation", op), slog.String("error", err.Error()))
+	} else {
+		response.JobID = run.ID
 	}
 
 	status := http.StatusCreated
 
+	if len(review) > 0 {
+		status = http.StatusMultiStatus
+	}
+
 	if len(errors) > 0 {
-		if len(createdGames) == 0 {
+		// Список из одних повторов — не сбой сервера
+		if len(createdGames) == 0 && len(review) == 0 && !onlyDuplicates(errors) {
 			status = http.StatusInternalServerError
 		} else {
 			status = http.StatusMultiStatus
@@ -623,254 +1094,222 @@ func (c *GameController) CreateMultiGamesIGDB(w http.ResponseWriter, r *http.Req
 	}
 }
 
-func (c *GameController) createThroughIGDB(ctx context.Context, name string, access *TwitchLoginResponse) (*models.Game, error) {
-	const op = "controllers.games.createThroughIGDB"
-	select {
-	case <-ctx.Done():
-		return nil, ErrUnknown
-	default:
+// retryItems — неудачные названия, которые имеет смысл повторить. Повторы и
+// игры, уже бывшие в библиотеке, не сохраняются: повтор ничего не изменит
+func retryItems(errors []*GameError) []models.ImportRunItem {
+	var items []models.ImportRunItem
+	for _, err := range errors {
+		if err.Category == ImportDuplicate {
+			continue
+		}
+		items = append(items, models.ImportRunItem{
+			Name:     err.Name,
+			Source:   err.source,
+			Category: err.Category,
+			Error:    err.Err,
+		})
+	}
+	return items
+}
+
+// onlyDuplicates — все ошибки импорта только из-за повторов
+func onlyDuplicates(errors []*GameError) bool {
+	for _, err := range errors {
+		if err.Category != ImportDuplicate {
+			return false
+		}
 	}
+	return true
+}
 
-	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+// importCandidates — сколько вариантов IGDB рассматривается для одного названия
+const importCandidates = 5
 
-	if !ok || userID <= 0 {
-		return nil, ErrUnauthorized
-	}
+// importProvider — провайдер в истории импорта, когда игры искались по цепочке источников
+const importProvider = "metadata"
 
-	var err error
+// createThroughProviders ищет игру по цепочке источников (или только в source,
+// если он указан) и импортирует первое точное совпадение. Если никто не нашёл
+// игру, а IGDB доступен, пользователю предлагаются кандидаты из IGDB
+func (c *GameController) createThroughProviders(ctx context.Context, name, source string, access *igdb.Token) (*CreatedGame, *ReviewItem, error) {
+	const op = "controllers.games.createThroughProviders"
 
-	result, err := c.getDataFromIGDB(name, access)
-	if err != nil {
-		return nil, ErrCreateGame
+	if c.metadata == nil {
+		return c.createThroughIGDB(ctx, name, access)
 	}
 
-	imageFilename, err := c.downloadAndSaveImage(result["cover_url"])
-	if err != nil {
-		c.log.Error(
-			"failed to save image",
-			slog.String("operation", op),
-			slog.String("error", err.Error()),
-			slog.String("game", name),
-			slog.String("url", result["cover"]),
-		)
-		imageFilename = ""
+	var (
+		found *metadata.Game
+		err   error
+	)
+	if source != "" {
+		found, err = c.metadata.FetchFrom(ctx, source, name)
+	} else {
+		found, err = c.metadata.Fetch(ctx, name)
 	}
-
-	releaseDate := result["release_date"]
-	releaseDate = strings.Split(releaseDate, "-")[0]
-
-	timeNow := time.Now()
-	game := &models.Game{
-		Title:     result["name"],
-		Preambula: result["summary"],
-		Image:     imageFilename,
-		Developer: result["developers"],
-		Publisher: result["publishers"],
-		Year:      releaseDate,
-		Genre:     result["genres"],
-		URL:       result["url"],
-		CreatedAt: &timeNow,
-		UpdatedAt: &timeNow,
+	if err == nil {
+		game, err := c.importMetadataGame(ctx, found)
+		return game, nil, err
 	}
 
-	createdGame, err := c.service.Create(game)
-	if err != nil {
-		if imageFilename != "" {
-			if delErr := c.uploads.DeleteImage(imageFilename); delErr != nil {
-				c.log.Error(
-					"failed to delete image",
-					slog.String("operation", op),
-					slog.String("error", delErr.Error()),
-					slog.String("filename", imageFilename),
-				)
-			}
-		}
-		c.log.Error(
-			ErrCreateGame.Error(),
+	switch {
+	case errors.Is(err, metadata.ErrUnknownProvider):
+		return nil, nil, ErrInvalidSource
+	case !errors.Is(err, metadata.ErrNotFound):
+		c.log.Warn("failed to get game metadata",
 			slog.String("operation", op),
 			slog.String("error", err.Error()),
 			slog.String("game", name))
-		return nil, ErrCreateGame
 	}
 
-	userGame := &models.UserGames{
-		UserID:   userID,
-		GameID:   createdGame.ID,
-		Status:   models.StatusPlanned,
-		Priority: 0,
-	}
-
-	if err := c.service.CreateUserGame(userGame); err != nil {
-		c.log.Error(
-			ErrCreateGame.Error(),
-			slog.String("operation", op),
-			slog.String("error", err.Error()),
-			slog.String("game", name))
-		return nil, ErrCreateGame
+	if access == nil || (source != "" && source != string(models.SourceIGDB)) {
+		return nil, nil, ErrGameNotFound
 	}
-	return game, nil
+	return c.createThroughIGDB(ctx, name, access)
 }
 
-func (c *GameController) getDataFromIGDB(name string, access *TwitchLoginResponse) (map[string]string, error) {
-	const op = "controllers.games.getDataFromIGDB"
-
-	url := "https://api.igdb.com/v4/games"
-
-	body := fmt.Sprintf(`
-		search "%s";
-		fields
-			name,
-			summary,
-			url,
-			cover.url,
-			involved_companies.company.name,
-			involved_companies.publisher,
-			involved_companies.developer,
-			first_release_date,
-			genres.name;
-		where version_parent = null & game_type = (0, 8, 9, 10) & (aggregated_rating != null | (aggregated_rating = null & hypes != null & hypes > 10));
-		limit 1;
-	`, name)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBufferString(body))
-	if err != nil {
-		c.log.Error("ошибка при создании запроса", slog.String("operation", op), slog.String("error", err.Error()))
-		return nil, ErrCreateGame
+// createThroughIGDB ищет игру в IGDB и импортирует её, если ровно один кандидат
+// совпадает с названием. Иначе возвращает кандидатов на выбор пользователю
+func (c *GameController) createThroughIGDB(ctx context.Context, name string, access *igdb.Token) (*CreatedGame, *ReviewItem, error) {
+	const op = "controllers.games.createThroughIGDB"
+	select {
+	case <-ctx.Done():
+		return nil, nil, ErrUnknown
+	default:
 	}
 
-	req.Header.Set("Client-ID", c.twitchClientId)
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access.AccessToken))
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
+	results, err := c.igdb.Search(ctx, name, importCandidates, access)
 	if err != nil {
-		c.log.Error("ошибка при выполнении запроса", slog.String("operation", op), slog.String("error", err.Error()))
-		return nil, err
+		c.log.Error(
+			"failed to get game from igdb",
+			slog.String("operation", op),
+			slog.String("error", err.Error()),
+			slog.String("game", name))
+		return nil, nil, ErrCreateGame
 	}
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.log.Error("ошибка при чтении тела ответа", slog.String("operation", op), slog.String("error", err.Error()))
-		return nil, ErrCreateGame
+	if len(results) == 0 {
+		return nil, nil, ErrGameNotFound
 	}
 
-	var result []struct {
-		Name             string `json:"name"`
-		Summary          string `json:"summary"`
-		FirstReleaseDate int    `json:"first_release_date"`
-		URL              string `json:"url"`
-		Cover            *struct {
-			URL string `json:"url"`
-		} `json:"cover"`
-		InvolvedCompanies []struct {
-			Company *struct {
-				Name string `json:"name"`
-			} `json:"company"`
-			Publisher bool `json:"publisher"`
-			Developer bool `json:"developer"`
-		} `json:"involved_companies"`
-		Genres []struct {
-			Name string `json:"name"`
-		} `json:"genres"`
-	}
-
-	err = json.Unmarshal(bodyBytes, &result)
-	if err != nil {
-		c.log.Error("ошибка при парсинге тела ответа", slog.String("operation", op), slog.String("error", err.Error()))
-		return nil, ErrCreateGame
+	var exact []igdb.GameInfo
+	for _, result := range results {
+		if services.SameTitle(result.Name, name) {
+			exact = append(exact, result)
+		}
 	}
 
-	if len(result) == 0 {
-		c.log.Error("игра не найдена", slog.String("operation", op), slog.String("error", "game not found"))
-		return nil, ErrGameNotFound
+	if len(exact) != 1 {
+		review := &ReviewItem{Name: name, Candidates: make([]IGDBCandidate, 0, len(results))}
+		for _, result := range results {
+			review.Candidates = append(review.Candidates, newIGDBCandidate(result))
+		}
+		return nil, review, nil
 	}
 
-	game := result[0]
+	game, err := c.importIGDBGame(ctx, &exact[0])
+	return game, nil, err
+}
 
-	var developers, publishers []string
+// importIGDBGame создаёт игру из результата IGDB в библиотеке пользователя
+func (c *GameController) importIGDBGame(ctx context.Context, result *igdb.GameInfo) (*CreatedGame, error) {
+	return c.importMetadataGame(ctx, &metadata.Game{
+		Title:       result.Name,
+		Summary:     result.Summary,
+		URL:         result.URL,
+		CoverURL:    result.CoverURL,
+		Year:        strings.Split(result.ReleaseDate, "-")[0],
+		ReleaseDate: result.ReleaseDate,
+		Developers:  result.Developers,
+		Publishers:  result.Publishers,
+		Genres:      result.Genres,
+		Platforms:   result.Platforms,
+		Source:      models.SourceIGDB,
+		ExternalID:  strconv.Itoa(result.ID),
+	})
+}
 
-	for _, ic := range game.InvolvedCompanies {
-		if ic.Developer {
-			developers = append(developers, ic.Company.Name)
-		}
-		if ic.Publisher {
-			publishers = append(publishers, ic.Company.Name)
-		}
-	}
+// importMetadataGame сохраняет обложку и создаёт игру из внешнего источника в
+// библиотеке пользователя
+func (c *GameController) importMetadataGame(ctx context.Context, result *metadata.Game) (*CreatedGame, error) {
+	const op = "controllers.games.importMetadataGame"
 
-	var releaseDate string
-	if game.FirstReleaseDate != 0 {
-		releaseDate = time.Unix(int64(game.FirstReleaseDate), 0).Format("2006-01-02")
-	}
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
 
-	coverURL := ""
-	if game.Cover != nil {
-		coverURL = "https:" + strings.Replace(game.Cover.URL, "t_thumb", "t_1080p", 1)
+	if !ok || userID <= 0 {
+		return nil, ErrUnauthorized
 	}
 
-	var genres []string
-	for _, g := range game.Genres {
-		genres = append(genres, g.Name)
-	}
+	name := result.Title
 
-	data := map[string]string{
-		"name":         game.Name,
-		"summary":      game.Summary,
-		"url":          game.URL,
-		"developers":   strings.Join(developers, ", "),
-		"publishers":   strings.Join(publishers, ", "),
-		"release_date": releaseDate,
-		"cover_url":    coverURL,
-		"genres":       strings.Join(genres, ", "),
+	imageFilename := ""
+	if result.CoverURL != "" {
+		filename, err := c.downloadAndSaveImage(ctx, result.CoverURL)
+		if err != nil {
+			c.log.Error(
+				"failed to save image",
+				slog.String("operation", op),
+				slog.String("error", err.Error()),
+				slog.String("game", name),
+				slog.String("url", result.CoverURL),
+			)
+		}
+		imageFilename = filename
 	}
 
-	return data, nil
-}
-
-type TwitchLoginResponse struct {
-	AccessToken string `json:"access_token"`
-	ExpiresIn   int    `json:"expires_in"`
-	TokenType   string `json:"token_type"`
-}
-
-func (c *GameController) loginTwitch() (*TwitchLoginResponse, error) {
-	const op = "controllers.games.loginTwitch"
-	twitchLoginUrl := "https://id.twitch.tv/oauth2/token"
+	// Источники отдают дату в нашем формате, ошибка означает, что даты нет
+	releaseDate, _ := parseReleaseDate(result.ReleaseDate)
 
-	clientId := c.twitchClientId
-	clientSecret := c.twitchClientSecret
+	timeNow := time.Now()
+	game := &models.Game{
+		Title:     result.Title,
+		Preambula: result.Summary,
+		TitleEn:   result.Title,
+		SummaryEn: result.Summary,
+		Image:     imageFilename,
+		Developer: strings.Join(result.Developers, ", "),
+		Publisher: strings.Join(result.Publishers, ", "),
+		Year:      result.Year,
+		Genre:     strings.Join(result.Genres, ", "),
+		Platforms: strings.Join(result.Platforms, ", "),
+		URL:       result.URL,
 
-	requestString := fmt.Sprintf(twitchLoginUrl+"?client_id=%s&client_secret=%s&grant_type=client_credentials", clientId, clientSecret)
+		ReleaseDate: releaseDate,
+		CreatedAt:   &timeNow,
+		UpdatedAt:   &timeNow,
 
-	req, err := http.NewRequest("POST", requestString, nil)
-	if err != nil {
-		c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		return nil, ErrLogin
+		Source:       result.Source,
+		ExternalID:   result.ExternalID,
+		LastSyncedAt: &timeNow,
 	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		return nil, err
+	userGame := &models.UserGames{
+		UserID:   userID,
+		Status:   models.StatusPlanned,
+		Priority: 0,
 	}
 
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		return nil, err
+	createdGame, created, err := c.service.CreateWithUserGame(ctx, game, userGame)
+	if err != nil || !created {
+		// Картинка не нужна, если игру создать не удалось или она уже была в базе
+		if imageFilename != "" {
+			c.releaseImage(ctx, op, imageFilename)
+		}
+	}
+	if fields, ok := gameFields(err); ok {
+		c.log.Warn(ErrGameFields.Error(), slog.String("operation", op), slog.String("game", name), slog.Any("fields", fields))
+		return nil, gameFieldsError(fields)
 	}
-
-	var data TwitchLoginResponse
-	err = json.Unmarshal(body, &data)
 	if err != nil {
-		c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		return nil, err
+		c.log.Error(
+			ErrCreateGame.Error(),
+			slog.String("operation", op),
+			slog.String("error", err.Error()),
+			slog.String("game", name))
+		return nil, ErrCreateGame
 	}
 
-	return &data, nil
+	// Запись библиотеки не создаётся, если игра в ней уже была
+	return &CreatedGame{Game: createdGame, Existing: !created, owned: userGame.ID == 0}, nil
 }
 
 // ======================
@@ -909,15 +1348,15 @@ func (c *GameController) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existingGame, err := c.service.GetByID(int(gameID))
+	existingGame, err := c.service.GetByID(r.Context(), int(gameID))
 	if err != nil {
 		c.log.Error(ErrGetGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrUpdateGame.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	isAdmin := r.Context().Value(middleware.IsAdminKey).(bool)
-	if !isAdmin && existingGame.Creator != userID {
+	// Данные игры правит её создатель или модератор
+	if !middleware.HasRole(r.Context(), models.RoleModerator) && existingGame.Creator != userID {
 		c.log.Error(ErrUpdateGame.Error(), slog.String("operation", op), slog.String("error", "user is not admin"))
 		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
 		return
@@ -926,65 +1365,85 @@ func (c *GameController) Update(w http.ResponseWriter, r *http.Request) {
 	contentType := r.Header.Get("Content-Type")
 	var filename string
 	var gameData map[string]interface{}
-	if strings.HasPrefix(contentType, "multipart/form-data") {
+	isMultipart := strings.HasPrefix(contentType, "multipart/form-data")
+	if isMultipart {
 		if err := r.ParseMultipartForm(10 << 20); err != nil {
 			c.log.Error(ErrParsingForm.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 			http.Error(w, ErrUpdateGame.Error(), http.StatusBadRequest)
 			return
 		}
-
-		file, h, err := r.FormFile("image")
-		if err == nil {
-			defer file.Close()
-
-			oldFilename, err := c.service.GetByID(int(gameID))
-			if err != nil {
-				c.log.Error(ErrGetGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-				http.Error(w, ErrUpdateGame.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			imageData, err := io.ReadAll(file)
-			if err != nil {
-				c.log.Error(ErrReadImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-				http.Error(w, ErrUpdateGame.Error(), http.StatusBadRequest)
-				return
-			}
-
-			filename = h.Filename
-			filename = generateImageFilename(filename, h.Header.Get("Content-Type"))
-
-			if err := c.uploads.ReplaceImage(imageData, oldFilename.Image, filename); err != nil {
-				c.log.Error(ErrSaveImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-				http.Error(w, ErrUpdateGame.Error(), http.StatusInternalServerError)
-				return
-			}
-		}
 	} else if strings.HasPrefix(contentType, "application/json") {
 		if err := json.NewDecoder(r.Body).Decode(&gameData); err != nil {
 			c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 			http.Error(w, ErrUpdateGame.Error(), http.StatusBadRequest)
 			return
 		}
-		if img, ok := gameData["image"].(string); ok {
-			filename = img
-		}
 	} else {
 		c.log.Error(ErrInvalidRequest.Error(), slog.String("operation", op))
 		http.Error(w, ErrInvalidRequest.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// Версию проверяем до загрузки картинки, чтобы при конфликте не заменить
+	// обложку, которую выбрал другой пользователь
+	version, err := gameVersion(r, getFormValue(r, gameData, "version"))
+	if err != nil {
+		c.log.Error(ErrGameVersionRequired.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGameVersionRequired.Error(), http.StatusPreconditionRequired)
+		return
+	}
+	if version != existingGame.Version {
+		c.log.Error(ErrGameChanged.Error(), slog.String("operation", op),
+			slog.Int("version", version), slog.Int("current", existingGame.Version))
+		w.Header().Set("ETag", gameETag(existingGame.Version))
+		http.Error(w, ErrGameChanged.Error(), http.StatusConflict)
+		return
+	}
+
+	// Приоритет сохраняется после игры, поэтому проверяем его заранее: иначе
+	// игра изменится, а запрос вернёт ошибку
 	priority, err := strconv.Atoi(getFormValue(r, gameData, "priority"))
 	if err != nil {
 		priority = 0
 	}
-	if priority > 10 {
-		c.log.Error(ErrInvalidPriority.Error(), slog.String("operation", op))
-		http.Error(w, ErrInvalidPriority.Error(), http.StatusBadRequest)
+	if writeGameFields(w, c.log, op, services.ValidatePriority(priority)) {
 		return
 	}
 
+	// Новая картинка проходит ту же проверку и учёт ссылок, что и при создании.
+	// В JSON image — ссылка или base64, как в Create; текущее имя файла означает,
+	// что картинка не меняется
+	var image io.ReadCloser
+	if isMultipart {
+		if file, _, err := r.FormFile("image"); err == nil {
+			image = file
+		}
+	} else if img := strings.TrimSpace(getFormValue(r, gameData, "image")); img != "" && img != existingGame.Image {
+		if image, err = jsonImage(r.Context(), img); err != nil {
+			c.log.Error(err.Error(), slog.String("operation", op))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if image != nil {
+		defer image.Close()
+
+		imageData, contentType, ok := readImage(w, c.log, op, c.uploads, image, ErrUpdateGame)
+		if !ok {
+			return
+		}
+
+		filename, err = c.storeImage(r.Context(), imageData, contentType, userID)
+		if writeUploadError(w, c.log, op, err) {
+			return
+		}
+		if err != nil {
+			c.log.Error(ErrSaveImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrUpdateGame.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	var createdAt *time.Time
 	if createdAtStr := getFormValue(r, gameData, "created_at"); createdAtStr != "" {
 		t, err := time.Parse(time.RFC3339, createdAtStr)
@@ -996,29 +1455,56 @@ func (c *GameController) Update(w http.ResponseWriter, r *http.Request) {
 		createdAt = &t
 	}
 
+	releaseDate, err := parseReleaseDate(getFormValue(r, gameData, "release_date"))
+	if err != nil {
+		c.log.Error(err.Error(), slog.String("operation", op))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	timeNow := time.Now()
 
 	game := &models.Game{
 		ID:        int(gameID),
 		Title:     getFormValue(r, gameData, "title"),
 		Preambula: getFormValue(r, gameData, "preambula"),
+		TitleEn:   getFormValue(r, gameData, "title_en"),
+		SummaryEn: getFormValue(r, gameData, "summary_en"),
 		Image:     filename,
 		Developer: getFormValue(r, gameData, "developer"),
 		Publisher: getFormValue(r, gameData, "publisher"),
 		Year:      getFormValue(r, gameData, "year"),
 		Genre:     getFormValue(r, gameData, "genre"),
+		Platforms: getFormValue(r, gameData, "platforms"),
 		URL:       getFormValue(r, gameData, "url"),
 		Creator:   existingGame.Creator,
 		CreatedAt: createdAt,
 		UpdatedAt: &timeNow,
+		Version:   version,
+
+		ReleaseDate: releaseDate,
 	}
 
-	res, err := c.service.Update(game)
+	res, err := c.service.Update(r.Context(), game)
 	if err != nil {
+		// Новая картинка не пригодилась: старая остаётся у игры
+		if filename != "" {
+			c.releaseImage(r.Context(), op, filename)
+		}
+		if errors.Is(err, storage.ErrConflict) {
+			c.log.Error(ErrGameChanged.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrGameChanged.Error(), http.StatusConflict)
+			return
+		}
+		if writeGameFields(w, c.log, op, err) {
+			return
+		}
 		c.log.Error(ErrUpdateGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrUpdateGame.Error(), http.StatusInternalServerError)
 		return
 	}
+	if filename != "" && existingGame.Image != "" {
+		c.releaseImage(r.Context(), op, existingGame.Image)
+	}
 
 	userGame := &models.UserGames{
 		UserID:   userID,
@@ -1027,13 +1513,14 @@ func (c *GameController) Update(w http.ResponseWriter, r *http.Request) {
 		Status:   models.GameStatus(getFormValue(r, gameData, "status")),
 	}
 
-	if err := c.service.UpdateUserGame(userGame); err != nil {
+	if err := c.service.UpdateUserGame(r.Context(), userGame); err != nil {
 		c.log.Error(ErrUpdateUserGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrUpdateUserGame.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", gameETag(res.Version))
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(res); err != nil {
 		c.log.Error(ErrUpdateGame.Error(), slog.String("error", err.Error()))
@@ -1060,7 +1547,7 @@ func (c *GameController) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existingGame, err := c.service.GetByID(int(gameID))
+	existingGame, err := c.service.GetByID(r.Context(), int(gameID))
 	fmt.Printf("%v", existingGame)
 	if err != nil {
 		c.log.Error(ErrGetGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
@@ -1086,7 +1573,7 @@ func (c *GameController) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 			Status:   models.GameStatus(request.Status),
 		}
 	} else {
-		existingUserGame, err := c.service.GetUserGame(userID, int(gameID))
+		existingUserGame, err := c.service.GetUserGame(r.Context(), userID, int(gameID))
 		if err != nil {
 			c.log.Error(ErrGetGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 			http.Error(w, ErrGetGame.Error(), http.StatusInternalServerError)
@@ -1100,7 +1587,7 @@ func (c *GameController) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := c.service.UpdateUserGame(&userGame); err != nil {
+	if err := c.service.UpdateUserGame(r.Context(), &userGame); err != nil {
 		c.log.Error(ErrUpdateUserGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrUpdateUserGame.Error(), http.StatusInternalServerError)
 		return
@@ -1133,7 +1620,7 @@ func (c *GameController) UpdatePriority(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	existingGame, err := c.service.GetByID(int(gameID))
+	existingGame, err := c.service.GetByID(r.Context(), int(gameID))
 	if err != nil {
 		c.log.Error(ErrGetGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrGetGame.Error(), http.StatusInternalServerError)
@@ -1147,7 +1634,8 @@ func (c *GameController) UpdatePriority(w http.ResponseWriter, r *http.Request)
 		http.Error(w, ErrUpdateGame.Error(), http.StatusBadRequest)
 		return
 	}
-	existingUserGame, err := c.service.GetUserGame(userID, int(gameID))
+
+	existingUserGame, err := c.service.GetUserGame(r.Context(), userID, int(gameID))
 	if err != nil {
 		c.log.Error(ErrGetGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrGetGame.Error(), http.StatusInternalServerError)
@@ -1161,7 +1649,10 @@ func (c *GameController) UpdatePriority(w http.ResponseWriter, r *http.Request)
 		Status:   existingUserGame.Status,
 	}
 
-	if err := c.service.UpdateUserGame(userGame); err != nil {
+	if err := c.service.UpdatePriority(r.Context(), userGame); err != nil {
+		if writeGameFields(w, c.log, op, err) {
+			return
+		}
 		c.log.Error(ErrUpdateUserGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrUpdateUserGame.Error(), http.StatusInternalServerError)
 		return
@@ -1176,6 +1667,31 @@ func (c *GameController) UpdatePriority(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// gameETag — ETag игры: её версия в кавычках
+func gameETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// gameVersion возвращает версию игры, которую клиент прочитал: из If-Match
+// (в том виде, в каком её отдаёт ETag) или из поля version тела запроса
+func gameVersion(r *http.Request, bodyVersion string) (int, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v != "" {
+		v = strings.Trim(strings.TrimPrefix(v, "W/"), `"`)
+	} else {
+		v = bodyVersion
+	}
+	if v == "" {
+		return 0, errors.New("no If-Match header or version field")
+	}
+
+	version, err := strconv.Atoi(v)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid version %q", v)
+	}
+	return version, nil
+}
+
 func getFormValue(r *http.Request, gameData map[string]interface{}, key string) string {
 	contentType := r.Header.Get("Content-Type")
 
@@ -1233,7 +1749,7 @@ func (c *GameController) DeleteUserGame(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Получаем игру по ID
-	game, err := c.service.GetByID(int(idInt))
+	game, err := c.service.GetByID(r.Context(), int(idInt))
 	if err != nil {
 		c.log.Error(
 			ErrGetGame.Error(),
@@ -1253,7 +1769,7 @@ func (c *GameController) DeleteUserGame(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = c.service.DeleteUserGame(userID, int(idInt))
+	err = c.service.DeleteUserGame(r.Context(), userID, int(idInt))
 	if err != nil {
 		c.log.Error(
 			ErrDeleteUserGame.Error(),
@@ -1267,6 +1783,11 @@ func (c *GameController) DeleteUserGame(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+type GameInUseResponse struct {
+	Error string `json:"error"`
+	Users int    `json:"users"`
+}
+
 func (c *GameController) Delete(w http.ResponseWriter, r *http.Request) {
 	const op = "controllers.games.Delete"
 
@@ -1297,7 +1818,7 @@ func (c *GameController) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Получаем игру по ID
-	game, err := c.service.GetByID(int(idInt))
+	game, err := c.service.GetByID(r.Context(), int(idInt))
 	if err != nil {
 		c.log.Error(
 			ErrGetGame.Error(),
@@ -1308,20 +1829,22 @@ func (c *GameController) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isAdmin := r.Context().Value(middleware.IsAdminKey).(bool)
+	if userID == game.Creator || middleware.HasRole(r.Context(), models.RoleAdmin) {
+		force := r.URL.Query().Get("force") == "true"
 
-	if userID == game.Creator || isAdmin {
-		if err := c.uploads.DeleteImage(game.Image); err != nil {
-			// Логируем, но не прерываем выполнение — игра всё равно будет удалена
+		// Удаляем игру и записи всех пользователей о ней
+		tracking, err := c.service.Delete(r.Context(), int(idInt), userID, force)
+		if errors.Is(err, services.ErrGameInUse) {
 			c.log.Error(
-				"Ошибка удаления изображения",
+				ErrGameInUse.Error(),
 				slog.String("operation", op),
-				slog.String("filename", game.Image),
-				slog.String("error", err.Error()))
+				slog.String("id", id),
+				slog.Int("users", tracking))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(GameInUseResponse{Error: ErrGameInUse.Error(), Users: tracking})
+			return
 		}
-
-		// Удаляем запись игры
-		err = c.service.Delete(int(idInt))
 		if err != nil {
 			c.log.Error(
 				ErrDeleteGame.Error(),
@@ -1332,9 +1855,14 @@ func (c *GameController) Delete(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Картинку удаляем только после успешного удаления игры, иначе останется игра без обложки
+		if game.Image != "" {
+			c.releaseImage(r.Context(), op, game.Image)
+		}
+		return
 	}
 
-	err = c.service.DeleteUserGame(userID, int(idInt))
+	err = c.service.DeleteUserGame(r.Context(), userID, int(idInt))
 	if err != nil {
 		c.log.Error(
 			ErrDeleteUserGame.Error(),
@@ -1347,18 +1875,62 @@ func (c *GameController) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 // ======================
-// STATS
+// PLAYTHROUGHS
 // ======================
 
-type GameStats struct {
-	Finished int `json:"finished"`
-	Playing  int `json:"playing"`
-	Planned  int `json:"planned"`
-	Dropped  int `json:"dropped"`
+type PlaythroughRequest struct {
+	Label    string            `json:"label"`
+	Status   models.GameStatus `json:"status"`
+	Priority int               `json:"priority"`
+	Sessions int               `json:"sessions"`
+
+	CompletionPercent int `json:"completion_percent"`
+	AchievementsDone  int `json:"achievements_done"`
+	AchievementsTotal int `json:"achievements_total"`
+
+	Rating     int        `json:"rating"`
+	Notes      *string    `json:"notes"`
+	Platform   *string    `json:"platform"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
 }
 
-func (c *GameController) GetGameStats(w http.ResponseWriter, r *http.Request) {
-	const op = "controllers.games.GetGameStats"
+// history проверяет оценку, заметки и даты прохождения
+func (p *PlaythroughRequest) history() error {
+	if p.Rating < 0 || p.Rating > 10 {
+		return ErrInvalidRating
+	}
+	if p.Notes != nil && utf8.RuneCountInString(*p.Notes) > maxNotesLength {
+		return ErrNotesTooLong
+	}
+	if p.Platform != nil && utf8.RuneCountInString(strings.TrimSpace(*p.Platform)) > maxPlatformLength {
+		return ErrInvalidPlatform
+	}
+	if p.StartedAt != nil && p.FinishedAt != nil && p.FinishedAt.Before(*p.StartedAt) {
+		return ErrInvalidDates
+	}
+	return nil
+}
+
+// completion проверяет прогресс из запроса. Если указаны только достижения,
+// процент считается по ним
+func (p *PlaythroughRequest) completion() (int, error) {
+	if p.CompletionPercent < 0 || p.CompletionPercent > 100 {
+		return 0, ErrInvalidCompletion
+	}
+	if p.AchievementsDone < 0 || p.AchievementsTotal < 0 || p.AchievementsDone > p.AchievementsTotal {
+		return 0, ErrInvalidCompletion
+	}
+
+	if p.CompletionPercent == 0 && p.AchievementsTotal > 0 {
+		return p.AchievementsDone * 100 / p.AchievementsTotal, nil
+	}
+	return p.CompletionPercent, nil
+}
+
+func (c *GameController) GetPlaythroughs(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetPlaythroughs"
+
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok || userID <= 0 {
 		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
@@ -1366,43 +1938,317 @@ func (c *GameController) GetGameStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	gs := GameStats{
-		Finished: 0,
-		Playing:  0,
-		Planned:  0,
-		Dropped:  0,
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
 	}
 
-	finished, err := c.service.GetFinishedGames(userID)
+	playthroughs, err := c.service.GetPlaythroughs(r.Context(), userID, gameID)
 	if err != nil {
-		c.log.Error(ErrGetGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
+		c.log.Error(ErrGetPlaythroughs.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetPlaythroughs.Error(), http.StatusInternalServerError)
 		return
 	}
-	playing, err := c.service.GetPlayingGames(userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(playthroughs); err != nil {
+		c.log.Error(ErrGetPlaythroughs.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetPlaythroughs.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *GameController) StartPlaythrough(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.StartPlaythrough"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		c.log.Error(ErrGetGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := c.service.GetByID(r.Context(), gameID); err != nil {
+		c.log.Error(ErrGetGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGameNotFound.Error(), http.StatusNotFound)
 		return
 	}
-	planned, err := c.service.GetPlannedGames(userID)
+
+	request := PlaythroughRequest{Label: models.PlaythroughFirstRun, Status: models.StatusPlaying}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !request.Status.IsValid() {
+		c.log.Error(ErrInvalidStatus.Error(), slog.String("operation", op), slog.String("status", string(request.Status)))
+		http.Error(w, ErrInvalidStatus.Error(), http.StatusBadRequest)
+		return
+	}
+
+	completion, err := request.completion()
 	if err != nil {
-		c.log.Error(ErrGetGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
-		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
+		c.log.Error(ErrInvalidCompletion.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidCompletion.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := request.history(); err != nil {
+		c.log.Error(err.Error(), slog.String("operation", op))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playthrough := &models.UserGames{
+		UserID:   userID,
+		GameID:   gameID,
+		Label:    request.Label,
+		Status:   request.Status,
+		Priority: request.Priority,
+		Sessions: request.Sessions,
+
+		CompletionPercent: completion,
+		AchievementsDone:  request.AchievementsDone,
+		AchievementsTotal: request.AchievementsTotal,
+
+		Rating:     request.Rating,
+		StartedAt:  request.StartedAt,
+		FinishedAt: request.FinishedAt,
+	}
+	if request.Notes != nil {
+		playthrough.Notes = *request.Notes
+	}
+	if request.Platform != nil {
+		playthrough.Platform = strings.TrimSpace(*request.Platform)
+	}
+
+	if err := c.service.StartPlaythrough(r.Context(), playthrough); err != nil {
+		if writeGameFields(w, c.log, op, err) {
+			return
+		}
+		c.log.Error(ErrCreatePlaythrough.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrCreatePlaythrough.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(playthrough); err != nil {
+		c.log.Error(ErrCreatePlaythrough.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrCreatePlaythrough.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (c *GameController) UpdatePlaythrough(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.UpdatePlaythrough"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playthroughID, err := strconv.Atoi(chi.URLParam(r, "playthroughID"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request PlaythroughRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !request.Status.IsValid() {
+		c.log.Error(ErrInvalidStatus.Error(), slog.String("operation", op), slog.String("status", string(request.Status)))
+		http.Error(w, ErrInvalidStatus.Error(), http.StatusBadRequest)
+		return
+	}
+
+	completion, err := request.completion()
+	if err != nil {
+		c.log.Error(ErrInvalidCompletion.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidCompletion.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := request.history(); err != nil {
+		c.log.Error(err.Error(), slog.String("operation", op))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playthrough := &models.UserGames{
+		ID:       playthroughID,
+		UserID:   userID,
+		GameID:   gameID,
+		Label:    request.Label,
+		Status:   request.Status,
+		Priority: request.Priority,
+		Sessions: request.Sessions,
+
+		CompletionPercent: completion,
+		AchievementsDone:  request.AchievementsDone,
+		AchievementsTotal: request.AchievementsTotal,
+
+		Rating:     request.Rating,
+		StartedAt:  request.StartedAt,
+		FinishedAt: request.FinishedAt,
+	}
+
+	if request.Platform != nil {
+		*request.Platform = strings.TrimSpace(*request.Platform)
+	}
+
+	err = c.service.UpdatePlaythrough(r.Context(), playthrough, request.Notes, request.Platform)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, ErrNoPlaythrough.Error(), http.StatusNotFound)
+		return
+	}
+	if writeGameFields(w, c.log, op, err) {
 		return
 	}
+	if err != nil {
+		c.log.Error(ErrUpdatePlaythrough.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdatePlaythrough.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *GameController) ActivatePlaythrough(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.ActivatePlaythrough"
 
-	dropped, err := c.service.GetDroppedGames(userID)
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playthroughID, err := strconv.Atoi(chi.URLParam(r, "playthroughID"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.ActivatePlaythrough(r.Context(), userID, gameID, playthroughID); err != nil {
+		c.log.Error(ErrUpdatePlaythrough.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdatePlaythrough.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *GameController) DeletePlaythrough(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.DeletePlaythrough"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playthroughID, err := strconv.Atoi(chi.URLParam(r, "playthroughID"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = c.service.DeletePlaythrough(r.Context(), userID, gameID, playthroughID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, ErrNoPlaythrough.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.log.Error(ErrDeletePlaythrough.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrDeletePlaythrough.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ======================
+// STATS
+// ======================
+
+type GameStats struct {
+	Finished  int `json:"finished"`
+	Playing   int `json:"playing"`
+	Planned   int `json:"planned"`
+	Dropped   int `json:"dropped"`
+	Favorites int `json:"favorites"`
+	// Средний процент прохождения по играм, где он указан
+	AverageCompletion float64 `json:"average_completion"`
+}
+
+func (c *GameController) GetGameStats(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetGameStats"
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	counts, err := c.service.GetStatusCounts(r.Context(), userID)
 	if err != nil {
 		c.log.Error(ErrGetGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
 		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	gs.Finished = finished
-	gs.Playing = playing
-	gs.Planned = planned
-	gs.Dropped = dropped
+	gs := GameStats{
+		Finished:          counts.Finished,
+		Playing:           counts.Playing,
+		Planned:           counts.Planned,
+		Dropped:           counts.Dropped,
+		Favorites:         counts.Favorites,
+		AverageCompletion: counts.AverageCompletion,
+	}
 
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(gs); err != nil {
@@ -0,0 +1,159 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"games_webapp/internal/controllers"
+	"games_webapp/internal/testutil"
+)
+
+// noisePNG — PNG из случайных пикселей: такой почти не сжимается, поэтому
+// размер файла предсказуемо растёт со стороной картинки
+func noisePNG(t *testing.T, side int) []byte {
+	t.Helper()
+
+	rnd := rand.New(rand.NewPCG(1, 2))
+	img := image.NewNRGBA(image.Rect(0, 0, side, side))
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(rnd.Uint32()), G: uint8(rnd.Uint32()), B: uint8(rnd.Uint32()), A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCreateJSONWithLargeBase64Image(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	_, token := srv.NewUser(t, "player@example.com", false)
+
+	data := noisePNG(t, 700)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	if len(encoded) <= int(srv.Config.MaxJSONBody) {
+		t.Fatalf("base64 image is %d bytes, want more than the JSON limit %d", len(encoded), srv.Config.MaxJSONBody)
+	}
+
+	resp := srv.Do(t, http.MethodPost, "/api/games", token, map[string]interface{}{
+		"title":    "Noise",
+		"year":     "2020",
+		"priority": 3,
+		"image":    "data:image/png;base64," + encoded,
+	})
+
+	var created controllers.CreatedGame
+	testutil.DecodeJSON(t, resp, http.StatusOK, &created)
+	if created.Game == nil || created.ID == 0 {
+		t.Fatalf("no game in response: %+v", created)
+	}
+	if created.Title != "Noise" {
+		t.Errorf("title = %q, want %q", created.Title, "Noise")
+	}
+	if created.Image == "" {
+		t.Error("image was not saved")
+	}
+}
+
+func TestCreateJSONRejectsOversizedBody(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	_, token := srv.NewUser(t, "player@example.com", false)
+
+	resp := srv.Do(t, http.MethodPost, "/api/games", token, map[string]interface{}{
+		"title": "Too big",
+		"image": base64.StdEncoding.EncodeToString(make([]byte, controllers.MaxCreateJSONBody)),
+	})
+	testutil.DecodeJSON(t, resp, http.StatusRequestEntityTooLarge, nil)
+}
+
+func TestCreateJSONRejectsPrivateImageURL(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	_, token := srv.NewUser(t, "player@example.com", false)
+
+	// Ссылка на сам тестовый сервер — это loopback
+	resp := srv.Do(t, http.MethodPost, "/api/games", token, map[string]interface{}{
+		"title": "Internal",
+		"image": srv.URL + "/api/health",
+	})
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), controllers.ErrImageHost.Error()) {
+		t.Fatalf("status %d, body %q; want 400 with %q", resp.StatusCode, body, controllers.ErrImageHost)
+	}
+}
+
+func TestUpdateJSONRejectsForeignImageFilename(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	_, token := srv.NewUser(t, "player@example.com", false)
+
+	create := func(title string, side int) controllers.CreatedGame {
+		resp := srv.Do(t, http.MethodPost, "/api/games", token, map[string]interface{}{
+			"title": title,
+			"year":  "2020",
+			"image": base64.StdEncoding.EncodeToString(noisePNG(t, side)),
+		})
+		var created controllers.CreatedGame
+		testutil.DecodeJSON(t, resp, http.StatusOK, &created)
+		return created
+	}
+	first, second := create("First", 16), create("Second", 17)
+
+	resp := srv.Do(t, http.MethodPut, "/api/games/"+strconv.Itoa(second.ID), token, map[string]interface{}{
+		"title":   "Second",
+		"image":   first.Image,
+		"version": second.Version,
+	})
+	testutil.DecodeJSON(t, resp, http.StatusBadRequest, nil)
+
+	game, err := srv.Store().Games().GetByID(second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Image != second.Image {
+		t.Errorf("image = %q, want it unchanged %q", game.Image, second.Image)
+	}
+}
+
+func TestFlexUsesTokenUser(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	ownerID, ownerToken := srv.NewUser(t, "owner@example.com", false)
+	_, token := srv.NewUser(t, "player@example.com", false)
+
+	for _, game := range []struct {
+		token, title string
+		side         int
+	}{{ownerToken, "Hades", 8}, {token, "Celeste", 9}} {
+		resp := srv.Do(t, http.MethodPost, "/api/games", game.token, map[string]interface{}{
+			"title": game.title,
+			"year":  "2020",
+			"image": base64.StdEncoding.EncodeToString(noisePNG(t, game.side)),
+		})
+		testutil.DecodeJSON(t, resp, http.StatusOK, nil)
+	}
+
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodPost, "/api/games/user/flex", "", map[string]interface{}{}), http.StatusUnauthorized, nil)
+
+	// user_id из тела не учитывается: выборка идёт по библиотеке владельца токена
+	var games []struct {
+		Title string `json:"title"`
+	}
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodPost, "/api/games/user/flex", token, map[string]interface{}{
+		"user_id": ownerID,
+		"fields":  []string{"title"},
+	}), http.StatusOK, &games)
+	if len(games) != 1 || games[0].Title != "Celeste" {
+		t.Errorf("flex games = %+v, want only Celeste", games)
+	}
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Probe проверяет одну зависимость сервиса
+type Probe struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthController struct {
+	probes  []Probe
+	timeout time.Duration
+	log     *slog.Logger
+}
+
+func NewHealthController(log *slog.Logger, timeout time.Duration, probes ...Probe) *HealthController {
+	return &HealthController{
+		probes:  probes,
+		timeout: timeout,
+		log:     log,
+	}
+}
+
+type HealthCheck struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
+
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+	healthDown     = "down"
+)
+
+// Live отвечает, что процесс жив. Зависимости не проверяются
+func (c *HealthController) Live(w http.ResponseWriter, r *http.Request) {
+	c.write(w, http.StatusOK, HealthResponse{Status: healthOK})
+}
+
+// Ready проверяет все зависимости параллельно, каждую с таймаутом.
+// Если хотя бы одна недоступна, возвращается 503
+func (c *HealthController) Ready(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.health.Ready"
+
+	response := HealthResponse{
+		Status: healthOK,
+		Checks: make(map[string]HealthCheck, len(c.probes)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, p := range c.probes {
+		wg.Add(1)
+		go func(p Probe) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := p.Check(ctx)
+			check := HealthCheck{Status: healthOK, Duration: time.Since(start).Milliseconds()}
+			if err != nil {
+				check.Status = healthDown
+				check.Error = err.Error()
+				c.log.Error("health probe failed", slog.String("operation", op), slog.String("probe", p.Name), slog.String("error", err.Error()))
+			}
+
+			mu.Lock()
+			response.Checks[p.Name] = check
+			if err != nil {
+				response.Status = healthDegraded
+			}
+			mu.Unlock()
+		}(p)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if response.Status != healthOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.write(w, status, response)
+}
+
+func (c *HealthController) write(w http.ResponseWriter, status int, response HealthResponse) {
+	const op = "controllers.health.write"
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		c.log.Error("failed to encode health response", slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
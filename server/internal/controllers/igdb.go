@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"games_webapp/internal/clients/igdb"
+	"games_webapp/internal/models"
+)
+
+// maxResolveGames — сколько выбранных игр можно импортировать одним запросом
+const maxResolveGames = 20
+
+// IGDBCandidate — игра из поиска по IGDB, которую пользователь может выбрать для импорта
+type IGDBCandidate struct {
+	IGDBID         int            `json:"igdb_id"`
+	Name           string         `json:"name"`
+	Summary        string         `json:"summary"`
+	URL            string         `json:"url"`
+	Cover          string         `json:"cover"`
+	Year           string         `json:"year"`
+	Genres         []string       `json:"genres"`
+	Developers     []string       `json:"developers"`
+	Platforms      []string       `json:"platforms"`
+	Rating         float64        `json:"rating"`
+	ResolvePayload ResolveRequest `json:"resolve_payload"` // Тело для POST /api/games/import/resolve
+}
+
+// ResolveRequest — выбор пользователя среди кандидатов IGDB
+type ResolveRequest struct {
+	IGDBIDs []int `json:"igdb_ids"`
+}
+
+func newIGDBCandidate(game igdb.GameInfo) IGDBCandidate {
+	return IGDBCandidate{
+		IGDBID:         game.ID,
+		Name:           game.Name,
+		Summary:        game.Summary,
+		URL:            game.URL,
+		Cover:          game.CoverURL,
+		Year:           strings.Split(game.ReleaseDate, "-")[0],
+		Genres:         game.Genres,
+		Developers:     game.Developers,
+		Platforms:      game.Platforms,
+		Rating:         game.Rating,
+		ResolvePayload: ResolveRequest{IGDBIDs: []int{game.ID}},
+	}
+}
+
+// SearchIGDB ищет игры в IGDB и возвращает несколько вариантов с обложками и годами
+func (c *GameController) SearchIGDB(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.igdb.SearchIGDB"
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		c.log.Error(ErrSearchQuery.Error(), slog.String("operation", op))
+		http.Error(w, ErrSearchQuery.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 10
+	} else if limit > 50 {
+		limit = 50
+	}
+
+	access, err := c.igdb.Login(r.Context())
+	if err != nil {
+		c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrSearchIGDB.Error(), http.StatusBadGateway)
+		return
+	}
+
+	games, err := c.igdb.Search(r.Context(), query, limit, access)
+	if err != nil {
+		c.log.Error(ErrSearchIGDB.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrSearchIGDB.Error(), http.StatusBadGateway)
+		return
+	}
+
+	candidates := make([]IGDBCandidate, 0, len(games))
+	for _, game := range games {
+		candidates = append(candidates, newIGDBCandidate(game))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(candidates); err != nil {
+		c.log.Error(ErrSearchIGDB.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+// ResolveImport импортирует игры, которые пользователь выбрал среди кандидатов
+// из needs_review или из поиска по IGDB
+func (c *GameController) ResolveImport(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.igdb.ResolveImport"
+
+	var request ResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(request.IGDBIDs) == 0 {
+		http.Error(w, ErrNoGamesNames.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(request.IGDBIDs) > maxResolveGames {
+		http.Error(w, ErrBulkTooMany.Error(), http.StatusBadRequest)
+		return
+	}
+
+	done, ok := c.tracker.Track()
+	if !ok {
+		http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer done()
+
+	access, err := c.igdb.Login(r.Context())
+	if err != nil {
+		c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrCreateGame.Error(), http.StatusBadGateway)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	games, err := c.igdb.GetByIDs(ctx, request.IGDBIDs, access)
+	if err != nil {
+		c.log.Error(ErrSearchIGDB.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrSearchIGDB.Error(), http.StatusBadGateway)
+		return
+	}
+
+	found := make(map[int]bool, len(games))
+	var response MultiGameResponse
+
+	for i := range games {
+		found[games[i].ID] = true
+
+		game, err := c.importIGDBGame(ctx, &games[i])
+		if err != nil {
+			gameErr := importError(ctx, RequestGame{Name: games[i].Name}, err)
+			response.Errors = append(response.Errors, &gameErr)
+			continue
+		}
+		response.Success = append(response.Success, game)
+	}
+
+	for _, id := range request.IGDBIDs {
+		if !found[id] {
+			response.Errors = append(response.Errors, &GameError{Name: strconv.Itoa(id), Err: ErrGameNotFound.Error(), Category: ImportNotFound})
+		}
+	}
+
+	c.writeImportResponse(w, r, op, string(models.SourceIGDB), len(request.IGDBIDs), response, 0)
+}
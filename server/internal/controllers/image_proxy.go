@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"games_webapp/internal/services"
+)
+
+type ImageProxyServicer interface {
+	Open(ctx context.Context, rawURL string) (*os.File, error)
+}
+
+type ImageProxyController struct {
+	proxy ImageProxyServicer
+	// maxAge — сколько браузер может не перезапрашивать картинку
+	maxAge time.Duration
+	log    *slog.Logger
+}
+
+func NewImageProxyController(proxy ImageProxyServicer, maxAge time.Duration, log *slog.Logger) *ImageProxyController {
+	return &ImageProxyController{
+		proxy:  proxy,
+		maxAge: maxAge,
+		log:    log,
+	}
+}
+
+// Serve отдаёт внешнюю картинку из ?url= через наш домен. Авторизация не нужна,
+// чтобы ссылку можно было вставить в <img>; берутся только разрешённые хосты
+func (c *ImageProxyController) Serve(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.image_proxy.Serve"
+
+	rawURL := r.URL.Query().Get("url")
+	if rawURL == "" {
+		http.Error(w, ErrInvalidURL.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f, err := c.proxy.Open(r.Context(), rawURL)
+	switch {
+	case errors.Is(err, services.ErrProxyURL):
+		http.Error(w, ErrInvalidURL.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrProxyHost):
+		http.Error(w, ErrProxyHost.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, services.ErrProxyTooLarge), errors.Is(err, services.ErrProxyNotImage):
+		c.log.Warn(ErrProxyImage.Error(), slog.String("operation", op), slog.String("url", rawURL), slog.String("error", err.Error()))
+		http.Error(w, ErrProxyImage.Error(), http.StatusBadGateway)
+		return
+	case err != nil:
+		c.log.Error(ErrProxyImage.Error(), slog.String("operation", op), slog.String("url", rawURL), slog.String("error", err.Error()))
+		http.Error(w, ErrProxyImage.Error(), http.StatusBadGateway)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.log.Error(ErrProxyImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrProxyImage.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Тип ответа ServeContent определит по содержимому, а браузер не должен
+	// угадывать его сам и исполнять что-либо из картинки
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(c.maxAge.Seconds())))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"games_webapp/internal/httpx"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage/uploads"
+)
+
+type ImageErrorResponse struct {
+	Error        string   `json:"error"`
+	ContentType  string   `json:"content_type,omitempty"`
+	MaxSize      int64    `json:"max_size,omitempty"`
+	AllowedTypes []string `json:"allowed_types,omitempty"`
+}
+
+// storeImage сохраняет картинку игры. Если файл с таким же содержимым уже есть,
+// новый не пишется: возвращается имя существующего, а у него растёт счётчик ссылок.
+// Картинка, загруженная пользователем uploadedBy, проверяется по его квоте;
+// 0 — картинка скачана из внешнего источника
+func (c *GameController) storeImage(ctx context.Context, data []byte, contentType string, uploadedBy int) (string, error) {
+	if uploadedBy != 0 && quotaApplies(ctx, c.quota) {
+		if err := c.quota.CheckCover(ctx, uploadedBy, int64(len(data))); err != nil {
+			return "", err
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	filename, isNew, err := c.service.AcquireImage(ctx, hash, hash[:32]+uploads.Extension(contentType), int64(len(data)), uploadedBy)
+	if err != nil {
+		return "", err
+	}
+	if !isNew {
+		return filename, nil
+	}
+
+	// Имя файла выводится из содержимого, так что существующий файл — та же картинка
+	if err := c.uploads.SaveImage(data, filename); err != nil && !errors.Is(err, uploads.ErrFileExists) {
+		if _, relErr := c.service.ReleaseImage(ctx, filename); relErr != nil {
+			c.log.Error("failed to release image", slog.String("filename", filename), slog.String("error", relErr.Error()))
+		}
+		return "", err
+	}
+
+	return filename, nil
+}
+
+// releaseImage снимает ссылку на картинку и удаляет файл, если он больше никому не нужен.
+// Ошибки только логируются: основная операция к этому моменту уже выполнена
+func (c *GameController) releaseImage(ctx context.Context, op, filename string) {
+	remove, err := c.service.ReleaseImage(ctx, filename)
+	if err != nil {
+		c.log.Error("failed to release image", slog.String("operation", op), slog.String("filename", filename), slog.String("error", err.Error()))
+		return
+	}
+	if !remove {
+		return
+	}
+
+	if err := c.uploads.DeleteImage(filename); err != nil {
+		c.log.Error("failed to delete image", slog.String("operation", op), slog.String("filename", filename), slog.String("error", err.Error()))
+	}
+}
+
+// readImage читает картинку из формы через uploads.ReadImage. Если картинка не
+// прошла проверку, отвечает 413, 415 или 422 и возвращает ok == false
+func readImage(w http.ResponseWriter, log *slog.Logger, op string, u uploads.IUploads, src io.Reader, fallback error) (data []byte, contentType string, ok bool) {
+	data, contentType, err := u.ReadImage(src)
+	if err == nil {
+		return data, contentType, true
+	}
+
+	var imgErr *uploads.ImageError
+	if !errors.As(err, &imgErr) {
+		log.Error(ErrReadImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, fallback.Error(), http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	log.Warn(ErrInvalidImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+
+	status := http.StatusUnsupportedMediaType
+	response := ImageErrorResponse{
+		Error:        ErrUnsupportedImage.Error(),
+		ContentType:  imgErr.ContentType,
+		AllowedTypes: imgErr.AllowedTypes,
+	}
+	switch {
+	case errors.Is(err, uploads.ErrImageTooLarge):
+		status = http.StatusRequestEntityTooLarge
+		response = ImageErrorResponse{
+			Error:   ErrImageTooLarge.Error(),
+			MaxSize: imgErr.MaxSize,
+		}
+	case errors.Is(err, uploads.ErrCorruptImage):
+		status = http.StatusUnprocessableEntity
+		response = ImageErrorResponse{
+			Error:       ErrCorruptImage.Error(),
+			ContentType: imgErr.ContentType,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error("encoding response", slog.String("operation", op), slog.String("error", err.Error()))
+	}
+	return nil, "", false
+}
+
+// writeUploadError отвечает на ошибку сохранения картинки, которую пользователь
+// может исправить сам: 403, если файл не поместился в квоту, 422 для
+// заражённого файла и 503, если антивирус не ответил. Для остальных ошибок
+// возвращает false
+func writeUploadError(w http.ResponseWriter, log *slog.Logger, op string, err error) bool {
+	var quotaErr *services.QuotaError
+	switch {
+	case errors.As(err, &quotaErr):
+		log.Warn(ErrQuotaExceeded.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(QuotaErrorResponse{
+			Error: ErrQuotaExceeded.Error(),
+			Used:  quotaErr.Used,
+			Size:  quotaErr.Size,
+			Limit: quotaErr.Limit,
+		})
+	case errors.Is(err, uploads.ErrInfected):
+		log.Warn(ErrInfectedImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(ImageErrorResponse{Error: ErrInfectedImage.Error()})
+	case errors.Is(err, uploads.ErrScanFailed):
+		log.Error(ErrScanUnavailable.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrScanUnavailable.Error(), http.StatusServiceUnavailable)
+	default:
+		return false
+	}
+	return true
+}
+
+// jsonImage открывает картинку из поля image JSON-запроса: http(s)-ссылку
+// скачивает, остальное читает как base64, в том числе в виде data:-URI.
+// Проверка содержимого та же, что у файла из формы, — через readImage
+func jsonImage(ctx context.Context, image string) (io.ReadCloser, error) {
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return nil, ErrMissingImage
+	}
+
+	if strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://") {
+		return remoteImage(ctx, image)
+	}
+
+	if strings.HasPrefix(image, "data:") {
+		header, payload, ok := strings.Cut(image, ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, ErrImageData
+		}
+		image = payload
+	}
+	return io.NopCloser(base64.NewDecoder(base64.StdEncoding, strings.NewReader(image))), nil
+}
+
+// remoteImage скачивает картинку по ссылке пользователя. Адреса внутренней сети
+// отклоняются при каждом соединении, в том числе после перенаправлений
+func remoteImage(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || u.User != nil {
+		return nil, ErrInvalidURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	resp, err := httpx.PublicClient(0).Do(req)
+	if err != nil {
+		if errors.Is(err, httpx.ErrPrivateAddress) {
+			return nil, ErrImageHost
+		}
+		return nil, ErrImageURL
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, ErrDownloadImage
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxNotesLength — ограничение на длину заметок в символах
+const maxNotesLength = 10000
+
+// maxPlatformLength — ограничение на длину названия платформы, как у колонки user_games.platform
+const maxPlatformLength = 64
+
+type UpdateNotesRequest struct {
+	Notes string `json:"notes"`
+}
+
+// UpdateNotes сохраняет личные заметки пользователя к игре из его библиотеки
+func (c *GameController) UpdateNotes(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.library.UpdateNotes"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request UpdateNotesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if utf8.RuneCountInString(request.Notes) > maxNotesLength {
+		c.log.Error(ErrNotesTooLong.Error(), slog.String("operation", op))
+		http.Error(w, ErrNotesTooLong.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ug, err := c.service.UpdateNotes(r.Context(), userID, gameID, request.Notes)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, ErrNotInLibrary.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.log.Error(ErrUpdateNotes.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdateNotes.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(ug); err != nil {
+		c.log.Error(ErrUpdateNotes.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+// ToggleFavorite добавляет игру в избранное или убирает из него
+func (c *GameController) ToggleFavorite(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.library.ToggleFavorite"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ug, err := c.service.ToggleFavorite(r.Context(), userID, gameID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, ErrNotInLibrary.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.log.Error(ErrToggleFavorite.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrToggleFavorite.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(ug); err != nil {
+		c.log.Error(ErrToggleFavorite.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+// ReorderRequest — новый порядок игр в списке статуса Status. Без Status
+// берётся статус перечисленных игр
+type ReorderRequest struct {
+	Status  models.GameStatus `json:"status"`
+	GameIDs []int             `json:"game_ids"`
+}
+
+// Reorder переписывает приоритеты игр одного статуса по порядку из запроса:
+// первая игра получает наивысший приоритет
+func (c *GameController) Reorder(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.library.Reorder"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var request ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Status != "" && !request.Status.IsValid() {
+		http.Error(w, ErrInvalidStatus.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(request.GameIDs) > models.MaxPriority {
+		http.Error(w, ErrReorderTooMany.Error(), http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[int]bool, len(request.GameIDs))
+	for _, id := range request.GameIDs {
+		if seen[id] {
+			http.Error(w, ErrReorderDuplicate.Error(), http.StatusBadRequest)
+			return
+		}
+		seen[id] = true
+	}
+
+	err := c.service.ReorderPriorities(r.Context(), userID, request.Status, request.GameIDs)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, ErrNotInLibrary.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrPriorityStatus) {
+		http.Error(w, ErrReorderStatus.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		c.log.Error(ErrReorder.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrReorder.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxBulkGames — ограничение на число игр в одной массовой операции
+const maxBulkGames = 500
+
+type BulkStatusRequest struct {
+	GameIDs []int             `json:"game_ids"`
+	Status  models.GameStatus `json:"status"`
+}
+
+// BulkUpdateStatus меняет статус сразу нескольких игр библиотеки и возвращает
+// результат по каждой игре
+func (c *GameController) BulkUpdateStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.library.BulkUpdateStatus"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var request BulkStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !request.Status.IsValid() {
+		http.Error(w, ErrInvalidStatus.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(request.GameIDs) == 0 {
+		http.Error(w, ErrBulkEmpty.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(request.GameIDs) > maxBulkGames {
+		http.Error(w, ErrBulkTooMany.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := c.service.BulkUpdateStatus(r.Context(), userID, request.GameIDs, request.Status)
+	if err != nil {
+		c.log.Error(ErrBulkUpdate.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrBulkUpdate.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		c.log.Error(ErrBulkUpdate.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+// BulkDelete убирает несколько игр из библиотеки. Тело запроса — массив ID игр.
+// С ?delete_games=true созданные пользователем игры, которые больше никто не
+// отслеживает, удаляются из каталога вместе с картинками
+func (c *GameController) BulkDelete(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.library.BulkDelete"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var gameIDs []int
+	if err := json.NewDecoder(r.Body).Decode(&gameIDs); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(gameIDs) == 0 {
+		http.Error(w, ErrBulkEmpty.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(gameIDs) > maxBulkGames {
+		http.Error(w, ErrBulkTooMany.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deleteOwned := r.URL.Query().Get("delete_games") == "true"
+
+	results, removed, err := c.service.BulkDeleteUserGames(r.Context(), userID, gameIDs, deleteOwned)
+	if err != nil {
+		c.log.Error(ErrBulkDelete.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrBulkDelete.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Картинки освобождаем только после коммита, как и при удалении одной игры
+	for _, game := range removed {
+		if game.Image != "" {
+			c.releaseImage(r.Context(), op, game.Image)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		c.log.Error(ErrBulkDelete.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+type LibraryPageResponse struct {
+	Total   int                   `json:"total"`
+	Pages   int                   `json:"pages"`
+	Current int                   `json:"current"`
+	Size    int                   `json:"size"`
+	Data    []models.LibraryEntry `json:"data"`
+}
+
+// GetLibraryEntries — вариант GetUserGames с теми же параметрами, который сразу
+// отдаёт жанры игр и сводку по прохождениям
+func (c *GameController) GetLibraryEntries(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.library.GetLibraryEntries"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+
+	var status *models.GameStatus
+	if s := query.Get("status"); s != "" {
+		st := models.GameStatus(s)
+		status = &st
+	}
+
+	search := strings.TrimSpace(query.Get("search"))
+	filter := gameFilter(query)
+
+	sortBy, sortOrder, pageSize := listDefaults(r.Context(), query)
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	entries, total, err := c.service.GetLibraryEntries(r.Context(), userID, status, search, filter, sortBy, sortOrder, page, pageSize)
+	if err != nil {
+		c.log.Error(ErrGetUserGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetUserGames.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []models.LibraryEntry{}
+	}
+
+	for i := range entries {
+		localizeGame(r.Context(), &entries[i].Game)
+	}
+
+	totalPages := total / pageSize
+	if total%pageSize != 0 {
+		totalPages++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(LibraryPageResponse{
+		Total:   total,
+		Pages:   totalPages,
+		Current: page,
+		Size:    pageSize,
+		Data:    entries,
+	}); err != nil {
+		c.log.Error(ErrGetUserGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"context"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+)
+
+// localizeGame подставляет английские название и описание, если клиент
+// предпочитает английский и перевод есть. Оригиналы остаются в title_en/summary_en
+func localizeGame(ctx context.Context, g *models.Game) {
+	if g == nil || middleware.LanguageFromContext(ctx) != middleware.LangEN {
+		return
+	}
+
+	if g.TitleEn != "" {
+		g.Title = g.TitleEn
+	}
+	if g.SummaryEn != "" {
+		g.Preambula = g.SummaryEn
+	}
+}
+
+func localizeGames(ctx context.Context, games []models.UserGameResponse) {
+	for i := range games {
+		localizeGame(ctx, &games[i].Game)
+	}
+}
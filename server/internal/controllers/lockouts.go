@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"games_webapp/internal/models"
+)
+
+type LockoutServicer interface {
+	ListLocked() ([]models.LoginAttempt, error)
+	Clear(email, ip string) (int, error)
+}
+
+type LockoutController struct {
+	service LockoutServicer
+	log     *slog.Logger
+}
+
+func NewLockoutController(s LockoutServicer, log *slog.Logger) *LockoutController {
+	return &LockoutController{
+		service: s,
+		log:     log,
+	}
+}
+
+type ClearLockoutResponse struct {
+	Cleared int `json:"cleared"`
+}
+
+// GetLockouts возвращает email и IP-адреса, вход с которых сейчас закрыт
+func (c *LockoutController) GetLockouts(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.lockouts.GetLockouts"
+
+	locked, err := c.service.ListLocked()
+	if err != nil {
+		c.log.Error(ErrGetLockouts.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetLockouts.Error(), http.StatusInternalServerError)
+		return
+	}
+	if locked == nil {
+		locked = []models.LoginAttempt{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(locked)
+}
+
+// ClearLockout снимает блокировку и сбрасывает счётчик неудач для ?email= и/или ?ip=
+func (c *LockoutController) ClearLockout(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.lockouts.ClearLockout"
+
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	ip := strings.TrimSpace(r.URL.Query().Get("ip"))
+	if email == "" && ip == "" {
+		c.log.Error(ErrMissingLockoutKey.Error(), slog.String("operation", op))
+		http.Error(w, ErrMissingLockoutKey.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cleared, err := c.service.Clear(email, ip)
+	if err != nil {
+		c.log.Error(ErrClearLockout.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrClearLockout.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ClearLockoutResponse{Cleared: cleared})
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// OverrideRequest — JSON-тело SetOverride. Обложка загружается только
+// через multipart/form-data, в JSON её можно лишь убрать
+type OverrideRequest struct {
+	Title       string `json:"title"`
+	Notes       string `json:"notes"`
+	RemoveImage bool   `json:"remove_image"`
+}
+
+// GetOverride возвращает личные правки пользователя к игре
+func (c *GameController) GetOverride(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetOverride"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	o, err := c.service.GetOverride(r.Context(), userID, gameID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, ErrOverrideNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrGetOverride.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetOverride.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(o)
+}
+
+// SetOverride сохраняет личные название, заметки и обложку игры. Общая запись
+// игры не меняется, поэтому создателем игры быть не нужно
+func (c *GameController) SetOverride(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.SetOverride"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req OverrideRequest
+	var image *string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			c.log.Error(ErrParsingForm.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrParsingForm.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Title = r.FormValue("title")
+		req.Notes = r.FormValue("notes")
+		req.RemoveImage, _ = strconv.ParseBool(r.FormValue("remove_image"))
+
+		if file, _, err := r.FormFile("image"); err == nil {
+			defer file.Close()
+
+			data, contentType, ok := readImage(w, c.log, op, c.uploads, file, ErrSetOverride)
+			if !ok {
+				return
+			}
+
+			filename, err := c.storeImage(r.Context(), data, contentType, userID)
+			if writeUploadError(w, c.log, op, err) {
+				return
+			}
+			if err != nil {
+				c.log.Error(ErrSaveImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+				http.Error(w, ErrSetOverride.Error(), http.StatusInternalServerError)
+				return
+			}
+			image = &filename
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+	if image == nil && req.RemoveImage {
+		image = new(string)
+	}
+	if utf8.RuneCountInString(req.Notes) > maxNotesLength {
+		if image != nil && *image != "" {
+			c.releaseImage(r.Context(), op, *image)
+		}
+		c.log.Error(ErrNotesTooLong.Error(), slog.String("operation", op))
+		http.Error(w, ErrNotesTooLong.Error(), http.StatusBadRequest)
+		return
+	}
+
+	o, oldImage, err := c.service.SetOverride(r.Context(), userID, gameID,
+		strings.TrimSpace(req.Title), strings.TrimSpace(req.Notes), image)
+	if err != nil {
+		if image != nil && *image != "" {
+			c.releaseImage(r.Context(), op, *image)
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, ErrGameNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrSetOverride.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrSetOverride.Error(), http.StatusInternalServerError)
+		return
+	}
+	if oldImage != "" {
+		c.releaseImage(r.Context(), op, oldImage)
+	}
+
+	// Пустая правка удаляется: игра снова выглядит как у всех
+	if o == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(o)
+}
+
+// DeleteOverride убирает личные правки пользователя к игре
+func (c *GameController) DeleteOverride(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.DeleteOverride"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	image, err := c.service.DeleteOverride(r.Context(), userID, gameID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, ErrOverrideNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrDeleteOverride.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrDeleteOverride.Error(), http.StatusInternalServerError)
+		return
+	}
+	if image != "" {
+		c.releaseImage(r.Context(), op, image)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
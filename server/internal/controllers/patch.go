@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// patchStringFields — текстовые поля игры, которые можно изменить через Patch
+func patchStringFields(p *models.GamePatch) map[string]**string {
+	return map[string]**string{
+		"title":      &p.Title,
+		"preambula":  &p.Preambula,
+		"title_en":   &p.TitleEn,
+		"summary_en": &p.SummaryEn,
+		"developer":  &p.Developer,
+		"publisher":  &p.Publisher,
+		"year":       &p.Year,
+		"genre":      &p.Genre,
+		"platforms":  &p.Platforms,
+		"url":        &p.URL,
+	}
+}
+
+// decodeGamePatch разбирает тело PATCH. Поля, которых нет в теле, не меняются;
+// null и пустая строка очищают поле. Возвращает версию из тела (если есть)
+// и имя поля, из-за которого тело отклонено
+func decodeGamePatch(body map[string]json.RawMessage) (patch models.GamePatch, version string, field string, err error) {
+	targets := patchStringFields(&patch)
+	for key, raw := range body {
+		var value *string
+		switch key {
+		case "version":
+			var v json.Number
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return patch, "", key, ErrInvalidRequest
+			}
+			version = v.String()
+			continue
+		case "release_date":
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return patch, "", key, ErrInvalidRequest
+			}
+			if value == nil || *value == "" {
+				patch.ClearReleaseDate = true
+				continue
+			}
+			if patch.ReleaseDate, err = parseReleaseDate(*value); err != nil {
+				return patch, "", key, err
+			}
+			continue
+		}
+
+		target, ok := targets[key]
+		if !ok {
+			return patch, "", key, ErrPatchField
+		}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return patch, "", key, ErrInvalidRequest
+		}
+		if value == nil {
+			value = new(string)
+		}
+		*target = value
+	}
+
+	return patch, version, "", nil
+}
+
+// Patch меняет только поля игры, присланные в JSON. В отличие от Update, не
+// трогает остальные поля, картинку и запись в библиотеке пользователя
+func (c *GameController) Patch(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.Patch"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	gameID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" && mediaType != "application/merge-patch+json" {
+		c.log.Error(ErrInvalidRequest.Error(), slog.String("operation", op), slog.String("content_type", mediaType))
+		http.Error(w, ErrInvalidRequest.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	existing, err := c.service.GetByID(r.Context(), gameID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, ErrGameNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrGetGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdateGame.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !middleware.HasRole(r.Context(), models.RoleModerator) && existing.Creator != userID {
+		c.log.Error(ErrUpdateGame.Error(), slog.String("operation", op), slog.String("error", "user is not admin"))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	patch, bodyVersion, field, err := decodeGamePatch(body)
+	if err != nil {
+		c.log.Error(err.Error(), slog.String("operation", op), slog.String("field", field))
+		http.Error(w, fmt.Sprintf("%s: %s", field, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	version, err := gameVersion(r, bodyVersion)
+	if err != nil {
+		c.log.Error(ErrGameVersionRequired.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGameVersionRequired.Error(), http.StatusPreconditionRequired)
+		return
+	}
+
+	res, err := c.service.Patch(r.Context(), gameID, version, patch)
+	if err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			c.log.Error(ErrGameChanged.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrGameChanged.Error(), http.StatusConflict)
+			return
+		}
+		if writeGameFields(w, c.log, op, err) {
+			return
+		}
+		c.log.Error(ErrUpdateGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdateGame.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", gameETag(res.Version))
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		c.log.Error(ErrUpdateGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdateGame.Error(), http.StatusInternalServerError)
+		return
+	}
+}
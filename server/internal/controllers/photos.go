@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"games_webapp/internal/lib/signer"
+	"games_webapp/internal/storage/uploads"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type PhotoStore interface {
+	Open(filename string) (*os.File, error)
+}
+
+type PhotoController struct {
+	photos PhotoStore
+	// legacy — общая папка загрузок, где лежат фото, загруженные до появления отдельной папки
+	legacy PhotoStore
+	signer *signer.Signer
+	log    *slog.Logger
+}
+
+func NewPhotoController(photos, legacy PhotoStore, signer *signer.Signer, log *slog.Logger) *PhotoController {
+	return &PhotoController{
+		photos: photos,
+		legacy: legacy,
+		signer: signer,
+		log:    log,
+	}
+}
+
+// photoURL возвращает подписанную ссылку на фото пользователя
+func photoURL(s *signer.Signer, path string) string {
+	if path == "" {
+		return ""
+	}
+
+	name := filepath.Base(path)
+	expires, signature := s.Sign(name)
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signature)
+
+	return fmt.Sprintf("/api/photos/%s?%s", url.PathEscape(name), query.Encode())
+}
+
+// Serve отдаёт фото пользователя по подписанной ссылке. Авторизация не нужна:
+// ссылка сама подтверждает доступ и перестаёт работать через TTL
+func (c *PhotoController) Serve(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.photos.Serve"
+
+	name := chi.URLParam(r, "name")
+	query := r.URL.Query()
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		http.Error(w, ErrInvalidPhotoLink.Error(), http.StatusForbidden)
+		return
+	}
+
+	if err := c.signer.Verify(name, expires, query.Get("signature")); err != nil {
+		c.log.Warn(ErrInvalidPhotoLink.Error(), slog.String("operation", op), slog.String("name", name), slog.String("error", err.Error()))
+		http.Error(w, ErrInvalidPhotoLink.Error(), http.StatusForbidden)
+		return
+	}
+
+	f, err := c.photos.Open(name)
+	if errors.Is(err, uploads.ErrFileNotExists) && c.legacy != nil {
+		f, err = c.legacy.Open(name)
+	}
+	if err != nil {
+		if errors.Is(err, uploads.ErrFileNotExists) || errors.Is(err, uploads.ErrInvalidFileName) {
+			http.Error(w, ErrPhotoNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrGetPhoto.Error(), slog.String("operation", op), slog.String("name", name), slog.String("error", err.Error()))
+		http.Error(w, ErrGetPhoto.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.log.Error(ErrGetPhoto.Error(), slog.String("operation", op), slog.String("name", name), slog.String("error", err.Error()))
+		http.Error(w, ErrGetPhoto.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Кэшировать можно только пока ссылка действует
+	maxAge := time.Until(time.Unix(expires, 0)) / time.Second
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
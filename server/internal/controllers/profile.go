@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage/uploads"
+
+	ssogrpc "games_webapp/internal/clients/sso/grpc"
+
+	ssov1 "github.com/Nergous/sso_protos/gen/go/sso"
+)
+
+// ssoAppID — идентификатор этого приложения в SSO
+const ssoAppID = 1
+
+// UpdateProfile меняет email, пароль, ссылку на Steam и фото текущего пользователя.
+// Принимает multipart/form-data, все поля необязательны. Для смены email или
+// пароля нужен current_password: его проверяем входом в SSO
+func (c *AuthController) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.auth.UpdateProfile"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		c.log.Error(ErrParsingForm.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingForm.Error(), http.StatusBadRequest)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(r.FormValue("email")))
+	password := r.FormValue("password")
+	currentPassword := r.FormValue("current_password")
+	steamURL := strings.TrimSpace(r.FormValue("steam_url"))
+	file, _, err := r.FormFile("image")
+	hasImage := err == nil
+	if hasImage {
+		defer file.Close()
+	}
+
+	if email == "" && password == "" && steamURL == "" && !hasImage {
+		c.log.Error(ErrEmptyProfileUpdate.Error(), slog.String("operation", op))
+		http.Error(w, ErrEmptyProfileUpdate.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields := make(map[string]string)
+	if email != "" {
+		if err := validateEmail(email); err != nil {
+			fields["email"] = err.Error()
+		}
+	}
+	if password != "" {
+		if err := validatePassword(password); err != nil {
+			fields["password"] = err.Error()
+		}
+	}
+	if steamURL != "" {
+		if err := validateSteamURL(steamURL); err != nil {
+			fields["steam_url"] = err.Error()
+		}
+	}
+	if (email != "" || password != "") && currentPassword == "" {
+		fields["current_password"] = ErrMissingCurrentPassword.Error()
+	}
+	if len(fields) > 0 {
+		c.writeProfileFields(w, op, fields)
+		return
+	}
+
+	currentEmail, _, oldPhoto, err := c.client.GetUserInfo(r.Context(), uint32(userID))
+	if err != nil {
+		c.log.Error("sso.GetUserInfo failed", slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdateProfile.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if email != "" || password != "" {
+		if ok := c.checkCurrentPassword(w, r, op, currentEmail, currentPassword); !ok {
+			return
+		}
+	}
+
+	update := &ssov1.UpdateUserRequest{
+		Id:       uint32(userID),
+		Email:    email,
+		Password: password,
+		SteamUrl: steamURL,
+	}
+
+	var photoSize int64
+	if hasImage {
+		imageData, contentType, ok := readImage(w, c.log, op, c.photos, file, ErrUpdateProfile)
+		if !ok {
+			return
+		}
+		if quotaApplies(r.Context(), c.quota) {
+			if err := c.quota.CheckPhoto(r.Context(), userID, int64(len(imageData))); err != nil {
+				if !writeUploadError(w, c.log, op, err) {
+					c.log.Error(ErrGetUploadUsage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+					http.Error(w, ErrUpdateProfile.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+		}
+		photoSize = int64(len(imageData))
+		update.PathToPhoto = generatePhotoFilename(currentEmail, uploads.Extension(contentType))
+		if err := c.photos.SaveImage(imageData, update.PathToPhoto); err != nil {
+			if writeUploadError(w, c.log, op, err) {
+				return
+			}
+			c.log.Error(ErrSaveImage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrUpdateProfile.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if _, err := c.client.UpdateUser(r.Context(), update); err != nil {
+		if update.PathToPhoto != "" {
+			c.deletePhoto(op, update.PathToPhoto)
+		}
+		if errors.Is(err, ssogrpc.ErrUserExists) {
+			c.writeProfileFields(w, op, map[string]string{"email": ErrEmailTaken.Error()})
+			return
+		}
+		c.log.Error("sso.UpdateUser failed", slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdateProfile.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if update.PathToPhoto != "" && oldPhoto != "" && oldPhoto != update.PathToPhoto {
+		c.deletePhoto(op, oldPhoto)
+	}
+	if update.PathToPhoto != "" && c.quota != nil {
+		if err := c.quota.SetPhoto(r.Context(), userID, update.PathToPhoto, photoSize); err != nil {
+			c.log.Error("failed to record photo size", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
+
+	var user GetUserInfoResponse
+	user.Email, user.SteamURL, user.Photo, err = c.client.GetUserInfo(r.Context(), uint32(userID))
+	if err != nil {
+		// Профиль уже обновлён, поэтому не отвечаем ошибкой
+		c.log.Error("sso.GetUserInfo failed", slog.String("operation", op), slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	user.Photo = photoURL(c.signer, user.Photo)
+	user.Role = middleware.RoleFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// checkCurrentPassword проверяет пароль входом в SSO и сразу закрывает
+// полученную сессию. Неудачи учитываются защитой от перебора, как при входе
+func (c *AuthController) checkCurrentPassword(w http.ResponseWriter, r *http.Request, op, email, password string) bool {
+	ip := clientIP(r)
+	if c.guard != nil {
+		if until, err := c.guard.Check(email, ip); err != nil {
+			if errors.Is(err, services.ErrLoginLocked) {
+				c.writeLoginLocked(w, op, ip, until)
+				return false
+			}
+			c.log.Error("failed to check login lockout", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
+
+	_, refreshToken, err := c.client.Login(r.Context(), email, password, ssoAppID)
+	if errors.Is(err, ssogrpc.ErrInvalidCredentials) {
+		if c.guard != nil {
+			if err := c.guard.Fail(email, ip); err != nil {
+				c.log.Error("failed to record failed login", slog.String("operation", op), slog.String("error", err.Error()))
+			}
+		}
+		c.writeProfileFields(w, op, map[string]string{"current_password": ErrWrongCurrentPassword.Error()})
+		return false
+	}
+	if err != nil {
+		c.log.Error("sso.Login failed", slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdateProfile.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	if err := c.client.Logout(context.WithoutCancel(r.Context()), refreshToken); err != nil {
+		c.log.Error("sso.Logout failed", slog.String("operation", op), slog.String("error", err.Error()))
+	}
+	return true
+}
+
+func (c *AuthController) writeProfileFields(w http.ResponseWriter, op string, fields map[string]string) {
+	c.log.Error(ErrProfileFields.Error(), slog.String("operation", op), slog.Any("fields", fields))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(ValidationErrorResponse{Error: ErrProfileFields.Error(), Fields: fields})
+}
+
+func (c *AuthController) deletePhoto(op, filename string) {
+	if err := c.photos.DeleteImage(filename); err != nil {
+		c.log.Error("failed to delete photo", slog.String("operation", op), slog.String("filename", filename), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// publicFeedSize — сколько последних событий попадает в публичную ленту
+const publicFeedSize = 50
+
+type PublicServicer interface {
+	GetPublicActivity(slug string, limit int) ([]models.FeedItem, error)
+}
+
+type PublicController struct {
+	service PublicServicer
+	log     *slog.Logger
+}
+
+func NewPublicController(s PublicServicer, log *slog.Logger) *PublicController {
+	return &PublicController{
+		service: s,
+		log:     log,
+	}
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string `xml:"id"`
+	Title   string `xml:"title"`
+	Updated string `xml:"updated"`
+}
+
+// GetUserFeedAtom отдаёт Atom-ленту добавлений и прохождений открытого профиля
+func (c *PublicController) GetUserFeedAtom(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.public.GetUserFeedAtom"
+
+	slug := chi.URLParam(r, "slug")
+
+	items, err := c.service.GetPublicActivity(slug, publicFeedSize)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, ErrProfileNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrGetPublicFeed.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetPublicFeed.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	feed := atomFeed{
+		ID:      "urn:games_webapp:user:" + slug,
+		Title:   fmt.Sprintf("Игры %s", slug),
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: slug},
+		Link:    atomLink{Rel: "self", Href: fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.Path)},
+		Entries: make([]atomEntry, 0, len(items)),
+	}
+	if len(items) > 0 && items[0].CreatedAt != nil {
+		feed.Updated = items[0].CreatedAt.UTC().Format(time.RFC3339)
+	}
+
+	for _, item := range items {
+		entry := atomEntry{
+			ID:    fmt.Sprintf("urn:games_webapp:event:%d", item.ID),
+			Title: atomTitle(item),
+		}
+		if item.CreatedAt != nil {
+			entry.Updated = item.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(feed); err != nil {
+		c.log.Error(ErrGetPublicFeed.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+func atomTitle(item models.FeedItem) string {
+	switch item.Type {
+	case models.EventGameFinished:
+		return fmt.Sprintf("Пройдена игра «%s»", item.GameTitle)
+	default:
+		return fmt.Sprintf("Добавлена игра «%s»", item.GameTitle)
+	}
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+)
+
+// QuickAddRequest — страница игры, которую пользователь добавляет из расширения
+// или букмарклета, и необязательный статус
+type QuickAddRequest struct {
+	URL    string            `json:"url"`
+	Status models.GameStatus `json:"status"`
+}
+
+// QuickAddResponse — игра и её статус в библиотеке после добавления
+type QuickAddResponse struct {
+	Game   *CreatedGame      `json:"game"`
+	Status models.GameStatus `json:"status"`
+	// StatusApplied — статус из запроса поставлен. false, если игра уже была в
+	// библиотеке не в planned: быстрое добавление не меняет выбранный статус
+	StatusApplied bool `json:"status_applied"`
+}
+
+// QuickAdd добавляет игру по ссылке одним запросом, как POST /api/games/from-url,
+// и сразу ставит статус. Рассчитан на расширения браузера: CORS для него
+// настраивается отдельно, см. http_server.extension_cors
+func (c *GameController) QuickAdd(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.quick_add.QuickAdd"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var request QuickAddRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.Status != "" && !request.Status.IsValid() {
+		c.log.Warn(ErrInvalidStatus.Error(), slog.String("operation", op), slog.String("status", string(request.Status)))
+		http.Error(w, ErrInvalidStatus.Error(), http.StatusBadRequest)
+		return
+	}
+
+	done, ok := c.tracker.Track()
+	if !ok {
+		http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer done()
+
+	game, err := c.gameFromURL(r.Context(), op, request.URL)
+	if err != nil {
+		writeFromURLError(w, err)
+		return
+	}
+
+	response := QuickAddResponse{Game: game, Status: models.StatusPlanned}
+	ug, err := c.service.GetUserGame(r.Context(), userID, game.ID)
+	if err != nil {
+		c.log.Warn(ErrGetUserGames.Error(), slog.String("operation", op), slog.Int("game_id", game.ID), slog.String("error", err.Error()))
+	} else {
+		response.Status = ug.Status
+	}
+
+	// Как и импорт трофеев, меняем только запланированную игру
+	switch {
+	case request.Status == "":
+	case request.Status == response.Status:
+		response.StatusApplied = true
+	case response.Status == models.StatusPlanned:
+		results, err := c.service.BulkUpdateStatus(r.Context(), userID, []int{game.ID}, request.Status)
+		if err != nil {
+			c.log.Error(ErrUpdateUserGame.Error(), slog.String("operation", op), slog.String("status", string(request.Status)), slog.String("error", err.Error()))
+		}
+		for _, res := range results {
+			if res.GameID == game.ID && res.Result == models.BulkUpdated {
+				response.Status, response.StatusApplied = request.Status, true
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		c.log.Error(ErrCreateGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
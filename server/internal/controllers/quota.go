@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+)
+
+// UploadQuota считает место пользователя в загрузках, см. services.QuotaService
+type UploadQuota interface {
+	Usage(ctx context.Context, userID int) (models.UploadUsage, error)
+	CheckCover(ctx context.Context, userID int, size int64) error
+	CheckPhoto(ctx context.Context, userID int, size int64) error
+	SetPhoto(ctx context.Context, userID int, filename string, size int64) error
+}
+
+type QuotaErrorResponse struct {
+	Error string `json:"error"`
+	Used  int64  `json:"used"`
+	Size  int64  `json:"size"`
+	Limit int64  `json:"limit"`
+}
+
+// quotaApplies сообщает, что загрузки пользователя из ctx ограничены квотой.
+// Администраторов квота не касается
+func quotaApplies(ctx context.Context, quota UploadQuota) bool {
+	return quota != nil && !middleware.HasRole(ctx, models.RoleAdmin)
+}
+
+type QuotaController struct {
+	quota UploadQuota
+	log   *slog.Logger
+}
+
+func NewQuotaController(quota UploadQuota, log *slog.Logger) *QuotaController {
+	return &QuotaController{
+		quota: quota,
+		log:   log,
+	}
+}
+
+// GetUsage возвращает место, которое пользователь занимает в загрузках, и квоту
+func (c *QuotaController) GetUsage(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.quota.GetUsage"
+
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	usage, err := c.quota.Usage(r.Context(), userID)
+	if err != nil {
+		c.log.Error(ErrGetUploadUsage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetUploadUsage.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !quotaApplies(r.Context(), c.quota) {
+		usage.Limit = 0
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		c.log.Error(ErrGetUploadUsage.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"games_webapp/internal/middleware"
+)
+
+const (
+	recommendationsTopN      = 3
+	recommendationsMinRating = 75
+)
+
+type Recommendation struct {
+	Name       string      `json:"name"`
+	Summary    string      `json:"summary"`
+	URL        string      `json:"url"`
+	Cover      string      `json:"cover"`
+	Year       string      `json:"year"`
+	Genres     []string    `json:"genres"`
+	Developers []string    `json:"developers"`
+	Rating     float64     `json:"rating"`
+	Reasons    []string    `json:"reasons"`
+	AddPayload RequestData `json:"add_payload"` // Тело для POST /api/games/twitch
+}
+
+func (c *GameController) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetRecommendations"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 10
+	} else if limit > 50 {
+		limit = 50
+	}
+
+	profile, err := c.service.GetLibraryProfile(r.Context(), userID, recommendationsTopN)
+	if err != nil {
+		c.log.Error(ErrGetRecommendations.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetRecommendations.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	recommendations := []Recommendation{}
+
+	if len(profile.TopGenres) > 0 || len(profile.TopDevelopers) > 0 {
+		access, err := c.igdb.Login(r.Context())
+		if err != nil {
+			c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrGetRecommendations.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		candidates, err := c.igdb.FindSimilar(r.Context(), profile.TopGenres, profile.TopDevelopers, recommendationsMinRating, access)
+		if err != nil {
+			c.log.Error(ErrGetRecommendations.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrGetRecommendations.Error(), http.StatusBadGateway)
+			return
+		}
+
+		for _, game := range candidates {
+			if profile.OwnedURLs[game.URL] || profile.OwnedTitles[strings.ToLower(strings.TrimSpace(game.Name))] {
+				continue
+			}
+
+			rec := Recommendation{
+				Name:       game.Name,
+				Summary:    game.Summary,
+				URL:        game.URL,
+				Cover:      game.CoverURL,
+				Year:       strings.Split(game.ReleaseDate, "-")[0],
+				Genres:     game.Genres,
+				Developers: game.Developers,
+				Rating:     game.Rating,
+			}
+			rec.Reasons = append(intersect(rec.Genres, profile.TopGenres), intersect(rec.Developers, profile.TopDevelopers)...)
+			rec.AddPayload = RequestData{Games: []RequestGame{{Name: rec.Name, Source: "igdb"}}}
+			recommendations = append(recommendations, rec)
+
+			if len(recommendations) == limit {
+				break
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(recommendations); err != nil {
+		c.log.Error(ErrGetRecommendations.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetRecommendations.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func intersect(a, b []string) []string {
+	var res []string
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(x, y) {
+				res = append(res, x)
+				break
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+)
+
+// releaseDateLayout — формат даты выхода в запросах: 2024-03-21
+const releaseDateLayout = "2006-01-02"
+
+// maxUpcomingDays — насколько далеко вперёд можно смотреть календарь релизов
+const maxUpcomingDays = 365
+
+// parseReleaseDate разбирает дату выхода. Пустая строка — даты нет
+func parseReleaseDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(releaseDateLayout, s)
+	if err != nil {
+		return nil, ErrInvalidReleaseDate
+	}
+	return &t, nil
+}
+
+// GetUpcomingGames возвращает запланированные игры пользователя, которые выходят
+// в ближайшие days дней (по умолчанию 30), от ближайших к дальним
+func (c *GameController) GetUpcomingGames(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetUpcomingGames"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+	if days < 1 {
+		days = 30
+	} else if days > maxUpcomingDays {
+		days = maxUpcomingDays
+	}
+
+	// Даты выхода хранятся без времени, поэтому считаем от начала текущего дня по UTC
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	to := from.AddDate(0, 0, days+1)
+
+	games, err := c.service.GetUpcomingGames(r.Context(), userID, from, to)
+	if err != nil {
+		c.log.Error(ErrGetUserGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetUserGames.Error(), http.StatusInternalServerError)
+		return
+	}
+	if games == nil {
+		games = []models.UserGameResponse{}
+	}
+
+	localizeGames(r.Context(), games)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(games); err != nil {
+		c.log.Error(ErrGetUserGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetUserGames.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// icsEscaper экранирует текстовые значения iCalendar (RFC 5545, 3.3.11)
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+// GetReleaseCalendar отдаёт даты выхода запланированных игр на год вперёд
+// в формате iCalendar, чтобы на календарь можно было подписаться
+func (c *GameController) GetReleaseCalendar(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetReleaseCalendar"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now().UTC()
+	from := now.Truncate(24 * time.Hour)
+	games, err := c.service.GetUpcomingGames(r.Context(), userID, from, from.AddDate(0, 0, maxUpcomingDays+1))
+	if err != nil {
+		c.log.Error(ErrGetUserGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetUserGames.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	localizeGames(r.Context(), games)
+
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//games_webapp//releases//RU")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "X-WR-CALNAME:Релизы запланированных игр")
+	for _, g := range games {
+		day := g.ReleaseDate.UTC()
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, fmt.Sprintf("UID:game-%d@games_webapp", g.ID))
+		writeICSLine(&b, "DTSTAMP:"+now.Format("20060102T150405Z"))
+		writeICSLine(&b, "DTSTART;VALUE=DATE:"+day.Format("20060102"))
+		writeICSLine(&b, "DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format("20060102"))
+		writeICSLine(&b, "SUMMARY:"+icsEscaper.Replace(g.Title))
+		if g.URL != "" {
+			writeICSLine(&b, "URL:"+g.URL)
+		}
+		writeICSLine(&b, "TRANSP:TRANSPARENT")
+		writeICSLine(&b, "END:VEVENT")
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="releases.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
+
+// writeICSLine пишет строку iCalendar, перенося её так, чтобы строки не были
+// длиннее 75 байт и не разрывали UTF-8 символы. Продолжение начинается с пробела
+func writeICSLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
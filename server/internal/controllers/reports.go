@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"games_webapp/internal/services"
+)
+
+type ReportServicer interface {
+	WriteMonthly(ctx context.Context, month time.Time, w services.ReportWriter) error
+}
+
+type ReportController struct {
+	service ReportServicer
+	log     *slog.Logger
+}
+
+func NewReportController(s ReportServicer, log *slog.Logger) *ReportController {
+	return &ReportController{
+		service: s,
+		log:     log,
+	}
+}
+
+func (c *ReportController) GetMonthly(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.reports.GetMonthly"
+
+	query := r.URL.Query()
+
+	// По умолчанию — прошлый месяц, текущий ещё не закончился
+	month := time.Now().AddDate(0, -1, 0)
+	if m := query.Get("month"); m != "" {
+		parsed, err := time.Parse("2006-01", m)
+		if err != nil {
+			c.log.Error(ErrInvalidMonth.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrInvalidMonth.Error(), http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	var writer services.ReportWriter
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writer = services.NewCSVReportWriter(w)
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		xw, err := services.NewXLSXReportWriter(w)
+		if err != nil {
+			c.log.Error(ErrBuildReport.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrBuildReport.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer = xw
+	default:
+		c.log.Error(ErrInvalidFormat.Error(), slog.String("operation", op), slog.String("format", format))
+		http.Error(w, ErrInvalidFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"report-%s.%s\"", month.Format("2006-01"), format),
+	)
+
+	// Заголовки уже отправлены вместе с первой строкой, поэтому после ошибки
+	// можно только залогировать её и оборвать ответ
+	if err := c.service.WriteMonthly(r.Context(), month, writer); err != nil {
+		c.log.Error(ErrBuildReport.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		c.log.Error(ErrBuildReport.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type RoleServicer interface {
+	List() ([]models.UserRole, error)
+	SetRole(userID int, role models.Role, grantedBy int) error
+}
+
+type RoleController struct {
+	service RoleServicer
+	log     *slog.Logger
+}
+
+func NewRoleController(s RoleServicer, log *slog.Logger) *RoleController {
+	return &RoleController{
+		service: s,
+		log:     log,
+	}
+}
+
+type SetRoleRequest struct {
+	Role models.Role `json:"role"`
+}
+
+// GetRoles возвращает пользователей с ролями, назначенными в приложении.
+// Администраторы назначаются в SSO и здесь не видны
+func (c *RoleController) GetRoles(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.roles.GetRoles"
+
+	roles, err := c.service.List()
+	if err != nil {
+		c.log.Error(ErrGetRoles.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetRoles.Error(), http.StatusInternalServerError)
+		return
+	}
+	if roles == nil {
+		roles = []models.UserRole{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(roles)
+}
+
+// SetRole назначает пользователю роль moderator или снимает её (role: user)
+func (c *RoleController) SetRole(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.roles.SetRole"
+
+	adminID, _ := middleware.UserIDFromContext(r.Context())
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil || userID <= 0 {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.SetRole(userID, req.Role, adminID); err != nil {
+		if errors.Is(err, services.ErrInvalidRole) {
+			http.Error(w, ErrInvalidRole.Error(), http.StatusBadRequest)
+			return
+		}
+		c.log.Error(ErrSetRole.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrSetRole.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
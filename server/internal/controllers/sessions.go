@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SessionServicer interface {
+	List(userID int, currentToken string) ([]models.Session, error)
+	Revoke(userID, sessionID int) error
+}
+
+type SessionController struct {
+	service SessionServicer
+	log     *slog.Logger
+}
+
+func NewSessionController(s SessionServicer, log *slog.Logger) *SessionController {
+	return &SessionController{
+		service: s,
+		log:     log,
+	}
+}
+
+// sessionUser, как и у токенов, не пускает запросы по API-токену: иначе
+// утёкший токен мог бы выкинуть владельца со всех устройств
+func (c *SessionController) sessionUser(w http.ResponseWriter, r *http.Request, op string) (int, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return 0, false
+	}
+	if viaToken, _ := r.Context().Value(middleware.APITokenKey).(bool); viaToken {
+		c.log.Error(ErrTokenAuthForbidden.Error(), slog.String("operation", op))
+		http.Error(w, ErrTokenAuthForbidden.Error(), http.StatusForbidden)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func (c *SessionController) GetSessions(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.sessions.GetSessions"
+
+	userID, ok := c.sessionUser(w, r, op)
+	if !ok {
+		return
+	}
+
+	var current string
+	if cookie, err := r.Cookie(refreshTokenCookieName); err == nil {
+		current = cookie.Value
+	}
+
+	sessions, err := c.service.List(userID, current)
+	if err != nil {
+		c.log.Error(ErrGetSessions.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetSessions.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (c *SessionController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.sessions.RevokeSession"
+
+	userID, ok := c.sessionUser(w, r, op)
+	if !ok {
+		return
+	}
+
+	sessionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || sessionID <= 0 {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.Revoke(userID, sessionID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.log.Error(ErrSessionNotFound.Error(), slog.String("operation", op))
+			http.Error(w, ErrSessionNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrRevokeSession.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrRevokeSession.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientIP возвращает адрес клиента без порта
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// defaultSorts — сортировки, которые можно сохранить по умолчанию. Пустая строка —
+// сортировка приложения
+var defaultSorts = map[string]bool{"": true, "title": true, "year": true, "priority": true, "favorite": true, "added": true}
+
+var publicSlugRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{2,31}$`)
+
+type SettingsServicer interface {
+	GetSettings(userID int) (*models.UserSettings, error)
+	UpdateSettings(userID int, patch *models.SettingsPatch) (*models.UserSettings, error)
+}
+
+type SettingsController struct {
+	service SettingsServicer
+	log     *slog.Logger
+}
+
+func NewSettingsController(s SettingsServicer, log *slog.Logger) *SettingsController {
+	return &SettingsController{
+		service: s,
+		log:     log,
+	}
+}
+
+func (c *SettingsController) GetSettings(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.settings.GetSettings"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := c.service.GetSettings(userID)
+	if err != nil {
+		c.log.Error(ErrGetSettings.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetSettings.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (c *SettingsController) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.settings.UpdateSettings"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var patch models.SettingsPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateSettings(&patch); err != nil {
+		c.log.Error(err.Error(), slog.String("operation", op))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := c.service.UpdateSettings(userID, &patch)
+	if err != nil {
+		if errors.Is(err, services.ErrSlugTaken) {
+			c.log.Error(ErrPublicSlugTaken.Error(), slog.String("operation", op))
+			http.Error(w, ErrPublicSlugTaken.Error(), http.StatusConflict)
+			return
+		}
+		c.log.Error(ErrUpdateSettings.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdateSettings.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings)
+}
+
+// SettingToggle — тело запросов и ответов отдельных переключателей настроек.
+// Те же поля есть в /api/settings, эти эндпоинты оставлены для старых клиентов
+type SettingToggle struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (c *SettingsController) GetPriorityAging(w http.ResponseWriter, r *http.Request) {
+	c.getToggle(w, r, "controllers.settings.GetPriorityAging", func(s *models.UserSettings) bool {
+		return s.PriorityAging
+	})
+}
+
+func (c *SettingsController) SetPriorityAging(w http.ResponseWriter, r *http.Request) {
+	c.setToggle(w, r, "controllers.settings.SetPriorityAging", func(enabled bool) *models.SettingsPatch {
+		return &models.SettingsPatch{PriorityAging: &enabled}
+	})
+}
+
+func (c *SettingsController) GetDiscordNotify(w http.ResponseWriter, r *http.Request) {
+	c.getToggle(w, r, "controllers.settings.GetDiscordNotify", func(s *models.UserSettings) bool {
+		return s.DiscordNotify
+	})
+}
+
+func (c *SettingsController) SetDiscordNotify(w http.ResponseWriter, r *http.Request) {
+	c.setToggle(w, r, "controllers.settings.SetDiscordNotify", func(enabled bool) *models.SettingsPatch {
+		return &models.SettingsPatch{DiscordNotify: &enabled}
+	})
+}
+
+func (c *SettingsController) GetWeeklyDigest(w http.ResponseWriter, r *http.Request) {
+	c.getToggle(w, r, "controllers.settings.GetWeeklyDigest", func(s *models.UserSettings) bool {
+		return s.WeeklyDigest
+	})
+}
+
+func (c *SettingsController) SetWeeklyDigest(w http.ResponseWriter, r *http.Request) {
+	c.setToggle(w, r, "controllers.settings.SetWeeklyDigest", func(enabled bool) *models.SettingsPatch {
+		return &models.SettingsPatch{WeeklyDigest: &enabled}
+	})
+}
+
+// getToggle отдаёт одно булево поле настроек
+func (c *SettingsController) getToggle(w http.ResponseWriter, r *http.Request, op string, field func(*models.UserSettings) bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := c.service.GetSettings(userID)
+	if err != nil {
+		c.log.Error(ErrGetSettings.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetSettings.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(SettingToggle{Enabled: field(settings)})
+}
+
+// setToggle меняет одно булево поле настроек через общий UpdateSettings
+func (c *SettingsController) setToggle(w http.ResponseWriter, r *http.Request, op string, patch func(enabled bool) *models.SettingsPatch) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req SettingToggle
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := c.service.UpdateSettings(userID, patch(req.Enabled)); err != nil {
+		c.log.Error(ErrUpdateSettings.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrUpdateSettings.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(req)
+}
+
+// validateSettings проверяет переданные поля. Пустые строки и 0 в умолчаниях
+// сбрасывают их к умолчаниям приложения
+func validateSettings(p *models.SettingsPatch) error {
+	if p.DefaultSort != nil && !defaultSorts[*p.DefaultSort] {
+		return ErrInvalidDefaultSort
+	}
+	if p.DefaultSortOrder != nil && *p.DefaultSortOrder != "" && *p.DefaultSortOrder != "asc" && *p.DefaultSortOrder != "desc" {
+		return ErrInvalidSortOrder
+	}
+	if p.DefaultPageSize != nil && (*p.DefaultPageSize < 0 || *p.DefaultPageSize > maxPageSize) {
+		return ErrInvalidPageSize
+	}
+	if p.Visibility != nil && !p.Visibility.IsValid() {
+		return ErrInvalidVisibility
+	}
+	if p.Language != nil && *p.Language != "" && *p.Language != middleware.LangRU && *p.Language != middleware.LangEN {
+		return ErrInvalidLanguage
+	}
+	if p.PublicSlug != nil && *p.PublicSlug != "" && !publicSlugRe.MatchString(*p.PublicSlug) {
+		return ErrInvalidPublicSlug
+	}
+	return nil
+}
+
+// listDefaults читает сортировку и размер страницы из запроса, подставляя
+// сохранённые пользователем умолчания для не заданных параметров
+func listDefaults(ctx context.Context, query url.Values) (sortBy, sortOrder string, pageSize int) {
+	sortBy = query.Get("sort_by")
+	sortOrder = query.Get("sort_order")
+	pageSize, _ = strconv.Atoi(query.Get("page_size"))
+
+	if settings := middleware.SettingsFromContext(ctx); settings != nil {
+		if sortBy == "" {
+			sortBy = settings.DefaultSort
+		}
+		if sortOrder == "" {
+			sortOrder = settings.DefaultSortOrder
+		}
+		if pageSize < 1 {
+			pageSize = settings.DefaultPageSize
+		}
+	}
+
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	} else if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return sortBy, sortOrder, pageSize
+}
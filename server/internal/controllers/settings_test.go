@@ -0,0 +1,36 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"games_webapp/internal/controllers"
+	"games_webapp/internal/models"
+	"games_webapp/internal/testutil"
+)
+
+func TestSettingTogglesShareSettings(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	_, token := srv.NewUser(t, "player@example.com", false)
+
+	// Первый переключатель создаёт запись настроек, второй дописывает в неё
+	var toggle controllers.SettingToggle
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodPut, "/api/games/user/aging", token,
+		controllers.SettingToggle{Enabled: true}), http.StatusOK, &toggle)
+	if !toggle.Enabled {
+		t.Error("aging toggle not enabled in response")
+	}
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodPut, "/api/games/user/notifications/digest", token,
+		controllers.SettingToggle{Enabled: true}), http.StatusOK, nil)
+
+	var settings models.UserSettings
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodGet, "/api/settings", token, nil), http.StatusOK, &settings)
+	if !settings.PriorityAging || !settings.WeeklyDigest || settings.DiscordNotify {
+		t.Errorf("settings = %+v, want aging and digest on, discord off", settings)
+	}
+
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodGet, "/api/games/user/notifications/discord", token, nil), http.StatusOK, &toggle)
+	if toggle.Enabled {
+		t.Error("discord toggle enabled, want off")
+	}
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"games_webapp/internal/middleware"
+)
+
+// maxStatsMonths — самый длинный ряд прохождений по месяцам
+const maxStatsMonths = 60
+
+// GetGameStatsV2 возвращает расширенную статистику: счётчики по статусам, прохождения
+// по месяцам за months месяцев (по умолчанию 12), среднее время до прохождения,
+// самые частые жанры и доли пройденных и брошенных игр
+func (c *GameController) GetGameStatsV2(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.games.GetGameStatsV2"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	months, _ := strconv.Atoi(r.URL.Query().Get("months"))
+	if months < 1 {
+		months = 12
+	} else if months > maxStatsMonths {
+		months = maxStatsMonths
+	}
+
+	stats, err := c.service.GetLibraryStats(r.Context(), userID, months, time.Now().UTC())
+	if err != nil {
+		c.log.Error(ErrGetGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		c.log.Error(ErrGetGames.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetGames.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+type SteamBackfillResponse struct {
+	Updated int `json:"updated"`
+}
+
+// BackfillSteamAppIDs заполняет appid у игр, добавленных по ссылке на Steam до
+// появления поля steam_app_id
+func (c *GameController) BackfillSteamAppIDs(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.steam.BackfillSteamAppIDs"
+
+	updated, err := c.service.BackfillSteamAppIDs(r.Context())
+	if err != nil {
+		c.log.Error(ErrBackfillSteam.Error(), slog.String("operation", op), slog.Int("updated", updated), slog.String("error", err.Error()))
+		http.Error(w, ErrBackfillSteam.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(SteamBackfillResponse{Updated: updated}); err != nil {
+		c.log.Error(ErrBackfillSteam.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/services"
+)
+
+type TelegramServicer interface {
+	CreateLink(userID int) (*services.TelegramLink, error)
+	IsLinked(userID int) (bool, error)
+	Unlink(userID int) error
+}
+
+type TelegramController struct {
+	service TelegramServicer
+	log     *slog.Logger
+}
+
+func NewTelegramController(s TelegramServicer, log *slog.Logger) *TelegramController {
+	return &TelegramController{
+		service: s,
+		log:     log,
+	}
+}
+
+type TelegramStatusResponse struct {
+	Linked bool `json:"linked"`
+}
+
+func (c *TelegramController) GetStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.telegram.GetStatus"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	linked, err := c.service.IsLinked(userID)
+	if err != nil {
+		c.log.Error(ErrGetTelegram.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetTelegram.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(TelegramStatusResponse{Linked: linked})
+}
+
+func (c *TelegramController) CreateLink(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.telegram.CreateLink"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	link, err := c.service.CreateLink(userID)
+	if err != nil {
+		c.log.Error(ErrTelegramLink.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrTelegramLink.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+func (c *TelegramController) Unlink(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.telegram.Unlink"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := c.service.Unlink(userID); err != nil {
+		c.log.Error(ErrTelegramUnlink.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrTelegramUnlink.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxTokenNameLength = 100
+
+type APITokenServicer interface {
+	Create(userID int, name string, scope models.TokenScope) (string, *models.APIToken, error)
+	List(userID int) ([]models.APIToken, error)
+	Revoke(userID, tokenID int) error
+}
+
+type TokenController struct {
+	service APITokenServicer
+	log     *slog.Logger
+}
+
+func NewTokenController(s APITokenServicer, log *slog.Logger) *TokenController {
+	return &TokenController{
+		service: s,
+		log:     log,
+	}
+}
+
+type CreateTokenRequest struct {
+	Name  string            `json:"name"`
+	Scope models.TokenScope `json:"scope"`
+}
+
+// CreateTokenResponse содержит сам токен — он возвращается только один раз
+type CreateTokenResponse struct {
+	Token string           `json:"token"`
+	Info  *models.APIToken `json:"info"`
+}
+
+// sessionUser достаёт пользователя и запрещает управление токенами по API-токену:
+// иначе утёкший токен мог бы выпускать новые
+func (c *TokenController) sessionUser(w http.ResponseWriter, r *http.Request, op string) (int, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return 0, false
+	}
+
+	if viaToken, _ := r.Context().Value(middleware.APITokenKey).(bool); viaToken {
+		c.log.Error(ErrTokenAuthForbidden.Error(), slog.String("operation", op))
+		http.Error(w, ErrTokenAuthForbidden.Error(), http.StatusForbidden)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func (c *TokenController) GetTokens(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.tokens.GetTokens"
+
+	userID, ok := c.sessionUser(w, r, op)
+	if !ok {
+		return
+	}
+
+	tokens, err := c.service.List(userID)
+	if err != nil {
+		c.log.Error(ErrGetTokens.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetTokens.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tokens == nil {
+		tokens = []models.APIToken{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (c *TokenController) CreateToken(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.tokens.CreateToken"
+
+	userID, ok := c.sessionUser(w, r, op)
+	if !ok {
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxTokenNameLength {
+		c.log.Error(ErrInvalidTokenName.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidTokenName.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = models.ScopeRead
+	}
+	if !scope.IsValid() {
+		c.log.Error(ErrInvalidTokenScope.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidTokenScope.Error(), http.StatusBadRequest)
+		return
+	}
+
+	raw, token, err := c.service.Create(userID, name, scope)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyTokens) {
+			c.log.Error(ErrTooManyTokens.Error(), slog.String("operation", op))
+			http.Error(w, ErrTooManyTokens.Error(), http.StatusConflict)
+			return
+		}
+		c.log.Error(ErrCreateToken.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrCreateToken.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateTokenResponse{Token: raw, Info: token})
+}
+
+func (c *TokenController) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.tokens.RevokeToken"
+
+	userID, ok := c.sessionUser(w, r, op)
+	if !ok {
+		return
+	}
+
+	tokenID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || tokenID <= 0 {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.Revoke(userID, tokenID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.log.Error(ErrTokenNotFound.Error(), slog.String("operation", op))
+			http.Error(w, ErrTokenNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrRevokeToken.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrRevokeToken.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// extensionTokenName — название токена, который выдаётся расширению браузера
+const extensionTokenName = "Расширение браузера"
+
+// ExtensionTokenRequest — необязательное название токена, например браузер
+type ExtensionTokenRequest struct {
+	Name string `json:"name"`
+}
+
+// ExchangeExtensionToken обменивает вход пользователя на персональный токен с
+// правом записи для расширения или букмарклета. Токен виден и отзывается в
+// /api/tokens, как и выпущенные вручную
+func (c *TokenController) ExchangeExtensionToken(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.tokens.ExchangeExtensionToken"
+
+	userID, ok := c.sessionUser(w, r, op)
+	if !ok {
+		return
+	}
+
+	var req ExtensionTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = extensionTokenName
+	}
+	if utf8.RuneCountInString(name) > maxTokenNameLength {
+		c.log.Error(ErrInvalidTokenName.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidTokenName.Error(), http.StatusBadRequest)
+		return
+	}
+
+	raw, token, err := c.service.Create(userID, name, models.ScopeReadWrite)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyTokens) {
+			c.log.Error(ErrTooManyTokens.Error(), slog.String("operation", op))
+			http.Error(w, ErrTooManyTokens.Error(), http.StatusConflict)
+			return
+		}
+		c.log.Error(ErrCreateToken.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrCreateToken.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateTokenResponse{Token: raw, Info: token})
+}
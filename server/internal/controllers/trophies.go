@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"games_webapp/internal/clients/igdb"
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+)
+
+const (
+	// maxTrophyRows — сколько игр можно импортировать из одного CSV
+	maxTrophyRows = 200
+	// trophyProvider — провайдер в истории импорта для CSV с трофеями
+	trophyProvider = "trophies"
+)
+
+// Итог обработки строки CSV с трофеями
+const (
+	TrophyImported    = "imported"     // игра найдена и добавлена в библиотеку
+	TrophyNeedsReview = "needs_review" // точного совпадения нет, есть кандидаты IGDB
+	TrophyFailed      = "failed"       // строку не удалось разобрать или игру не нашли
+)
+
+// TrophyImportItem — строка отчёта об импорте трофеев
+type TrophyImportItem struct {
+	models.TrophyRow
+	Result        string          `json:"result"`
+	StatusApplied bool            `json:"status_applied"` // статус в библиотеке изменён по прогрессу
+	Game          *CreatedGame    `json:"game,omitempty"`
+	Candidates    []IGDBCandidate `json:"candidates,omitempty"`
+}
+
+type TrophyImportResponse struct {
+	Imported int                 `json:"imported"`
+	Review   int                 `json:"review"`
+	Failed   int                 `json:"failed"`
+	Rows     []*TrophyImportItem `json:"rows"`
+}
+
+// ImportTrophies импортирует библиотеку из CSV с трофеями PlayStation или
+// достижениями Xbox. Каждое название ищется по цепочке источников, а статус
+// ставится по проценту полученных трофеев. В ответе — итог по каждой строке
+func (c *GameController) ImportTrophies(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.trophies.ImportTrophies"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		c.log.Error(ErrParsingForm.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingForm.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, ErrMissingTrophyFile.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	rows, err := services.ParseTrophyCSV(file, services.TrophyMapping{
+		Title:    r.FormValue("title_column"),
+		Earned:   r.FormValue("earned_column"),
+		Total:    r.FormValue("total_column"),
+		Progress: r.FormValue("progress_column"),
+	}, maxTrophyRows)
+	if err != nil {
+		c.log.Warn(ErrParseTrophies.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParseTrophies.Error()+": "+trophyParseReason(err), http.StatusBadRequest)
+		return
+	}
+	if len(rows) == 0 {
+		http.Error(w, ErrNoGamesNames.Error(), http.StatusBadRequest)
+		return
+	}
+
+	done, ok := c.tracker.Track()
+	if !ok {
+		http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer done()
+
+	access, err := c.importAccess(r.Context(), op)
+	if err != nil {
+		c.log.Error(ErrLoginTwitch.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrCreateGame.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pending := 0
+	for _, row := range rows {
+		if row.Err == "" {
+			pending++
+		}
+	}
+
+	limits := c.limits
+	deadline := limits.deadline(pending)
+	// Ответ пишется после импорта, поэтому общего срока записи сервера может не хватить
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(deadline + 5*time.Second)); err != nil {
+		c.log.Debug("failed to extend write deadline", slog.String("operation", op), slog.String("error", err.Error()))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), deadline)
+	defer cancel()
+
+	items := make([]*TrophyImportItem, len(rows))
+	var (
+		sem = make(chan struct{}, limits.Workers)
+		wg  sync.WaitGroup
+	)
+	for i := range rows {
+		items[i] = &TrophyImportItem{TrophyRow: rows[i]}
+		if rows[i].Err != "" {
+			items[i].Result = TrophyFailed
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(item *TrophyImportItem, access *igdb.Token) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			itemCtx, cancel := context.WithTimeout(ctx, limits.ItemTimeout)
+			defer cancel()
+
+			c.importTrophyRow(itemCtx, item, access)
+		}(items[i], access)
+	}
+	wg.Wait()
+
+	c.applyTrophyStatuses(r.Context(), op, userID, items)
+
+	response := TrophyImportResponse{Rows: items}
+	for _, item := range items {
+		switch item.Result {
+		case TrophyImported:
+			response.Imported++
+		case TrophyNeedsReview:
+			response.Review++
+		default:
+			response.Failed++
+		}
+	}
+
+	runAt := time.Now()
+	if err := c.service.RecordImportRun(r.Context(), &models.ImportRun{
+		UserID:    userID,
+		Provider:  trophyProvider,
+		Requested: len(rows),
+		Succeeded: response.Imported,
+		Failed:    response.Failed,
+		Review:    response.Review,
+		CreatedAt: &runAt,
+	}); err != nil {
+		c.log.Error("failed to record import run", slog.String("operation", op), slog.String("error", err.Error()))
+	}
+
+	status := http.StatusCreated
+	switch {
+	case response.Imported == 0 && response.Review == 0:
+		status = http.StatusInternalServerError
+	case response.Review > 0 || response.Failed > 0:
+		status = http.StatusMultiStatus
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		c.log.Error(ErrCreateGame.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
+
+// importTrophyRow ищет игру из строки и добавляет её в библиотеку. ctx
+// ограничен limits.ItemTimeout на строку
+func (c *GameController) importTrophyRow(ctx context.Context, item *TrophyImportItem, access *igdb.Token) {
+	game, review, err := c.createThroughProviders(ctx, item.Title, "", access)
+	switch {
+	case err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		item.Result = TrophyFailed
+		item.Err = ErrImportTimeout.Error()
+	case err != nil:
+		item.Result = TrophyFailed
+		item.Err = err.Error()
+	case review != nil:
+		item.Result = TrophyNeedsReview
+		item.Candidates = review.Candidates
+	default:
+		item.Result = TrophyImported
+		item.Game = game
+	}
+}
+
+// applyTrophyStatuses ставит статусы по прогрессу. Меняются только игры в
+// статусе planned, чтобы импорт не откатил пройденную или брошенную игру
+func (c *GameController) applyTrophyStatuses(ctx context.Context, op string, userID int, items []*TrophyImportItem) {
+	byStatus := make(map[models.GameStatus][]int)
+	itemsByGame := make(map[int][]*TrophyImportItem)
+
+	for _, item := range items {
+		if item.Result != TrophyImported || item.Status == "" || item.Status == models.StatusPlanned {
+			continue
+		}
+
+		gameID := item.Game.ID
+		ug, err := c.service.GetUserGame(ctx, userID, gameID)
+		if err != nil {
+			c.log.Warn(ErrGetUserGames.Error(), slog.String("operation", op), slog.Int("game_id", gameID), slog.String("error", err.Error()))
+			continue
+		}
+		if ug.Status != models.StatusPlanned {
+			continue
+		}
+
+		if len(itemsByGame[gameID]) == 0 {
+			byStatus[item.Status] = append(byStatus[item.Status], gameID)
+		}
+		itemsByGame[gameID] = append(itemsByGame[gameID], item)
+	}
+
+	for status, gameIDs := range byStatus {
+		results, err := c.service.BulkUpdateStatus(ctx, userID, gameIDs, status)
+		if err != nil {
+			c.log.Error(ErrUpdateUserGame.Error(), slog.String("operation", op), slog.String("status", string(status)), slog.String("error", err.Error()))
+			continue
+		}
+		for _, res := range results {
+			if res.Result != models.BulkUpdated {
+				continue
+			}
+			for _, item := range itemsByGame[res.GameID] {
+				item.StatusApplied = true
+			}
+		}
+	}
+}
+
+// trophyParseReason объясняет пользователю, что не так с CSV
+func trophyParseReason(err error) string {
+	switch {
+	case errors.Is(err, services.ErrTrophyNoTitle):
+		return "нет колонки с названием игры, укажите title_column"
+	case errors.Is(err, services.ErrTrophyNoProgress):
+		return "нет колонки с прогрессом, укажите progress_column или earned_column и total_column"
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"games_webapp/internal/models"
+)
+
+type UploadsGCServicer interface {
+	Collect(ctx context.Context, dryRun bool) (*models.UploadsGCReport, error)
+}
+
+type UploadsController struct {
+	gc  UploadsGCServicer
+	log *slog.Logger
+}
+
+func NewUploadsController(gc UploadsGCServicer, log *slog.Logger) *UploadsController {
+	return &UploadsController{
+		gc:  gc,
+		log: log,
+	}
+}
+
+// CollectGarbage удаляет файлы загрузок, на которые не ссылаются игры и пользователи.
+// С ?dry_run=true только возвращает список таких файлов
+func (c *UploadsController) CollectGarbage(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.uploads.CollectGarbage"
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			c.log.Error(ErrInvalidRequest.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrInvalidRequest.Error(), http.StatusBadRequest)
+			return
+		}
+		dryRun = parsed
+	}
+
+	report, err := c.gc.Collect(r.Context(), dryRun)
+	if err != nil {
+		c.log.Error(ErrCollectUploads.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrCollectUploads.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	c.log.Info(
+		"uploads garbage collected",
+		slog.Bool("dry_run", dryRun),
+		slog.Int("orphaned", len(report.Orphaned)),
+		slog.Int("deleted", report.Deleted))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		c.log.Error(ErrCollectUploads.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+	}
+}
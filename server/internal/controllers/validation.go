@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"games_webapp/internal/services"
+)
+
+const (
+	maxEmailLength = 254
+	// minPasswordLength и minPasswordBits — нижняя граница длины и оценки энтропии пароля
+	minPasswordLength = 8
+	minPasswordBits   = 40
+	// maxPasswordLength — bcrypt в SSO учитывает только первые 72 байта
+	maxPasswordLength = 72
+	minPasswordUnique = 4
+)
+
+// ValidationErrorResponse — ответ 400 с ошибками по отдельным полям формы
+type ValidationErrorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields"`
+}
+
+// steamProfilePath — /id/<пользовательский адрес> или /profiles/<SteamID64>
+var steamProfilePath = regexp.MustCompile(`^/(id/[A-Za-z0-9_-]{2,32}|profiles/7656119\d{10})/?$`)
+
+// validateEmail проверяет адрес по RFC 5322. Имя отправителя и угловые скобки
+// не допускаются: нужен голый адрес с доменом
+func validateEmail(email string) error {
+	if email == "" {
+		return ErrMissingEmail
+	}
+	if len(email) > maxEmailLength {
+		return ErrInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return ErrInvalidEmail
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return ErrInvalidEmail
+	}
+
+	return nil
+}
+
+// validatePassword проверяет длину и грубую оценку энтропии: длина пароля,
+// умноженная на log2 размера алфавита из встреченных классов символов
+func validatePassword(password string) error {
+	if password == "" {
+		return ErrMissingPassword
+	}
+	if len(password) > maxPasswordLength {
+		return ErrPasswordTooLong
+	}
+	length := utf8.RuneCountInString(password)
+	if length < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	var lower, upper, digit, symbol, other bool
+	unique := make(map[rune]struct{})
+	for _, r := range password {
+		unique[r] = struct{}{}
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			lower = true
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		seen bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.seen {
+			pool += class.size
+		}
+	}
+
+	if len(unique) < minPasswordUnique || float64(length)*math.Log2(float64(pool)) < minPasswordBits {
+		return ErrWeakPassword
+	}
+
+	return nil
+}
+
+// validateSteamURL принимает только ссылку на профиль steamcommunity.com
+func validateSteamURL(rawURL string) error {
+	if rawURL == "" {
+		return ErrMissingSteamURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return ErrInvalidSteamURL
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	if host != "steamcommunity.com" || !steamProfilePath.MatchString(u.Path) {
+		return ErrInvalidSteamURL
+	}
+
+	return nil
+}
+
+// gameFieldErrors — тексты для ошибок полей из services.ValidationError
+var gameFieldErrors = map[error]error{
+	services.ErrTitleRequired:   ErrEmptyTitle,
+	services.ErrTitleTooLong:    ErrTitleTooLong,
+	services.ErrYearInvalid:     ErrInvalidYear,
+	services.ErrYearOutOfRange:  ErrYearOutOfRange,
+	services.ErrURLInvalid:      ErrInvalidGameURL,
+	services.ErrPriorityInvalid: ErrInvalidPriority,
+	services.ErrGenreTooLong:    ErrGenreTooLong,
+}
+
+// gameFields переводит ошибки полей игры для ответа клиенту. ok = false, если
+// err — не ошибка проверки
+func gameFields(err error) (fields map[string]string, ok bool) {
+	var invalid *services.ValidationError
+	if !errors.As(err, &invalid) {
+		return nil, false
+	}
+
+	fields = make(map[string]string, len(invalid.Fields))
+	for field, fieldErr := range invalid.Fields {
+		if text, ok := gameFieldErrors[fieldErr]; ok {
+			fieldErr = text
+		}
+		fields[field] = fieldErr.Error()
+	}
+	return fields, true
+}
+
+// gameFieldsError — ошибка проверки игры одной строкой, для результатов импорта
+func gameFieldsError(fields map[string]string) error {
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, field := range names {
+		parts[i] = field + ": " + fields[field]
+	}
+	return fmt.Errorf("%w: %s", ErrGameFields, strings.Join(parts, "; "))
+}
+
+// writeGameFields отвечает 400 с ошибками по полям, если игра не прошла
+// проверку в GameService. Возвращает false для остальных ошибок
+func writeGameFields(w http.ResponseWriter, log *slog.Logger, op string, err error) bool {
+	fields, ok := gameFields(err)
+	if !ok {
+		return false
+	}
+
+	log.Warn(ErrGameFields.Error(), slog.String("operation", op), slog.Any("fields", fields))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(ValidationErrorResponse{Error: ErrGameFields.Error(), Fields: fields})
+	return true
+}
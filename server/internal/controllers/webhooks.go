@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+	"games_webapp/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type WebhookServicer interface {
+	Create(userID int, rawURL string, events []models.WebhookEvent) (string, *models.Webhook, error)
+	List(userID int) ([]models.Webhook, error)
+	Delete(userID, webhookID int) error
+	ListDeliveries(userID, webhookID, limit int) ([]models.WebhookDelivery, error)
+}
+
+type WebhookController struct {
+	service WebhookServicer
+	log     *slog.Logger
+}
+
+func NewWebhookController(s WebhookServicer, log *slog.Logger) *WebhookController {
+	return &WebhookController{
+		service: s,
+		log:     log,
+	}
+}
+
+type CreateWebhookRequest struct {
+	URL    string                `json:"url"`
+	Events []models.WebhookEvent `json:"events"`
+}
+
+// CreateWebhookResponse содержит секрет подписи — он возвращается только один раз
+type CreateWebhookResponse struct {
+	Secret  string          `json:"secret"`
+	Webhook *models.Webhook `json:"webhook"`
+}
+
+func (c *WebhookController) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.webhooks.GetWebhooks"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	hooks, err := c.service.List(userID)
+	if err != nil {
+		c.log.Error(ErrGetWebhooks.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetWebhooks.Error(), http.StatusInternalServerError)
+		return
+	}
+	if hooks == nil {
+		hooks = []models.Webhook{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hooks)
+}
+
+func (c *WebhookController) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.webhooks.CreateWebhook"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.log.Error(ErrParsingJSON.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrParsingJSON.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, ok := webhookEvents(req.Events)
+	if !ok {
+		c.log.Error(ErrInvalidWebhookEvents.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidWebhookEvents.Error(), http.StatusBadRequest)
+		return
+	}
+
+	secret, hook, err := c.service.Create(userID, strings.TrimSpace(req.URL), events)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWebhookURL):
+			c.log.Error(ErrInvalidWebhookURL.Error(), slog.String("operation", op))
+			http.Error(w, ErrInvalidWebhookURL.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrPrivateWebhookURL):
+			c.log.Error(ErrPrivateWebhookURL.Error(), slog.String("operation", op))
+			http.Error(w, ErrPrivateWebhookURL.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrTooManyWebhooks):
+			c.log.Error(ErrTooManyWebhooks.Error(), slog.String("operation", op))
+			http.Error(w, ErrTooManyWebhooks.Error(), http.StatusConflict)
+		default:
+			c.log.Error(ErrCreateWebhook.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+			http.Error(w, ErrCreateWebhook.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateWebhookResponse{Secret: secret, Webhook: hook})
+}
+
+func (c *WebhookController) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.webhooks.DeleteWebhook"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	webhookID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || webhookID <= 0 {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.service.Delete(userID, webhookID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.log.Error(ErrWebhookNotFound.Error(), slog.String("operation", op))
+			http.Error(w, ErrWebhookNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrDeleteWebhook.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrDeleteWebhook.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *WebhookController) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	const op = "controllers.webhooks.GetDeliveries"
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok || userID <= 0 {
+		c.log.Error(ErrUnauthorized.Error(), slog.String("operation", op))
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	webhookID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || webhookID <= 0 {
+		c.log.Error(ErrInvalidID.Error(), slog.String("operation", op))
+		http.Error(w, ErrInvalidID.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 50
+	} else if limit > 200 {
+		limit = 200
+	}
+
+	deliveries, err := c.service.ListDeliveries(userID, webhookID, limit)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.log.Error(ErrWebhookNotFound.Error(), slog.String("operation", op))
+			http.Error(w, ErrWebhookNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		c.log.Error(ErrGetDeliveries.Error(), slog.String("operation", op), slog.String("error", err.Error()))
+		http.Error(w, ErrGetDeliveries.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// webhookEvents проверяет список событий и убирает повторы. Пустой список неверен
+func webhookEvents(requested []models.WebhookEvent) ([]models.WebhookEvent, bool) {
+	if len(requested) == 0 {
+		return nil, false
+	}
+
+	seen := make(map[models.WebhookEvent]bool, len(requested))
+	events := make([]models.WebhookEvent, 0, len(requested))
+	for _, e := range requested {
+		if !e.IsValid() {
+			return nil, false
+		}
+		if seen[e] {
+			continue
+		}
+		seen[e] = true
+		events = append(events, e)
+	}
+	return events, true
+}
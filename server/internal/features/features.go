@@ -0,0 +1,235 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+)
+
+// Флаги рискованных возможностей
+const (
+	// IGDBImport — поиск в IGDB и импорт игр оттуда
+	IGDBImport = "igdb_import"
+	// PublicProfiles — публичные ленты профилей
+	PublicProfiles = "public_profiles"
+)
+
+// defaults — флаги, известные коду, и их значения, если в конфиге их нет
+var defaults = map[string]config.Feature{
+	IGDBImport:     {Enabled: true},
+	PublicProfiles: {Enabled: true},
+}
+
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// State — флаг, как его видит администратор
+type State struct {
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled"`
+	Rollout  int    `json:"rollout"`
+	Users    []int  `json:"users"`
+	Override *bool  `json:"override"` // включён или выключен администратором поверх конфига
+}
+
+// Flags отвечает, включена ли возможность для пользователя. Значения из
+// конфига можно заменить при перечитывании, а администратор может включить
+// или выключить флаг для всех, и это сохраняется в базе
+type Flags struct {
+	store repository.Store
+	log   *slog.Logger
+
+	mu        sync.RWMutex
+	config    map[string]config.Feature
+	overrides map[string]bool
+}
+
+func New(store repository.Store, log *slog.Logger, cfg map[string]config.Feature) *Flags {
+	f := &Flags{store: store, log: log, overrides: make(map[string]bool)}
+	f.SetConfig(cfg)
+	return f
+}
+
+// Load читает переопределения администратора из базы
+func (f *Flags) Load() error {
+	const op = "features.Load"
+
+	saved, err := f.store.FeatureOverrides().List()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	overrides := make(map[string]bool, len(saved))
+	for _, o := range saved {
+		overrides[o.Name] = o.Enabled
+	}
+
+	f.mu.Lock()
+	f.overrides = overrides
+	f.mu.Unlock()
+	return nil
+}
+
+// SetConfig заменяет настройки флагов из конфига. Флаги, которых в конфиге
+// нет, получают значения по умолчанию
+func (f *Flags) SetConfig(cfg map[string]config.Feature) {
+	merged := make(map[string]config.Feature, len(defaults)+len(cfg))
+	for name, def := range defaults {
+		merged[name] = def
+	}
+	for name, c := range cfg {
+		if _, known := defaults[name]; !known {
+			f.log.Warn("unknown feature flag in config", slog.String("flag", name))
+		}
+		merged[name] = c
+	}
+
+	f.mu.Lock()
+	f.config = merged
+	f.mu.Unlock()
+}
+
+// Enabled сообщает, включена ли возможность для userID (0 — без входа)
+func (f *Flags) Enabled(name string, userID int) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if enabled, ok := f.overrides[name]; ok {
+		return enabled
+	}
+
+	c, ok := f.config[name]
+	if !ok || !c.Enabled {
+		return false
+	}
+	if c.Rollout == 0 || c.Rollout >= 100 {
+		return true
+	}
+	for _, id := range c.Users {
+		if id == userID {
+			return true
+		}
+	}
+	return userID > 0 && bucket(name, userID) < c.Rollout
+}
+
+// ForUser возвращает все флаги для пользователя, чтобы клиент мог скрыть
+// выключенные возможности
+func (f *Flags) ForUser(userID int) map[string]bool {
+	f.mu.RLock()
+	names := make([]string, 0, len(f.config))
+	for name := range f.config {
+		names = append(names, name)
+	}
+	f.mu.RUnlock()
+
+	result := make(map[string]bool, len(names))
+	for _, name := range names {
+		result[name] = f.Enabled(name, userID)
+	}
+	return result
+}
+
+// List возвращает все флаги с настройками и переопределениями
+func (f *Flags) List() []State {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	states := make([]State, 0, len(f.config))
+	for name, c := range f.config {
+		state := State{Name: name, Enabled: c.Enabled, Rollout: c.Rollout, Users: c.Users}
+		if state.Users == nil {
+			state.Users = []int{}
+		}
+		if enabled, ok := f.overrides[name]; ok {
+			state.Override = &enabled
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// SetOverride включает или выключает флаг для всех поверх конфига
+func (f *Flags) SetOverride(name string, enabled bool, by int) error {
+	const op = "features.SetOverride"
+
+	if !f.known(name) {
+		return fmt.Errorf("%s: %w", op, ErrUnknownFlag)
+	}
+
+	now := time.Now()
+	if err := f.store.FeatureOverrides().Save(&models.FeatureOverride{
+		Name:      name,
+		Enabled:   enabled,
+		UpdatedBy: by,
+		UpdatedAt: &now,
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	f.mu.Lock()
+	f.overrides[name] = enabled
+	f.mu.Unlock()
+	return nil
+}
+
+// ClearOverride возвращает флаг к настройкам из конфига
+func (f *Flags) ClearOverride(name string) error {
+	const op = "features.ClearOverride"
+
+	if !f.known(name) {
+		return fmt.Errorf("%s: %w", op, ErrUnknownFlag)
+	}
+	if err := f.store.FeatureOverrides().Delete(name); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	f.mu.Lock()
+	delete(f.overrides, name)
+	f.mu.Unlock()
+	return nil
+}
+
+// Require пропускает запрос, только если флаг включён для пользователя запроса.
+// Иначе отвечает 404, как будто маршрута нет
+func (f *Flags) Require(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := middleware.UserIDFromContext(r.Context())
+			if !f.Enabled(name, userID) {
+				http.Error(w, "Возможность отключена", http.StatusNotFound)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (f *Flags) known(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	_, ok := f.config[name]
+	return ok
+}
+
+// bucket распределяет пользователей по 100 корзинам. От имени флага зависит,
+// чтобы при нескольких раскатках на 10% не попадали одни и те же люди
+func bucket(name string, userID int) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{':'})
+	h.Write([]byte(strconv.Itoa(userID)))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,158 @@
+// Package httpx — общий клиент для запросов к внешним сайтам. Все клиенты пакета
+// делят один пул соединений с ограничением на хост, подставляют User-Agent,
+// повторяют идемпотентные запросы при временных сбоях и трассируются.
+// Пока Setup не вызван, действуют значения по умолчанию из config.Outbound
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/tracing"
+)
+
+// shared — текущий пул. Клиенты запоминают его при создании, поэтому Setup
+// вызывается до того, как собираются сервисы
+var shared atomic.Pointer[pool]
+
+type pool struct {
+	transport http.RoundTripper
+	// public — транспорт для ссылок от пользователей, см. PublicClient
+	public  http.RoundTripper
+	timeout time.Duration
+}
+
+// ErrPrivateAddress — ссылка ведёт на адрес внутренней сети
+var ErrPrivateAddress = errors.New("address is not public")
+
+func init() {
+	shared.Store(newPool(config.Outbound{
+		Timeout:             30 * time.Second,
+		MaxConnsPerHost:     16,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+		Retries:             2,
+		RetryBackoff:        500 * time.Millisecond,
+		MaxRetryWait:        10 * time.Second,
+		UserAgent:           "games_webapp",
+	}))
+}
+
+// Setup заменяет общий пул настройками из конфига
+func Setup(cfg config.Outbound) {
+	shared.Store(newPool(cfg))
+}
+
+func newPool(cfg config.Outbound) *pool {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxConnsPerHost = cfg.MaxConnsPerHost
+	base.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	base.IdleConnTimeout = cfg.IdleConnTimeout
+
+	public := base.Clone()
+	public.Proxy = nil // через прокси проверенный адрес не совпал бы с тем, куда идёт запрос
+	public.DialContext = dialPublic(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+
+	return &pool{
+		transport: wrap(base, cfg),
+		public:    wrap(public, cfg),
+		timeout:   cfg.Timeout,
+	}
+}
+
+// wrap добавляет к транспорту User-Agent, повторы и трассировку. Спан
+// открывается на каждую попытку: повтор — отдельный запрос к сайту
+func wrap(base http.RoundTripper, cfg config.Outbound) http.RoundTripper {
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = "games_webapp"
+	}
+
+	return &retryTransport{
+		next:      tracing.Transport(base),
+		userAgent: userAgent,
+		retries:   cfg.Retries,
+		backoff:   cfg.RetryBackoff,
+		maxWait:   cfg.MaxRetryWait,
+	}
+}
+
+// dialPublic разрешает имя один раз, проверяет все адреса и соединяется с уже
+// проверенным. Повторное разрешение имени при соединении дало бы сайту
+// подменить адрес после проверки (DNS rebinding)
+func dialPublic(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		for _, ip := range ips {
+			if !isPublic(ip.IP) {
+				return nil, ErrPrivateAddress
+			}
+		}
+
+		var dialErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+}
+
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast()
+}
+
+// CheckPublicHost отклоняет хосты, которые заведомо ведут во внутреннюю сеть:
+// localhost и IP-адреса не из публичных диапазонов. Имена здесь не
+// разрешаются — их проверяет PublicClient при каждом соединении
+func CheckPublicHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublic(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// Client возвращает клиент на общем пуле. timeout ограничивает запрос вместе с
+// повторами, 0 — значение из конфига
+func Client(timeout time.Duration) *http.Client {
+	p := shared.Load()
+	if timeout <= 0 {
+		timeout = p.timeout
+	}
+	return &http.Client{Timeout: timeout, Transport: p.transport}
+}
+
+// PublicClient — клиент для ссылок, которые прислал пользователь. Соединяется
+// только с публичными адресами, в том числе после перенаправлений; иначе запрос
+// завершается ошибкой ErrPrivateAddress
+func PublicClient(timeout time.Duration) *http.Client {
+	p := shared.Load()
+	if timeout <= 0 {
+		timeout = p.timeout
+	}
+	return &http.Client{Timeout: timeout, Transport: p.public}
+}
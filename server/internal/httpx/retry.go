@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryTransport ставит User-Agent и повторяет GET и HEAD без тела при сетевых
+// ошибках и ответах, которые обычно проходят сами: 429, 502, 503, 504
+type retryTransport struct {
+	next      http.RoundTripper
+	userAgent string
+	retries   int
+	backoff   time.Duration
+	// maxWait — предел паузы по Retry-After; если сайт просит ждать дольше,
+	// вызывающий получает его ответ
+	maxWait time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	if !retryable(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.retries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		var wait time.Duration
+		switch {
+		case errors.Is(err, ErrPrivateAddress):
+			return resp, err
+		case err != nil:
+			wait = t.pause(attempt)
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusBadGateway ||
+			resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout:
+			var ok bool
+			if wait, ok = retryAfter(resp.Header.Get("Retry-After")); !ok {
+				wait = t.pause(attempt)
+			}
+			if t.maxWait > 0 && wait > t.maxWait {
+				return resp, nil
+			}
+			// Тело дочитывается, чтобы соединение вернулось в пул
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable — можно ли отправить запрос ещё раз, ничего не изменив на сайте
+func retryable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+// pause — backoff·2^attempt со случайной добавкой до половины, чтобы повторы
+// разных запросов не приходили к сайту одновременно
+func (t *retryTransport) pause(attempt int) time.Duration {
+	d := t.backoff << attempt
+	if d <= 0 {
+		return 0
+	}
+	return d + rand.N(d/2+1)
+}
+
+// retryAfter разбирает Retry-After: число секунд или дату
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,92 @@
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// Filter — потокобезопасный bloom-фильтр для строк. Отрицательный ответ
+// MightContain точный, положительный нужно перепроверять
+type Filter struct {
+	mu    sync.RWMutex
+	bits  []uint64
+	m     uint64
+	k     uint64
+	ready bool
+}
+
+// New создаёт фильтр, рассчитанный на n элементов с долей ложных срабатываний p
+func New(n int, p float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &Filter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+func (f *Filter) Add(s string) {
+	h1, h2 := hashes(s)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (f *Filter) MightContain(s string) bool {
+	h1, h2 := hashes(s)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SetReady помечает фильтр заполненным. Пока фильтр не готов,
+// на его отрицательные ответы полагаться нельзя
+func (f *Filter) SetReady() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ready = true
+}
+
+func (f *Filter) Ready() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.ready
+}
+
+func hashes(s string) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	h1 := h.Sum64()
+
+	h = fnv.New64()
+	_, _ = h.Write([]byte(s))
+	h2 := h.Sum64() | 1
+
+	return h1, h2
+}
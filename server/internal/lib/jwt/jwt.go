@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed        = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrExpired          = errors.New("token expired")
+	ErrWrongApp         = errors.New("token issued for another app")
+	ErrInvalidPublicKey = errors.New("invalid rsa public key")
+)
+
+// leeway — допуск на расхождение часов с SSO
+const leeway = 30 * time.Second
+
+// Claims — поля access-токена, которые выдаёт SSO
+type Claims struct {
+	UserID    uint32 `json:"uid"`
+	Email     string `json:"email"`
+	AppID     int    `json:"app_id"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Verifier проверяет access-токены SSO без обращения к нему: HS256 с общим
+// секретом приложения или RS256 с открытым ключом SSO
+type Verifier struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+	appID     int
+}
+
+// NewHMAC проверяет токены, подписанные HS256 секретом приложения в SSO.
+// С пустым секретом HS256 не принимается: такую подпись может сделать любой
+func NewHMAC(secret string, appID int) *Verifier {
+	v := &Verifier{appID: appID}
+	if secret != "" {
+		v.secret = []byte(secret)
+	}
+	return v
+}
+
+// NewRSA проверяет токены, подписанные RS256. pemData — открытый ключ в PEM
+// (PKIX "PUBLIC KEY" или PKCS#1 "RSA PUBLIC KEY")
+func NewRSA(pemData []byte, appID int) (*Verifier, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	var key *rsa.PublicKey
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		k, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+		}
+		key = k
+	default:
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+		}
+		rsaKey, ok := k.(*rsa.PublicKey)
+		if !ok {
+			return nil, ErrInvalidPublicKey
+		}
+		key = rsaKey
+	}
+
+	return &Verifier{publicKey: key, appID: appID}, nil
+}
+
+// Verify проверяет подпись, срок действия и приложение токена
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if err := v.verifySignature(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if claims.UserID == 0 || claims.ExpiresAt == 0 {
+		return nil, ErrMalformed
+	}
+	if time.Now().Add(-leeway).Unix() > claims.ExpiresAt {
+		return nil, ErrExpired
+	}
+	if v.appID != 0 && claims.AppID != v.appID {
+		return nil, ErrWrongApp
+	}
+
+	return &claims, nil
+}
+
+func (v *Verifier) verifySignature(alg, signed string, signature []byte) error {
+	switch alg {
+	case "HS256":
+		if v.secret == nil {
+			return ErrUnsupportedAlg
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case "RS256":
+		if v.publicKey == nil {
+			return ErrUnsupportedAlg
+		}
+		sum := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(v.publicKey, crypto.SHA256, sum[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	case "":
+		return ErrMalformed
+	default:
+		return ErrUnsupportedAlg
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-app-secret"
+
+func segment(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hs256(key []byte, signed string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey, signed string) string {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// token собирает токен из заголовка и claims, sign подписывает "header.payload"
+func token(t *testing.T, alg string, claims interface{}, sign func(signed string) string) string {
+	t.Helper()
+
+	signed := segment(t, map[string]string{"alg": alg, "typ": "JWT"}) + "." + segment(t, claims)
+	return signed + "." + sign(signed)
+}
+
+func validClaims() Claims {
+	return Claims{UserID: 42, Email: "player@example.com", AppID: 1, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	hmacVerifier := NewHMAC(testSecret, 1)
+	rsaVerifier, err := NewRSA(publicPEM, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withHMAC := func(signed string) string { return hs256([]byte(testSecret), signed) }
+	withRSA := func(signed string) string { return rs256(t, rsaKey, signed) }
+	unsigned := func(string) string { return "" }
+
+	claimsWith := func(change func(c *Claims)) Claims {
+		c := validClaims()
+		change(&c)
+		return c
+	}
+
+	valid := token(t, "HS256", validClaims(), withHMAC)
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + segment(t, claimsWith(func(c *Claims) { c.UserID = 1 })) + "." + parts[2]
+
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		verifier *Verifier
+		token    string
+		want     error
+	}{
+		{"hs256", hmacVerifier, valid, nil},
+		{"rs256", rsaVerifier, token(t, "RS256", validClaims(), withRSA), nil},
+
+		{"alg none", hmacVerifier, token(t, "none", validClaims(), unsigned), ErrUnsupportedAlg},
+		{"alg none on rsa", rsaVerifier, token(t, "none", validClaims(), unsigned), ErrUnsupportedAlg},
+		{"empty alg", hmacVerifier, token(t, "", validClaims(), withHMAC), ErrMalformed},
+		{"unknown alg", hmacVerifier, token(t, "HS512", validClaims(), withHMAC), ErrUnsupportedAlg},
+
+		// Подмена алгоритма: открытый ключ RSA как секрет HMAC и наоборот
+		{"hs256 on rsa verifier", rsaVerifier, token(t, "HS256", validClaims(), func(signed string) string {
+			return hs256(publicPEM, signed)
+		}), ErrUnsupportedAlg},
+		{"rs256 on hmac verifier", hmacVerifier, token(t, "RS256", validClaims(), withRSA), ErrUnsupportedAlg},
+
+		{"empty hmac secret", NewHMAC("", 1), token(t, "HS256", validClaims(), func(signed string) string {
+			return hs256(nil, signed)
+		}), ErrUnsupportedAlg},
+
+		{"tampered payload", hmacVerifier, tampered, ErrInvalidSignature},
+		{"wrong hmac secret", hmacVerifier, token(t, "HS256", validClaims(), func(signed string) string {
+			return hs256([]byte("another-secret"), signed)
+		}), ErrInvalidSignature},
+		{"wrong rsa key", rsaVerifier, token(t, "RS256", validClaims(), func(signed string) string {
+			return rs256(t, otherKey, signed)
+		}), ErrInvalidSignature},
+
+		{"inside leeway", hmacVerifier, token(t, "HS256", claimsWith(func(c *Claims) {
+			c.ExpiresAt = now.Add(-leeway + 2*time.Second).Unix()
+		}), withHMAC), nil},
+		{"past leeway", hmacVerifier, token(t, "HS256", claimsWith(func(c *Claims) {
+			c.ExpiresAt = now.Add(-leeway - 2*time.Second).Unix()
+		}), withHMAC), ErrExpired},
+
+		{"wrong app", hmacVerifier, token(t, "HS256", claimsWith(func(c *Claims) { c.AppID = 2 }), withHMAC), ErrWrongApp},
+		{"any app", NewHMAC(testSecret, 0), token(t, "HS256", claimsWith(func(c *Claims) { c.AppID = 2 }), withHMAC), nil},
+		{"zero uid", hmacVerifier, token(t, "HS256", claimsWith(func(c *Claims) { c.UserID = 0 }), withHMAC), ErrMalformed},
+		{"zero exp", hmacVerifier, token(t, "HS256", claimsWith(func(c *Claims) { c.ExpiresAt = 0 }), withHMAC), ErrMalformed},
+
+		{"bad header base64", hmacVerifier, "!!!." + parts[1] + "." + parts[2], ErrMalformed},
+		{"bad payload base64", hmacVerifier, parts[0] + ".!!!." + hs256([]byte(testSecret), parts[0]+".!!!"), ErrMalformed},
+		{"bad signature base64", hmacVerifier, parts[0] + "." + parts[1] + ".!!!", ErrMalformed},
+		{"header not json", hmacVerifier, base64.RawURLEncoding.EncodeToString([]byte("alg")) + "." + parts[1] + "." + parts[2], ErrMalformed},
+		{"two segments", hmacVerifier, parts[0] + "." + parts[1], ErrMalformed},
+		{"four segments", hmacVerifier, valid + ".x", ErrMalformed},
+		{"empty", hmacVerifier, "", ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.verifier.Verify(tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && claims.UserID != 42 {
+				t.Errorf("uid = %d, want 42", claims.UserID)
+			}
+			if tt.want != nil && claims != nil {
+				t.Errorf("claims = %+v, want nil", claims)
+			}
+		})
+	}
+}
+
+func TestNewRSARejectsBadKeys(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"not pem":   []byte("not a key"),
+		"bad der":   pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("junk")}),
+		"bad pkcs1": pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: []byte("junk")}),
+		"ecdsa key": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+	} {
+		if _, err := NewRSA(data, 1); !errors.Is(err, ErrInvalidPublicKey) {
+			t.Errorf("%s: err = %v, want %v", name, err, ErrInvalidPublicKey)
+		}
+	}
+}
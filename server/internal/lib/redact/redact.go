@@ -0,0 +1,127 @@
+// Package redact скрывает секреты в логах и выводе при запуске. Правило одно
+// для всего приложения: значения с ключами, похожими на пароль, токен, секрет,
+// DSN или заголовок авторизации, не попадают в логи ни на каком уровне
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask заменяет скрытые значения
+const Mask = "[redacted]"
+
+// sensitive — части ключей, значения которых нельзя писать в логи
+var sensitive = []string{"password", "passwd", "secret", "token", "dsn", "authorization", "cookie", "api_key", "apikey"}
+
+// Sensitive сообщает, что значение с ключом key нужно скрыть
+func Sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitive {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Attr подходит для slog.HandlerOptions.ReplaceAttr: скрывает значения
+// атрибутов с чувствительными ключами, в том числе внутри групп
+func Attr(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindGroup && Sensitive(a.Key) {
+		return slog.String(a.Key, Mask)
+	}
+	return a
+}
+
+// String возвращает Mask для непустого s и пустую строку для пустого, чтобы в
+// выводе было видно, задано ли значение
+func String(s string) string {
+	if s == "" {
+		return ""
+	}
+	return Mask
+}
+
+// Query скрывает в строке запроса значения чувствительных параметров, например
+// ?token= у ссылок на календарь
+func Query(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Mask
+	}
+
+	changed := false
+	for key := range values {
+		if Sensitive(key) {
+			values[key] = []string{Mask}
+			changed = true
+		}
+	}
+	if !changed {
+		return rawQuery
+	}
+	return values.Encode()
+}
+
+// jsonField — строковое поле JSON, в том числе оборванное на середине значения
+var jsonField = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*"(?:[^"\\]|\\.)*"?`)
+
+// Body скрывает значения чувствительных полей в теле запроса или ответа: в JSON —
+// по ключам на любой глубине, в application/x-www-form-urlencoded — как в Query.
+// В обрезанном JSON строковые поля ищутся регулярным выражением. Тела других
+// типов, кроме текста, заменяются описанием
+func Body(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case len(body) == 0:
+		return ""
+	case mediaType == "application/json" || (mediaType == "" && json.Valid(body)):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			if out, err := json.Marshal(jsonValue(v)); err == nil {
+				return string(out)
+			}
+		}
+		return jsonField.ReplaceAllStringFunc(string(body), func(field string) string {
+			key := jsonField.FindStringSubmatch(field)[1]
+			if !Sensitive(key) {
+				return field
+			}
+			return `"` + key + `":"` + Mask + `"`
+		})
+	case mediaType == "application/x-www-form-urlencoded":
+		return Query(string(body))
+	case strings.HasPrefix(mediaType, "text/"):
+		return string(body)
+	}
+	return fmt.Sprintf("[%d bytes of %s]", len(body), mediaType)
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if Sensitive(key) {
+				if _, isString := value.(string); isString || value == nil {
+					v[key] = Mask
+					continue
+				}
+			}
+			v[key] = jsonValue(value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonValue(v[i])
+		}
+	}
+	return v
+}
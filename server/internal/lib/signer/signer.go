@@ -0,0 +1,52 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrExpired          = errors.New("signature expired")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Signer подписывает имена файлов для короткоживущих ссылок. Подпись — HMAC-SHA256
+// от имени и времени истечения, так что ссылку нельзя ни продлить, ни перенести на другой файл
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func New(secret string, ttl time.Duration) *Signer {
+	return &Signer{secret: []byte(secret), ttl: ttl}
+}
+
+// Sign возвращает время истечения (unix) и подпись для name
+func (s *Signer) Sign(name string) (expires int64, signature string) {
+	expires = time.Now().Add(s.ttl).Unix()
+	return expires, s.sign(name, expires)
+}
+
+// Verify проверяет подпись и что срок действия ещё не истёк
+func (s *Signer) Verify(name string, expires int64, signature string) error {
+	expected := s.sign(name, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *Signer) sign(name string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,134 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+var ErrDrainTimeout = errors.New("drain timeout exceeded")
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Manager владеет фоновой работой приложения: долгоживущими воркерами (Go),
+// разовыми задачами, начатыми из запросов (Track), и хуками, которые нужно
+// выполнить перед выходом (OnShutdown), например сброс кэшей
+type Manager struct {
+	log *slog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	group  *errgroup.Group
+
+	inflight sync.WaitGroup
+
+	mu       sync.Mutex
+	stopping bool
+	hooks    []hook
+}
+
+func New(log *slog.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	group, ctx := errgroup.WithContext(ctx)
+
+	return &Manager{
+		log:    log,
+		ctx:    ctx,
+		cancel: cancel,
+		group:  group,
+	}
+}
+
+// Context отменяется, когда начинается остановка или один из воркеров вернул ошибку
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// Go запускает воркер, который должен завершиться после отмены ctx
+func (m *Manager) Go(name string, fn func(ctx context.Context) error) {
+	m.group.Go(func() error {
+		if err := fn(m.ctx); err != nil && !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Track отмечает начало разовой работы, которую нужно дождаться при остановке.
+// Возвращает функцию завершения и false, если остановка уже началась
+func (m *Manager) Track() (done func(), ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopping {
+		return func() {}, false
+	}
+
+	m.inflight.Add(1)
+	var once sync.Once
+	return func() { once.Do(m.inflight.Done) }, true
+}
+
+// OnShutdown регистрирует хук, который выполняется после того, как все воркеры
+// и отслеживаемые задачи завершились. Хуки выполняются в обратном порядке регистрации
+func (m *Manager) OnShutdown(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+// Shutdown отменяет контекст воркеров, ждёт их и отслеживаемые задачи,
+// затем выполняет хуки. Всё вместе должно уложиться в timeout
+func (m *Manager) Shutdown(timeout time.Duration) error {
+	const op = "lifecycle.Shutdown"
+
+	m.mu.Lock()
+	m.stopping = true
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	m.cancel()
+
+	drained := make(chan error, 1)
+	go func() {
+		m.inflight.Wait()
+		drained <- m.group.Wait()
+	}()
+
+	var errs []error
+
+	select {
+	case err := <-drained:
+		if err != nil {
+			errs = append(errs, err)
+		}
+	case <-ctx.Done():
+		errs = append(errs, ErrDrainTimeout)
+		m.log.Warn("background work did not drain in time", slog.String("operation", op), slog.Duration("timeout", timeout))
+	}
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if err := h.fn(ctx); err != nil {
+			m.log.Error("shutdown hook failed", slog.String("operation", op), slog.String("hook", h.name), slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
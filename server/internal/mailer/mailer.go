@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// Mailer отправляет письма через SMTP-сервер с авторизацией PLAIN.
+// Порт 465 не поддерживается: соединение поднимается через STARTTLS
+type Mailer struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+func New(host string, port int, username, password, from string) *Mailer {
+	m := &Mailer{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		host: host,
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send отправляет текстовое письмо в UTF-8
+func (m *Mailer) Send(to, subject, body string) error {
+	const op = "mailer.Send"
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body)
+
+	// В конверте SMTP нужен только адрес, без имени отправителя
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := smtp.SendMail(m.addr, m.auth, sender.Address, []string{to}, msg.Bytes()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
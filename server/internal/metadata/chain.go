@@ -0,0 +1,134 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrUnknownProvider — в цепочке нет источника с таким именем
+var ErrUnknownProvider = errors.New("unknown metadata provider")
+
+// Step — источник в цепочке и сколько его ждать
+type Step struct {
+	Provider MetadataProvider
+	Timeout  time.Duration
+}
+
+// Matcher решает, подходит ли найденная по названию игра к запросу query
+type Matcher func(query string, g *Game) bool
+
+// Chain спрашивает источники по очереди и возвращает первый подходящий ответ.
+// Источник, который не знает игру, не понимает запрос или не отвечает,
+// пропускается. Сама цепочка тоже MetadataProvider
+type Chain struct {
+	steps []Step
+	match Matcher
+	log   *slog.Logger
+}
+
+func NewChain(log *slog.Logger, steps ...Step) *Chain {
+	return &Chain{steps: steps, log: log}
+}
+
+// UseMatcher проверяет игры, найденные по названию. Без него принимается
+// первый ответ любого источника; для ссылок проверка не нужна
+func (c *Chain) UseMatcher(m Matcher) {
+	c.match = m
+}
+
+func (c *Chain) Name() string {
+	return "chain"
+}
+
+// Providers возвращает имена источников в порядке опроса
+func (c *Chain) Providers() []string {
+	names := make([]string, 0, len(c.steps))
+	for _, s := range c.steps {
+		names = append(names, s.Provider.Name())
+	}
+	return names
+}
+
+// Has сообщает, есть ли источник name в цепочке
+func (c *Chain) Has(name string) bool {
+	_, ok := c.step(name)
+	return ok
+}
+
+// Fetch опрашивает источники по порядку. Если ни один не дал игру, возвращается
+// ErrUnavailable, когда хотя бы один источник не ответил (можно повторить
+// позже), иначе ErrNotFound
+func (c *Chain) Fetch(ctx context.Context, query string) (*Game, error) {
+	const op = "metadata.chain.Fetch"
+
+	unavailable := false
+	for _, s := range c.steps {
+		game, err := c.fetch(ctx, s, query)
+		if err == nil {
+			return game, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+		if errors.Is(err, ErrUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+			unavailable = true
+		}
+		if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrUnsupported) {
+			c.log.Warn("metadata provider failed",
+				slog.String("operation", op),
+				slog.String("provider", s.Provider.Name()),
+				slog.String("query", query),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	if unavailable {
+		return nil, fmt.Errorf("%s: %w", op, ErrUnavailable)
+	}
+	return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+}
+
+// FetchFrom спрашивает только источник name, например когда пользователь сам
+// указал, откуда брать игру
+func (c *Chain) FetchFrom(ctx context.Context, name, query string) (*Game, error) {
+	const op = "metadata.chain.FetchFrom"
+
+	s, ok := c.step(name)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: %s", op, ErrUnknownProvider, name)
+	}
+	game, err := c.fetch(ctx, s, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return game, nil
+}
+
+func (c *Chain) fetch(ctx context.Context, s Step, query string) (*Game, error) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	game, err := s.Provider.Fetch(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if c.match != nil && parseURL(query) == nil && !c.match(query, game) {
+		return nil, ErrNotFound
+	}
+	return game, nil
+}
+
+func (c *Chain) step(name string) (Step, bool) {
+	for _, s := range c.steps {
+		if s.Provider.Name() == name {
+			return s, true
+		}
+	}
+	return Step{}, false
+}
@@ -0,0 +1,127 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"games_webapp/internal/models"
+)
+
+const epicProductURL = "https://store-content-ipv4.ak.epicgames.com/api/en-US/content/products/"
+
+// Epic получает сведения из Epic Games Store по ссылке на страницу игры.
+// Поиска по названию у магазина без авторизации нет
+type Epic struct {
+	http *http.Client
+	log  *slog.Logger
+}
+
+func NewEpic(log *slog.Logger, timeout time.Duration) *Epic {
+	return &Epic{http: newHTTPClient(timeout), log: log}
+}
+
+func (e *Epic) Name() string {
+	return string(models.SourceEpic)
+}
+
+type epicProduct struct {
+	ProductName string `json:"productName"`
+	Namespace   string `json:"namespace"`
+	Pages       []struct {
+		Type string `json:"type"`
+		Data struct {
+			About struct {
+				ShortDescription string `json:"shortDescription"`
+			} `json:"about"`
+			Hero struct {
+				PortraitBackgroundImageURL string `json:"portraitBackgroundImageUrl"`
+				BackgroundImageURL         string `json:"backgroundImageUrl"`
+			} `json:"hero"`
+			Meta struct {
+				ReleaseDate string   `json:"releaseDate"`
+				Developer   []string `json:"developer"`
+				Publisher   []string `json:"publisher"`
+				Platform    []string `json:"platform"`
+				Tags        []string `json:"tags"`
+			} `json:"meta"`
+		} `json:"data"`
+	} `json:"pages"`
+}
+
+func (e *Epic) Fetch(ctx context.Context, query string) (*Game, error) {
+	const op = "metadata.epic.Fetch"
+
+	slug, err := epicSlug(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var product epicProduct
+	if err := getJSON(ctx, e.http, epicProductURL+url.PathEscape(slug), &product); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if product.ProductName == "" || len(product.Pages) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
+
+	// Первая страница — основная игра, остальные — издания и дополнения
+	page := product.Pages[0].Data
+	for _, p := range product.Pages {
+		if p.Type == "productHome" {
+			page = p.Data
+			break
+		}
+	}
+
+	game := &Game{
+		Title:       product.ProductName,
+		Summary:     stripTags(page.About.ShortDescription),
+		URL:         "https://store.epicgames.com/en-US/p/" + slug,
+		CoverURL:    page.Hero.PortraitBackgroundImageURL,
+		Year:        yearOf(page.Meta.ReleaseDate),
+		ReleaseDate: epicReleaseDate(page.Meta.ReleaseDate),
+		Developers:  page.Meta.Developer,
+		Publishers:  page.Meta.Publisher,
+		Genres:      page.Meta.Tags,
+		Platforms:   page.Meta.Platform,
+		Source:      models.SourceEpic,
+		ExternalID:  slug,
+	}
+	if game.CoverURL == "" {
+		game.CoverURL = page.Hero.BackgroundImageURL
+	}
+
+	e.log.Debug("epic metadata fetched", slog.String("operation", op), slog.String("slug", slug), slog.String("game", game.Title))
+	return game, nil
+}
+
+// epicSlug достаёт slug игры из ссылки вида store.epicgames.com/<lang>/p/<slug>
+// или старой epicgames.com/store/<lang>/product/<slug>
+func epicSlug(query string) (string, error) {
+	u := parseURL(query)
+	if u == nil || !hostIs(u, "epicgames.com") {
+		return "", ErrUnsupported
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if (parts[i] == "p" || parts[i] == "product") && parts[i+1] != "" {
+			return strings.ToLower(parts[i+1]), nil
+		}
+	}
+	return "", ErrUnsupported
+}
+
+// epicReleaseDate переводит дату из магазина (RFC 3339) в ГГГГ-ММ-ДД
+func epicReleaseDate(date string) string {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(date))
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02")
+}
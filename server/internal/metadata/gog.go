@@ -0,0 +1,151 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"games_webapp/internal/models"
+)
+
+const (
+	gogSearchURL  = "https://embed.gog.com/games/ajax/filtered"
+	gogProductURL = "https://api.gog.com/products/"
+)
+
+// GOG получает сведения из магазина GOG по ссылке на страницу игры. Названия
+// не ищутся: магазин находит по ним дополнения и сборники чаще, чем саму игру
+type GOG struct {
+	http *http.Client
+	log  *slog.Logger
+}
+
+func NewGOG(log *slog.Logger, timeout time.Duration) *GOG {
+	return &GOG{http: newHTTPClient(timeout), log: log}
+}
+
+func (g *GOG) Name() string {
+	return string(models.SourceGOG)
+}
+
+type gogSearchResponse struct {
+	Products []gogProduct `json:"products"`
+}
+
+type gogProduct struct {
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	Slug        string   `json:"slug"`
+	Image       string   `json:"image"`
+	URL         string   `json:"url"`
+	ReleaseDate int64    `json:"releaseDate"` // unix-время, 0 — неизвестна
+	Developer   string   `json:"developer"`
+	Publisher   string   `json:"publisher"`
+	Genres      []string `json:"genres"`
+	WorksOn     struct {
+		Windows bool `json:"Windows"`
+		Mac     bool `json:"Mac"`
+		Linux   bool `json:"Linux"`
+	} `json:"worksOn"`
+}
+
+type gogDetails struct {
+	Description struct {
+		Lead string `json:"lead"`
+	} `json:"description"`
+}
+
+func (g *GOG) Fetch(ctx context.Context, query string) (*Game, error) {
+	const op = "metadata.gog.Fetch"
+
+	slug, err := gogSlug(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// У магазина нет поиска по slug, поэтому ищем по словам из него и сверяем slug
+	params := url.Values{}
+	params.Set("mediaType", "game")
+	params.Set("search", strings.ReplaceAll(slug, "_", " "))
+
+	var found gogSearchResponse
+	if err := getJSON(ctx, g.http, gogSearchURL+"?"+params.Encode(), &found); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var product *gogProduct
+	for i := range found.Products {
+		if found.Products[i].Slug == slug {
+			product = &found.Products[i]
+			break
+		}
+	}
+	if product == nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
+
+	game := &Game{
+		Title:      product.Title,
+		URL:        "https://www.gog.com/en/game/" + product.Slug,
+		Genres:     product.Genres,
+		Source:     models.SourceGOG,
+		ExternalID: strconv.Itoa(product.ID),
+	}
+	if product.Image != "" {
+		game.CoverURL = "https:" + product.Image + ".jpg"
+	}
+	if product.ReleaseDate > 0 {
+		released := time.Unix(product.ReleaseDate, 0).UTC()
+		game.Year = strconv.Itoa(released.Year())
+		game.ReleaseDate = released.Format("2006-01-02")
+	}
+	if product.Developer != "" {
+		game.Developers = []string{product.Developer}
+	}
+	if product.Publisher != "" {
+		game.Publishers = []string{product.Publisher}
+	}
+	if product.WorksOn.Windows {
+		game.Platforms = append(game.Platforms, "PC (Microsoft Windows)")
+	}
+	if product.WorksOn.Mac {
+		game.Platforms = append(game.Platforms, "Mac")
+	}
+	if product.WorksOn.Linux {
+		game.Platforms = append(game.Platforms, "Linux")
+	}
+
+	// Описание есть только в карточке товара; без него игра всё равно пригодна
+	var details gogDetails
+	if err := getJSON(ctx, g.http, gogProductURL+strconv.Itoa(product.ID)+"?expand=description", &details); err != nil {
+		g.log.Warn("failed to get gog description",
+			slog.String("operation", op),
+			slog.Int("gog_id", product.ID),
+			slog.String("error", err.Error()))
+	}
+	game.Summary = stripTags(details.Description.Lead)
+
+	g.log.Debug("gog metadata fetched", slog.String("operation", op), slog.Int("gog_id", product.ID), slog.String("game", game.Title))
+	return game, nil
+}
+
+// gogSlug достаёт slug игры из ссылки вида gog.com/en/game/<slug>
+func gogSlug(query string) (string, error) {
+	u := parseURL(query)
+	if u == nil || !hostIs(u, "gog.com") {
+		return "", ErrUnsupported
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "game" && parts[i+1] != "" {
+			return strings.ToLower(parts[i+1]), nil
+		}
+	}
+	return "", ErrUnsupported
+}
@@ -0,0 +1,52 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"games_webapp/internal/httpx"
+)
+
+// maxResponse — предельный размер ответа источника
+const maxResponse = 4 << 20
+
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return httpx.Client(timeout)
+}
+
+// getJSON запрашивает rawURL и разбирает ответ в out. 404 превращается в
+// ErrNotFound, сетевые ошибки, 429 и 5xx — в ErrUnavailable
+func getJSON(ctx context.Context, client *http.Client, rawURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %s", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("%w: status %d", ErrUnavailable, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%w: status %d", ErrBadResponse, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponse)).Decode(out); err != nil {
+		return fmt.Errorf("%w: %s", ErrBadResponse, err)
+	}
+	return nil
+}
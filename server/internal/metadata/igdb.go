@@ -0,0 +1,99 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"games_webapp/internal/clients/igdb"
+	"games_webapp/internal/models"
+)
+
+// IGDBClient — часть клиента IGDB, которая нужна источнику
+type IGDBClient interface {
+	Login(ctx context.Context) (*igdb.Token, error)
+	SearchGame(ctx context.Context, name string, token *igdb.Token) (*igdb.GameInfo, error)
+	GetBySlug(ctx context.Context, slug string, token *igdb.Token) (*igdb.GameInfo, error)
+}
+
+// IGDB ищет игру в IGDB по названию или по ссылке igdb.com/games/<slug>.
+// Нужны учётные данные Twitch
+type IGDB struct {
+	client IGDBClient
+	log    *slog.Logger
+}
+
+func NewIGDB(client IGDBClient, log *slog.Logger) *IGDB {
+	return &IGDB{client: client, log: log}
+}
+
+func (p *IGDB) Name() string {
+	return string(models.SourceIGDB)
+}
+
+func (p *IGDB) Fetch(ctx context.Context, query string) (*Game, error) {
+	const op = "metadata.igdb.Fetch"
+
+	var slug string
+	if parseURL(query) != nil {
+		var err error
+		if slug, err = igdbSlug(query); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	token, err := p.client.Login(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", op, ErrUnavailable, err)
+	}
+
+	var info *igdb.GameInfo
+	if slug != "" {
+		info, err = p.client.GetBySlug(ctx, slug, token)
+	} else {
+		info, err = p.client.SearchGame(ctx, strings.TrimSpace(query), token)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, igdb.ErrGameNotFound):
+			return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+		case ctx.Err() != nil:
+			return nil, fmt.Errorf("%s: %w", op, ctx.Err())
+		default:
+			return nil, fmt.Errorf("%s: %w: %s", op, ErrUnavailable, err)
+		}
+	}
+
+	p.log.Debug("igdb metadata fetched", slog.String("operation", op), slog.Int("igdb_id", info.ID), slog.String("game", info.Name))
+	return &Game{
+		Title:       info.Name,
+		Summary:     info.Summary,
+		URL:         info.URL,
+		CoverURL:    info.CoverURL,
+		Year:        yearOf(info.ReleaseDate),
+		ReleaseDate: info.ReleaseDate,
+		Developers:  info.Developers,
+		Publishers:  info.Publishers,
+		Genres:      info.Genres,
+		Platforms:   info.Platforms,
+		Source:      models.SourceIGDB,
+		ExternalID:  strconv.Itoa(info.ID),
+	}, nil
+}
+
+// igdbSlug достаёт slug игры из ссылки вида igdb.com/games/<slug>
+func igdbSlug(query string) (string, error) {
+	u := parseURL(query)
+	if u == nil || !hostIs(u, "igdb.com") {
+		return "", ErrUnsupported
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "games" || parts[1] == "" {
+		return "", ErrUnsupported
+	}
+	return strings.ToLower(parts[1]), nil
+}
@@ -0,0 +1,109 @@
+// Package metadata получает сведения об играх из внешних источников: Steam,
+// Википедии, IGDB, GOG и Epic Games Store. Все источники отвечают одинаковой структурой Game и
+// одинаковыми ошибками, а запросы к ним отменяются вместе с контекстом
+package metadata
+
+import (
+	"context"
+	"errors"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"games_webapp/internal/models"
+)
+
+var (
+	// ErrNotFound — источник не знает такой игры
+	ErrNotFound = errors.New("game not found")
+	// ErrUnsupported — запрос не подходит источнику, например ссылка на другой сайт
+	ErrUnsupported = errors.New("query is not supported by provider")
+	// ErrUnavailable — источник не ответил, ответил 429 или 5xx; можно повторить позже
+	ErrUnavailable = errors.New("provider is unavailable")
+	// ErrBadResponse — источник ответил, но ответ не удалось разобрать
+	ErrBadResponse = errors.New("unexpected provider response")
+)
+
+// Game — сведения об игре из внешнего источника
+type Game struct {
+	Title       string
+	Summary     string
+	URL         string
+	CoverURL    string
+	Year        string // ГГГГ, пусто — неизвестен
+	ReleaseDate string // ГГГГ-ММ-ДД, пусто — точная дата неизвестна
+	Developers  []string
+	Publishers  []string
+	Genres      []string
+	Platforms   []string
+
+	Source     models.GameSource
+	ExternalID string
+}
+
+// MetadataProvider — источник сведений об играх. query — название игры или
+// ссылка на её страницу в этом источнике. По названию возвращается лучшее
+// совпадение; проверять, та ли это игра, должен вызывающий
+type MetadataProvider interface {
+	Name() string
+	Fetch(ctx context.Context, query string) (*Game, error)
+}
+
+// parseURL возвращает ссылку, если query похож на ссылку, и nil для названия
+func parseURL(query string) *url.URL {
+	u, err := url.Parse(strings.TrimSpace(query))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+	return u
+}
+
+// providerHosts — сайты, по ссылкам на которые можно определить источник
+var providerHosts = []struct {
+	domain   string
+	provider models.GameSource
+}{
+	{"steampowered.com", models.SourceSteam},
+	{"steamcommunity.com", models.SourceSteam},
+	{"wikipedia.org", models.SourceWiki},
+	{"igdb.com", models.SourceIGDB},
+	{"gog.com", models.SourceGOG},
+	{"epicgames.com", models.SourceEpic},
+}
+
+// ProviderFor возвращает имя источника, которому принадлежит ссылка rawURL.
+// ok == false, если это не ссылка или сайт не поддерживается. Путь ссылки
+// проверяет уже сам источник
+func ProviderFor(rawURL string) (provider string, ok bool) {
+	u := parseURL(rawURL)
+	if u == nil {
+		return "", false
+	}
+	for _, h := range providerHosts {
+		if hostIs(u, h.domain) {
+			return string(h.provider), true
+		}
+	}
+	return "", false
+}
+
+// hostIs сообщает, что ссылка ведёт на domain или его поддомен
+func hostIs(u *url.URL, domain string) bool {
+	host := strings.ToLower(u.Hostname())
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+var yearPattern = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+
+// yearOf достаёт год из даты в произвольном формате
+func yearOf(date string) string {
+	return yearPattern.FindString(date)
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags превращает HTML-описание из магазина в обычный текст
+func stripTags(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(s, " "))), " ")
+}
@@ -0,0 +1,166 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"games_webapp/internal/models"
+)
+
+const (
+	steamSearchURL  = "https://store.steampowered.com/api/storesearch/"
+	steamDetailsURL = "https://store.steampowered.com/api/appdetails"
+)
+
+// Steam получает сведения из магазина Steam по ссылке на страницу игры,
+// appid или названию
+type Steam struct {
+	http *http.Client
+	log  *slog.Logger
+}
+
+func NewSteam(log *slog.Logger, timeout time.Duration) *Steam {
+	return &Steam{http: newHTTPClient(timeout), log: log}
+}
+
+func (s *Steam) Name() string {
+	return string(models.SourceSteam)
+}
+
+type steamSearchResponse struct {
+	Items []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"items"`
+}
+
+type steamDetails struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Name             string   `json:"name"`
+		ShortDescription string   `json:"short_description"`
+		HeaderImage      string   `json:"header_image"`
+		Developers       []string `json:"developers"`
+		Publishers       []string `json:"publishers"`
+		Genres           []struct {
+			Description string `json:"description"`
+		} `json:"genres"`
+		Platforms struct {
+			Windows bool `json:"windows"`
+			Mac     bool `json:"mac"`
+			Linux   bool `json:"linux"`
+		} `json:"platforms"`
+		ReleaseDate struct {
+			Date string `json:"date"`
+		} `json:"release_date"`
+	} `json:"data"`
+}
+
+func (s *Steam) Fetch(ctx context.Context, query string) (*Game, error) {
+	const op = "metadata.steam.Fetch"
+
+	appID, err := s.appID(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	params := url.Values{}
+	params.Set("appids", strconv.Itoa(appID))
+	params.Set("l", "english")
+
+	var details map[string]steamDetails
+	if err := getJSON(ctx, s.http, steamDetailsURL+"?"+params.Encode(), &details); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	app, ok := details[strconv.Itoa(appID)]
+	if !ok || !app.Success {
+		return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
+
+	d := app.Data
+	game := &Game{
+		Title:       d.Name,
+		Summary:     d.ShortDescription,
+		URL:         fmt.Sprintf("https://store.steampowered.com/app/%d/", appID),
+		CoverURL:    d.HeaderImage,
+		Year:        yearOf(d.ReleaseDate.Date),
+		ReleaseDate: steamReleaseDate(d.ReleaseDate.Date),
+		Developers:  d.Developers,
+		Publishers:  d.Publishers,
+		Source:      models.SourceSteam,
+		ExternalID:  strconv.Itoa(appID),
+	}
+	for _, g := range d.Genres {
+		game.Genres = append(game.Genres, g.Description)
+	}
+	if d.Platforms.Windows {
+		game.Platforms = append(game.Platforms, "PC (Microsoft Windows)")
+	}
+	if d.Platforms.Mac {
+		game.Platforms = append(game.Platforms, "Mac")
+	}
+	if d.Platforms.Linux {
+		game.Platforms = append(game.Platforms, "Linux")
+	}
+
+	s.log.Debug("steam metadata fetched", slog.String("operation", op), slog.Int("appid", appID), slog.String("game", game.Title))
+	return game, nil
+}
+
+// appID находит appid по ссылке на магазин или сообщество, по числу или,
+// для названия, через поиск магазина
+func (s *Steam) appID(ctx context.Context, query string) (int, error) {
+	if u := parseURL(query); u != nil {
+		if !hostIs(u, "steampowered.com") && !hostIs(u, "steamcommunity.com") {
+			return 0, ErrUnsupported
+		}
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) < 2 || parts[0] != "app" {
+			return 0, ErrUnsupported
+		}
+		id, err := strconv.Atoi(parts[1])
+		if err != nil || id <= 0 {
+			return 0, ErrUnsupported
+		}
+		return id, nil
+	}
+
+	query = strings.TrimSpace(query)
+	if id, err := strconv.Atoi(query); err == nil && id > 0 {
+		return id, nil
+	}
+
+	params := url.Values{}
+	params.Set("term", query)
+	params.Set("l", "english")
+	params.Set("cc", "US")
+
+	var found steamSearchResponse
+	if err := getJSON(ctx, s.http, steamSearchURL+"?"+params.Encode(), &found); err != nil {
+		return 0, err
+	}
+	if len(found.Items) == 0 {
+		return 0, ErrNotFound
+	}
+	return found.Items[0].ID, nil
+}
+
+// steamDateLayouts — форматы дат выхода в магазине Steam
+var steamDateLayouts = []string{"Jan 2, 2006", "2 Jan, 2006", "January 2, 2006", "2 January 2006"}
+
+// steamReleaseDate переводит дату из магазина в ГГГГ-ММ-ДД. «Q3 2025», «Coming soon»
+// и другие неточные даты дают пустую строку
+func steamReleaseDate(date string) string {
+	for _, layout := range steamDateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(date)); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return ""
+}
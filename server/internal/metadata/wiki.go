@@ -0,0 +1,109 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"games_webapp/internal/models"
+)
+
+// Wiki получает название, описание и картинку из статьи Википедии по ссылке
+// на неё или по названию (в языковом разделе lang)
+type Wiki struct {
+	http *http.Client
+	log  *slog.Logger
+	lang string
+}
+
+func NewWiki(log *slog.Logger, timeout time.Duration, lang string) *Wiki {
+	if lang == "" {
+		lang = "en"
+	}
+	return &Wiki{http: newHTTPClient(timeout), log: log, lang: lang}
+}
+
+func (w *Wiki) Name() string {
+	return string(models.SourceWiki)
+}
+
+type wikiSummary struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	PageID  int    `json:"pageid"`
+	Extract string `json:"extract"`
+	Image   *struct {
+		Source string `json:"source"`
+	} `json:"originalimage"`
+	ContentURLs struct {
+		Desktop struct {
+			Page string `json:"page"`
+		} `json:"desktop"`
+	} `json:"content_urls"`
+}
+
+func (w *Wiki) Fetch(ctx context.Context, query string) (*Game, error) {
+	const op = "metadata.wiki.Fetch"
+
+	lang, title, err := w.article(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	summaryURL := fmt.Sprintf("https://%s.wikipedia.org/api/rest_v1/page/summary/%s",
+		lang, url.PathEscape(strings.ReplaceAll(title, " ", "_")))
+
+	var summary wikiSummary
+	if err := getJSON(ctx, w.http, summaryURL, &summary); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// Страница значений — не статья об игре
+	if summary.Type == "disambiguation" {
+		return nil, fmt.Errorf("%s: %w", op, ErrNotFound)
+	}
+
+	game := &Game{
+		Title:      summary.Title,
+		Summary:    summary.Extract,
+		URL:        summary.ContentURLs.Desktop.Page,
+		Year:       yearOf(summary.Extract),
+		Source:     models.SourceWiki,
+		ExternalID: lang + ":" + strconv.Itoa(summary.PageID),
+	}
+	if summary.Image != nil {
+		game.CoverURL = summary.Image.Source
+	}
+
+	w.log.Debug("wiki metadata fetched", slog.String("operation", op), slog.String("lang", lang), slog.String("game", game.Title))
+	return game, nil
+}
+
+// article возвращает языковой раздел и название статьи из ссылки или названия
+func (w *Wiki) article(query string) (string, string, error) {
+	u := parseURL(query)
+	if u == nil {
+		title := strings.TrimSpace(query)
+		if title == "" {
+			return "", "", ErrNotFound
+		}
+		return w.lang, title, nil
+	}
+
+	if !hostIs(u, "wikipedia.org") || !strings.HasPrefix(u.Path, "/wiki/") {
+		return "", "", ErrUnsupported
+	}
+	lang := strings.Split(strings.ToLower(u.Hostname()), ".")[0]
+	if lang == "wikipedia" || lang == "www" || lang == "m" {
+		lang = w.lang
+	}
+	title := strings.TrimPrefix(u.Path, "/wiki/")
+	if title == "" {
+		return "", "", ErrUnsupported
+	}
+	return lang, title, nil
+}
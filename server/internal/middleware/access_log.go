@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"games_webapp/internal/lib/redact"
+	"games_webapp/internal/tracing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// AccessLogPolicy — что и как часто пишет AccessLog
+type AccessLogPolicy struct {
+	Enabled bool
+	// SampleRate — доля успешных запросов (статус до 400), которые попадают в лог.
+	// Ошибки пишутся всегда
+	SampleRate float64
+	// BodySampleRate — доля ошибочных ответов, для которых пишутся тела запроса и
+	// ответа со скрытыми секретами
+	BodySampleRate float64
+	// MaxBodySize — сколько байт тела писать
+	MaxBodySize int
+	// SkipPaths — префиксы путей, успешные запросы к которым не пишутся
+	SkipPaths []string
+}
+
+// AccessLog пишет запросы в slog: метод, шаблон маршрута, статус, время и
+// пользователя. Правила можно заменить на ходу при перечитывании конфига
+type AccessLog struct {
+	log    *slog.Logger
+	policy atomic.Pointer[AccessLogPolicy]
+}
+
+func NewAccessLog(log *slog.Logger, policy AccessLogPolicy) *AccessLog {
+	l := &AccessLog{log: log}
+	l.SetPolicy(policy)
+	return l
+}
+
+func (l *AccessLog) SetPolicy(p AccessLogPolicy) {
+	l.policy.Store(&p)
+}
+
+// accessEntry — то, что о запросе узнают внутренние middleware, например
+// пользователь после проверки токена
+type accessEntry struct {
+	userID atomic.Int64
+}
+
+const accessLogKey = contextKey("accessLog")
+
+// noteUser сообщает логу запроса пользователя
+func noteUser(ctx context.Context, userID int) {
+	if entry, ok := ctx.Value(accessLogKey).(*accessEntry); ok {
+		entry.userID.Store(int64(userID))
+	}
+}
+
+func (l *AccessLog) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := l.policy.Load()
+		if !p.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		entry := &accessEntry{}
+		r = r.WithContext(context.WithValue(r.Context(), accessLogKey, entry))
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		// Тела копируются заранее: статус станет известен, когда ответ уже записан
+		var reqBody, respBody *limitedBuffer
+		if p.BodySampleRate > 0 && p.MaxBodySize > 0 && rand.Float64() < p.BodySampleRate {
+			respBody = &limitedBuffer{max: p.MaxBodySize}
+			ww.Tee(respBody)
+			if r.Body != nil && r.Body != http.NoBody && !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+				reqBody = &limitedBuffer{max: p.MaxBodySize}
+				r.Body = teeReadCloser{Reader: io.TeeReader(r.Body, reqBody), Closer: r.Body}
+			}
+		}
+
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+		duration := time.Since(start)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status < http.StatusBadRequest && (skipped(r.URL.Path, p.SkipPaths) || rand.Float64() >= p.SampleRate) {
+			return
+		}
+
+		path := r.URL.Path
+		if query := redact.Query(r.URL.RawQuery); query != "" {
+			path += "?" + query
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", path),
+			slog.String("route", routePattern(r)),
+			slog.Int("status", status),
+			slog.Duration("duration", duration),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.String("remote", r.RemoteAddr),
+		}
+		if userID := entry.userID.Load(); userID > 0 {
+			attrs = append(attrs, slog.Int64("user_id", userID))
+		}
+		if traceID := tracing.TraceID(r.Context()); traceID != "" {
+			attrs = append(attrs, slog.String("trace_id", traceID))
+		}
+		if status >= http.StatusBadRequest && respBody != nil {
+			if reqBody != nil {
+				attrs = append(attrs, slog.String("request_body", reqBody.redacted(r.Header.Get("Content-Type"))))
+			}
+			attrs = append(attrs, slog.String("response_body", respBody.redacted(ww.Header().Get("Content-Type"))))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		l.log.LogAttrs(r.Context(), level, "http request", attrs...)
+	})
+}
+
+// routePattern — шаблон маршрута chi, например /api/games/{id}. У запросов мимо
+// маршрутов он пуст
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+func skipped(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedBuffer запоминает первые max байт и молча отбрасывает остальное
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) redacted(contentType string) string {
+	data := b.buf.Bytes()
+	if !b.truncated {
+		return redact.Body(contentType, data)
+	}
+	// Обрезка могла разрезать многобайтовый символ
+	for i := 0; i < utf8.UTFMax-1 && len(data) > 0 && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	return redact.Body(contentType, data) + "…"
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -6,21 +6,50 @@ import (
 	"strings"
 
 	"games_webapp/internal/clients/sso/grpc"
+	"games_webapp/internal/models"
 )
 
+// SettingsLoader отдаёт настройки пользователя, которые служат умолчаниями для запросов
+type SettingsLoader interface {
+	GetSettings(userID int) (*models.UserSettings, error)
+}
+
+// APITokenValidator проверяет персональные API-токены (Authorization: Token ...)
+type APITokenValidator interface {
+	ValidateAPIToken(token string) (userID int, scope models.TokenScope, err error)
+}
+
 type AuthMiddleware struct {
 	ssoClient *grpc.Client
+	apiTokens APITokenValidator
+	settings  SettingsLoader
+	roles     RoleLoader
 }
 
 func NewAuthMiddleware(client *grpc.Client) *AuthMiddleware {
 	return &AuthMiddleware{ssoClient: client}
 }
 
+// UseAPITokens включает вход по персональным API-токенам
+func (m *AuthMiddleware) UseAPITokens(v APITokenValidator) {
+	m.apiTokens = v
+}
+
+// UseSettings включает загрузку настроек пользователя в контекст запроса
+// и выбор языка по сохранённому предпочтению
+func (m *AuthMiddleware) UseSettings(l SettingsLoader) {
+	m.settings = l
+}
+
 type contextKey string
 
 const (
-	UserIDKey  = contextKey("userID")
-	IsAdminKey = contextKey("isAdmin")
+	UserIDKey = contextKey("userID")
+	// RoleKey — models.Role пользователя запроса, см. RoleFromContext
+	RoleKey = contextKey("role")
+	// APITokenKey — true, если запрос авторизован персональным API-токеном, а не SSO
+	APITokenKey = contextKey("apiToken")
+	SettingsKey = contextKey("settings")
 )
 
 func UserIDFromContext(ctx context.Context) (int, bool) {
@@ -28,9 +57,39 @@ func UserIDFromContext(ctx context.Context) (int, bool) {
 	return id, ok
 }
 
+// SettingsFromContext возвращает настройки пользователя запроса или nil,
+// если они не загружались
+func SettingsFromContext(ctx context.Context) *models.UserSettings {
+	settings, _ := ctx.Value(SettingsKey).(*models.UserSettings)
+	return settings
+}
+
+// withSettings кладёт в контекст настройки пользователя. Если их не удалось
+// загрузить, запрос выполняется с умолчаниями приложения
+func (m *AuthMiddleware) withSettings(ctx context.Context, userID int) context.Context {
+	if m.settings == nil {
+		return ctx
+	}
+
+	settings, err := m.settings.GetSettings(userID)
+	if err != nil {
+		return ctx
+	}
+
+	ctx = context.WithValue(ctx, SettingsKey, settings)
+	if settings.Language == LangRU || settings.Language == LangEN {
+		ctx = context.WithValue(ctx, LanguageKey, settings.Language)
+	}
+	return ctx
+}
+
 func (m *AuthMiddleware) ValidateToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
+		if m.apiTokens != nil && strings.HasPrefix(authHeader, "Token ") {
+			m.validateAPIToken(next, w, r, strings.TrimPrefix(authHeader, "Token "))
+			return
+		}
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
 			http.Error(w, "Отсутствует или неправильный заголовок авторизации", http.StatusUnauthorized)
 			return
@@ -49,8 +108,46 @@ func (m *AuthMiddleware) ValidateToken(next http.Handler) http.Handler {
 			isAdmin = false
 		}
 
+		noteUser(r.Context(), int(userID))
 		ctx := context.WithValue(r.Context(), UserIDKey, int(userID))
-		ctx = context.WithValue(ctx, IsAdminKey, isAdmin)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		ctx = context.WithValue(ctx, RoleKey, m.role(int(userID), isAdmin))
+		next.ServeHTTP(w, r.WithContext(m.withSettings(ctx, int(userID))))
 	})
 }
+
+// validateAPIToken авторизует запрос персональным токеном. Токены дают только
+// роль user, а токен только для чтения допускает лишь безопасные методы
+func (m *AuthMiddleware) validateAPIToken(next http.Handler, w http.ResponseWriter, r *http.Request, token string) {
+	userID, scope, err := m.apiTokens.ValidateAPIToken(token)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if scope != models.ScopeReadWrite && !isReadOnlyMethod(r.Method) {
+		http.Error(w, "Токен позволяет только чтение", http.StatusForbidden)
+		return
+	}
+
+	noteUser(r.Context(), userID)
+	ctx := context.WithValue(r.Context(), UserIDKey, userID)
+	ctx = context.WithValue(ctx, RoleKey, models.RoleUser)
+	ctx = context.WithValue(ctx, APITokenKey, true)
+	next.ServeHTTP(w, r.WithContext(m.withSettings(ctx, userID)))
+}
+
+// APITokenFromQuery принимает персональный токен из параметра token, если заголовка
+// авторизации нет. Нужен для подписок, которые не умеют передавать заголовки
+// (например, календарь Google). Ставится перед ValidateToken
+func (m *AuthMiddleware) APITokenFromQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Token "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
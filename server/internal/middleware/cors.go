@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORSFor пропускает запросы, путь которых начинается с prefix, через политику
+// dedicated, а остальные — через app. Политики не складываются: ответ получает
+// заголовки только одной из них, и preflight тоже обрабатывает только она
+func CORSFor(prefix string, dedicated, app func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		own, common := dedicated(next), app(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, prefix+"/") {
+				own.ServeHTTP(w, r)
+				return
+			}
+			common.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Языки, на которых отдаются названия и описания игр
+const (
+	LangRU = "ru"
+	LangEN = "en"
+)
+
+const LanguageKey = contextKey("language")
+
+func LanguageFromContext(ctx context.Context) string {
+	lang, ok := ctx.Value(LanguageKey).(string)
+	if !ok {
+		return LangRU
+	}
+	return lang
+}
+
+// Language выбирает язык ответа по заголовку Accept-Language (по умолчанию русский)
+// и кладёт его в контекст запроса
+func Language(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := parseAcceptLanguage(r.Header.Get("Accept-Language"))
+
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Set("Content-Language", lang)
+
+		ctx := context.WithValue(r.Context(), LanguageKey, lang)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// parseAcceptLanguage возвращает поддерживаемый язык с наибольшим весом q
+func parseAcceptLanguage(header string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.IndexByte(lang, '-'); i > 0 {
+			lang = lang[:i]
+		}
+		if lang != LangRU && lang != LangEN {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{lang: lang, q: q})
+		}
+	}
+
+	if len(tags) == 0 {
+		return LangRU
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	return tags[0].lang
+}
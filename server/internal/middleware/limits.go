@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// RouteLimit — свой предел для не-multipart тела одного маршрута, например
+// JSON с картинкой в base64. Path сравнивается без завершающего слэша,
+// "*" в нём заменяет один сегмент пути: /api/games/*
+type RouteLimit struct {
+	Method string
+	Path   string
+	Limit  int64
+}
+
+// BodyLimit ограничивает размер тела запроса: multipart/form-data (загрузка картинок)
+// получает предел multipartMax, остальные запросы — jsonMax. Запрос с заведомо
+// большим Content-Length отклоняется сразу с 413, а тело без длины обрезается
+// http.MaxBytesReader, и обработчик получает ошибку чтения. 0 — без ограничения.
+// routes заменяют jsonMax для отдельных маршрутов
+func BodyLimit(jsonMax, multipartMax int64, routes ...RouteLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := jsonMax
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+				limit = multipartMax
+			} else {
+				for _, route := range routes {
+					if r.Method == route.Method && route.matches(r.URL.Path) {
+						limit = route.Limit
+						break
+					}
+				}
+			}
+
+			if limit > 0 && r.Body != nil && r.Body != http.NoBody {
+				if r.ContentLength > limit {
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("Connection", "close")
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"error":    "слишком большое тело запроса",
+						"max_size": limit,
+					})
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (l RouteLimit) matches(path string) bool {
+	want := strings.Split(strings.TrimSuffix(l.Path, "/"), "/")
+	got := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if want[i] != "*" && want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MethodOverrideHeader — заголовок, которым клиенты без поддержки PUT и DELETE
+// (старые прокси, HTML-формы) передают настоящий метод POST-запроса
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+var overridableMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// MethodOverride подменяет метод POST-запроса на указанный в X-HTTP-Method-Override.
+// Другие методы и неизвестные значения заголовка не меняются
+func MethodOverride(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			method := strings.ToUpper(strings.TrimSpace(r.Header.Get(MethodOverrideHeader)))
+			if overridableMethods[method] {
+				r.Method = method
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
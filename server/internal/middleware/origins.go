@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Origins — источники, которым CORS разрешает запросы. Список можно заменить
+// на ходу при перечитывании конфига. Поддерживаются "*" и одна звёздочка
+// внутри источника, например https://*.example.com
+type Origins struct {
+	list atomic.Pointer[[]string]
+}
+
+func NewOrigins(list []string) *Origins {
+	o := &Origins{}
+	o.Set(list)
+	return o
+}
+
+func (o *Origins) Set(list []string) {
+	normalized := make([]string, 0, len(list))
+	for _, origin := range list {
+		normalized = append(normalized, strings.ToLower(origin))
+	}
+	o.list.Store(&normalized)
+}
+
+// Allow подходит для cors.Options.AllowOriginFunc
+func (o *Origins) Allow(r *http.Request, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range *o.list.Load() {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if i := strings.IndexByte(allowed, '*'); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"games_webapp/internal/models"
+)
+
+// RoleLoader отдаёт роль, назначенную пользователю в приложении. Роль admin
+// приходит из SSO и здесь не хранится
+type RoleLoader interface {
+	GetRole(userID int) (models.Role, error)
+}
+
+// UseRoles включает загрузку ролей, назначенных в приложении (moderator)
+func (m *AuthMiddleware) UseRoles(l RoleLoader) {
+	m.roles = l
+}
+
+// RoleFromContext возвращает роль пользователя запроса. Без авторизации — user
+func RoleFromContext(ctx context.Context) models.Role {
+	if role, ok := ctx.Value(RoleKey).(models.Role); ok {
+		return role
+	}
+	return models.RoleUser
+}
+
+// HasRole сообщает, что у пользователя запроса есть права роли min
+func HasRole(ctx context.Context, min models.Role) bool {
+	return RoleFromContext(ctx).AtLeast(min)
+}
+
+// role определяет роль пользователя: admin из SSO, иначе назначенная в приложении.
+// Если роль не удалось загрузить, пользователь получает наименьшие права
+func (m *AuthMiddleware) role(userID int, isAdmin bool) models.Role {
+	if isAdmin {
+		return models.RoleAdmin
+	}
+	if m.roles == nil {
+		return models.RoleUser
+	}
+
+	role, err := m.roles.GetRole(userID)
+	if err != nil {
+		return models.RoleUser
+	}
+	return role
+}
+
+// RequireRole пропускает только пользователей с ролью не ниже min. Ставится
+// после ValidateToken
+func RequireRole(min models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := UserIDFromContext(r.Context()); !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !HasRole(r.Context(), min) {
+				http.Error(w, "Недостаточно прав", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+// BulkResult — итог обработки одной игры в массовой операции
+type BulkResult string
+
+const (
+	BulkUpdated     BulkResult = "updated"
+	BulkRemoved     BulkResult = "removed"      // Игра убрана из библиотеки
+	BulkGameDeleted BulkResult = "game_deleted" // Игра убрана из библиотеки и удалена из каталога
+	BulkNotFound    BulkResult = "not_found"    // Игры нет в библиотеке пользователя
+)
+
+// BulkItem — результат массовой операции для одной игры
+type BulkItem struct {
+	GameID int        `json:"game_id"`
+	Result BulkResult `json:"result"`
+}
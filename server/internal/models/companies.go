@@ -0,0 +1,35 @@
+package models
+
+// Developer — разработчик из справочника. Slug — нормализованное название
+type Developer struct {
+	ID   int    `json:"id" gorm:"primary_key"`
+	Name string `json:"name" gorm:"type:varchar(255)"`
+	Slug string `json:"slug" gorm:"type:varchar(255);uniqueIndex"`
+}
+
+// Publisher — издатель из справочника. Slug — нормализованное название
+type Publisher struct {
+	ID   int    `json:"id" gorm:"primary_key"`
+	Name string `json:"name" gorm:"type:varchar(255)"`
+	Slug string `json:"slug" gorm:"type:varchar(255);uniqueIndex"`
+}
+
+// GameDeveloper связывает игру с разработчиком
+type GameDeveloper struct {
+	GameID      int `gorm:"primaryKey;autoIncrement:false"`
+	DeveloperID int `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+// GamePublisher связывает игру с издателем
+type GamePublisher struct {
+	GameID      int `gorm:"primaryKey;autoIncrement:false"`
+	PublisherID int `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+// DeveloperCount — разработчик и количество его игр в библиотеке пользователя
+type DeveloperCount struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Games int    `json:"games"`
+}
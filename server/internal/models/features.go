@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// FeatureOverride — флаг, включённый или выключенный администратором поверх
+// конфига. Действует для всех пользователей, пока его не снимут
+type FeatureOverride struct {
+	Name      string     `json:"name" gorm:"primaryKey;type:varchar(64)"`
+	Enabled   bool       `json:"enabled"`
+	UpdatedBy int        `json:"updated_by"`
+	UpdatedAt *time.Time `json:"updated_at" gorm:"type:timestamp NULL"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+type EventType string
+
+const (
+	EventGameAdded    EventType = "game_added"
+	EventGameFinished EventType = "game_finished"
+	// EventGameRated пишется, когда прохождению ставят или меняют оценку,
+	// Value — новая оценка. Снятие оценки событием не считается
+	EventGameRated EventType = "game_rated"
+	// EventStatusChanged пишется при каждой смене статуса, Value — новый статус.
+	// В ленту не попадает, нужен для дайджеста
+	EventStatusChanged EventType = "status_changed"
+	// EventBacklogAged — итог фонового устаревания бэклога для самого пользователя,
+	// Value — сколько запланированных игр затронуто. Подписчикам не показывается
+	EventBacklogAged EventType = "backlog_aged"
+)
+
+type Follow struct {
+	ID         int        `json:"id" gorm:"primary_key"`
+	FollowerID int        `json:"follower_id" gorm:"uniqueIndex:idx_follower_followee"`
+	FolloweeID int        `json:"followee_id" gorm:"uniqueIndex:idx_follower_followee;index"`
+	CreatedAt  *time.Time `json:"created_at" gorm:"type:timestamp"`
+}
+
+type Event struct {
+	ID        int        `json:"id" gorm:"primary_key"`
+	UserID    int        `json:"user_id" gorm:"index"`
+	GameID    int        `json:"game_id"`
+	Type      EventType  `json:"type" gorm:"type:varchar(32)"`
+	Value     string     `json:"value"`
+	CreatedAt *time.Time `json:"created_at" gorm:"type:timestamp;index"`
+}
+
+type FeedItem struct {
+	Event
+	GameTitle string `json:"game_title"`
+	GameImage string `json:"game_image"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// GameFilter — дополнительные условия выборки списков игр. Пустые поля не фильтруют
+type GameFilter struct {
+	Genre     string
+	Developer string
+	Publisher string
+	Platform  string // Платформа, на которой вышла игра
+	PlayedOn  string // Платформа, выбранная пользователем в библиотеке
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// FlagReason — на что пользователь жалуется в записи игры
+type FlagReason string
+
+const (
+	FlagWrongMetadata      FlagReason = "wrong_metadata"
+	FlagInappropriateImage FlagReason = "inappropriate_image"
+	FlagDuplicate          FlagReason = "duplicate"
+	FlagOther              FlagReason = "other"
+)
+
+func (r FlagReason) IsValid() bool {
+	switch r {
+	case FlagWrongMetadata, FlagInappropriateImage, FlagDuplicate, FlagOther:
+		return true
+	}
+	return false
+}
+
+// FlagStatus — состояние жалобы. Открытые жалобы видят модераторы
+type FlagStatus string
+
+const (
+	FlagOpen      FlagStatus = "open"
+	FlagResolved  FlagStatus = "resolved"
+	FlagDismissed FlagStatus = "dismissed"
+)
+
+func (s FlagStatus) IsValid() bool {
+	switch s {
+	case FlagOpen, FlagResolved, FlagDismissed:
+		return true
+	}
+	return false
+}
+
+// FlagAction — чем модератор закрыл жалобу
+type FlagAction string
+
+const (
+	// FlagActionEdit исправляет игру и закрывает все её открытые жалобы
+	FlagActionEdit FlagAction = "edit"
+	// FlagActionDelete удаляет игру и закрывает все её открытые жалобы
+	FlagActionDelete FlagAction = "delete"
+	// FlagActionDismiss отклоняет одну жалобу, игра не меняется
+	FlagActionDismiss FlagAction = "dismiss"
+)
+
+// GameFlag — жалоба пользователя на запись игры в общем каталоге
+type GameFlag struct {
+	ID      int        `json:"id" gorm:"primary_key"`
+	GameID  int        `json:"game_id" gorm:"index"`
+	UserID  int        `json:"user_id" gorm:"index"`
+	Reason  FlagReason `json:"reason" gorm:"type:varchar(32)"`
+	Comment string     `json:"comment" gorm:"type:text"`
+	Status  FlagStatus `json:"status" gorm:"type:varchar(16);index"`
+
+	Resolution FlagAction `json:"resolution,omitempty" gorm:"type:varchar(16)"`
+	ResolvedBy int        `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty" gorm:"type:timestamp NULL"`
+	CreatedAt  *time.Time `json:"created_at" gorm:"type:timestamp"`
+
+	// GameTitle заполняется при выборке списка; пусто, если игра уже удалена
+	GameTitle string `json:"game_title" gorm:"->;-:migration"`
+}
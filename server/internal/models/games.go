@@ -4,6 +4,27 @@ import (
 	"time"
 )
 
+// GameSource — откуда игра попала в каталог
+type GameSource string
+
+const (
+	SourceIGDB   GameSource = "igdb"
+	SourceSteam  GameSource = "steam"
+	SourceWiki   GameSource = "wiki"
+	SourceGOG    GameSource = "gog"
+	SourceEpic   GameSource = "epic"
+	SourceManual GameSource = "manual"
+)
+
+// DatePrecision — насколько точно известна дата выхода. Если известен только год,
+// release_date — 1 января этого года
+type DatePrecision string
+
+const (
+	PrecisionDay  DatePrecision = "day"
+	PrecisionYear DatePrecision = "year"
+)
+
 type Game struct {
 	ID        int    `json:"id" gorm:"primary_key"`
 	Title     string `json:"title"`
@@ -11,19 +32,87 @@ type Game struct {
 	Image     string `json:"image"`
 	Developer string `json:"developer"`
 	Publisher string `json:"publisher"`
-	Year      string `json:"year"`
+	Year      string `json:"year"` // Год из release_date, оставлен для совместимости
 	Genre     string `json:"genre"`
+	Platforms string `json:"platforms"` // Платформы через запятую: PC, PS5, Switch
 	Creator   int    `json:"creator"`
+	TitleKey  string `json:"-" gorm:"type:varchar(255);index"` // Нормализованное название для поиска дубликатов
+
+	// Английские название и описание. Title и Preambula — на языке источника
+	// (IGDB отдаёт английский, ручной ввод обычно русский)
+	TitleEn   string `json:"title_en"`
+	SummaryEn string `json:"summary_en" gorm:"type:text"`
+
+	// Внешний идентификатор игры у источника (ID в IGDB, appid в Steam).
+	// По нему ищутся дубликаты и обновляются данные
+	Source       GameSource `json:"source" gorm:"type:varchar(16);index:idx_games_external"`
+	ExternalID   string     `json:"external_id" gorm:"type:varchar(64);index:idx_games_external"`
+	LastSyncedAt *time.Time `json:"last_synced_at" gorm:"type:timestamp NULL"`
+	SteamAppID   int        `json:"steam_app_id"` // Для ссылок steam://run/<appid>, 0 — игры нет в Steam
+
+	ReleaseDate      *time.Time    `json:"release_date" gorm:"type:date;index"`
+	ReleasePrecision DatePrecision `json:"release_precision" gorm:"type:varchar(8)"`
 
 	URL       string     `json:"url"`
 	CreatedAt *time.Time `json:"created_at" gorm:"type:timestamp"`
 	UpdatedAt *time.Time `json:"updated_at" gorm:"type:timestamp"`
+
+	// Version растёт при каждом изменении игры через Update. Клиент присылает
+	// прочитанную версию обратно, чтобы не затереть чужие правки
+	Version int `json:"version" gorm:"not null;default:1"`
+
+	// Flagged — на игру есть открытые жалобы, см. GameFlag
+	Flagged bool `json:"flagged" gorm:"not null;default:false;index"`
 }
 
 type UserGameResponse struct {
 	Game
-	Priority int        `json:"priority"`
-	Status   GameStatus `json:"status"`
+	Priority   int        `json:"priority"`
+	Status     GameStatus `json:"status"`
+	Notes      string     `json:"notes"`
+	IsFavorite bool       `json:"is_favorite"`
+
+	CompletionPercent int `json:"completion_percent"`
+	AchievementsDone  int `json:"achievements_done"`
+	AchievementsTotal int `json:"achievements_total"`
+
+	Platform string `json:"platform"` // Платформа, на которой пользователь играет
+
+	// created_at и updated_at записи библиотеки. Имена отличаются от полей
+	// Game, у которой есть свои created_at и updated_at
+	AddedAt        *time.Time `json:"added_at"`
+	EntryUpdatedAt *time.Time `json:"entry_updated_at"`
+
+	// Override — личные правки пользователя. Если они есть, Title и Image уже
+	// заменены на значения из них, исходные можно получить из самой игры
+	Override *GameOverride `json:"override,omitempty" gorm:"-"`
+}
+
+// LibraryEntry — игра библиотеки вместе с жанрами и сводкой по всем прохождениям,
+// чтобы клиенту не приходилось запрашивать их отдельно для каждой игры
+type LibraryEntry struct {
+	UserGameResponse
+	Genres       []Genre            `json:"genres" gorm:"-"`
+	Playthroughs PlaythroughSummary `json:"playthroughs" gorm:"embedded;embeddedPrefix:pt_"`
+}
+
+type PlaythroughSummary struct {
+	Count    int     `json:"count"`
+	Finished int     `json:"finished"`
+	Rating   float64 `json:"rating"` // Средняя оценка оценённых прохождений, 0 — оценок нет
+}
+
+type DuplicateGroup struct {
+	NormalizedTitle string `json:"normalized_title"`
+	Games           []Game `json:"games"`
+}
+
+// LibraryProfile — сводка по библиотеке пользователя для подбора рекомендаций
+type LibraryProfile struct {
+	TopGenres     []string
+	TopDevelopers []string
+	OwnedURLs     map[string]bool
+	OwnedTitles   map[string]bool
 }
 
 type WhereQuery struct {
@@ -36,3 +125,39 @@ type Sort struct {
 	Field     string `json:"field"`
 	Direction string `json:"direction"`
 }
+
+// GamePatch — частичное изменение игры. nil — поле не меняется, пустая строка
+// очищает его. Картинка меняется только через Update вместе с файлом
+type GamePatch struct {
+	Title     *string
+	Preambula *string
+	TitleEn   *string
+	SummaryEn *string
+	Developer *string
+	Publisher *string
+	Year      *string
+	Genre     *string
+	Platforms *string
+	URL       *string
+
+	ReleaseDate      *time.Time
+	ClearReleaseDate bool
+}
+
+// FlexAggregation — группировка и агрегаты для GetFlex. Строка результата
+// содержит ключи group_by ("genres.name" становится "genres_name"), count и
+// min_<поле>/max_<поле>
+type FlexAggregation struct {
+	GroupBy []string `json:"group_by"`
+	Count   bool     `json:"count"`
+	Min     []string `json:"min"`
+	Max     []string `json:"max"`
+}
+
+// Triage — список того, что стоит почистить в библиотеке пользователя
+type Triage struct {
+	Stale           []UserGameResponse `json:"stale"`
+	Duplicates      []DuplicateGroup   `json:"duplicates"`
+	MissingMetadata []UserGameResponse `json:"missing_metadata"`
+	MissingCover    []UserGameResponse `json:"missing_cover"`
+}
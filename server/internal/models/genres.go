@@ -0,0 +1,16 @@
+package models
+
+// Genre — жанр из справочника. Slug — нормализованное название, по нему жанры
+// сравниваются между собой и ищутся в фильтрах
+type Genre struct {
+	ID   int    `json:"id" gorm:"primary_key"`
+	Name string `json:"name" gorm:"type:varchar(100)"`
+	Slug string `json:"slug" gorm:"type:varchar(100);uniqueIndex"`
+}
+
+// GameGenre связывает игру с жанром. Строка Game.Genre остаётся для отображения,
+// а фильтрация идёт по этой таблице
+type GameGenre struct {
+	GameID  int `gorm:"primaryKey;autoIncrement:false"`
+	GenreID int `gorm:"primaryKey;autoIncrement:false;index"`
+}
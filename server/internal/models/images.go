@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Image — загруженный файл картинки. Одинаковые по содержимому картинки хранятся
+// одним файлом, Refs — сколько игр на него ссылается
+type Image struct {
+	ID        int        `json:"id" gorm:"primary_key"`
+	Hash      string     `json:"hash" gorm:"type:varchar(64);uniqueIndex"`
+	Filename  string     `json:"filename" gorm:"type:varchar(255);uniqueIndex"`
+	Refs      int        `json:"refs"`
+	CreatedAt *time.Time `json:"created_at" gorm:"type:timestamp"`
+
+	// Size и UploadedBy учитываются в квоте загрузок. UploadedBy — кто первым
+	// загрузил файл; 0 — обложка скачана из внешнего источника
+	Size       int64 `json:"size"`
+	UploadedBy int   `json:"uploaded_by" gorm:"index"`
+}
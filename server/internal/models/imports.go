@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// ImportRun — запись о запуске множественного импорта игр
+type ImportRun struct {
+	ID        int        `json:"id" gorm:"primary_key"`
+	UserID    int        `json:"user_id" gorm:"index"`
+	Provider  string     `json:"provider" gorm:"type:varchar(32)"`
+	Requested int        `json:"requested"`
+	Succeeded int        `json:"succeeded"`
+	Failed    int        `json:"failed"`
+	Review    int        `json:"review"` // Неоднозначные названия, ожидающие выбора пользователя
+	CreatedAt *time.Time `json:"created_at" gorm:"type:timestamp;index"`
+
+	// Items — неудачные названия, которые можно повторить. После повтора они
+	// переходят к новому запуску
+	Items []ImportRunItem `json:"-" gorm:"foreignKey:RunID"`
+}
+
+// ImportRunItem — название из импорта, которое не удалось импортировать
+type ImportRunItem struct {
+	ID       int    `json:"id" gorm:"primary_key"`
+	RunID    int    `json:"run_id" gorm:"index"`
+	Name     string `json:"name" gorm:"type:text"`
+	Source   string `json:"source" gorm:"type:varchar(32)"`
+	Category string `json:"category" gorm:"type:varchar(32)"` // см. категории ошибок импорта в controllers
+	Error    string `json:"error" gorm:"type:varchar(255)"`
+}
+
+// TrophyRow — строка экспорта трофеев или достижений: игра и процент полученных
+// трофеев. Err — почему строку не удалось разобрать
+type TrophyRow struct {
+	Line     int        `json:"line"`
+	Title    string     `json:"title"`
+	Progress int        `json:"progress"`
+	Status   GameStatus `json:"status"`
+	Err      string     `json:"error,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginAttempt — счётчик неудачных входов по одному ключу: email или IP-адресу
+// (key вида "email:user@example.com" или "ip:203.0.113.7")
+type LoginAttempt struct {
+	Key          string     `json:"key" gorm:"column:attempt_key;primaryKey;type:varchar(191)"`
+	Failures     int        `json:"failures" gorm:"not null;default:0"`
+	LastFailedAt *time.Time `json:"last_failed_at" gorm:"type:timestamp NULL;index"`
+	LockedUntil  *time.Time `json:"locked_until" gorm:"type:timestamp NULL"`
+}
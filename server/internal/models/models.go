@@ -0,0 +1,33 @@
+package models
+
+// All возвращает модели, таблицы которых создаёт Migrate
+func All() []interface{} {
+	return []interface{}{
+		&Game{},
+		&UserGames{},
+		&Follow{},
+		&Event{},
+		&ImportRun{},
+		&ImportRunItem{},
+		&UserSettings{},
+		&Image{},
+		&Genre{},
+		&GameGenre{},
+		&Developer{},
+		&Publisher{},
+		&GameDeveloper{},
+		&GamePublisher{},
+		&Platform{},
+		&GamePlatform{},
+		&APIToken{},
+		&Webhook{},
+		&WebhookDelivery{},
+		&GameOverride{},
+		&UserRole{},
+		&Session{},
+		&LoginAttempt{},
+		&FeatureOverride{},
+		&GameFlag{},
+		&UserPhoto{},
+	}
+}
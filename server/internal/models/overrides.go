@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// GameOverride — личные правки пользователя к общей игре: своё название, обложка
+// и заметки. Общая запись Game при этом не меняется, поэтому править её может
+// кто угодно, а не только создатель игры. Пустое поле значит «как у игры»
+type GameOverride struct {
+	UserID    int        `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	GameID    int        `json:"game_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Title     string     `json:"title"`
+	Image     string     `json:"image"`
+	Notes     string     `json:"notes" gorm:"type:text"`
+	UpdatedAt *time.Time `json:"updated_at" gorm:"type:timestamp NULL"`
+}
+
+// IsEmpty сообщает, что в правке не осталось ни одного поля
+func (o *GameOverride) IsEmpty() bool {
+	return o.Title == "" && o.Image == "" && o.Notes == ""
+}
@@ -0,0 +1,14 @@
+package models
+
+// Platform — платформа из справочника (PC, PS5, Switch и т. д.). Slug — нормализованное название
+type Platform struct {
+	ID   int    `json:"id" gorm:"primary_key"`
+	Name string `json:"name" gorm:"type:varchar(100)"`
+	Slug string `json:"slug" gorm:"type:varchar(100);uniqueIndex"`
+}
+
+// GamePlatform связывает игру с платформой, на которой она вышла
+type GamePlatform struct {
+	GameID     int `gorm:"primaryKey;autoIncrement:false"`
+	PlatformID int `gorm:"primaryKey;autoIncrement:false;index"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Role — уровень доступа пользователя. Каждая следующая роль включает права предыдущей
+type Role string
+
+const (
+	RoleUser Role = "user"
+	// RoleModerator может править данные любой игры, но не управляет пользователями
+	RoleModerator Role = "moderator"
+	// RoleAdmin выдаётся в SSO, локально её назначить нельзя
+	RoleAdmin Role = "admin"
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleModerator:
+		return 1
+	case RoleAdmin:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// AtLeast сообщает, что роль даёт права не меньше, чем min
+func (r Role) AtLeast(min Role) bool {
+	return r.rank() >= min.rank()
+}
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// UserRole — роль, назначенная пользователю в приложении. Пользователи без
+// записи имеют роль user
+type UserRole struct {
+	UserID    int        `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Role      Role       `json:"role" gorm:"type:varchar(16)"`
+	GrantedBy int        `json:"granted_by"`
+	UpdatedAt *time.Time `json:"updated_at" gorm:"type:timestamp NULL"`
+}
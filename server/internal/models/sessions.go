@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session — вход пользователя с одного устройства. SSO не отдаёт список своих
+// refresh-токенов, поэтому сессии ведём сами: запись создаётся при входе и
+// переходит на новый токен при каждом обновлении. Хранится только хеш токена
+type Session struct {
+	ID         int        `json:"id" gorm:"primary_key"`
+	UserID     int        `json:"-" gorm:"index"`
+	TokenHash  string     `json:"-" gorm:"type:varchar(64);uniqueIndex"`
+	UserAgent  string     `json:"user_agent" gorm:"type:varchar(255)"`
+	IP         string     `json:"ip" gorm:"type:varchar(64)"`
+	CreatedAt  *time.Time `json:"created_at" gorm:"type:timestamp NULL"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"type:timestamp NULL"`
+	RevokedAt  *time.Time `json:"-" gorm:"type:timestamp NULL"`
+
+	// Current — сессия, из которой сделан запрос
+	Current bool `json:"current" gorm:"-"`
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+)
+
+// Visibility — кому видна активность пользователя
+type Visibility string
+
+const (
+	// VisibilityFollowers — события видны подписчикам в ленте (по умолчанию)
+	VisibilityFollowers Visibility = "followers"
+	// VisibilityPrivate — события не попадают в ленту подписчиков
+	VisibilityPrivate Visibility = "private"
+	// VisibilityPublic — как followers, плюс публичная страница по PublicSlug
+	VisibilityPublic Visibility = "public"
+)
+
+func (v Visibility) IsValid() bool {
+	return v == VisibilityFollowers || v == VisibilityPrivate || v == VisibilityPublic
+}
+
+// UserSettings — настройки пользователя, хранящиеся в этом приложении (не в SSO).
+// Пустые значения умолчаний значат «как задано в приложении»
+type UserSettings struct {
+	ID            int  `json:"-" gorm:"primary_key"`
+	UserID        int  `json:"-" gorm:"uniqueIndex"`
+	PriorityAging bool `json:"priority_aging"`
+	DiscordNotify bool `json:"discord_notify"`
+	WeeklyDigest  bool `json:"weekly_digest"`
+
+	DefaultSort      string     `json:"default_sort" gorm:"type:varchar(16)"`
+	DefaultSortOrder string     `json:"default_sort_order" gorm:"type:varchar(4)"`
+	DefaultPageSize  int        `json:"default_page_size"`
+	Visibility       Visibility `json:"visibility" gorm:"type:varchar(16);default:'followers'"`
+	Language         string     `json:"language" gorm:"type:varchar(8)"`
+	// PublicSlug — адрес публичного профиля; nil — не задан
+	PublicSlug *string `json:"public_slug" gorm:"type:varchar(32);uniqueIndex"`
+
+	// DigestSentAt — когда отправлен последний дайджест, чтобы перезапуск не слал его повторно
+	DigestSentAt *time.Time `json:"-" gorm:"type:timestamp NULL"`
+
+	// TelegramChatID — привязанный чат Telegram, 0 — не привязан. Привязка идёт через
+	// одноразовый код, который пользователь отправляет боту командой /start
+	TelegramChatID      int64      `json:"-"`
+	TelegramLinkCode    string     `json:"-" gorm:"type:varchar(32);index"`
+	TelegramLinkExpires *time.Time `json:"-" gorm:"type:timestamp NULL"`
+}
+
+// IsPublic сообщает, открыт ли публичный профиль пользователя
+func (s *UserSettings) IsPublic() bool {
+	return s.Visibility == VisibilityPublic && s.PublicSlug != nil
+}
+
+// DefaultSettings — настройки пользователя, который их ещё не менял
+func DefaultSettings(userID int) *UserSettings {
+	return &UserSettings{UserID: userID, Visibility: VisibilityFollowers}
+}
+
+// SettingsPatch — изменения настроек; nil-поля остаются как есть
+type SettingsPatch struct {
+	PriorityAging    *bool       `json:"priority_aging"`
+	DiscordNotify    *bool       `json:"discord_notify"`
+	WeeklyDigest     *bool       `json:"weekly_digest"`
+	DefaultSort      *string     `json:"default_sort"`
+	DefaultSortOrder *string     `json:"default_sort_order"`
+	DefaultPageSize  *int        `json:"default_page_size"`
+	Visibility       *Visibility `json:"visibility"`
+	Language         *string     `json:"language"`
+	PublicSlug       *string     `json:"public_slug"` // пустая строка убирает адрес
+}
+
+// Apply переносит заданные поля в s
+func (p *SettingsPatch) Apply(s *UserSettings) {
+	if p.PriorityAging != nil {
+		s.PriorityAging = *p.PriorityAging
+	}
+	if p.DiscordNotify != nil {
+		s.DiscordNotify = *p.DiscordNotify
+	}
+	if p.WeeklyDigest != nil {
+		s.WeeklyDigest = *p.WeeklyDigest
+	}
+	if p.DefaultSort != nil {
+		s.DefaultSort = *p.DefaultSort
+	}
+	if p.DefaultSortOrder != nil {
+		s.DefaultSortOrder = *p.DefaultSortOrder
+	}
+	if p.DefaultPageSize != nil {
+		s.DefaultPageSize = *p.DefaultPageSize
+	}
+	if p.Visibility != nil {
+		s.Visibility = *p.Visibility
+	}
+	if p.Language != nil {
+		s.Language = *p.Language
+	}
+	if p.PublicSlug != nil {
+		if *p.PublicSlug == "" {
+			s.PublicSlug = nil
+		} else {
+			slug := *p.PublicSlug
+			s.PublicSlug = &slug
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// StatusCounts — количество записей активной библиотеки по статусам
+type StatusCounts struct {
+	Total     int `json:"total"`
+	Finished  int `json:"finished"`
+	Playing   int `json:"playing"`
+	Planned   int `json:"planned"`
+	Dropped   int `json:"dropped"`
+	Favorites int `json:"favorites"`
+	// Средний процент прохождения по играм, где он указан
+	AverageCompletion float64 `json:"average_completion"`
+}
+
+// MonthCount — значение за месяц. Month в формате 2006-01
+type MonthCount struct {
+	Month string `json:"month"`
+	Count int    `json:"count"`
+}
+
+// GenreCount — жанр и количество игр пользователя этого жанра
+type GenreCount struct {
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Games int    `json:"games"`
+}
+
+// FinishSpan — когда игра была добавлена в библиотеку и когда пройдена.
+// Игра может добавляться несколько раз (новые прохождения), берётся самое раннее
+type FinishSpan struct {
+	UserGameID int
+	AddedAt    time.Time
+	FinishedAt time.Time
+}
+
+// LibraryStats — расширенная статистика библиотеки пользователя
+type LibraryStats struct {
+	StatusCounts
+
+	FinishedPerMonth []MonthCount `json:"finished_per_month"` // От старых месяцев к новым, включая пустые
+	AvgDaysToFinish  float64      `json:"avg_days_to_finish"` // 0 — нет данных
+	TopGenres        []GenreCount `json:"top_genres"`
+
+	FinishedRatio  float64 `json:"finished_ratio"`  // finished / total
+	DroppedRatio   float64 `json:"dropped_ratio"`   // dropped / total
+	CompletionRate float64 `json:"completion_rate"` // finished / (finished + dropped)
+}
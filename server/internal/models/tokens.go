@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// TokenScope — права персонального API-токена
+type TokenScope string
+
+const (
+	ScopeRead      TokenScope = "read"
+	ScopeReadWrite TokenScope = "read_write"
+)
+
+func (s TokenScope) IsValid() bool {
+	return s == ScopeRead || s == ScopeReadWrite
+}
+
+// APIToken — персональный токен для скриптов и интеграций. Сам токен не хранится,
+// только его SHA-256; Prefix — начало токена, чтобы пользователь мог его узнать
+type APIToken struct {
+	ID         int        `json:"id" gorm:"primary_key"`
+	UserID     int        `json:"-" gorm:"index"`
+	Name       string     `json:"name" gorm:"type:varchar(100)"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16)"`
+	Hash       string     `json:"-" gorm:"type:varchar(64);uniqueIndex"`
+	Scope      TokenScope `json:"scope" gorm:"type:varchar(16)"`
+	CreatedAt  *time.Time `json:"created_at" gorm:"type:timestamp"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"type:timestamp NULL"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// UploadsGCReport — результат проверки папки загрузок на файлы, на которые
+// не ссылаются ни игры, ни фото пользователей
+type UploadsGCReport struct {
+	DryRun   bool     `json:"dry_run"`
+	Scanned  int      `json:"scanned"`
+	Orphaned []string `json:"orphaned"`
+	Deleted  int      `json:"deleted"`
+	Bytes    int64    `json:"bytes"` // размер найденных файлов (в dry-run) или освобождённое место
+}
+
+// CoverReport — результат повторного скачивания обложек, которых нет в папке загрузок
+type CoverReport struct {
+	DryRun  bool          `json:"dry_run"`
+	Missing int           `json:"missing"`
+	Fixed   []CoverResult `json:"fixed"`
+	Failed  []CoverResult `json:"failed"`
+}
+
+// CoverResult — обложка одной игры: откуда скачана или почему не удалось
+type CoverResult struct {
+	GameID int    `json:"game_id"`
+	Title  string `json:"title"`
+	URL    string `json:"url,omitempty"`
+	Image  string `json:"image,omitempty"`
+	Err    string `json:"error,omitempty"`
+}
+
+// UserPhoto — текущее фото пользователя в папке фото. Имя файла хранится в SSO,
+// здесь — только размер для квоты загрузок
+type UserPhoto struct {
+	UserID    int        `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Filename  string     `json:"filename" gorm:"type:varchar(255)"`
+	Size      int64      `json:"size"`
+	UpdatedAt *time.Time `json:"updated_at" gorm:"type:timestamp NULL"`
+}
+
+// UploadUsage — сколько места в загрузках занимает пользователь. Limit 0 — без ограничения
+type UploadUsage struct {
+	Covers int64 `json:"covers"`
+	Photo  int64 `json:"photo"`
+	Used   int64 `json:"used"`
+	Limit  int64 `json:"limit"`
+}
@@ -1,5 +1,9 @@
 package models
 
+import (
+	"time"
+)
+
 type GameStatus string
 
 const (
@@ -9,10 +13,60 @@ const (
 	StatusDropped  GameStatus = "dropped"
 )
 
+func (s GameStatus) IsValid() bool {
+	switch s {
+	case StatusPlanned, StatusPlaying, StatusFinished, StatusDropped:
+		return true
+	}
+	return false
+}
+
+// MaxPriority — наивысший приоритет игры. 0 означает, что приоритет не задан.
+// Приоритеты считаются отдельно внутри каждого статуса: у запланированных и
+// проходимых игр свои списки
+const MaxPriority = 10
+
+// PriorityScope — список приоритетов: активные игры пользователя в одном статусе
+type PriorityScope struct {
+	UserID int
+	Status GameStatus
+}
+
+// AgedBacklog — сколько запланированных игр пользователя затронул один проход устаревания
+type AgedBacklog struct {
+	UserID int
+	Games  int
+}
+
+// Метки прохождений. Клиент может прислать и свою метку, эти — общепринятые
+const (
+	PlaythroughFirstRun      = "first_run"
+	PlaythroughNewGamePlus   = "ng_plus"
+	PlaythroughCompletionist = "completionist"
+)
+
+// UserGames — запись игры в библиотеке пользователя. Одна игра может иметь
+// несколько записей (прохождений), из них активна ровно одна
 type UserGames struct {
-	ID       int        `json:"id" gorm:"primary_key"`
-	UserID   int        `json:"user_id"`
-	GameID   int        `json:"game_id"`
-	Priority int        `json:"priority"`
-	Status   GameStatus `json:"status" gorm:"type:varchar(20);default:'planned'"`
+	ID                int        `json:"id" gorm:"primary_key"`
+	UserID            int        `json:"user_id" gorm:"index:idx_user_games_user_game;index:idx_user_games_priority,priority:1"`
+	GameID            int        `json:"game_id" gorm:"index:idx_user_games_user_game;index"`
+	Priority          int        `json:"priority" gorm:"index;index:idx_user_games_priority,priority:3"`
+	Status            GameStatus `json:"status" gorm:"type:varchar(20);default:'planned';index;index:idx_user_games_priority,priority:2"`
+	Label             string     `json:"label" gorm:"type:varchar(64);default:'first_run'"`
+	Sessions          int        `json:"sessions"`
+	Notes             string     `json:"notes" gorm:"type:text"` // Личные заметки, видны только владельцу
+	IsFavorite        bool       `json:"is_favorite" gorm:"index"`
+	CompletionPercent int        `json:"completion_percent"` // 0 — прогресс не отслеживается
+	AchievementsDone  int        `json:"achievements_done"`
+	AchievementsTotal int        `json:"achievements_total"`
+	Rating            int        `json:"rating"` // Оценка прохождения 1–10, 0 — без оценки
+	StartedAt         *time.Time `json:"started_at" gorm:"type:timestamp NULL"`
+	FinishedAt        *time.Time `json:"finished_at" gorm:"type:timestamp NULL"`
+	Platform          string     `json:"platform" gorm:"type:varchar(64)"` // На чём играет пользователь: PC, PS5, Switch
+	IsActive          bool       `json:"is_active" gorm:"default:true"`
+	Stale             bool       `json:"stale"`
+	CreatedAt         *time.Time `json:"created_at" gorm:"type:timestamp NULL;index"` // Когда запись добавлена в библиотеку
+	UpdatedAt         *time.Time `json:"updated_at" gorm:"type:timestamp NULL"`
+	AgedAt            *time.Time `json:"-" gorm:"type:timestamp NULL"` // Когда приоритет последний раз понижался из-за давности
 }
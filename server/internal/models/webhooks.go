@@ -0,0 +1,85 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// WebhookEvent — событие библиотеки, на которое можно подписать вебхук
+type WebhookEvent string
+
+const (
+	WebhookGameCreated   WebhookEvent = "game.created"
+	WebhookStatusChanged WebhookEvent = "status.changed"
+	WebhookGameFinished  WebhookEvent = "game.finished"
+)
+
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookGameCreated, WebhookStatusChanged, WebhookGameFinished:
+		return true
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Webhook — адрес, на который отправляются события библиотеки пользователя.
+// Events хранит подписки через запятую; Secret подписывает тело запроса
+type Webhook struct {
+	ID        int        `json:"id" gorm:"primary_key"`
+	UserID    int        `json:"-" gorm:"index"`
+	URL       string     `json:"url" gorm:"type:varchar(2048)"`
+	Events    string     `json:"-" gorm:"type:varchar(255)"`
+	Secret    string     `json:"-" gorm:"type:varchar(64)"`
+	CreatedAt *time.Time `json:"created_at" gorm:"type:timestamp"`
+
+	EventList []WebhookEvent `json:"events" gorm:"-"`
+}
+
+// Subscribed сообщает, подписан ли вебхук на событие
+func (w *Webhook) Subscribed(e WebhookEvent) bool {
+	for _, name := range strings.Split(w.Events, ",") {
+		if WebhookEvent(name) == e {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery — одна отправка события. Пишется в той же транзакции, что и
+// изменение библиотеки, а отправляется фоновой задачей с повторами
+type WebhookDelivery struct {
+	ID            int            `json:"id" gorm:"primary_key"`
+	WebhookID     int            `json:"webhook_id" gorm:"index"`
+	Event         WebhookEvent   `json:"event" gorm:"type:varchar(32)"`
+	Payload       string         `json:"payload" gorm:"type:text"`
+	Status        DeliveryStatus `json:"status" gorm:"type:varchar(16);index:idx_delivery_due"`
+	Attempts      int            `json:"attempts"`
+	ResponseCode  int            `json:"response_code"`
+	Error         string         `json:"error" gorm:"type:varchar(512)"`
+	NextAttemptAt *time.Time     `json:"next_attempt_at" gorm:"type:timestamp NULL;index:idx_delivery_due"`
+	DeliveredAt   *time.Time     `json:"delivered_at" gorm:"type:timestamp NULL"`
+	CreatedAt     *time.Time     `json:"created_at" gorm:"type:timestamp"`
+}
+
+// WebhookPayload — тело запроса вебхука
+type WebhookPayload struct {
+	Event     WebhookEvent `json:"event"`
+	CreatedAt time.Time    `json:"created_at"`
+	Data      WebhookData  `json:"data"`
+}
+
+type WebhookData struct {
+	UserID         int        `json:"user_id"`
+	GameID         int        `json:"game_id"`
+	GameTitle      string     `json:"game_title"`
+	Status         GameStatus `json:"status"`
+	PreviousStatus GameStatus `json:"previous_status,omitempty"`
+}
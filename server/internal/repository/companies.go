@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type companyRepo struct {
+	db *gorm.DB
+}
+
+func (r *companyRepo) GetOrCreateDeveloper(name, slug string) (*models.Developer, error) {
+	const op = "repository.companies.GetOrCreateDeveloper"
+
+	var d models.Developer
+	if err := r.db.Where(models.Developer{Slug: slug}).Attrs(models.Developer{Name: name}).FirstOrCreate(&d).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &d, nil
+}
+
+func (r *companyRepo) GetOrCreatePublisher(name, slug string) (*models.Publisher, error) {
+	const op = "repository.companies.GetOrCreatePublisher"
+
+	var p models.Publisher
+	if err := r.db.Where(models.Publisher{Slug: slug}).Attrs(models.Publisher{Name: name}).FirstOrCreate(&p).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &p, nil
+}
+
+func (r *companyRepo) SetDevelopers(gameID int, developerIDs []int) error {
+	const op = "repository.companies.SetDevelopers"
+
+	if err := r.db.Where("game_id = ?", gameID).Delete(&models.GameDeveloper{}).Error; err != nil {
+		return wrap(op, err)
+	}
+	if len(developerIDs) == 0 {
+		return nil
+	}
+
+	links := make([]models.GameDeveloper, 0, len(developerIDs))
+	for _, id := range developerIDs {
+		links = append(links, models.GameDeveloper{GameID: gameID, DeveloperID: id})
+	}
+	return wrap(op, r.db.Create(&links).Error)
+}
+
+func (r *companyRepo) SetPublishers(gameID int, publisherIDs []int) error {
+	const op = "repository.companies.SetPublishers"
+
+	if err := r.db.Where("game_id = ?", gameID).Delete(&models.GamePublisher{}).Error; err != nil {
+		return wrap(op, err)
+	}
+	if len(publisherIDs) == 0 {
+		return nil
+	}
+
+	links := make([]models.GamePublisher, 0, len(publisherIDs))
+	for _, id := range publisherIDs {
+		links = append(links, models.GamePublisher{GameID: gameID, PublisherID: id})
+	}
+	return wrap(op, r.db.Create(&links).Error)
+}
+
+func (r *companyRepo) DeveloperCounts(userID int) ([]models.DeveloperCount, error) {
+	const op = "repository.companies.DeveloperCounts"
+
+	var counts []models.DeveloperCount
+	if err := r.db.Table("developers").
+		Select("developers.id, developers.name, developers.slug, COUNT(DISTINCT user_games.game_id) AS games").
+		Joins("JOIN game_developers ON game_developers.developer_id = developers.id").
+		Joins("JOIN user_games ON user_games.game_id = game_developers.game_id AND user_games.user_id = ? AND user_games.is_active = ?", userID, true).
+		Group("developers.id, developers.name, developers.slug").
+		Order("games DESC, developers.name").
+		Scan(&counts).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"time"
+
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type eventRepo struct {
+	db *gorm.DB
+}
+
+func (r *eventRepo) Create(e *models.Event) error {
+	const op = "repository.events.Create"
+	return wrap(op, r.db.Create(e).Error)
+}
+
+func (r *eventRepo) Reassign(fromGameID, toGameID int) error {
+	const op = "repository.events.Reassign"
+	return wrap(op, r.db.Model(&models.Event{}).
+		Where("game_id = ?", fromGameID).
+		Update("game_id", toGameID).Error)
+}
+
+func (r *eventRepo) ListForUser(userID int, since time.Time) ([]models.FeedItem, error) {
+	const op = "repository.events.ListForUser"
+
+	var results []models.FeedItem
+	if err := r.db.
+		Table("events").
+		Select("events.*, COALESCE(games.title, '') as game_title, COALESCE(games.image, '') as game_image").
+		Joins("LEFT JOIN games ON games.id = events.game_id").
+		Where("events.user_id = ? AND events.created_at >= ?", userID, since).
+		Order("events.created_at asc").
+		Scan(&results).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return results, nil
+}
+
+func (r *eventRepo) ListRecentForUser(userID int, types []models.EventType, limit int) ([]models.FeedItem, error) {
+	const op = "repository.events.ListRecentForUser"
+
+	var results []models.FeedItem
+	if err := r.db.
+		Table("events").
+		Select("events.*, COALESCE(games.title, '') as game_title, COALESCE(games.image, '') as game_image").
+		Joins("LEFT JOIN games ON games.id = events.game_id").
+		Where("events.user_id = ? AND events.type IN ?", userID, types).
+		Order("events.created_at desc, events.id desc").
+		Limit(limit).
+		Scan(&results).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return results, nil
+}
+
+type importRunRepo struct {
+	db *gorm.DB
+}
+
+func (r *importRunRepo) Create(run *models.ImportRun) error {
+	const op = "repository.import_runs.Create"
+	return wrap(op, r.db.Create(run).Error)
+}
+
+func (r *importRunRepo) Get(id, userID int) (*models.ImportRun, error) {
+	const op = "repository.import_runs.Get"
+
+	var run models.ImportRun
+	if err := r.db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("id = ? AND user_id = ?", id, userID).First(&run).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &run, nil
+}
+
+func (r *importRunRepo) DeleteItems(runID int) error {
+	const op = "repository.import_runs.DeleteItems"
+	return wrap(op, r.db.Where("run_id = ?", runID).Delete(&models.ImportRunItem{}).Error)
+}
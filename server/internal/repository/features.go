@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type featureOverrideRepo struct {
+	db *gorm.DB
+}
+
+func (r *featureOverrideRepo) List() ([]models.FeatureOverride, error) {
+	const op = "repository.features.List"
+
+	var overrides []models.FeatureOverride
+	if err := r.db.Order("name").Find(&overrides).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return overrides, nil
+}
+
+func (r *featureOverrideRepo) Save(o *models.FeatureOverride) error {
+	const op = "repository.features.Save"
+	return wrap(op, r.db.Save(o).Error)
+}
+
+func (r *featureOverrideRepo) Delete(name string) error {
+	const op = "repository.features.Delete"
+	return wrap(op, r.db.Where("name = ?", name).Delete(&models.FeatureOverride{}).Error)
+}
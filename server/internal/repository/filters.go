@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// applyGameFilter добавляет к выборке из games условия фильтра. Значения фильтра
+// сравниваются со slug справочников, поэтому должны быть уже нормализованы
+func applyGameFilter(db *gorm.DB, f models.GameFilter) *gorm.DB {
+	if f.Genre != "" {
+		db = db.Where(`EXISTS (SELECT 1 FROM game_genres
+			JOIN genres ON genres.id = game_genres.genre_id
+			WHERE game_genres.game_id = games.id AND genres.slug = ?)`, f.Genre)
+	}
+	if f.Developer != "" {
+		db = db.Where(`EXISTS (SELECT 1 FROM game_developers
+			JOIN developers ON developers.id = game_developers.developer_id
+			WHERE game_developers.game_id = games.id AND developers.slug = ?)`, f.Developer)
+	}
+	if f.Publisher != "" {
+		db = db.Where(`EXISTS (SELECT 1 FROM game_publishers
+			JOIN publishers ON publishers.id = game_publishers.publisher_id
+			WHERE game_publishers.game_id = games.id AND publishers.slug = ?)`, f.Publisher)
+	}
+	if f.Platform != "" {
+		db = db.Where(`EXISTS (SELECT 1 FROM game_platforms
+			JOIN platforms ON platforms.id = game_platforms.platform_id
+			WHERE game_platforms.game_id = games.id AND platforms.slug = ?)`, f.Platform)
+	}
+	// user_games есть в выборке и у Library, и у Catalog (через LEFT JOIN)
+	if f.PlayedOn != "" {
+		db = db.Where("LOWER(user_games.platform) = ?", f.PlayedOn)
+	}
+	return db
+}
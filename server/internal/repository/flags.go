@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"time"
+
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type flagRepo struct {
+	db *gorm.DB
+}
+
+// withTitle добавляет к жалобам название игры; у удалённой игры оно пустое
+func (r *flagRepo) withTitle() *gorm.DB {
+	return r.db.Model(&models.GameFlag{}).
+		Select("game_flags.*, COALESCE(games.title, '') AS game_title").
+		Joins("LEFT JOIN games ON games.id = game_flags.game_id")
+}
+
+func (r *flagRepo) Create(f *models.GameFlag) error {
+	const op = "repository.flags.Create"
+	return wrap(op, r.db.Create(f).Error)
+}
+
+func (r *flagRepo) GetByID(id int) (*models.GameFlag, error) {
+	const op = "repository.flags.GetByID"
+
+	var f models.GameFlag
+	if err := r.withTitle().Where("game_flags.id = ?", id).First(&f).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &f, nil
+}
+
+func (r *flagRepo) HasOpen(userID, gameID int) (bool, error) {
+	const op = "repository.flags.HasOpen"
+
+	var count int64
+	if err := r.db.Model(&models.GameFlag{}).
+		Where("user_id = ? AND game_id = ? AND status = ?", userID, gameID, models.FlagOpen).
+		Count(&count).Error; err != nil {
+		return false, wrap(op, err)
+	}
+	return count > 0, nil
+}
+
+func (r *flagRepo) CountOpen(gameID int) (int, error) {
+	const op = "repository.flags.CountOpen"
+
+	var count int64
+	if err := r.db.Model(&models.GameFlag{}).
+		Where("game_id = ? AND status = ?", gameID, models.FlagOpen).
+		Count(&count).Error; err != nil {
+		return 0, wrap(op, err)
+	}
+	return int(count), nil
+}
+
+func (r *flagRepo) List(status models.FlagStatus, limit, offset int) ([]models.GameFlag, int, error) {
+	const op = "repository.flags.List"
+
+	var total int64
+	if err := r.db.Model(&models.GameFlag{}).Where("status = ?", status).Count(&total).Error; err != nil {
+		return nil, 0, wrap(op, err)
+	}
+
+	var flags []models.GameFlag
+	if err := r.withTitle().
+		Where("game_flags.status = ?", status).
+		Order("game_flags.id").
+		Limit(limit).Offset(offset).
+		Find(&flags).Error; err != nil {
+		return nil, 0, wrap(op, err)
+	}
+	return flags, int(total), nil
+}
+
+func (r *flagRepo) Save(f *models.GameFlag) error {
+	const op = "repository.flags.Save"
+	return wrap(op, r.db.Omit("game_title").Save(f).Error)
+}
+
+func (r *flagRepo) ResolveOpen(gameID int, action models.FlagAction, by int, at time.Time) (int, error) {
+	const op = "repository.flags.ResolveOpen"
+
+	res := r.db.Model(&models.GameFlag{}).
+		Where("game_id = ? AND status = ?", gameID, models.FlagOpen).
+		Updates(map[string]interface{}{
+			"status":      models.FlagResolved,
+			"resolution":  action,
+			"resolved_by": by,
+			"resolved_at": at,
+		})
+	if res.Error != nil {
+		return 0, wrap(op, res.Error)
+	}
+	return int(res.RowsAffected), nil
+}
+
+func (r *flagRepo) Reassign(fromGameID, toGameID int) error {
+	const op = "repository.flags.Reassign"
+	return wrap(op, r.db.Model(&models.GameFlag{}).Where("game_id = ?", fromGameID).
+		Update("game_id", toGameID).Error)
+}
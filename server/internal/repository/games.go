@@ -0,0 +1,691 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/storage"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type gameRepo struct {
+	db *gorm.DB
+}
+
+func (r *gameRepo) GetByID(id int) (*models.Game, error) {
+	const op = "repository.games.GetByID"
+
+	var g models.Game
+	if err := r.db.First(&g, id).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &g, nil
+}
+
+func (r *gameRepo) GetByURL(url string) (*models.Game, error) {
+	const op = "repository.games.GetByURL"
+
+	var g models.Game
+	if err := r.db.Where("url = ?", url).First(&g).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &g, nil
+}
+
+func (r *gameRepo) GetByExternalID(source models.GameSource, externalID string) (*models.Game, error) {
+	const op = "repository.games.GetByExternalID"
+
+	var g models.Game
+	if err := r.db.Where("source = ? AND external_id = ?", source, externalID).First(&g).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &g, nil
+}
+
+func (r *gameRepo) FindByTitleKey(key string) ([]models.Game, error) {
+	const op = "repository.games.FindByTitleKey"
+
+	var games []models.Game
+	if err := r.db.Where("title_key = ?", key).Order("id asc").Find(&games).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return games, nil
+}
+
+func (r *gameRepo) Search(query string) ([]models.Game, error) {
+	const op = "repository.games.Search"
+
+	var games []models.Game
+	pattern := "%" + strings.ToLower(query) + "%"
+	if err := replica(r.db).Where("LOWER(title) LIKE ? OR LOWER(title_en) LIKE ?", pattern, pattern).Find(&games).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return games, nil
+}
+
+func (r *gameRepo) List() ([]models.Game, error) {
+	const op = "repository.games.List"
+
+	var games []models.Game
+	if err := r.db.Order("id asc").Find(&games).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return games, nil
+}
+
+func (r *gameRepo) ListWithoutTitleKey() ([]models.Game, error) {
+	const op = "repository.games.ListWithoutTitleKey"
+
+	var games []models.Game
+	if err := r.db.
+		Select("id, title").
+		Where("title_key = '' OR title_key IS NULL").
+		Find(&games).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return games, nil
+}
+
+func (r *gameRepo) ListWithoutSource() ([]models.Game, error) {
+	const op = "repository.games.ListWithoutSource"
+
+	var games []models.Game
+	if err := r.db.
+		Select("id, url").
+		Where("source = '' OR source IS NULL").
+		Find(&games).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return games, nil
+}
+
+func (r *gameRepo) ListWithoutSteamAppID() ([]models.Game, error) {
+	const op = "repository.games.ListWithoutSteamAppID"
+
+	var games []models.Game
+	if err := r.db.
+		Select("id, url, source, external_id").
+		Where("(steam_app_id = 0 OR steam_app_id IS NULL) AND (url LIKE ? OR url LIKE ?)", "%steampowered.com/%", "%steamcommunity.com/%").
+		Find(&games).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return games, nil
+}
+
+func (r *gameRepo) ListWithoutReleaseDate() ([]models.Game, error) {
+	const op = "repository.games.ListWithoutReleaseDate"
+
+	var games []models.Game
+	if err := r.db.
+		Select("id, year").
+		Where("release_date IS NULL AND year <> ''").
+		Find(&games).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return games, nil
+}
+
+func (r *gameRepo) ListWithoutGenres() ([]models.Game, error) {
+	const op = "repository.games.ListWithoutGenres"
+
+	var games []models.Game
+	if err := r.db.
+		Select("id, genre").
+		Where("genre <> '' AND NOT EXISTS (SELECT 1 FROM game_genres WHERE game_genres.game_id = games.id)").
+		Find(&games).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return games, nil
+}
+
+func (r *gameRepo) ListWithoutCompanies() ([]models.Game, error) {
+	const op = "repository.games.ListWithoutCompanies"
+
+	var games []models.Game
+	if err := r.db.
+		Select("id, developer, publisher").
+		Where(`(developer <> '' AND NOT EXISTS (SELECT 1 FROM game_developers WHERE game_developers.game_id = games.id))
+			OR (publisher <> '' AND NOT EXISTS (SELECT 1 FROM game_publishers WHERE game_publishers.game_id = games.id))`).
+		Find(&games).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return games, nil
+}
+
+func (r *gameRepo) Count() (int, error) {
+	const op = "repository.games.Count"
+
+	var count int64
+	if err := r.db.Model(&models.Game{}).Count(&count).Error; err != nil {
+		return 0, wrap(op, err)
+	}
+	return int(count), nil
+}
+
+// EachURL вызывает fn для каждого непустого URL, не загружая все игры в память
+func (r *gameRepo) EachURL(fn func(url string)) error {
+	const op = "repository.games.EachURL"
+
+	rows, err := r.db.Model(&models.Game{}).Where("url <> ''").Select("url").Rows()
+	if err != nil {
+		return wrap(op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return wrap(op, err)
+		}
+		fn(url)
+	}
+	return wrap(op, rows.Err())
+}
+
+func (r *gameRepo) ListImages() ([]string, error) {
+	const op = "repository.games.ListImages"
+
+	var images []string
+	if err := r.db.Model(&models.Game{}).
+		Where("image <> ''").
+		Distinct().
+		Pluck("image", &images).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return images, nil
+}
+
+func (r *gameRepo) Create(g *models.Game) error {
+	const op = "repository.games.Create"
+	return wrap(op, r.db.Create(g).Error)
+}
+
+// Update обновляет только непустые поля g. Если g.Version задана, строка
+// обновляется только при совпадении версии, иначе возвращается storage.ErrConflict.
+// Версия увеличивается тем же запросом, поэтому два параллельных Update с одной
+// версией не пройдут оба
+func (r *gameRepo) Update(g *models.Game) error {
+	const op = "repository.games.Update"
+
+	if g.Version == 0 {
+		if err := r.db.Model(&models.Game{}).Where("id = ?", g.ID).Updates(g).Error; err != nil {
+			return wrap(op, err)
+		}
+		return wrap(op, r.db.Model(&models.Game{}).Where("id = ?", g.ID).
+			UpdateColumn("version", gorm.Expr("version + 1")).Error)
+	}
+
+	expected := g.Version
+	g.Version++
+	res := r.db.Model(&models.Game{}).Where("id = ? AND version = ?", g.ID, expected).Updates(g)
+	if res.Error != nil {
+		g.Version = expected
+		return wrap(op, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		g.Version = expected
+		return wrap(op, storage.ErrConflict)
+	}
+	return nil
+}
+
+// Replace записывает все поля g, включая пустые, если версия в базе всё ещё
+// равна g.Version. Иначе возвращает storage.ErrConflict
+func (r *gameRepo) Replace(g *models.Game) error {
+	const op = "repository.games.Replace"
+
+	expected := g.Version
+	g.Version++
+	res := r.db.Model(&models.Game{}).Where("id = ? AND version = ?", g.ID, expected).
+		Select("*").Omit("id", "creator", "created_at", "flagged").Updates(g)
+	if res.Error != nil {
+		g.Version = expected
+		return wrap(op, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		g.Version = expected
+		return wrap(op, storage.ErrConflict)
+	}
+	return nil
+}
+
+func (r *gameRepo) Save(g *models.Game) error {
+	const op = "repository.games.Save"
+	return wrap(op, r.db.Save(g).Error)
+}
+
+func (r *gameRepo) SetSource(id int, source models.GameSource, externalID string) error {
+	const op = "repository.games.SetSource"
+	return wrap(op, r.db.Model(&models.Game{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"source":      source,
+		"external_id": externalID,
+	}).Error)
+}
+
+func (r *gameRepo) SetSteamAppID(id, appID int) error {
+	const op = "repository.games.SetSteamAppID"
+	return wrap(op, r.db.Model(&models.Game{}).Where("id = ?", id).Update("steam_app_id", appID).Error)
+}
+
+func (r *gameRepo) SetImage(id int, image string) error {
+	const op = "repository.games.SetImage"
+	return wrap(op, r.db.Model(&models.Game{}).Where("id = ?", id).Update("image", image).Error)
+}
+
+func (r *gameRepo) SetFlagged(id int, flagged bool) error {
+	const op = "repository.games.SetFlagged"
+	return wrap(op, r.db.Model(&models.Game{}).Where("id = ?", id).UpdateColumn("flagged", flagged).Error)
+}
+
+func (r *gameRepo) SetReleaseDate(id int, date time.Time, precision models.DatePrecision, year string) error {
+	const op = "repository.games.SetReleaseDate"
+	return wrap(op, r.db.Model(&models.Game{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"release_date":      date,
+		"release_precision": precision,
+		"year":              year,
+	}).Error)
+}
+
+func (r *gameRepo) SetTitleKey(id int, key string) error {
+	const op = "repository.games.SetTitleKey"
+	return wrap(op, r.db.Model(&models.Game{}).Where("id = ?", id).Update("title_key", key).Error)
+}
+
+func (r *gameRepo) Delete(id int) error {
+	const op = "repository.games.Delete"
+
+	for _, link := range []interface{}{&models.GameGenre{}, &models.GameDeveloper{}, &models.GamePublisher{}, &models.GamePlatform{}} {
+		if err := r.db.Where("game_id = ?", id).Delete(link).Error; err != nil {
+			return wrap(op, err)
+		}
+	}
+	return wrap(op, r.db.Delete(&models.Game{}, id).Error)
+}
+
+func (r *gameRepo) Catalog(q LibraryQuery) ([]models.UserGameResponse, int, error) {
+	const op = "repository.games.Catalog"
+
+	var results []models.UserGameResponse
+	var count int64
+
+	db := replica(r.db).Table("games").
+		Select(append([]string{"games.*"}, librarySelect(true)...)).
+		Joins("LEFT JOIN user_games ON user_games.game_id = games.id AND user_games.user_id = ? AND user_games.is_active = ?", q.UserID, true)
+
+	if q.Search != "" {
+		pattern := "%" + strings.ToLower(q.Search) + "%"
+		db = db.Where("LOWER(games.title) LIKE ? OR LOWER(games.title_en) LIKE ?", pattern, pattern)
+	}
+	db = applyGameFilter(db, q.Filter)
+
+	if err := db.Count(&count).Error; err != nil {
+		return nil, 0, wrap(op, err)
+	}
+
+	allowedSort := map[string]string{
+		"title": "games.title",
+		"year":  "games.release_date",
+	}
+
+	// Игры без даты выхода — в конце при любом направлении сортировки
+	if q.SortBy == "year" {
+		db = db.Order("games.release_date IS NULL")
+	}
+
+	if err := db.
+		Order(orderBy(allowedSort, q.SortBy, q.SortOrder)).
+		Offset(q.Offset).
+		Limit(q.Limit).
+		Scan(&results).Error; err != nil {
+		return nil, 0, wrap(op, err)
+	}
+
+	return results, int(count), nil
+}
+
+// flexColumn — колонка, доступная в Flex. Имена полей из запроса не подставляются
+// в SQL напрямую: так запрос не зависит от диалекта СУБД и не допускает инъекций
+type flexColumn struct {
+	name string
+	kind schema.DataType
+}
+
+// Колонки Flex выводятся из моделей: поле доступно под своим JSON-именем.
+// Поля со значением json:"-" не отдаются и здесь
+var (
+	flexGameColumns     = flexColumnsOf(&models.Game{}, "games")
+	flexUserGameColumns = flexColumnsOf(&models.UserGames{}, "user_games")
+	flexGenreColumns    = flexColumnsOf(&models.Genre{}, "genres")
+)
+
+// flexAliases — старые имена полей, которые принимались до вывода списка из моделей
+var flexAliases = map[string]string{
+	"favorite": "is_favorite",
+}
+
+var flexConditions = map[string]string{
+	"gt":  ">",
+	"lt":  "<",
+	"gte": ">=",
+	"lte": "<=",
+	"eq":  "=",
+	"neq": "!=",
+}
+
+// Таблицы, которые можно присоединить в Flex
+const (
+	flexJoinUserGames = "user_games"
+	flexJoinGenres    = "genres"
+)
+
+type flexJoins struct {
+	userGames bool
+	genres    bool
+}
+
+func flexColumnsOf(model interface{}, table string) map[string]flexColumn {
+	s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		panic(fmt.Sprintf("flex columns of %s: %v", table, err))
+	}
+
+	cols := make(map[string]flexColumn, len(s.Fields))
+	for _, f := range s.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.DBName
+		}
+		cols[name] = flexColumn{name: table + "." + f.DBName, kind: f.DataType}
+	}
+	return cols
+}
+
+// lookupFlexColumn принимает как "title", так и "games.title". Имя без таблицы
+// ищется сначала среди полей игры, затем среди полей записи библиотеки.
+// Поля жанра совпадают по именам с полями игры и доступны только как "genres.name"
+func lookupFlexColumn(field string, joins flexJoins) (flexColumn, error) {
+	name := strings.ToLower(strings.TrimSpace(field))
+
+	table := ""
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		table, name = name[:i], name[i+1:]
+	}
+	if alias, ok := flexAliases[name]; ok {
+		name = alias
+	}
+
+	switch table {
+	case "", "games":
+		if col, ok := flexGameColumns[name]; ok {
+			return col, nil
+		}
+		if table == "games" {
+			break
+		}
+		fallthrough
+	case flexJoinUserGames:
+		if col, ok := flexUserGameColumns[name]; ok {
+			if !joins.userGames {
+				return flexColumn{}, &storage.FieldError{Field: field, Reason: "поле доступно только с join user_games"}
+			}
+			return col, nil
+		}
+	case flexJoinGenres:
+		if col, ok := flexGenreColumns[name]; ok {
+			if !joins.genres {
+				return flexColumn{}, &storage.FieldError{Field: field, Reason: "поле доступно только с join genres"}
+			}
+			return col, nil
+		}
+	default:
+		return flexColumn{}, &storage.FieldError{Field: field, Reason: "неизвестная таблица"}
+	}
+
+	return flexColumn{}, &storage.FieldError{Field: field, Reason: "неизвестное поле"}
+}
+
+// flexValue приводит значение условия к типу колонки: Postgres не сравнивает
+// строку с числом или bool сам
+func flexValue(col flexColumn, value string) (interface{}, bool) {
+	switch col.kind {
+	case schema.Int, schema.Uint:
+		n, err := strconv.ParseInt(value, 10, 64)
+		return n, err == nil
+	case schema.Float:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	case schema.Bool:
+		b, err := strconv.ParseBool(value)
+		return b, err == nil
+	default:
+		return value, true
+	}
+}
+
+// flexBase присоединяет запрошенные таблицы и накладывает условия Where.
+// Возвращает набор доступных join'ов, по нему проверяются имена полей
+func (r *gameRepo) flexBase(q FlexQuery) (*gorm.DB, flexJoins, error) {
+	var joins flexJoins
+	joins.userGames = q.UserID != 0
+	for _, j := range q.Joins {
+		switch strings.ToLower(strings.TrimSpace(j)) {
+		case flexJoinUserGames:
+			if q.UserID == 0 {
+				return nil, joins, &storage.FieldError{Field: j, Reason: "join user_games требует user_id"}
+			}
+			joins.userGames = true
+		case flexJoinGenres:
+			// Игра с несколькими жанрами даёт несколько строк, поэтому
+			// жанры присоединяются только для подсчётов
+			if q.Aggregate == nil {
+				return nil, joins, &storage.FieldError{Field: j, Reason: "join genres доступен только с aggregate"}
+			}
+			joins.genres = true
+		default:
+			return nil, joins, &storage.FieldError{Field: j, Reason: "неизвестный join"}
+		}
+	}
+
+	db := r.db.Model(&models.Game{})
+	if joins.userGames {
+		db = db.Joins("JOIN user_games ON user_games.game_id = games.id and user_games.user_id = ? and user_games.is_active = ?", q.UserID, true)
+	}
+	if joins.genres {
+		db = db.Joins("JOIN game_genres ON game_genres.game_id = games.id").
+			Joins("JOIN genres ON genres.id = game_genres.genre_id")
+	}
+
+	for _, wq := range q.Where {
+		col, err := lookupFlexColumn(wq.Field, joins)
+		if err != nil {
+			return nil, joins, err
+		}
+
+		condition, ok := flexConditions[strings.ToLower(wq.Condition)]
+		if !ok {
+			return nil, joins, &storage.FieldError{Field: wq.Field, Reason: fmt.Sprintf("неизвестное условие %q", wq.Condition)}
+		}
+
+		value, ok := flexValue(col, wq.Value)
+		if !ok {
+			return nil, joins, &storage.FieldError{Field: wq.Field, Reason: fmt.Sprintf("значение %q не подходит к типу поля", wq.Value)}
+		}
+
+		db = db.Where(fmt.Sprintf("%s %s ?", col.name, condition), value)
+	}
+
+	return db, joins, nil
+}
+
+func (r *gameRepo) Flex(q FlexQuery) ([]models.UserGameResponse, error) {
+	const op = "repository.games.Flex"
+
+	db, joins, err := r.flexBase(q)
+	if err != nil {
+		return nil, wrap(op, err)
+	}
+
+	selected := []string{"games.*"}
+	if len(q.Fields) > 0 {
+		selected = selected[:0]
+	}
+	for _, f := range q.Fields {
+		col, err := lookupFlexColumn(f, joins)
+		if err != nil {
+			return nil, wrap(op, err)
+		}
+		selected = append(selected, col.name)
+	}
+	if joins.userGames {
+		selected = append(selected, librarySelect(false)...)
+	}
+	db = db.Select(selected)
+
+	for _, s := range q.Order {
+		col, err := lookupFlexColumn(s.Field, joins)
+		if err != nil {
+			return nil, wrap(op, err)
+		}
+
+		dir, err := flexDirection(s)
+		if err != nil {
+			return nil, wrap(op, err)
+		}
+
+		db = db.Order(fmt.Sprintf("%s %s", col.name, dir))
+	}
+
+	var res []models.UserGameResponse
+	if err := flexPage(db, q).Scan(&res).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+
+	return res, nil
+}
+
+func (r *gameRepo) FlexAggregate(q FlexQuery) ([]map[string]interface{}, error) {
+	const op = "repository.games.FlexAggregate"
+
+	agg := q.Aggregate
+	if agg == nil || (len(agg.GroupBy) == 0 && !agg.Count && len(agg.Min) == 0 && len(agg.Max) == 0) {
+		return nil, wrap(op, &storage.FieldError{Field: "aggregate", Reason: "не указано, что считать"})
+	}
+
+	db, joins, err := r.flexBase(q)
+	if err != nil {
+		return nil, wrap(op, err)
+	}
+
+	// Сортировать можно только по тому, что попало в выборку: по ключам
+	// группировки и по псевдонимам агрегатов
+	var selected, groups []string
+	sortable := make(map[string]string)
+	for _, f := range agg.GroupBy {
+		col, err := lookupFlexColumn(f, joins)
+		if err != nil {
+			return nil, wrap(op, err)
+		}
+		alias := flexAlias(f)
+		selected = append(selected, fmt.Sprintf("%s AS %s", col.name, alias))
+		groups = append(groups, col.name)
+		sortable[alias] = col.name
+	}
+	if agg.Count {
+		// Через join жанров одна игра попадает в выборку по разу на каждый
+		// свой жанр, поэтому считаются разные игры
+		selected = append(selected, "COUNT(DISTINCT games.id) AS count")
+		sortable["count"] = "count"
+	}
+	for fn, fields := range map[string][]string{"MIN": agg.Min, "MAX": agg.Max} {
+		for _, f := range fields {
+			col, err := lookupFlexColumn(f, joins)
+			if err != nil {
+				return nil, wrap(op, err)
+			}
+			alias := strings.ToLower(fn) + "_" + flexAlias(f)
+			selected = append(selected, fmt.Sprintf("%s(%s) AS %s", fn, col.name, alias))
+			sortable[alias] = alias
+		}
+	}
+
+	db = db.Select(selected)
+	if len(groups) > 0 {
+		db = db.Group(strings.Join(groups, ", "))
+	}
+
+	for _, s := range q.Order {
+		expr, ok := sortable[flexAlias(s.Field)]
+		if !ok {
+			return nil, wrap(op, &storage.FieldError{Field: s.Field, Reason: "сортировать можно только по group_by и агрегатам"})
+		}
+
+		dir, err := flexDirection(s)
+		if err != nil {
+			return nil, wrap(op, err)
+		}
+
+		db = db.Order(fmt.Sprintf("%s %s", expr, dir))
+	}
+
+	var res []map[string]interface{}
+	if err := flexPage(db, q).Scan(&res).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+
+	return res, nil
+}
+
+// flexAlias превращает имя поля вроде "genres.name" в безопасный псевдоним
+// колонки "genres_name". Само имя к этому моменту уже проверено по белому списку
+func flexAlias(field string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(field)), ".", "_")
+}
+
+func flexDirection(s models.Sort) (string, error) {
+	switch strings.ToLower(s.Direction) {
+	case "", "asc":
+		return "ASC", nil
+	case "desc":
+		return "DESC", nil
+	default:
+		return "", &storage.FieldError{Field: s.Field, Reason: fmt.Sprintf("неизвестное направление %q", s.Direction)}
+	}
+}
+
+func flexPage(db *gorm.DB, q FlexQuery) *gorm.DB {
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit)
+	}
+
+	if q.Offset > 0 {
+		db = db.Offset(q.Offset)
+	}
+
+	return db
+}
+
+// orderBy строит ORDER BY по белому списку полей; по умолчанию — по названию
+func orderBy(allowed map[string]string, sortBy, sortOrder string) string {
+	field, ok := allowed[sortBy]
+	if !ok {
+		field = "games.title"
+	}
+
+	if strings.ToLower(sortOrder) != "desc" {
+		sortOrder = "asc"
+	}
+
+	return fmt.Sprintf("%s %s", field, sortOrder)
+}
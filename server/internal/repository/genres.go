@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type genreRepo struct {
+	db *gorm.DB
+}
+
+func (r *genreRepo) GetOrCreate(name, slug string) (*models.Genre, error) {
+	const op = "repository.genres.GetOrCreate"
+
+	var g models.Genre
+	if err := r.db.Where(models.Genre{Slug: slug}).Attrs(models.Genre{Name: name}).FirstOrCreate(&g).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &g, nil
+}
+
+func (r *genreRepo) SetForGame(gameID int, genreIDs []int) error {
+	const op = "repository.genres.SetForGame"
+
+	if err := r.db.Where("game_id = ?", gameID).Delete(&models.GameGenre{}).Error; err != nil {
+		return wrap(op, err)
+	}
+	if len(genreIDs) == 0 {
+		return nil
+	}
+
+	links := make([]models.GameGenre, 0, len(genreIDs))
+	for _, id := range genreIDs {
+		links = append(links, models.GameGenre{GameID: gameID, GenreID: id})
+	}
+	return wrap(op, r.db.Create(&links).Error)
+}
+
+func (r *genreRepo) TopForUser(userID, limit int) ([]models.GenreCount, error) {
+	const op = "repository.genres.TopForUser"
+
+	var counts []models.GenreCount
+	if err := r.db.Table("genres").
+		Select("genres.name, genres.slug, COUNT(DISTINCT user_games.game_id) AS games").
+		Joins("JOIN game_genres ON game_genres.genre_id = genres.id").
+		Joins("JOIN user_games ON user_games.game_id = game_genres.game_id AND user_games.user_id = ? AND user_games.is_active = ?", userID, true).
+		Group("genres.id, genres.name, genres.slug").
+		Order("games DESC, genres.name").
+		Limit(limit).
+		Scan(&counts).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type imageRepo struct {
+	db *gorm.DB
+}
+
+func (r *imageRepo) GetByHash(hash string) (*models.Image, error) {
+	const op = "repository.images.GetByHash"
+
+	var img models.Image
+	if err := r.db.Where("hash = ?", hash).First(&img).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &img, nil
+}
+
+func (r *imageRepo) GetByFilename(filename string) (*models.Image, error) {
+	const op = "repository.images.GetByFilename"
+
+	var img models.Image
+	if err := r.db.Where("filename = ?", filename).First(&img).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &img, nil
+}
+
+func (r *imageRepo) Create(img *models.Image) error {
+	const op = "repository.images.Create"
+	return wrap(op, r.db.Create(img).Error)
+}
+
+// AddRefs меняет счётчик ссылок на delta одним запросом, без чтения
+func (r *imageRepo) AddRefs(id, delta int) error {
+	const op = "repository.images.AddRefs"
+	return wrap(op, r.db.Model(&models.Image{}).
+		Where("id = ?", id).
+		UpdateColumn("refs", gorm.Expr("refs + ?", delta)).Error)
+}
+
+func (r *imageRepo) Delete(id int) error {
+	const op = "repository.images.Delete"
+	return wrap(op, r.db.Delete(&models.Image{}, id).Error)
+}
+
+func (r *imageRepo) SizeByUploader(userID int) (int64, error) {
+	const op = "repository.images.SizeByUploader"
+
+	var size int64
+	if err := r.db.Model(&models.Image{}).
+		Where("uploaded_by = ?", userID).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&size).Error; err != nil {
+		return 0, wrap(op, err)
+	}
+	return size, nil
+}
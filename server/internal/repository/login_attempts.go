@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"time"
+
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type loginAttemptRepo struct {
+	db *gorm.DB
+}
+
+func (r *loginAttemptRepo) Get(keys []string) ([]models.LoginAttempt, error) {
+	const op = "repository.login_attempts.Get"
+
+	var attempts []models.LoginAttempt
+	if err := r.db.Where("attempt_key IN ?", keys).Find(&attempts).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return attempts, nil
+}
+
+func (r *loginAttemptRepo) ListLocked(now time.Time) ([]models.LoginAttempt, error) {
+	const op = "repository.login_attempts.ListLocked"
+
+	var attempts []models.LoginAttempt
+	if err := r.db.Where("locked_until > ?", now).Order("locked_until DESC").Find(&attempts).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return attempts, nil
+}
+
+// Increment атомарно добавляет неудачу по ключу и возвращает новый счётчик.
+// Счётчик без неудач после windowStart начинается заново. Чтобы прочитанное
+// значение было своим, а не соседнего запроса, вызывается в транзакции:
+// обновление держит блокировку строки до её конца
+func (r *loginAttemptRepo) Increment(key string, now, windowStart time.Time) (int, error) {
+	const op = "repository.login_attempts.Increment"
+
+	if err := r.db.
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "attempt_key"}}, DoNothing: true}).
+		Create(&models.LoginAttempt{Key: key}).Error; err != nil {
+		return 0, wrap(op, err)
+	}
+
+	// failures стоит в SET первым: MySQL вычисляет присваивания по порядку, и
+	// CASE должен видеть прежнее last_failed_at
+	if err := r.db.Exec(
+		"UPDATE login_attempts SET "+
+			"failures = CASE WHEN last_failed_at IS NULL OR last_failed_at < ? THEN 1 ELSE failures + 1 END, "+
+			"last_failed_at = ? WHERE attempt_key = ?",
+		windowStart, now, key).Error; err != nil {
+		return 0, wrap(op, err)
+	}
+
+	var a models.LoginAttempt
+	if err := r.db.Where("attempt_key = ?", key).First(&a).Error; err != nil {
+		return 0, wrap(op, err)
+	}
+	return a.Failures, nil
+}
+
+func (r *loginAttemptRepo) Lock(key string, until time.Time) error {
+	const op = "repository.login_attempts.Lock"
+	return wrap(op, r.db.Model(&models.LoginAttempt{}).
+		Where("attempt_key = ?", key).
+		Update("locked_until", until).Error)
+}
+
+func (r *loginAttemptRepo) Delete(keys []string) (int, error) {
+	const op = "repository.login_attempts.Delete"
+
+	res := r.db.Where("attempt_key IN ?", keys).Delete(&models.LoginAttempt{})
+	return int(res.RowsAffected), wrap(op, res.Error)
+}
+
+func (r *loginAttemptRepo) DeleteStale(failedBefore, now time.Time) (int, error) {
+	const op = "repository.login_attempts.DeleteStale"
+
+	res := r.db.Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", failedBefore, now).
+		Delete(&models.LoginAttempt{})
+	return int(res.RowsAffected), wrap(op, res.Error)
+}
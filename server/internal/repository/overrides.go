@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type overrideRepo struct {
+	db *gorm.DB
+}
+
+func (r *overrideRepo) Get(userID, gameID int) (*models.GameOverride, error) {
+	const op = "repository.overrides.Get"
+
+	var o models.GameOverride
+	if err := r.db.Where("user_id = ? AND game_id = ?", userID, gameID).First(&o).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &o, nil
+}
+
+func (r *overrideRepo) ListForGames(userID int, gameIDs []int) ([]models.GameOverride, error) {
+	const op = "repository.overrides.ListForGames"
+
+	var overrides []models.GameOverride
+	if len(gameIDs) == 0 {
+		return overrides, nil
+	}
+	if err := r.db.Where("user_id = ? AND game_id IN ?", userID, gameIDs).Find(&overrides).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return overrides, nil
+}
+
+func (r *overrideRepo) Save(o *models.GameOverride) error {
+	const op = "repository.overrides.Save"
+	return wrap(op, r.db.Save(o).Error)
+}
+
+func (r *overrideRepo) Delete(userID, gameID int) error {
+	const op = "repository.overrides.Delete"
+	return wrap(op, r.db.Where("user_id = ? AND game_id = ?", userID, gameID).Delete(&models.GameOverride{}).Error)
+}
+
+func (r *overrideRepo) DeleteByGame(gameID int) error {
+	const op = "repository.overrides.DeleteByGame"
+	return wrap(op, r.db.Where("game_id = ?", gameID).Delete(&models.GameOverride{}).Error)
+}
+
+func (r *overrideRepo) Reassign(fromGameID, toGameID int) error {
+	const op = "repository.overrides.Reassign"
+
+	// У кого уже есть правка оставшейся игры, тот сохраняет её
+	if err := r.db.Where("game_id = ? AND user_id IN (?)", fromGameID,
+		r.db.Model(&models.GameOverride{}).Select("user_id").Where("game_id = ?", toGameID)).
+		Delete(&models.GameOverride{}).Error; err != nil {
+		return wrap(op, err)
+	}
+	return wrap(op, r.db.Model(&models.GameOverride{}).Where("game_id = ?", fromGameID).
+		Update("game_id", toGameID).Error)
+}
+
+func (r *overrideRepo) ListImages() ([]string, error) {
+	const op = "repository.overrides.ListImages"
+
+	var images []string
+	if err := r.db.Model(&models.GameOverride{}).
+		Where("image <> ''").
+		Distinct().
+		Pluck("image", &images).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return images, nil
+}
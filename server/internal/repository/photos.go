@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type photoRepo struct {
+	db *gorm.DB
+}
+
+func (r *photoRepo) Get(userID int) (*models.UserPhoto, error) {
+	const op = "repository.photos.Get"
+
+	var p models.UserPhoto
+	if err := r.db.Where("user_id = ?", userID).First(&p).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &p, nil
+}
+
+func (r *photoRepo) Save(p *models.UserPhoto) error {
+	const op = "repository.photos.Save"
+	return wrap(op, r.db.Save(p).Error)
+}
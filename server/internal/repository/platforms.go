@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type platformRepo struct {
+	db *gorm.DB
+}
+
+func (r *platformRepo) GetOrCreate(name, slug string) (*models.Platform, error) {
+	const op = "repository.platforms.GetOrCreate"
+
+	var p models.Platform
+	if err := r.db.Where(models.Platform{Slug: slug}).Attrs(models.Platform{Name: name}).FirstOrCreate(&p).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &p, nil
+}
+
+func (r *platformRepo) SetForGame(gameID int, platformIDs []int) error {
+	const op = "repository.platforms.SetForGame"
+
+	if err := r.db.Where("game_id = ?", gameID).Delete(&models.GamePlatform{}).Error; err != nil {
+		return wrap(op, err)
+	}
+	if len(platformIDs) == 0 {
+		return nil
+	}
+
+	links := make([]models.GamePlatform, 0, len(platformIDs))
+	for _, id := range platformIDs {
+		links = append(links, models.GamePlatform{GameID: gameID, PlatformID: id})
+	}
+	return wrap(op, r.db.Create(&links).Error)
+}
@@ -0,0 +1,416 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/storage"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// LibraryQuery — параметры выборки игр с пагинацией. SortBy — логическое имя поля
+// (title, year, priority, favorite), неизвестные значения заменяются сортировкой по названию
+type LibraryQuery struct {
+	UserID    int
+	Status    *models.GameStatus
+	Search    string
+	Filter    models.GameFilter
+	SortBy    string
+	SortOrder string
+	Offset    int
+	Limit     int
+}
+
+// FlexQuery — произвольная выборка для GetFlex. Поля проверяются по белому списку
+type FlexQuery struct {
+	UserID int
+	// Joins — присоединяемые таблицы: user_games (записи библиотеки UserID;
+	// при UserID != 0 она присоединяется и без явного запроса) и genres
+	// (только вместе с Aggregate)
+	Joins  []string
+	Fields []string
+	Where  []models.WhereQuery
+	Order  []models.Sort
+	Limit  int
+	Offset int
+	// Aggregate заменяет Fields группировкой и агрегатами; см. FlexAggregate
+	Aggregate *models.FlexAggregation
+}
+
+type GameRepo interface {
+	GetByID(id int) (*models.Game, error)
+	GetByURL(url string) (*models.Game, error)
+	GetByExternalID(source models.GameSource, externalID string) (*models.Game, error)
+	FindByTitleKey(key string) ([]models.Game, error)
+	Search(query string) ([]models.Game, error)
+	List() ([]models.Game, error)
+	ListWithoutTitleKey() ([]models.Game, error)
+	ListWithoutSource() ([]models.Game, error)
+	// ListWithoutSteamAppID возвращает игры со ссылкой на Steam, у которых не заполнен appid
+	ListWithoutSteamAppID() ([]models.Game, error)
+	// ListWithoutReleaseDate возвращает игры, у которых есть year, но нет release_date
+	ListWithoutReleaseDate() ([]models.Game, error)
+	// ListWithoutGenres возвращает игры с заполненным жанром, не связанные со справочником жанров
+	ListWithoutGenres() ([]models.Game, error)
+	// ListWithoutCompanies возвращает игры с заполненным разработчиком или издателем,
+	// не связанные со справочниками компаний
+	ListWithoutCompanies() ([]models.Game, error)
+	Count() (int, error)
+	EachURL(fn func(url string)) error
+	// ListImages возвращает имена файлов картинок, на которые ссылаются игры
+	ListImages() ([]string, error)
+
+	Create(g *models.Game) error
+	Update(g *models.Game) error
+	Replace(g *models.Game) error
+	Save(g *models.Game) error
+	SetTitleKey(id int, key string) error
+	SetSource(id int, source models.GameSource, externalID string) error
+	SetSteamAppID(id, appID int) error
+	SetImage(id int, image string) error
+	SetFlagged(id int, flagged bool) error
+	SetReleaseDate(id int, date time.Time, precision models.DatePrecision, year string) error
+	Delete(id int) error
+
+	// Catalog возвращает все игры с приоритетом и статусом пользователя, если игра есть у него в библиотеке
+	Catalog(q LibraryQuery) ([]models.UserGameResponse, int, error)
+	Flex(q FlexQuery) ([]models.UserGameResponse, error)
+	FlexAggregate(q FlexQuery) ([]map[string]interface{}, error)
+}
+
+type UserGameRepo interface {
+	GetActive(userID, gameID int) (*models.UserGames, error)
+	GetPlaythrough(id, userID, gameID int) (*models.UserGames, error)
+	ListPlaythroughs(userID, gameID int) ([]models.UserGames, error)
+	Exists(userID, gameID int) (bool, error)
+
+	Create(ug *models.UserGames) error
+	Save(ug *models.UserGames) error
+	UpdateProgress(ug *models.UserGames) error
+	SetNotes(id int, notes string) error
+	SetFavorite(id int, favorite bool) error
+	// ListRanked возвращает активные записи пользователя в статусе status с
+	// приоритетом от 1 до maxPriority, от большего приоритета к меньшему
+	ListRanked(userID int, status models.GameStatus, maxPriority int) ([]models.UserGames, error)
+	SetPriority(id, priority int) error
+	// ShiftDown понижает приоритет записей на единицу
+	ShiftDown(ids []int) error
+	// ResetPriorities обнуляет приоритет активных записей пользователя в статусе status
+	ResetPriorities(userID int, status models.GameStatus) error
+	// ListPriorityClashes возвращает списки приоритетов, в которых одно значение
+	// занимают несколько записей
+	ListPriorityClashes() ([]models.PriorityScope, error)
+	Activate(id int) error
+	DeactivateAll(userID, gameID int) error
+	Delete(userID, gameID int) error
+	DeletePlaythrough(id int) error
+	DeleteByGame(gameID int) error
+
+	// StatusCounts считает записи активной библиотеки по статусам одним запросом
+	StatusCounts(userID int) (*models.StatusCounts, error)
+	// FinishedSince возвращает даты прохождения всех прохождений пользователя начиная с since
+	FinishedSince(userID int, since time.Time) ([]time.Time, error)
+	// FinishSpans возвращает для пройденных активных записей даты добавления и прохождения
+	FinishSpans(userID int) ([]models.FinishSpan, error)
+	CountOtherUsers(gameID, exceptUserID int) (int, error)
+	UsersOf(gameID int) ([]int, error)
+	// Reassign переносит записи с одной игры на другую. Записи пользователей из
+	// deactivateFor становятся неактивными прохождениями
+	Reassign(fromGameID, toGameID int, deactivateFor []int) error
+
+	// Library возвращает активные записи пользователя с пагинацией
+	Library(q LibraryQuery) ([]models.UserGameResponse, int, error)
+	// LibraryEntries — то же, что Library, но с жанрами и сводкой прохождений.
+	// Страница собирается двумя запросами независимо от её размера
+	LibraryEntries(q LibraryQuery) ([]models.LibraryEntry, int, error)
+	// ListLibrary возвращает все активные записи пользователя, упорядоченные по id игры
+	ListLibrary(userID int) ([]models.UserGameResponse, error)
+	ListStale(userID int, olderThan time.Time) ([]models.UserGameResponse, error)
+	// ListUpcoming возвращает запланированные игры пользователя с точно известной
+	// датой выхода в [from, to)
+	ListUpcoming(userID int, from, to time.Time) ([]models.UserGameResponse, error)
+	// Age понижает приоритет (decay) или только помечает stale записи пользователей
+	// с включённым устареванием. Возвращает количество изменённых записей по пользователям
+	Age(olderThan time.Time, decay bool) ([]models.AgedBacklog, error)
+}
+
+type EventRepo interface {
+	Create(e *models.Event) error
+	Reassign(fromGameID, toGameID int) error
+	// ListForUser возвращает события пользователя с since вместе с названиями игр, старые первыми
+	ListForUser(userID int, since time.Time) ([]models.FeedItem, error)
+	// ListRecentForUser возвращает последние события пользователя указанных типов, новые первыми
+	ListRecentForUser(userID int, types []models.EventType, limit int) ([]models.FeedItem, error)
+}
+
+type SettingsRepo interface {
+	Get(userID int) (*models.UserSettings, error)
+	Create(s *models.UserSettings) error
+	CreateDefaults(userID int) error
+	SavePreferences(s *models.UserSettings) error
+	GetByPublicSlug(slug string) (*models.UserSettings, error)
+	GetByTelegramLinkCode(code string) (*models.UserSettings, error)
+	SetTelegramLinkCode(userID int, code string, expires time.Time) error
+	SetTelegramChat(userID int, chatID int64) error
+	// ListDigestDue возвращает настройки подписанных на дайджест, которым он
+	// не отправлялся с before
+	ListDigestDue(before time.Time) ([]models.UserSettings, error)
+	SetDigestSent(userID int, at time.Time) error
+}
+
+type ImageRepo interface {
+	GetByHash(hash string) (*models.Image, error)
+	GetByFilename(filename string) (*models.Image, error)
+	Create(img *models.Image) error
+	AddRefs(id, delta int) error
+	Delete(id int) error
+	// SizeByUploader возвращает общий размер файлов, загруженных пользователем
+	SizeByUploader(userID int) (int64, error)
+}
+
+type PhotoRepo interface {
+	Get(userID int) (*models.UserPhoto, error)
+	Save(p *models.UserPhoto) error
+}
+
+type GenreRepo interface {
+	// GetOrCreate возвращает жанр с указанным slug, создавая его при необходимости
+	GetOrCreate(name, slug string) (*models.Genre, error)
+	// SetForGame заменяет жанры игры на переданные
+	SetForGame(gameID int, genreIDs []int) error
+	// TopForUser возвращает самые частые жанры активной библиотеки пользователя
+	TopForUser(userID, limit int) ([]models.GenreCount, error)
+}
+
+type PlatformRepo interface {
+	// GetOrCreate возвращает платформу с указанным slug, создавая её при необходимости
+	GetOrCreate(name, slug string) (*models.Platform, error)
+	// SetForGame заменяет платформы игры на переданные
+	SetForGame(gameID int, platformIDs []int) error
+}
+
+type CompanyRepo interface {
+	GetOrCreateDeveloper(name, slug string) (*models.Developer, error)
+	GetOrCreatePublisher(name, slug string) (*models.Publisher, error)
+	// SetDevelopers и SetPublishers заменяют компании игры на переданные
+	SetDevelopers(gameID int, developerIDs []int) error
+	SetPublishers(gameID int, publisherIDs []int) error
+	// DeveloperCounts возвращает разработчиков игр из активной библиотеки пользователя
+	// с количеством игр, от большего к меньшему
+	DeveloperCounts(userID int) ([]models.DeveloperCount, error)
+}
+
+type APITokenRepo interface {
+	Create(t *models.APIToken) error
+	GetByHash(hash string) (*models.APIToken, error)
+	ListByUser(userID int) ([]models.APIToken, error)
+	CountByUser(userID int) (int, error)
+	Touch(id int, at time.Time) error
+	// Delete удаляет токен пользователя. Чужой или несуществующий токен — storage.ErrNotFound
+	Delete(id, userID int) error
+}
+
+type WebhookRepo interface {
+	Create(w *models.Webhook) error
+	// Get возвращает вебхук пользователя. Чужой или несуществующий — storage.ErrNotFound
+	Get(id, userID int) (*models.Webhook, error)
+	GetByID(id int) (*models.Webhook, error)
+	ListByUser(userID int) ([]models.Webhook, error)
+	CountByUser(userID int) (int, error)
+	// Delete удаляет вебхук пользователя вместе с журналом отправок
+	Delete(id, userID int) error
+
+	CreateDelivery(d *models.WebhookDelivery) error
+	SaveDelivery(d *models.WebhookDelivery) error
+	// ListDue возвращает ожидающие отправки, время попытки которых наступило
+	ListDue(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// ListDeliveries возвращает последние отправки вебхука, новые первыми
+	ListDeliveries(webhookID, limit int) ([]models.WebhookDelivery, error)
+	// DeleteDeliveriesBefore удаляет завершённые отправки старше t
+	DeleteDeliveriesBefore(t time.Time) (int, error)
+}
+
+type OverrideRepo interface {
+	Get(userID, gameID int) (*models.GameOverride, error)
+	// ListForGames возвращает правки пользователя для указанных игр
+	ListForGames(userID int, gameIDs []int) ([]models.GameOverride, error)
+	Save(o *models.GameOverride) error
+	Delete(userID, gameID int) error
+	DeleteByGame(gameID int) error
+	// Reassign переносит правки с одной игры на другую. Если у пользователя
+	// уже есть правка второй игры, правка первой удаляется
+	Reassign(fromGameID, toGameID int) error
+	// ListImages возвращает имена файлов обложек из правок
+	ListImages() ([]string, error)
+}
+
+type RoleRepo interface {
+	Get(userID int) (*models.UserRole, error)
+	List() ([]models.UserRole, error)
+	Save(role *models.UserRole) error
+	Delete(userID int) error
+}
+
+type SessionRepo interface {
+	Create(s *models.Session) error
+	GetByTokenHash(hash string) (*models.Session, error)
+	// ListActive возвращает неотозванные сессии пользователя, последние использованные первыми
+	ListActive(userID int) ([]models.Session, error)
+	// Rotate переводит сессию на новый refresh-токен
+	Rotate(id int, tokenHash string, at time.Time) error
+	// Revoke отзывает сессию пользователя. Чужая, отозванная или несуществующая — storage.ErrNotFound
+	Revoke(id, userID int, at time.Time) error
+	Delete(id int) error
+	// DeleteUnusedSince удаляет сессии, которые не использовались с before
+	DeleteUnusedSince(before time.Time) (int, error)
+}
+
+type FeatureOverrideRepo interface {
+	List() ([]models.FeatureOverride, error)
+	Save(o *models.FeatureOverride) error
+	Delete(name string) error
+}
+
+type LoginAttemptRepo interface {
+	Get(keys []string) ([]models.LoginAttempt, error)
+	// ListLocked возвращает ключи, заблокированные на момент now
+	ListLocked(now time.Time) ([]models.LoginAttempt, error)
+	// Increment атомарно учитывает неудачу и возвращает счётчик; сбрасывает его,
+	// если последняя неудача была до windowStart
+	Increment(key string, now, windowStart time.Time) (int, error)
+	Lock(key string, until time.Time) error
+	Delete(keys []string) (int, error)
+	// DeleteStale удаляет счётчики без неудач после failedBefore и без действующей блокировки
+	DeleteStale(failedBefore, now time.Time) (int, error)
+}
+
+type ImportRunRepo interface {
+	// Create сохраняет запуск вместе с его неудачными названиями
+	Create(run *models.ImportRun) error
+	// Get возвращает запуск пользователя с неудачными названиями. Чужой или
+	// несуществующий — storage.ErrNotFound
+	Get(id, userID int) (*models.ImportRun, error)
+	DeleteItems(runID int) error
+}
+
+type FlagRepo interface {
+	Create(f *models.GameFlag) error
+	GetByID(id int) (*models.GameFlag, error)
+	// HasOpen сообщает, что у пользователя уже есть открытая жалоба на игру
+	HasOpen(userID, gameID int) (bool, error)
+	CountOpen(gameID int) (int, error)
+	// List возвращает жалобы в статусе status с названиями игр, старые первыми
+	List(status models.FlagStatus, limit, offset int) ([]models.GameFlag, int, error)
+	Save(f *models.GameFlag) error
+	// ResolveOpen закрывает все открытые жалобы на игру. Возвращает их количество
+	ResolveOpen(gameID int, action models.FlagAction, by int, at time.Time) (int, error)
+	Reassign(fromGameID, toGameID int) error
+}
+
+// Store объединяет репозитории и позволяет выполнить несколько операций в одной транзакции
+type Store interface {
+	Games() GameRepo
+	UserGames() UserGameRepo
+	Events() EventRepo
+	Settings() SettingsRepo
+	ImportRuns() ImportRunRepo
+	Images() ImageRepo
+	Photos() PhotoRepo
+	Genres() GenreRepo
+	Companies() CompanyRepo
+	Platforms() PlatformRepo
+	APITokens() APITokenRepo
+	Webhooks() WebhookRepo
+	Overrides() OverrideRepo
+	Roles() RoleRepo
+	Sessions() SessionRepo
+	LoginAttempts() LoginAttemptRepo
+	FeatureOverrides() FeatureOverrideRepo
+	Flags() FlagRepo
+
+	// Transaction выполняет fn в транзакции. Репозитории tx работают внутри неё;
+	// если fn вернула ошибку или запаниковала, транзакция откатывается
+	Transaction(fn func(tx Store) error) error
+	// WithContext возвращает Store, запросы которого отменяются вместе с ctx
+	WithContext(ctx context.Context) Store
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Games() GameRepo                       { return &gameRepo{db: s.db} }
+func (s *gormStore) UserGames() UserGameRepo               { return &userGameRepo{db: s.db} }
+func (s *gormStore) Events() EventRepo                     { return &eventRepo{db: s.db} }
+func (s *gormStore) Settings() SettingsRepo                { return &settingsRepo{db: s.db} }
+func (s *gormStore) ImportRuns() ImportRunRepo             { return &importRunRepo{db: s.db} }
+func (s *gormStore) Images() ImageRepo                     { return &imageRepo{db: s.db} }
+func (s *gormStore) Photos() PhotoRepo                     { return &photoRepo{db: s.db} }
+func (s *gormStore) Genres() GenreRepo                     { return &genreRepo{db: s.db} }
+func (s *gormStore) Companies() CompanyRepo                { return &companyRepo{db: s.db} }
+func (s *gormStore) Platforms() PlatformRepo               { return &platformRepo{db: s.db} }
+func (s *gormStore) APITokens() APITokenRepo               { return &apiTokenRepo{db: s.db} }
+func (s *gormStore) Webhooks() WebhookRepo                 { return &webhookRepo{db: s.db} }
+func (s *gormStore) Overrides() OverrideRepo               { return &overrideRepo{db: s.db} }
+func (s *gormStore) Roles() RoleRepo                       { return &roleRepo{db: s.db} }
+func (s *gormStore) Sessions() SessionRepo                 { return &sessionRepo{db: s.db} }
+func (s *gormStore) LoginAttempts() LoginAttemptRepo       { return &loginAttemptRepo{db: s.db} }
+func (s *gormStore) FeatureOverrides() FeatureOverrideRepo { return &featureOverrideRepo{db: s.db} }
+func (s *gormStore) Flags() FlagRepo                       { return &flagRepo{db: s.db} }
+
+func (s *gormStore) WithContext(ctx context.Context) Store {
+	return &gormStore{db: s.db.WithContext(ctx)}
+}
+
+func (s *gormStore) Transaction(fn func(tx Store) error) (err error) {
+	const op = "repository.Transaction"
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("%s: %w", op, tx.Error)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(&gormStore{db: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// replica отправляет тяжёлые списки на реплику для чтения, если реплики настроены.
+// Внутри транзакции запрос остаётся в ней
+func replica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(storage.ReplicaResolver))
+}
+
+// wrap приводит gorm.ErrRecordNotFound к storage.ErrNotFound, чтобы сервисы не зависели от GORM
+func wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
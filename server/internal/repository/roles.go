@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type roleRepo struct {
+	db *gorm.DB
+}
+
+func (r *roleRepo) Get(userID int) (*models.UserRole, error) {
+	const op = "repository.roles.Get"
+
+	var role models.UserRole
+	if err := r.db.Where("user_id = ?", userID).First(&role).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &role, nil
+}
+
+func (r *roleRepo) List() ([]models.UserRole, error) {
+	const op = "repository.roles.List"
+
+	var roles []models.UserRole
+	if err := r.db.Order("user_id").Find(&roles).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return roles, nil
+}
+
+func (r *roleRepo) Save(role *models.UserRole) error {
+	const op = "repository.roles.Save"
+	return wrap(op, r.db.Save(role).Error)
+}
+
+func (r *roleRepo) Delete(userID int) error {
+	const op = "repository.roles.Delete"
+	return wrap(op, r.db.Where("user_id = ?", userID).Delete(&models.UserRole{}).Error)
+}
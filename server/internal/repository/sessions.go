@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+type sessionRepo struct {
+	db *gorm.DB
+}
+
+func (r *sessionRepo) Create(s *models.Session) error {
+	const op = "repository.sessions.Create"
+	return wrap(op, r.db.Create(s).Error)
+}
+
+func (r *sessionRepo) GetByTokenHash(hash string) (*models.Session, error) {
+	const op = "repository.sessions.GetByTokenHash"
+
+	var s models.Session
+	if err := r.db.Where("token_hash = ?", hash).First(&s).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &s, nil
+}
+
+func (r *sessionRepo) ListActive(userID int) ([]models.Session, error) {
+	const op = "repository.sessions.ListActive"
+
+	var sessions []models.Session
+	if err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepo) Rotate(id int, tokenHash string, at time.Time) error {
+	const op = "repository.sessions.Rotate"
+	return wrap(op, r.db.Model(&models.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"token_hash":   tokenHash,
+		"last_used_at": at,
+	}).Error)
+}
+
+func (r *sessionRepo) Revoke(id, userID int, at time.Time) error {
+	const op = "repository.sessions.Revoke"
+
+	res := r.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", at)
+	if res.Error != nil {
+		return wrap(op, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return wrap(op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *sessionRepo) Delete(id int) error {
+	const op = "repository.sessions.Delete"
+	return wrap(op, r.db.Delete(&models.Session{}, id).Error)
+}
+
+func (r *sessionRepo) DeleteUnusedSince(before time.Time) (int, error) {
+	const op = "repository.sessions.DeleteUnusedSince"
+
+	res := r.db.Where("last_used_at < ?", before).Delete(&models.Session{})
+	return int(res.RowsAffected), wrap(op, res.Error)
+}
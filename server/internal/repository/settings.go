@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"time"
+
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type settingsRepo struct {
+	db *gorm.DB
+}
+
+func (r *settingsRepo) Get(userID int) (*models.UserSettings, error) {
+	const op = "repository.settings.Get"
+
+	var settings models.UserSettings
+	if err := r.db.Where("user_id = ?", userID).First(&settings).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &settings, nil
+}
+
+func (r *settingsRepo) Create(s *models.UserSettings) error {
+	const op = "repository.settings.Create"
+	return wrap(op, r.db.Create(s).Error)
+}
+
+// CreateDefaults создаёт настройки по умолчанию, если у пользователя их ещё нет.
+// Существующая запись не трогается, так что параллельные вызовы не конфликтуют
+func (r *settingsRepo) CreateDefaults(userID int) error {
+	const op = "repository.settings.CreateDefaults"
+	return wrap(op, r.db.
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).
+		Create(models.DefaultSettings(userID)).Error)
+}
+
+// SavePreferences сохраняет редактируемые пользователем поля. Служебные поля
+// (привязка Telegram, время дайджеста) не трогаются
+func (r *settingsRepo) SavePreferences(s *models.UserSettings) error {
+	const op = "repository.settings.SavePreferences"
+	return wrap(op, r.db.Model(&models.UserSettings{}).
+		Where("user_id = ?", s.UserID).
+		Updates(map[string]interface{}{
+			"priority_aging":     s.PriorityAging,
+			"discord_notify":     s.DiscordNotify,
+			"weekly_digest":      s.WeeklyDigest,
+			"default_sort":       s.DefaultSort,
+			"default_sort_order": s.DefaultSortOrder,
+			"default_page_size":  s.DefaultPageSize,
+			"visibility":         s.Visibility,
+			"language":           s.Language,
+			"public_slug":        s.PublicSlug,
+		}).Error)
+}
+
+func (r *settingsRepo) GetByPublicSlug(slug string) (*models.UserSettings, error) {
+	const op = "repository.settings.GetByPublicSlug"
+
+	var settings models.UserSettings
+	if err := r.db.Where("public_slug = ?", slug).First(&settings).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &settings, nil
+}
+
+func (r *settingsRepo) GetByTelegramLinkCode(code string) (*models.UserSettings, error) {
+	const op = "repository.settings.GetByTelegramLinkCode"
+
+	var settings models.UserSettings
+	if err := r.db.Where("telegram_link_code = ?", code).First(&settings).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &settings, nil
+}
+
+func (r *settingsRepo) SetTelegramLinkCode(userID int, code string, expires time.Time) error {
+	const op = "repository.settings.SetTelegramLinkCode"
+	return wrap(op, r.db.Model(&models.UserSettings{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"telegram_link_code":    code,
+			"telegram_link_expires": expires,
+		}).Error)
+}
+
+// SetTelegramChat привязывает чат (0 — отвязывает) и сбрасывает код привязки
+func (r *settingsRepo) SetTelegramChat(userID int, chatID int64) error {
+	const op = "repository.settings.SetTelegramChat"
+	return wrap(op, r.db.Model(&models.UserSettings{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"telegram_chat_id":      chatID,
+			"telegram_link_code":    "",
+			"telegram_link_expires": nil,
+		}).Error)
+}
+
+func (r *settingsRepo) ListDigestDue(before time.Time) ([]models.UserSettings, error) {
+	const op = "repository.settings.ListDigestDue"
+
+	var settings []models.UserSettings
+	if err := r.db.
+		Where("weekly_digest = ?", true).
+		Where("digest_sent_at IS NULL OR digest_sent_at < ?", before).
+		Order("user_id").
+		Find(&settings).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return settings, nil
+}
+
+func (r *settingsRepo) SetDigestSent(userID int, at time.Time) error {
+	const op = "repository.settings.SetDigestSent"
+	return wrap(op, r.db.Model(&models.UserSettings{}).
+		Where("user_id = ?", userID).
+		Update("digest_sent_at", at).Error)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+type apiTokenRepo struct {
+	db *gorm.DB
+}
+
+func (r *apiTokenRepo) Create(t *models.APIToken) error {
+	const op = "repository.tokens.Create"
+	return wrap(op, r.db.Create(t).Error)
+}
+
+func (r *apiTokenRepo) GetByHash(hash string) (*models.APIToken, error) {
+	const op = "repository.tokens.GetByHash"
+
+	var t models.APIToken
+	if err := r.db.Where("hash = ?", hash).First(&t).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &t, nil
+}
+
+func (r *apiTokenRepo) ListByUser(userID int) ([]models.APIToken, error) {
+	const op = "repository.tokens.ListByUser"
+
+	var tokens []models.APIToken
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&tokens).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return tokens, nil
+}
+
+func (r *apiTokenRepo) CountByUser(userID int) (int, error) {
+	const op = "repository.tokens.CountByUser"
+
+	var count int64
+	if err := r.db.Model(&models.APIToken{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, wrap(op, err)
+	}
+	return int(count), nil
+}
+
+func (r *apiTokenRepo) Touch(id int, at time.Time) error {
+	const op = "repository.tokens.Touch"
+	return wrap(op, r.db.Model(&models.APIToken{}).Where("id = ?", id).Update("last_used_at", at).Error)
+}
+
+func (r *apiTokenRepo) Delete(id, userID int) error {
+	const op = "repository.tokens.Delete"
+
+	res := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIToken{})
+	if res.Error != nil {
+		return wrap(op, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return wrap(op, storage.ErrNotFound)
+	}
+	return nil
+}
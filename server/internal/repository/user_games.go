@@ -0,0 +1,557 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"games_webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type userGameRepo struct {
+	db *gorm.DB
+}
+
+// libraryColumn — поле записи библиотеки, которое попадает в UserGameResponse.
+// zero подставляется, когда игры нет в библиотеке (LEFT JOIN в Catalog).
+// as — имя в ответе, если оно не совпадает с именем колонки
+type libraryColumn struct {
+	name string
+	zero string
+	as   string
+}
+
+var libraryColumns = []libraryColumn{
+	{"priority", "0", ""},
+	{"status", "''", ""},
+	{"notes", "''", ""},
+	{"is_favorite", "false", ""},
+	{"completion_percent", "0", ""},
+	{"achievements_done", "0", ""},
+	{"achievements_total", "0", ""},
+	{"platform", "''", ""},
+	{"created_at", "NULL", "added_at"},
+	{"updated_at", "NULL", "entry_updated_at"},
+}
+
+// librarySelect возвращает колонки user_games для SELECT. С withDefaults
+// пустые значения заменяются нулевыми. Колонки без нулевого значения идут
+// как есть: COALESCE с NULL ничего не меняет, а SQLite теряет на нём тип
+// колонки и отдаёт время строкой
+func librarySelect(withDefaults bool) []string {
+	cols := make([]string, len(libraryColumns))
+	for i, c := range libraryColumns {
+		as := c.as
+		if as == "" {
+			as = c.name
+		}
+		switch {
+		case withDefaults && c.zero != "NULL":
+			cols[i] = fmt.Sprintf("COALESCE(user_games.%s, %s) as %s", c.name, c.zero, as)
+		case c.as != "":
+			cols[i] = fmt.Sprintf("user_games.%s as %s", c.name, as)
+		default:
+			cols[i] = "user_games." + c.name
+		}
+	}
+	return cols
+}
+
+func (r *userGameRepo) GetActive(userID, gameID int) (*models.UserGames, error) {
+	const op = "repository.user_games.GetActive"
+
+	var ug models.UserGames
+	if err := r.db.
+		Where("user_id = ? AND game_id = ? AND is_active = ?", userID, gameID, true).
+		First(&ug).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &ug, nil
+}
+
+func (r *userGameRepo) GetPlaythrough(id, userID, gameID int) (*models.UserGames, error) {
+	const op = "repository.user_games.GetPlaythrough"
+
+	var ug models.UserGames
+	if err := r.db.
+		Where("id = ? AND user_id = ? AND game_id = ?", id, userID, gameID).
+		First(&ug).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &ug, nil
+}
+
+func (r *userGameRepo) ListPlaythroughs(userID, gameID int) ([]models.UserGames, error) {
+	const op = "repository.user_games.ListPlaythroughs"
+
+	var playthroughs []models.UserGames
+	if err := r.db.
+		Where("user_id = ? AND game_id = ?", userID, gameID).
+		Order("id asc").
+		Find(&playthroughs).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return playthroughs, nil
+}
+
+// Exists проверяет, есть ли у пользователя хоть одно прохождение игры
+func (r *userGameRepo) Exists(userID, gameID int) (bool, error) {
+	const op = "repository.user_games.Exists"
+
+	var count int64
+	if err := r.db.
+		Model(&models.UserGames{}).
+		Where("user_id = ? AND game_id = ?", userID, gameID).
+		Count(&count).Error; err != nil {
+		return false, wrap(op, err)
+	}
+	return count > 0, nil
+}
+
+func (r *userGameRepo) Create(ug *models.UserGames) error {
+	const op = "repository.user_games.Create"
+	return wrap(op, r.db.Create(ug).Error)
+}
+
+func (r *userGameRepo) Save(ug *models.UserGames) error {
+	const op = "repository.user_games.Save"
+	return wrap(op, r.db.Save(ug).Error)
+}
+
+// UpdateProgress обновляет метку, статус, приоритет, количество сессий, прогресс
+// и историю прохождения (даты, оценку, заметки)
+func (r *userGameRepo) UpdateProgress(ug *models.UserGames) error {
+	const op = "repository.user_games.UpdateProgress"
+	return wrap(op, r.db.Model(&models.UserGames{}).Where("id = ?", ug.ID).Updates(map[string]interface{}{
+		"label":              ug.Label,
+		"status":             ug.Status,
+		"priority":           ug.Priority,
+		"sessions":           ug.Sessions,
+		"completion_percent": ug.CompletionPercent,
+		"achievements_done":  ug.AchievementsDone,
+		"achievements_total": ug.AchievementsTotal,
+		"rating":             ug.Rating,
+		"started_at":         ug.StartedAt,
+		"finished_at":        ug.FinishedAt,
+		"notes":              ug.Notes,
+		"platform":           ug.Platform,
+	}).Error)
+}
+
+func (r *userGameRepo) SetNotes(id int, notes string) error {
+	const op = "repository.user_games.SetNotes"
+	return wrap(op, r.db.Model(&models.UserGames{}).Where("id = ?", id).Update("notes", notes).Error)
+}
+
+func (r *userGameRepo) SetFavorite(id int, favorite bool) error {
+	const op = "repository.user_games.SetFavorite"
+	return wrap(op, r.db.Model(&models.UserGames{}).Where("id = ?", id).Update("is_favorite", favorite).Error)
+}
+
+func (r *userGameRepo) ListRanked(userID int, status models.GameStatus, maxPriority int) ([]models.UserGames, error) {
+	const op = "repository.user_games.ListRanked"
+
+	var ranked []models.UserGames
+	if err := r.db.
+		Where("user_id = ? AND status = ? AND is_active = ? AND priority BETWEEN 1 AND ?", userID, status, true, maxPriority).
+		Order("priority desc, updated_at desc").
+		Find(&ranked).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return ranked, nil
+}
+
+func (r *userGameRepo) SetPriority(id, priority int) error {
+	const op = "repository.user_games.SetPriority"
+	return wrap(op, r.db.Model(&models.UserGames{}).Where("id = ?", id).Update("priority", priority).Error)
+}
+
+func (r *userGameRepo) ShiftDown(ids []int) error {
+	const op = "repository.user_games.ShiftDown"
+
+	if len(ids) == 0 {
+		return nil
+	}
+	return wrap(op, r.db.Model(&models.UserGames{}).
+		Where("id IN ?", ids).
+		Update("priority", gorm.Expr("priority - 1")).Error)
+}
+
+func (r *userGameRepo) ResetPriorities(userID int, status models.GameStatus) error {
+	const op = "repository.user_games.ResetPriorities"
+	return wrap(op, r.db.Model(&models.UserGames{}).
+		Where("user_id = ? AND status = ? AND is_active = ? AND priority > 0", userID, status, true).
+		Update("priority", 0).Error)
+}
+
+func (r *userGameRepo) ListPriorityClashes() ([]models.PriorityScope, error) {
+	const op = "repository.user_games.ListPriorityClashes"
+
+	var scopes []models.PriorityScope
+	if err := r.db.
+		Model(&models.UserGames{}).
+		Distinct("user_id", "status").
+		Where("is_active = ? AND priority > 0", true).
+		Group("user_id, status, priority").
+		Having("COUNT(*) > 1").
+		Find(&scopes).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return scopes, nil
+}
+
+func (r *userGameRepo) Activate(id int) error {
+	const op = "repository.user_games.Activate"
+	return wrap(op, r.db.Model(&models.UserGames{}).Where("id = ?", id).Update("is_active", true).Error)
+}
+
+func (r *userGameRepo) DeactivateAll(userID, gameID int) error {
+	const op = "repository.user_games.DeactivateAll"
+	return wrap(op, r.db.Model(&models.UserGames{}).
+		Where("user_id = ? AND game_id = ?", userID, gameID).
+		Update("is_active", false).Error)
+}
+
+func (r *userGameRepo) Delete(userID, gameID int) error {
+	const op = "repository.user_games.Delete"
+	return wrap(op, r.db.Where("user_id = ? AND game_id = ?", userID, gameID).Delete(&models.UserGames{}).Error)
+}
+
+func (r *userGameRepo) DeletePlaythrough(id int) error {
+	const op = "repository.user_games.DeletePlaythrough"
+	return wrap(op, r.db.Where("id = ?", id).Delete(&models.UserGames{}).Error)
+}
+
+func (r *userGameRepo) DeleteByGame(gameID int) error {
+	const op = "repository.user_games.DeleteByGame"
+	return wrap(op, r.db.Where("game_id = ?", gameID).Delete(&models.UserGames{}).Error)
+}
+
+func (r *userGameRepo) StatusCounts(userID int) (*models.StatusCounts, error) {
+	const op = "repository.user_games.StatusCounts"
+
+	var counts models.StatusCounts
+	if err := r.db.
+		Model(&models.UserGames{}).
+		Select(`COUNT(*) AS total,
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS finished,
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS playing,
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS planned,
+			COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS dropped,
+			COALESCE(SUM(CASE WHEN is_favorite = ? THEN 1 ELSE 0 END), 0) AS favorites,
+			COALESCE(AVG(CASE WHEN completion_percent > 0 THEN completion_percent END), 0) AS average_completion`,
+			models.StatusFinished, models.StatusPlaying, models.StatusPlanned, models.StatusDropped, true).
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Scan(&counts).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &counts, nil
+}
+
+func (r *userGameRepo) FinishedSince(userID int, since time.Time) ([]time.Time, error) {
+	const op = "repository.user_games.FinishedSince"
+
+	var dates []time.Time
+	if err := r.db.
+		Model(&models.UserGames{}).
+		Where("user_id = ? AND status = ? AND finished_at >= ?", userID, models.StatusFinished, since).
+		Pluck("finished_at", &dates).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return dates, nil
+}
+
+func (r *userGameRepo) FinishSpans(userID int) ([]models.FinishSpan, error) {
+	const op = "repository.user_games.FinishSpans"
+
+	// Без агрегатов: MIN по дате в SQLite возвращает строку, а не время,
+	// поэтому самое раннее добавление выбирается уже в Go
+	var rows []models.FinishSpan
+	if err := r.db.
+		Table("user_games").
+		Select("user_games.id AS user_game_id, events.created_at AS added_at, user_games.finished_at").
+		Joins("JOIN events ON events.user_id = user_games.user_id AND events.game_id = user_games.game_id AND events.type = ?", models.EventGameAdded).
+		Where("user_games.user_id = ? AND user_games.is_active = ? AND user_games.status = ?", userID, true, models.StatusFinished).
+		Where("user_games.finished_at IS NOT NULL").
+		Scan(&rows).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+
+	earliest := make(map[int]int, len(rows))
+	spans := make([]models.FinishSpan, 0, len(rows))
+	for _, row := range rows {
+		if i, ok := earliest[row.UserGameID]; ok {
+			if row.AddedAt.Before(spans[i].AddedAt) {
+				spans[i].AddedAt = row.AddedAt
+			}
+			continue
+		}
+		earliest[row.UserGameID] = len(spans)
+		spans = append(spans, row)
+	}
+	return spans, nil
+}
+
+func (r *userGameRepo) CountOtherUsers(gameID, exceptUserID int) (int, error) {
+	const op = "repository.user_games.CountOtherUsers"
+
+	var count int64
+	if err := r.db.
+		Model(&models.UserGames{}).
+		Where("game_id = ? AND user_id <> ?", gameID, exceptUserID).
+		Distinct("user_id").
+		Count(&count).Error; err != nil {
+		return 0, wrap(op, err)
+	}
+	return int(count), nil
+}
+
+func (r *userGameRepo) UsersOf(gameID int) ([]int, error) {
+	const op = "repository.user_games.UsersOf"
+
+	var users []int
+	if err := r.db.Model(&models.UserGames{}).
+		Where("game_id = ?", gameID).
+		Distinct().
+		Pluck("user_id", &users).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return users, nil
+}
+
+func (r *userGameRepo) Reassign(fromGameID, toGameID int, deactivateFor []int) error {
+	const op = "repository.user_games.Reassign"
+
+	if len(deactivateFor) > 0 {
+		if err := r.db.Model(&models.UserGames{}).
+			Where("game_id = ? AND user_id IN ?", fromGameID, deactivateFor).
+			Updates(map[string]interface{}{"game_id": toGameID, "is_active": false}).Error; err != nil {
+			return wrap(op, err)
+		}
+	}
+
+	return wrap(op, r.db.Model(&models.UserGames{}).
+		Where("game_id = ?", fromGameID).
+		Update("game_id", toGameID).Error)
+}
+
+// library — активные записи пользователя вместе с данными игр
+func (r *userGameRepo) library(userID int) *gorm.DB {
+	return r.db.
+		Table("games").
+		Select(append([]string{"games.*"}, librarySelect(false)...)).
+		Joins("JOIN user_games ON user_games.game_id = games.id").
+		Where("user_games.user_id = ? AND user_games.is_active = ?", userID, true)
+}
+
+// filteredLibrary — библиотека пользователя с фильтрами q, без сортировки и пагинации
+func (r *userGameRepo) filteredLibrary(q LibraryQuery) *gorm.DB {
+	db := replica(r.library(q.UserID))
+
+	if q.Status != nil {
+		db = db.Where("user_games.status = ?", q.Status)
+	}
+
+	if q.Search != "" {
+		pattern := "%" + strings.ToLower(q.Search) + "%"
+		db = db.Where("LOWER(games.title) LIKE ? OR LOWER(games.title_en) LIKE ?", pattern, pattern)
+	}
+	return applyGameFilter(db, q.Filter)
+}
+
+// pageLibrary добавляет к запросу сортировку и пагинацию q
+func pageLibrary(db *gorm.DB, q LibraryQuery) *gorm.DB {
+	allowedSort := map[string]string{
+		"title":    "games.title",
+		"year":     "games.release_date",
+		"priority": "user_games.priority",
+		"added":    "user_games.created_at",
+	}
+
+	// favorite — избранные игры первыми, внутри групп по названию
+	if q.SortBy == "favorite" {
+		db = db.Order("user_games.is_favorite DESC")
+	}
+	// Игры без даты выхода — в конце при любом направлении сортировки
+	if q.SortBy == "year" {
+		db = db.Order("games.release_date IS NULL")
+	}
+
+	return db.
+		Order(orderBy(allowedSort, q.SortBy, q.SortOrder)).
+		Offset(q.Offset).
+		Limit(q.Limit)
+}
+
+func (r *userGameRepo) Library(q LibraryQuery) ([]models.UserGameResponse, int, error) {
+	const op = "repository.user_games.Library"
+
+	var results []models.UserGameResponse
+	var count int64
+
+	db := r.filteredLibrary(q)
+	if err := db.Count(&count).Error; err != nil {
+		return nil, 0, wrap(op, err)
+	}
+
+	if err := pageLibrary(db, q).Find(&results).Error; err != nil {
+		return nil, 0, wrap(op, err)
+	}
+
+	return results, int(count), nil
+}
+
+func (r *userGameRepo) LibraryEntries(q LibraryQuery) ([]models.LibraryEntry, int, error) {
+	const op = "repository.user_games.LibraryEntries"
+
+	var results []models.LibraryEntry
+	var count int64
+
+	db := r.filteredLibrary(q)
+	if err := db.Count(&count).Error; err != nil {
+		return nil, 0, wrap(op, err)
+	}
+
+	// Сводка считается по всем прохождениям, а не только по активному
+	summary := r.db.Model(&models.UserGames{}).
+		Select(
+			"game_id, COUNT(*) AS pt_count, "+
+				"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS pt_finished, "+
+				"AVG(CASE WHEN rating > 0 THEN rating END) AS pt_rating",
+			models.StatusFinished).
+		Where("user_id = ?", q.UserID).
+		Group("game_id")
+
+	db = db.
+		Select(append(append([]string{"games.*"}, librarySelect(false)...),
+			"COALESCE(pt.pt_count, 0) AS pt_count",
+			"COALESCE(pt.pt_finished, 0) AS pt_finished",
+			"COALESCE(pt.pt_rating, 0) AS pt_rating")).
+		Joins("LEFT JOIN (?) AS pt ON pt.game_id = games.id", summary)
+
+	if err := pageLibrary(db, q).Scan(&results).Error; err != nil {
+		return nil, 0, wrap(op, err)
+	}
+	if len(results) == 0 {
+		return results, int(count), nil
+	}
+
+	ids := make([]int, len(results))
+	for i := range results {
+		ids[i] = results[i].ID
+	}
+
+	var links []struct {
+		GameID int
+		models.Genre
+	}
+	if err := replica(r.db).Table("genres").
+		Select("game_genres.game_id, genres.*").
+		Joins("JOIN game_genres ON game_genres.genre_id = genres.id").
+		Where("game_genres.game_id IN ?", ids).
+		Order("genres.name").
+		Scan(&links).Error; err != nil {
+		return nil, 0, wrap(op, err)
+	}
+
+	genres := make(map[int][]models.Genre, len(results))
+	for _, l := range links {
+		genres[l.GameID] = append(genres[l.GameID], l.Genre)
+	}
+	for i := range results {
+		results[i].Genres = genres[results[i].ID]
+		if results[i].Genres == nil {
+			results[i].Genres = []models.Genre{}
+		}
+	}
+
+	return results, int(count), nil
+}
+
+func (r *userGameRepo) ListLibrary(userID int) ([]models.UserGameResponse, error) {
+	const op = "repository.user_games.ListLibrary"
+
+	var results []models.UserGameResponse
+	if err := r.library(userID).Order("games.id asc").Scan(&results).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return results, nil
+}
+
+func (r *userGameRepo) ListStale(userID int, olderThan time.Time) ([]models.UserGameResponse, error) {
+	const op = "repository.user_games.ListStale"
+
+	var results []models.UserGameResponse
+	if err := r.library(userID).
+		Where("user_games.status = ?", models.StatusPlanned).
+		Where("user_games.stale = ? OR user_games.updated_at < ?", true, olderThan).
+		Order("user_games.updated_at asc").
+		Scan(&results).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return results, nil
+}
+
+func (r *userGameRepo) ListUpcoming(userID int, from, to time.Time) ([]models.UserGameResponse, error) {
+	const op = "repository.user_games.ListUpcoming"
+
+	var results []models.UserGameResponse
+	if err := r.library(userID).
+		Where("user_games.status = ?", models.StatusPlanned).
+		Where("games.release_date >= ? AND games.release_date < ?", from, to).
+		Where("games.release_precision = ?", models.PrecisionDay).
+		Order("games.release_date asc, games.title asc").
+		Scan(&results).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return results, nil
+}
+
+func (r *userGameRepo) Age(olderThan time.Time, decay bool) ([]models.AgedBacklog, error) {
+	const op = "repository.user_games.Age"
+
+	optedIn := r.db.
+		Model(&models.UserSettings{}).
+		Select("user_id").
+		Where("priority_aging = ?", true)
+
+	candidates := func() *gorm.DB {
+		db := r.db.
+			Model(&models.UserGames{}).
+			Where("user_id IN (?)", optedIn).
+			Where("is_active = ? AND status = ?", true, models.StatusPlanned).
+			Where("COALESCE(aged_at, updated_at) < ?", olderThan)
+		if decay {
+			return db.Where("priority > 0 OR stale = ?", false)
+		}
+		return db.Where("stale = ?", false)
+	}
+
+	var aged []models.AgedBacklog
+	if err := candidates().
+		Select("user_id, COUNT(*) AS games").
+		Group("user_id").
+		Find(&aged).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	if len(aged) == 0 {
+		return nil, nil
+	}
+
+	updates := map[string]interface{}{
+		"stale":   true,
+		"aged_at": time.Now(),
+	}
+	if decay {
+		updates["priority"] = gorm.Expr("CASE WHEN priority > 0 THEN priority - 1 ELSE 0 END")
+	}
+
+	// UpdateColumns не трогает updated_at: понижение приоритета не считается действием пользователя
+	if err := candidates().UpdateColumns(updates).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+
+	return aged, nil
+}
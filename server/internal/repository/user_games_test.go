@@ -0,0 +1,108 @@
+package repository_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// mockStore — репозиторий поверх sqlmock с диалектом MariaDB
+func mockStore(t *testing.T) (repository.Store, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gdb, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repository.New(gdb), mock
+}
+
+var statusCountsQuery = regexp.QuoteMeta("SELECT COUNT(*) AS total") + ".*" + regexp.QuoteMeta("FROM `user_games` WHERE user_id = ? AND is_active = ?")
+
+var statusCountsColumns = []string{"total", "finished", "playing", "planned", "dropped", "favorites", "average_completion"}
+
+func TestStatusCountsAllStatuses(t *testing.T) {
+	store, mock := mockStore(t)
+
+	mock.ExpectQuery(statusCountsQuery).
+		WithArgs(models.StatusFinished, models.StatusPlaying, models.StatusPlanned, models.StatusDropped, true, 7, true).
+		WillReturnRows(sqlmock.NewRows(statusCountsColumns).AddRow(10, 4, 3, 2, 1, 5, 62.5))
+
+	counts, err := store.UserGames().StatusCounts(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := models.StatusCounts{Total: 10, Finished: 4, Playing: 3, Planned: 2, Dropped: 1, Favorites: 5, AverageCompletion: 62.5}
+	if *counts != want {
+		t.Errorf("counts = %+v, want %+v", *counts, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatusCountsMissingStatusesAreZero(t *testing.T) {
+	store, mock := mockStore(t)
+
+	// Пустая библиотека: COALESCE отдаёт 0 вместо NULL у SUM и AVG
+	mock.ExpectQuery(statusCountsQuery).
+		WillReturnRows(sqlmock.NewRows(statusCountsColumns).AddRow(0, 0, 0, 0, 0, 0, 0))
+
+	counts, err := store.UserGames().StatusCounts(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *counts != (models.StatusCounts{}) {
+		t.Errorf("counts = %+v, want all zero", *counts)
+	}
+
+	// Статусов, которых нет в ответе, в счётчиках тоже нет
+	mock.ExpectQuery(statusCountsQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"total", "playing"}).AddRow(2, 2))
+
+	counts, err = store.UserGames().StatusCounts(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.StatusCounts{Total: 2, Playing: 2}
+	if *counts != want {
+		t.Errorf("counts = %+v, want %+v", *counts, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatusCountsDBError(t *testing.T) {
+	store, mock := mockStore(t)
+
+	dbErr := errors.New("connection reset")
+	mock.ExpectQuery(statusCountsQuery).WillReturnError(dbErr)
+
+	counts, err := store.UserGames().StatusCounts(7)
+	if !errors.Is(err, dbErr) {
+		t.Fatalf("err = %v, want %v", err, dbErr)
+	}
+	if counts != nil {
+		t.Errorf("counts = %+v, want nil", *counts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
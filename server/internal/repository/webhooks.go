@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+type webhookRepo struct {
+	db *gorm.DB
+}
+
+func (r *webhookRepo) Create(w *models.Webhook) error {
+	const op = "repository.webhooks.Create"
+	return wrap(op, r.db.Create(w).Error)
+}
+
+func (r *webhookRepo) Get(id, userID int) (*models.Webhook, error) {
+	const op = "repository.webhooks.Get"
+
+	var w models.Webhook
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&w).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &w, nil
+}
+
+func (r *webhookRepo) GetByID(id int) (*models.Webhook, error) {
+	const op = "repository.webhooks.GetByID"
+
+	var w models.Webhook
+	if err := r.db.First(&w, id).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return &w, nil
+}
+
+func (r *webhookRepo) ListByUser(userID int) ([]models.Webhook, error) {
+	const op = "repository.webhooks.ListByUser"
+
+	var hooks []models.Webhook
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&hooks).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return hooks, nil
+}
+
+func (r *webhookRepo) CountByUser(userID int) (int, error) {
+	const op = "repository.webhooks.CountByUser"
+
+	var count int64
+	if err := r.db.Model(&models.Webhook{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, wrap(op, err)
+	}
+	return int(count), nil
+}
+
+func (r *webhookRepo) Delete(id, userID int) error {
+	const op = "repository.webhooks.Delete"
+
+	res := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Webhook{})
+	if res.Error != nil {
+		return wrap(op, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return wrap(op, storage.ErrNotFound)
+	}
+	return wrap(op, r.db.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error)
+}
+
+func (r *webhookRepo) CreateDelivery(d *models.WebhookDelivery) error {
+	const op = "repository.webhooks.CreateDelivery"
+	return wrap(op, r.db.Create(d).Error)
+}
+
+func (r *webhookRepo) SaveDelivery(d *models.WebhookDelivery) error {
+	const op = "repository.webhooks.SaveDelivery"
+	return wrap(op, r.db.Save(d).Error)
+}
+
+func (r *webhookRepo) ListDue(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	const op = "repository.webhooks.ListDue"
+
+	var deliveries []models.WebhookDelivery
+	if err := r.db.
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepo) ListDeliveries(webhookID, limit int) ([]models.WebhookDelivery, error) {
+	const op = "repository.webhooks.ListDeliveries"
+
+	var deliveries []models.WebhookDelivery
+	if err := r.db.
+		Where("webhook_id = ?", webhookID).
+		Order("id desc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, wrap(op, err)
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepo) DeleteDeliveriesBefore(t time.Time) (int, error) {
+	const op = "repository.webhooks.DeleteDeliveriesBefore"
+
+	res := r.db.Where("status <> ? AND created_at < ?", models.DeliveryPending, t).Delete(&models.WebhookDelivery{})
+	if res.Error != nil {
+		return 0, wrap(op, res.Error)
+	}
+	return int(res.RowsAffected), nil
+}
@@ -1,16 +1,28 @@
 package routes
 
 import (
-	"encoding/json"
+	"context"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"games_webapp/internal/clients/clamav"
+	"games_webapp/internal/clients/discord"
+	"games_webapp/internal/clients/igdb"
+	"games_webapp/internal/clients/telegram"
 	"games_webapp/internal/config"
 	"games_webapp/internal/controllers"
+	"games_webapp/internal/features"
+	"games_webapp/internal/lib/signer"
+	"games_webapp/internal/lifecycle"
+	"games_webapp/internal/metadata"
 	games_middleware "games_webapp/internal/middleware"
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
 	"games_webapp/internal/services"
-	"games_webapp/internal/storage/mariadb"
+	"games_webapp/internal/storage"
 	"games_webapp/internal/storage/uploads"
+	"games_webapp/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -21,73 +33,378 @@ import (
 
 func SetupRouter(
 	log *slog.Logger,
-	storage *mariadb.Storage,
+	storage storage.Storage,
 	uploads *uploads.Uploads,
+	photos *uploads.Uploads,
 	authMiddleware *games_middleware.AuthMiddleware,
 	ssoClient *ssogrpc.Client,
+	lc *lifecycle.Manager,
 	cfg *config.Config,
+	reloader *config.Reloader,
 ) *chi.Mux {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
+	origins := games_middleware.NewOrigins(cfg.Cors)
+	extensionOrigins := games_middleware.NewOrigins(cfg.ExtensionCors)
+	reloader.OnReload("cors", func(c *config.Config) error {
+		origins.Set(c.Cors)
+		extensionOrigins.Set(c.ExtensionCors)
+		return nil
+	})
 
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   cfg.Cors,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
+	accessLog := games_middleware.NewAccessLog(log, AccessLogPolicy(cfg.AccessLog))
+	reloader.OnReload("access_log", func(c *config.Config) error {
+		accessLog.SetPolicy(AccessLogPolicy(c.AccessLog))
+		return nil
+	})
+	r.Use(tracing.Middleware)
+	r.Use(accessLog.Handler)
+	if cfg.TLS.Enabled() && cfg.TLS.HSTS {
+		r.Use(games_middleware.HSTS(cfg.TLS.HSTSMaxAge, cfg.TLS.HSTSSubdomains))
+	}
 
-	gameService := services.NewGameService(storage, log)
-	gameController := controllers.NewGameController(gameService, log, uploads, cfg.TwitchClientId, cfg.TwitchClientSecret)
+	// Расширения и букмарклеты ходят только в /api/quick-add и со своей политикой:
+	// без cookies, поэтому чужой сайт не сможет действовать от имени пользователя
+	r.Use(games_middleware.CORSFor("/api/quick-add",
+		cors.Handler(cors.Options{
+			AllowOriginFunc:  extensionOrigins.Allow,
+			AllowedMethods:   []string{"POST", "OPTIONS"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+			AllowCredentials: false,
+			MaxAge:           600,
+		}),
+		cors.Handler(cors.Options{
+			AllowOriginFunc:  origins.Allow,
+			AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", games_middleware.MethodOverrideHeader},
+			ExposedHeaders:   []string{"ETag"},
+			AllowCredentials: true,
+			MaxAge:           300,
+		}),
+	))
 
-	authController := controllers.NewAuthController(log, ssoClient, uploads)
+	r.Use(games_middleware.MethodOverride)
+	r.Use(games_middleware.BodyLimit(cfg.MaxJSONBody, cfg.MaxMultipartBody,
+		games_middleware.RouteLimit{Method: http.MethodPost, Path: "/api/games", Limit: controllers.MaxCreateJSONBody},
+		games_middleware.RouteLimit{Method: http.MethodPut, Path: "/api/games/*", Limit: controllers.MaxCreateJSONBody}))
+	r.Use(middleware.GetHead)
+	r.Use(games_middleware.Language)
 
-	r.Route("/api", func(r chi.Router) {
-		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-			response := map[string]interface{}{
-				"status": "ok",
-			}
-			w.Header().Set("Content-Type", "application/json")
-			if err := json.NewEncoder(w).Encode(response); err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Ставятся до регистрации маршрутов, чтобы их унаследовали вложенные роутеры
+	r.NotFound(controllers.NotFound)
+	r.MethodNotAllowed(controllers.MethodNotAllowed(r))
+
+	gameService := services.NewGameService(repository.New(storage.DB()), log)
+	if cfg.Discord.WebhookURL != "" {
+		notifier, err := services.NewDiscordNotifier(repository.New(storage.DB()), log,
+			discord.New(cfg.Discord.WebhookURL, cfg.Discord.Timeout), lc,
+			cfg.Discord.FinishedTemplate, cfg.Discord.ImportTemplate)
+		if err != nil {
+			log.Error("failed to set up discord notifications", slog.String("error", err.Error()))
+		} else {
+			gameService.UseNotifier(notifier)
+		}
+	}
+	var telegramController *controllers.TelegramController
+	if cfg.Telegram.Token != "" {
+		telegramService := services.NewTelegramService(repository.New(storage.DB()), log,
+			telegram.New(cfg.Telegram.Token, cfg.Telegram.Timeout), lc, cfg.Telegram.BotName)
+		gameService.UseNotifier(telegramService)
+		telegramController = controllers.NewTelegramController(telegramService, log)
+	}
+	if err := gameService.LoadURLFilter(); err != nil {
+		log.Error("failed to load url filter", slog.String("error", err.Error()))
+	}
+	if err := gameService.BackfillTitleKeys(); err != nil {
+		log.Error("failed to backfill title keys", slog.String("error", err.Error()))
+	}
+	if err := gameService.BackfillSources(); err != nil {
+		log.Error("failed to backfill game sources", slog.String("error", err.Error()))
+	}
+	if err := gameService.BackfillReleaseDates(); err != nil {
+		log.Error("failed to backfill release dates", slog.String("error", err.Error()))
+	}
+	if err := gameService.BackfillGenres(); err != nil {
+		log.Error("failed to backfill genres", slog.String("error", err.Error()))
+	}
+	if err := gameService.BackfillCompanies(); err != nil {
+		log.Error("failed to backfill developers and publishers", slog.String("error", err.Error()))
+	}
+	if err := gameService.BackfillPriorityLists(); err != nil {
+		log.Error("failed to backfill priority lists", slog.String("error", err.Error()))
+	}
+	igdbClient := igdb.New(log, cfg.TwitchClientId, cfg.TwitchClientSecret)
+	gameController := controllers.NewGameController(gameService, log, uploads, igdbClient, lc)
+	metadataChain := MetadataChain(log, cfg, igdbClient)
+	gameController.UseMetadata(metadataChain)
+	gameController.UseImportLimits(controllers.ImportLimits{
+		Workers:     cfg.Import.Workers,
+		ItemTimeout: cfg.Import.ItemTimeout,
+		Budget:      cfg.Import.Budget,
+	})
+
+	if cfg.Antivirus.Enabled {
+		scanner := clamav.New(cfg.Antivirus.Address, cfg.Antivirus.Timeout)
+		if err := scanner.Ping(context.Background()); err != nil {
+			log.Warn("clamd is not reachable, uploads will be rejected until it is",
+				slog.String("address", cfg.Antivirus.Address), slog.Bool("fail_open", cfg.Antivirus.FailOpen),
+				slog.String("error", err.Error()))
+		}
+		uploads.UseScanner(scanner, ScanPolicy(cfg.Antivirus, log))
+		photos.UseScanner(scanner, ScanPolicy(cfg.Antivirus, log))
+		log.Info("antivirus scanning enabled", slog.String("address", cfg.Antivirus.Address))
+	}
+
+	photoSigner := signer.New(cfg.Photos.Secret, cfg.Photos.TTL)
+	authController := controllers.NewAuthController(log, ssoClient, photos, photoSigner)
+	photoController := controllers.NewPhotoController(photos, uploads, photoSigner, log)
+
+	quotaService := services.NewQuotaService(repository.New(storage.DB()), log, cfg.UploadQuota.Limit())
+	reloader.OnReload("upload_quota", func(c *config.Config) error {
+		quotaService.SetLimit(c.UploadQuota.Limit())
+		return nil
+	})
+	gameController.UseUploadQuota(quotaService)
+	authController.UseUploadQuota(quotaService)
+	quotaController := controllers.NewQuotaController(quotaService, log)
+
+	tokenService := services.NewAPITokenService(repository.New(storage.DB()), log)
+	authMiddleware.UseAPITokens(tokenService)
+	settingsService := services.NewSettingsService(repository.New(storage.DB()), log)
+	authMiddleware.UseSettings(settingsService)
+	roleService := services.NewRoleService(repository.New(storage.DB()), log)
+	authMiddleware.UseRoles(roleService)
+	roleController := controllers.NewRoleController(roleService, log)
+	settingsController := controllers.NewSettingsController(settingsService, log)
+	publicController := controllers.NewPublicController(services.NewPublicService(repository.New(storage.DB()), log), log)
+	tokenController := controllers.NewTokenController(tokenService, log)
+
+	sessionService := services.NewSessionService(repository.New(storage.DB()), log)
+	authController.UseSessions(sessionService)
+	sessionController := controllers.NewSessionController(sessionService, log)
+
+	loginGuard := services.NewLoginGuardService(repository.New(storage.DB()), log, LoginPolicy(cfg.LoginGuard))
+	reloader.OnReload("login_guard", func(c *config.Config) error {
+		loginGuard.SetPolicy(LoginPolicy(c.LoginGuard))
+		return nil
+	})
+	if cfg.LoginGuard.Enabled {
+		authController.UseLoginGuard(loginGuard)
+	}
+	lockoutController := controllers.NewLockoutController(loginGuard, log)
+
+	flags := features.New(repository.New(storage.DB()), log, cfg.Features)
+	if err := flags.Load(); err != nil {
+		log.Error("failed to load feature overrides", slog.String("error", err.Error()))
+	}
+	reloader.OnReload("features", func(c *config.Config) error {
+		flags.SetConfig(c.Features)
+		return nil
+	})
+	featureController := controllers.NewFeatureController(flags, log)
+
+	webhookService := services.NewWebhookService(repository.New(storage.DB()), log, cfg.Webhooks.Timeout)
+	webhookController := controllers.NewWebhookController(webhookService, log)
+
+	feedService := services.NewFeedService(storage, log)
+	feedController := controllers.NewFeedController(feedService, log)
+
+	reportService := services.NewReportService(storage, log, ssoClient, uploads)
+	reportController := controllers.NewReportController(reportService, log)
+
+	uploadsGC := services.NewUploadsGCService(repository.New(storage.DB()), log, ssoClient, uploads, cfg.UploadsGC.MinAge)
+	uploadsController := controllers.NewUploadsController(uploadsGC, log)
+
+	coverService := services.NewCoverService(repository.New(storage.DB()), log, metadataChain, uploads, gameService)
+	coverController := controllers.NewCoverController(coverService, log)
+
+	flagService := services.NewFlagService(repository.New(storage.DB()), log, gameService, uploads)
+	flagController := controllers.NewFlagController(flagService, log)
+
+	var imageProxyController *controllers.ImageProxyController
+	if cfg.ImageProxy.Enabled {
+		imageProxy, err := services.NewImageProxyService(log, cfg.ImageProxy.CacheDir, ImageProxyPolicy(cfg.ImageProxy))
+		if err != nil {
+			log.Error("failed to set up image proxy", slog.String("error", err.Error()))
+		} else {
+			imageProxyController = controllers.NewImageProxyController(imageProxy, cfg.ImageProxy.CacheTTL, log)
+		}
+	}
+
+	healthController := controllers.NewHealthController(log, 2*time.Second,
+		controllers.Probe{Name: "database", Check: func(ctx context.Context) error {
+			db, err := storage.DB().DB()
+			if err != nil {
+				return err
 			}
-			w.WriteHeader(http.StatusOK)
-		})
+			return db.PingContext(ctx)
+		}},
+		controllers.Probe{Name: "uploads", Check: func(ctx context.Context) error {
+			return uploads.CheckWritable()
+		}},
+		controllers.Probe{Name: "photos", Check: func(ctx context.Context) error {
+			return photos.CheckWritable()
+		}},
+		controllers.Probe{Name: "sso", Check: ssoClient.Ping},
+	)
+
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/health", healthController.Live)
+		r.Get("/health/live", healthController.Live)
+		r.Get("/health/ready", healthController.Ready)
 		r.Post("/register", authController.Register)
 		r.Post("/login", authController.Login)
 		r.Post("/logout", authController.Logout)
 		r.Post("/refresh", authController.Refresh)
+		r.Get("/photos/{name}", photoController.Serve)
+		if imageProxyController != nil {
+			r.Get("/images/proxy", imageProxyController.Serve)
+		}
+		r.With(flags.Require(features.PublicProfiles)).Get("/public/users/{slug}/feed.atom", publicController.GetUserFeedAtom)
 
 		r.Route("/users", func(r chi.Router) {
 			r.Group(func(r chi.Router) {
 				r.Use(authMiddleware.ValidateToken)
-				r.Get("/", authController.GetUsers)
-				r.Put("/{id}", authController.UpdateUser)
-				r.Delete("/{id}", authController.DeleteUser)
+				r.Put("/me", authController.UpdateProfile)
+				r.Get("/me/uploads", quotaController.GetUsage)
+				r.With(games_middleware.RequireRole(models.RoleAdmin)).Get("/", authController.GetUsers)
+				r.With(games_middleware.RequireRole(models.RoleAdmin)).Put("/{id}", authController.UpdateUser)
+				r.With(games_middleware.RequireRole(models.RoleAdmin)).Delete("/{id}", authController.DeleteUser)
+
+				r.Get("/following", feedController.GetFollowing)
+				r.Get("/followers", feedController.GetFollowers)
+				r.Post("/{id}/follow", feedController.Follow)
+				r.Delete("/{id}/follow", feedController.Unfollow)
+			})
+		})
+
+		r.Route("/quick-add", func(r chi.Router) {
+			r.Use(authMiddleware.ValidateToken)
+			r.With(flags.Require(features.IGDBImport)).Post("/", gameController.QuickAdd)
+			r.Post("/token", tokenController.ExchangeExtensionToken)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.ValidateToken)
+			r.Get("/feed", feedController.GetFeed)
+			r.With(flags.Require(features.IGDBImport)).Get("/igdb/search", gameController.SearchIGDB)
+			r.Get("/developers", gameController.GetDevelopers)
+
+			r.Get("/features", featureController.GetFeatures)
+
+			r.Get("/settings", settingsController.GetSettings)
+			r.Put("/settings", settingsController.UpdateSettings)
+
+			r.Get("/tokens", tokenController.GetTokens)
+			r.Post("/tokens", tokenController.CreateToken)
+			r.Delete("/tokens/{id}", tokenController.RevokeToken)
+
+			r.Get("/sessions", sessionController.GetSessions)
+			r.Delete("/sessions/{id}", sessionController.RevokeSession)
+
+			r.Get("/webhooks", webhookController.GetWebhooks)
+			r.Post("/webhooks", webhookController.CreateWebhook)
+			r.Delete("/webhooks/{id}", webhookController.DeleteWebhook)
+			r.Get("/webhooks/{id}/deliveries", webhookController.GetDeliveries)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(authMiddleware.ValidateToken)
+
+			// Жалобы разбирают и модераторы, остальное — только администраторы
+			r.Group(func(r chi.Router) {
+				r.Use(games_middleware.RequireRole(models.RoleModerator))
+				r.Get("/flags", flagController.GetFlags)
+				r.Post("/flags/{id}/resolve", flagController.ResolveFlag)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(games_middleware.RequireRole(models.RoleAdmin))
+				r.Get("/reports/monthly", reportController.GetMonthly)
+
+				r.Get("/roles", roleController.GetRoles)
+				r.Put("/roles/{userID}", roleController.SetRole)
+
+				r.Get("/lockouts", lockoutController.GetLockouts)
+				r.Delete("/lockouts", lockoutController.ClearLockout)
+
+				r.Get("/features", featureController.GetAdminFeatures)
+				r.Put("/features/{name}", featureController.SetFeature)
+				r.Delete("/features/{name}", featureController.ClearFeature)
+
+				r.Get("/games/duplicates", gameController.FindDuplicates)
+				r.Post("/games/merge", gameController.MergeGames)
+				r.Post("/games/steam-backfill", gameController.BackfillSteamAppIDs)
+				r.Get("/games/missing-covers", coverController.GetMissingCovers)
+				r.Post("/games/{id}/refetch-cover", coverController.RefetchCover)
+
+				r.Post("/uploads/gc", uploadsController.CollectGarbage)
 			})
 		})
 
 		r.Route("/games", func(r chi.Router) {
+			r.With(authMiddleware.APITokenFromQuery, authMiddleware.ValidateToken).
+				Get("/user/calendar.ics", gameController.GetReleaseCalendar)
+
 			r.Group(func(r chi.Router) {
 				r.Use(authMiddleware.ValidateToken)
 				r.Get("/", gameController.GetAll)
 				r.Get("/user", gameController.GetUserGames)
+				r.Get("/user/entries", gameController.GetLibraryEntries)
 				r.Get("/user/info", authController.GetUserInfo)
 				r.Get("/user/stats", gameController.GetGameStats)
+				r.Get("/user/stats/v2", gameController.GetGameStatsV2)
+				r.Get("/user/stale", gameController.GetStaleGames)
+				r.Get("/user/upcoming", gameController.GetUpcomingGames)
+				r.Get("/user/triage", gameController.GetTriage)
+				r.Post("/user/flex", gameController.GetFlex)
+				r.Get("/user/aging", settingsController.GetPriorityAging)
+				r.Put("/user/aging", settingsController.SetPriorityAging)
+				r.Get("/user/notifications/discord", settingsController.GetDiscordNotify)
+				r.Put("/user/notifications/discord", settingsController.SetDiscordNotify)
+				r.Get("/user/notifications/digest", settingsController.GetWeeklyDigest)
+				r.Put("/user/notifications/digest", settingsController.SetWeeklyDigest)
+				if telegramController != nil {
+					r.Get("/user/notifications/telegram", telegramController.GetStatus)
+					r.Post("/user/notifications/telegram/link", telegramController.CreateLink)
+					r.Delete("/user/notifications/telegram", telegramController.Unlink)
+				}
+				r.Post("/user/reorder", gameController.Reorder)
+				r.Put("/user/status", gameController.BulkUpdateStatus)
+				r.Delete("/user", gameController.BulkDelete)
 
-				r.Post("/twitch", gameController.CreateMultiGamesIGDB)
+				r.Group(func(r chi.Router) {
+					r.Use(flags.Require(features.IGDBImport))
+					r.Post("/twitch", gameController.CreateMultiGamesIGDB)
+					r.Post("/import/resolve", gameController.ResolveImport)
+					r.Post("/import/trophies", gameController.ImportTrophies)
+					r.Post("/multi/{jobID}/retry", gameController.RetryImport)
+					r.Post("/from-url", gameController.CreateFromURL)
+				})
 
 				r.Get("/search", gameController.SearchAllGames)
+				r.Get("/recommendations", gameController.GetRecommendations)
 				r.Post("/", gameController.Create)
 				r.Route("/{id}", func(r chi.Router) {
 					r.Get("/", gameController.GetByID)
 					r.Put("/", gameController.Update)
+					r.Patch("/", gameController.Patch)
+					r.Get("/override", gameController.GetOverride)
+					r.Put("/override", gameController.SetOverride)
+					r.Delete("/override", gameController.DeleteOverride)
+					r.Post("/flags", flagController.CreateFlag)
 					r.Put("/status", gameController.UpdateStatus)
 					r.Put("/priority", gameController.UpdatePriority)
+					r.Put("/notes", gameController.UpdateNotes)
+					r.Post("/favorite", gameController.ToggleFavorite)
 					r.Delete("/", gameController.Delete)
 					r.Delete("/delete-user-game", gameController.DeleteUserGame)
+
+					r.Get("/playthroughs", gameController.GetPlaythroughs)
+					r.Post("/playthroughs", gameController.StartPlaythrough)
+					r.Put("/playthroughs/{playthroughID}", gameController.UpdatePlaythrough)
+					r.Put("/playthroughs/{playthroughID}/activate", gameController.ActivatePlaythrough)
+					r.Delete("/playthroughs/{playthroughID}", gameController.DeletePlaythrough)
 				})
 			})
 		})
@@ -95,3 +412,77 @@ func SetupRouter(
 
 	return r
 }
+
+// MetadataChain собирает источники сведений об играх в порядке metadata.providers.
+// IGDB пропускается, если не заданы учётные данные Twitch
+func MetadataChain(log *slog.Logger, cfg *config.Config, igdbClient *igdb.Client) *metadata.Chain {
+	var steps []metadata.Step
+	for _, p := range cfg.Metadata.ProviderChain() {
+		var provider metadata.MetadataProvider
+		switch p.Name {
+		case config.ProviderSteam:
+			provider = metadata.NewSteam(log, p.Timeout)
+		case config.ProviderWiki:
+			provider = metadata.NewWiki(log, p.Timeout, cfg.Metadata.WikiLang)
+		case config.ProviderIGDB:
+			if cfg.TwitchClientId == "" || cfg.TwitchClientSecret == "" {
+				log.Warn("igdb metadata provider skipped: twitch credentials are not set")
+				continue
+			}
+			provider = metadata.NewIGDB(igdbClient, log)
+		case config.ProviderGOG:
+			provider = metadata.NewGOG(log, p.Timeout)
+		case config.ProviderEpic:
+			provider = metadata.NewEpic(log, p.Timeout)
+		}
+		steps = append(steps, metadata.Step{Provider: provider, Timeout: p.Timeout})
+	}
+
+	chain := metadata.NewChain(log, steps...)
+	chain.UseMatcher(func(query string, g *metadata.Game) bool {
+		return services.SameTitle(query, g.Title)
+	})
+	log.Info("metadata providers", slog.Any("order", chain.Providers()))
+	return chain
+}
+
+// AccessLogPolicy переводит настройки журнала запросов в правила AccessLog
+func AccessLogPolicy(cfg config.AccessLog) games_middleware.AccessLogPolicy {
+	return games_middleware.AccessLogPolicy{
+		Enabled:        cfg.Enabled,
+		SampleRate:     cfg.SampleRate,
+		BodySampleRate: cfg.BodySampleRate,
+		MaxBodySize:    cfg.MaxBodySize,
+		SkipPaths:      cfg.SkipPaths,
+	}
+}
+
+// ImageProxyPolicy переводит настройки прокси картинок в правила ImageProxyService
+func ImageProxyPolicy(cfg config.ImageProxy) services.ImageProxyPolicy {
+	return services.ImageProxyPolicy{
+		AllowedHosts: cfg.AllowedHosts,
+		MaxSize:      cfg.MaxSize,
+		CacheTTL:     cfg.CacheTTL,
+	}
+}
+
+// ScanPolicy переводит настройки антивируса в правила проверки загрузок.
+// Результаты проверок пишутся в журнал аудита — лог с component=audit
+func ScanPolicy(cfg config.Antivirus, log *slog.Logger) uploads.ScanPolicy {
+	return uploads.ScanPolicy{
+		FailOpen: cfg.FailOpen,
+		Audit:    log.With(slog.String("component", "audit")),
+	}
+}
+
+// LoginPolicy переводит настройки защиты входа в правила LoginGuardService
+func LoginPolicy(cfg config.LoginGuard) services.LoginPolicy {
+	return services.LoginPolicy{
+		FreeAttempts:    cfg.FreeAttempts,
+		BaseDelay:       cfg.BaseDelay,
+		MaxDelay:        cfg.MaxDelay,
+		LockoutAfter:    cfg.LockoutAfter,
+		LockoutDuration: cfg.LockoutDuration,
+		Window:          cfg.Window,
+	}
+}
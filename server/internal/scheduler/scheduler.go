@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type JobFunc func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      JobFunc
+}
+
+// Scheduler запускает фоновые задачи с заданным интервалом
+type Scheduler struct {
+	log  *slog.Logger
+	jobs []job
+	wg   sync.WaitGroup
+}
+
+func New(log *slog.Logger) *Scheduler {
+	return &Scheduler{log: log}
+}
+
+// Add регистрирует задачу. Вызывать до Start
+func (s *Scheduler) Add(name string, interval time.Duration, run JobFunc) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// Start запускает все задачи. Каждая выполняется сразу и затем раз в interval,
+// пока не будет отменён ctx
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func(j job) {
+			defer s.wg.Done()
+
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+
+			for {
+				s.runOnce(ctx, j)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(j)
+	}
+}
+
+// Wait ждёт завершения всех задач после отмены контекста
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, j job) {
+	const op = "scheduler.runOnce"
+
+	start := time.Now()
+	if err := j.run(ctx); err != nil {
+		s.log.Error(
+			"job failed",
+			slog.String("operation", op),
+			slog.String("job", j.name),
+			slog.String("error", err.Error()))
+		return
+	}
+
+	s.log.Debug(
+		"job finished",
+		slog.String("operation", op),
+		slog.String("job", j.name),
+		slog.Duration("duration", time.Since(start)))
+}
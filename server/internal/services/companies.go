@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+)
+
+// syncDevelopers приводит связи игры со справочником разработчиков в соответствие
+// со строкой Developer. store может быть транзакцией
+func syncDevelopers(store repository.Store, g *models.Game) error {
+	var ids []int
+	for _, n := range splitNames(g.Developer) {
+		d, err := store.Companies().GetOrCreateDeveloper(n.name, n.slug)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, d.ID)
+	}
+	return store.Companies().SetDevelopers(g.ID, ids)
+}
+
+// syncPublishers приводит связи игры со справочником издателей в соответствие
+// со строкой Publisher. store может быть транзакцией
+func syncPublishers(store repository.Store, g *models.Game) error {
+	var ids []int
+	for _, n := range splitNames(g.Publisher) {
+		p, err := store.Companies().GetOrCreatePublisher(n.name, n.slug)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, p.ID)
+	}
+	return store.Companies().SetPublishers(g.ID, ids)
+}
+
+// BackfillCompanies раскладывает по справочникам разработчиков и издателей игр,
+// созданных до их появления
+func (s *GameService) BackfillCompanies() error {
+	const op = "services.games.BackfillCompanies"
+
+	games, err := s.store.Games().ListWithoutCompanies()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range games {
+		if err := s.store.Transaction(func(tx repository.Store) error {
+			if err := syncDevelopers(tx, &games[i]); err != nil {
+				return err
+			}
+			return syncPublishers(tx, &games[i])
+		}); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// GetDevelopers возвращает разработчиков игр из библиотеки пользователя с количеством игр
+func (s *GameService) GetDevelopers(ctx context.Context, userID int) ([]models.DeveloperCount, error) {
+	const op = "services.games.GetDevelopers"
+
+	store := s.store.WithContext(ctx)
+
+	counts, err := store.Companies().DeveloperCounts(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return counts, nil
+}
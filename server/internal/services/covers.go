@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"games_webapp/internal/httpx"
+	"games_webapp/internal/metadata"
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage/uploads"
+)
+
+var ErrNoCover = errors.New("no cover found")
+
+// CoverFetcher находит игру во внешних источниках, см. metadata.Chain
+type CoverFetcher interface {
+	Fetch(ctx context.Context, query string) (*metadata.Game, error)
+	FetchFrom(ctx context.Context, name, query string) (*metadata.Game, error)
+}
+
+// CoverFiles — папка загрузок, в которую сохраняются обложки
+type CoverFiles interface {
+	List() ([]uploads.File, error)
+	SaveImage(image []byte, filename string) error
+	ReadImage(src io.Reader) ([]byte, string, error)
+}
+
+// ImageRegistry учитывает ссылки на картинки, см. GameService.AcquireImage
+type ImageRegistry interface {
+	AcquireImage(ctx context.Context, hash, filename string, size int64, uploadedBy int) (string, bool, error)
+	ReleaseImage(ctx context.Context, filename string) (bool, error)
+}
+
+// CoverService заново скачивает обложки игр, у которых картинки нет или её
+// файл пропал из папки загрузок
+type CoverService struct {
+	store   repository.Store
+	log     *slog.Logger
+	fetcher CoverFetcher
+	files   CoverFiles
+	images  ImageRegistry
+	http    *http.Client
+}
+
+func NewCoverService(store repository.Store, log *slog.Logger, fetcher CoverFetcher, files CoverFiles, images ImageRegistry) *CoverService {
+	return &CoverService{
+		store:   store,
+		log:     log,
+		fetcher: fetcher,
+		files:   files,
+		images:  images,
+		http:    httpx.Client(0),
+	}
+}
+
+// Missing возвращает игры без обложки или с обложкой, файла которой нет
+func (s *CoverService) Missing(ctx context.Context) ([]models.Game, error) {
+	const op = "services.covers.Missing"
+
+	games, err := s.store.WithContext(ctx).Games().List()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	files, err := s.files.List()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	present := make(map[string]bool, len(files))
+	for _, f := range files {
+		present[f.Name] = true
+	}
+
+	var missing []models.Game
+	for _, g := range games {
+		if g.Image == "" || !present[filepath.Base(g.Image)] {
+			missing = append(missing, g)
+		}
+	}
+	return missing, nil
+}
+
+// Refetch ищет обложки для игр из Missing и, если dryRun == false, сохраняет
+// их. Игра ищется по ссылке на её страницу, а если источник ссылку не понял,
+// то по названию
+func (s *CoverService) Refetch(ctx context.Context, dryRun bool) (*models.CoverReport, error) {
+	const op = "services.covers.Refetch"
+
+	missing, err := s.Missing(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	report := &models.CoverReport{
+		DryRun:  dryRun,
+		Missing: len(missing),
+		Fixed:   []models.CoverResult{},
+		Failed:  []models.CoverResult{},
+	}
+	for i := range missing {
+		if ctx.Err() != nil {
+			return report, fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+
+		result, err := s.refetch(ctx, &missing[i], dryRun)
+		if err != nil {
+			result.Err = err.Error()
+			report.Failed = append(report.Failed, result)
+			s.log.Warn("failed to refetch cover",
+				slog.String("operation", op),
+				slog.Int("game_id", missing[i].ID),
+				slog.String("error", err.Error()))
+			continue
+		}
+		report.Fixed = append(report.Fixed, result)
+	}
+
+	return report, nil
+}
+
+// RefetchGame заново скачивает обложку игры id, даже если файл на месте
+func (s *CoverService) RefetchGame(ctx context.Context, id int) (models.CoverResult, error) {
+	const op = "services.covers.RefetchGame"
+
+	g, err := s.store.WithContext(ctx).Games().GetByID(id)
+	if err != nil {
+		return models.CoverResult{GameID: id}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, err := s.refetch(ctx, g, false)
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", op, err)
+	}
+	return result, nil
+}
+
+func (s *CoverService) refetch(ctx context.Context, g *models.Game, dryRun bool) (models.CoverResult, error) {
+	result := models.CoverResult{GameID: g.ID, Title: g.Title}
+
+	coverURL, err := s.findCover(ctx, g)
+	if err != nil {
+		return result, err
+	}
+	result.URL = coverURL
+	if dryRun {
+		return result, nil
+	}
+
+	filename, err := s.download(ctx, coverURL)
+	if err != nil {
+		return result, err
+	}
+	if err := s.store.WithContext(ctx).Games().SetImage(g.ID, filename); err != nil {
+		s.release(ctx, filename)
+		return result, err
+	}
+
+	// Старая картинка больше не нужна этой игре
+	if g.Image != "" && g.Image != filename {
+		if _, err := s.images.ReleaseImage(ctx, filepath.Base(g.Image)); err != nil {
+			s.log.Warn("failed to release image", slog.String("filename", g.Image), slog.String("error", err.Error()))
+		}
+	}
+
+	result.Image = filename
+	return result, nil
+}
+
+// findCover спрашивает сначала источник, из которого игра импортирована, по её
+// ссылке или id в нём, потом все источники по ссылке и по названию
+func (s *CoverService) findCover(ctx context.Context, g *models.Game) (string, error) {
+	lastErr := ErrNoCover
+
+	if g.Source != "" && g.Source != models.SourceManual {
+		sourceQueries := []string{g.URL}
+		// Из внешних id только appid Steam годится как запрос
+		if g.Source == models.SourceSteam {
+			sourceQueries = append(sourceQueries, g.ExternalID)
+		}
+		for _, q := range sourceQueries {
+			if q = strings.TrimSpace(q); q == "" {
+				continue
+			}
+			found, err := s.fetcher.FetchFrom(ctx, string(g.Source), q)
+			if err != nil {
+				if !errors.Is(err, metadata.ErrUnknownProvider) {
+					lastErr = err
+				}
+				continue
+			}
+			if found.CoverURL != "" {
+				return found.CoverURL, nil
+			}
+		}
+	}
+
+	queries := make([]string, 0, 3)
+	for _, q := range []string{g.URL, g.TitleEn, g.Title} {
+		if q = strings.TrimSpace(q); q != "" {
+			queries = append(queries, q)
+		}
+	}
+
+	for _, q := range queries {
+		found, err := s.fetcher.Fetch(ctx, q)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if found.CoverURL != "" {
+			return found.CoverURL, nil
+		}
+	}
+	return "", lastErr
+}
+
+// download скачивает картинку и сохраняет её под именем из хэша содержимого
+func (s *CoverService) download(ctx context.Context, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cover download: status %d", resp.StatusCode)
+	}
+
+	data, contentType, err := s.files.ReadImage(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return SaveImage(ctx, s.files, s.images, data, contentType)
+}
+
+// ImageSaver пишет файл картинки в папку загрузок
+type ImageSaver interface {
+	SaveImage(image []byte, filename string) error
+}
+
+// SaveImage сохраняет проверенную картинку под именем из хэша содержимого и
+// учитывает ссылку на неё. Одинаковые картинки хранятся одним файлом
+func SaveImage(ctx context.Context, files ImageSaver, images ImageRegistry, data []byte, contentType string) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	filename, _, err := images.AcquireImage(ctx, hash, hash[:32]+uploads.Extension(contentType), int64(len(data)), 0)
+	if err != nil {
+		return "", err
+	}
+
+	// Запись о картинке могла пережить её файл, поэтому пишем и в этом случае
+	if err := files.SaveImage(data, filename); err != nil && !errors.Is(err, uploads.ErrFileExists) {
+		_, _ = images.ReleaseImage(ctx, filename)
+		return "", err
+	}
+	return filename, nil
+}
+
+func (s *CoverService) release(ctx context.Context, filename string) {
+	if _, err := s.images.ReleaseImage(ctx, filename); err != nil {
+		s.log.Error("failed to release image", slog.String("filename", filename), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"text/template"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+)
+
+const (
+	// digestPeriod — за какой срок собирается дайджест и как часто он отправляется
+	digestPeriod = 7 * 24 * time.Hour
+	// digestUpcomingDays — на сколько дней вперёд смотрятся релизы запланированных игр
+	digestUpcomingDays = 30
+	digestSubject      = "Ваша неделя в библиотеке игр"
+)
+
+var digestTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"status": func(s string) string { return statusNames[models.GameStatus(s)] },
+	"date":   func(t *time.Time) string { return t.Format("02.01.2006") },
+}).Parse(`Привет! Вот что произошло в вашей библиотеке за неделю.
+{{if .Added}}
+Добавлены ({{len .Added}}):
+{{range .Added}}  • {{.GameTitle}}
+{{end}}{{end}}{{if .Changed}}
+Смены статусов ({{len .Changed}}):
+{{range .Changed}}  • {{.GameTitle}} — {{status .Value}}
+{{end}}{{end}}{{if .Upcoming}}
+Скоро выходят запланированные игры:
+{{range .Upcoming}}  • {{.Title}} — {{date .ReleaseDate}}
+{{end}}{{end}}
+Отписаться от дайджеста можно в настройках приложения.
+`))
+
+// Digest — содержимое еженедельного письма
+type Digest struct {
+	Added    []models.FeedItem
+	Changed  []models.FeedItem
+	Upcoming []models.UserGameResponse
+}
+
+func (d *Digest) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Upcoming) == 0
+}
+
+type MailSender interface {
+	Send(to, subject, body string) error
+}
+
+type UserEmailProvider interface {
+	GetUserInfo(ctx context.Context, userID uint32) (email, steamURL, pathToPhoto string, err error)
+}
+
+// DigestService рассылает еженедельные письма пользователям, подписавшимся на дайджест
+type DigestService struct {
+	store  repository.Store
+	log    *slog.Logger
+	mailer MailSender
+	users  UserEmailProvider
+}
+
+func NewDigestService(store repository.Store, log *slog.Logger, mailer MailSender, users UserEmailProvider) *DigestService {
+	return &DigestService{
+		store:  store,
+		log:    log,
+		mailer: mailer,
+		users:  users,
+	}
+}
+
+// SendDue отправляет дайджест всем подписчикам, которым он не отправлялся неделю.
+// Ошибка для одного пользователя не останавливает рассылку: он получит письмо
+// при следующем запуске. Пустые дайджесты не отправляются, но отмечаются
+func (s *DigestService) SendDue(ctx context.Context, now time.Time) (int, error) {
+	const op = "services.digest.SendDue"
+
+	due, err := s.store.Settings().ListDigestDue(now.Add(-digestPeriod))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sent := 0
+	for _, settings := range due {
+		if ctx.Err() != nil {
+			break
+		}
+
+		ok, err := s.sendDigest(ctx, settings.UserID, now)
+		if err != nil {
+			s.log.Error(
+				"failed to send digest",
+				slog.String("operation", op),
+				slog.Int("user_id", settings.UserID),
+				slog.String("error", err.Error()))
+			continue
+		}
+		if ok {
+			sent++
+		}
+
+		if err := s.store.Settings().SetDigestSent(settings.UserID, now); err != nil {
+			return sent, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return sent, nil
+}
+
+func (s *DigestService) sendDigest(ctx context.Context, userID int, now time.Time) (bool, error) {
+	digest, err := s.BuildDigest(userID, now)
+	if err != nil {
+		return false, err
+	}
+	if digest.Empty() {
+		return false, nil
+	}
+
+	email, _, _, err := s.users.GetUserInfo(ctx, uint32(userID))
+	if err != nil {
+		return false, err
+	}
+	if email == "" {
+		return false, nil
+	}
+
+	var body bytes.Buffer
+	if err := digestTemplate.Execute(&body, digest); err != nil {
+		return false, err
+	}
+
+	if err := s.mailer.Send(email, digestSubject, body.String()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// BuildDigest собирает добавленные игры и смены статусов за последнюю неделю
+// и запланированные игры, выходящие в ближайший месяц
+func (s *DigestService) BuildDigest(userID int, now time.Time) (*Digest, error) {
+	const op = "services.digest.BuildDigest"
+
+	events, err := s.store.Events().ListForUser(userID, now.Add(-digestPeriod))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	digest := &Digest{}
+	for _, e := range events {
+		switch e.Type {
+		case models.EventGameAdded:
+			digest.Added = append(digest.Added, e)
+		case models.EventStatusChanged:
+			digest.Changed = append(digest.Changed, e)
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	digest.Upcoming, err = s.store.UserGames().ListUpcoming(userID, today, today.AddDate(0, 0, digestUpcomingDays+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return digest, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+
+	"gorm.io/gorm/clause"
+)
+
+var ErrSelfFollow = errors.New("cannot follow yourself")
+
+type FeedService struct {
+	storage storage.Storage
+	log     *slog.Logger
+}
+
+func NewFeedService(s storage.Storage, log *slog.Logger) *FeedService {
+	return &FeedService{
+		storage: s,
+		log:     log,
+	}
+}
+
+func (s *FeedService) Follow(followerID, followeeID int) error {
+	const op = "services.feed.Follow"
+
+	if followerID == followeeID {
+		return fmt.Errorf("%s: %w", op, ErrSelfFollow)
+	}
+
+	timeNow := time.Now()
+	follow := &models.Follow{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+		CreatedAt:  &timeNow,
+	}
+
+	// Повторная подписка ничего не меняет, в том числе когда два запроса пришли разом
+	if err := s.storage.DB().
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "follower_id"}, {Name: "followee_id"}},
+			DoNothing: true,
+		}).
+		Create(follow).Error; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *FeedService) Unfollow(followerID, followeeID int) error {
+	const op = "services.feed.Unfollow"
+
+	if err := s.storage.DB().
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Delete(&models.Follow{}).Error; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *FeedService) GetFollowing(userID int) ([]models.Follow, error) {
+	const op = "services.feed.GetFollowing"
+
+	var follows []models.Follow
+	if err := s.storage.DB().
+		Where("follower_id = ?", userID).
+		Order("created_at desc").
+		Find(&follows).Error; err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return follows, nil
+}
+
+func (s *FeedService) GetFollowers(userID int) ([]models.Follow, error) {
+	const op = "services.feed.GetFollowers"
+
+	var follows []models.Follow
+	if err := s.storage.DB().
+		Where("followee_id = ?", userID).
+		Order("created_at desc").
+		Find(&follows).Error; err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return follows, nil
+}
+
+// privateEventTypes — события, которые не показываются подписчикам
+var privateEventTypes = []models.EventType{models.EventStatusChanged, models.EventBacklogAged}
+
+// GetFeed возвращает последние события пользователей, на которых подписан userID,
+// и итоги устаревания бэклога самого userID
+func (s *FeedService) GetFeed(userID int, page, pageSize int) ([]models.FeedItem, int, error) {
+	const op = "services.feed.GetFeed"
+
+	var results []models.FeedItem
+	var count int64
+
+	offset := (page - 1) * pageSize
+
+	db := s.storage.DB()
+	followees := db.
+		Model(&models.Follow{}).
+		Select("followee_id").
+		Where("follower_id = ?", userID)
+	private := db.
+		Model(&models.UserSettings{}).
+		Select("user_id").
+		Where("visibility = ?", models.VisibilityPrivate)
+
+	db = db.
+		Table("events").
+		Select("events.*, COALESCE(games.title, '') as game_title, COALESCE(games.image, '') as game_image").
+		Joins("LEFT JOIN games ON games.id = events.game_id").
+		Where(s.storage.DB().
+			Where("events.user_id IN (?) AND events.user_id NOT IN (?) AND events.type NOT IN ?", followees, private, privateEventTypes).
+			Or("events.user_id = ? AND events.type = ?", userID, models.EventBacklogAged))
+
+	if err := db.Count(&count).Error; err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.
+		Order("events.created_at desc").
+		Offset(offset).
+		Limit(pageSize).
+		Scan(&results).Error; err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, int(count), nil
+}
+
+// recordEvent пишет событие в ленту. Ошибка не должна ломать основную операцию,
+// поэтому она только логируется
+func recordEvent(events repository.EventRepo, log *slog.Logger, userID, gameID int, t models.EventType, value string) {
+	const op = "services.feed.recordEvent"
+
+	timeNow := time.Now()
+	event := &models.Event{
+		UserID:    userID,
+		GameID:    gameID,
+		Type:      t,
+		Value:     value,
+		CreatedAt: &timeNow,
+	}
+
+	if err := events.Create(event); err != nil {
+		log.Error(
+			"failed to record event",
+			slog.String("operation", op),
+			slog.String("type", string(t)),
+			slog.String("error", err.Error()))
+	}
+}
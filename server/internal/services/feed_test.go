@@ -0,0 +1,50 @@
+package services_test
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/testutil"
+)
+
+func TestFollowConcurrent(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	_, token := srv.NewUser(t, "player@example.com", false)
+	followeeID, _ := srv.NewUser(t, "friend@example.com", false)
+
+	// Двойной клик по «подписаться» не должен заканчиваться 500
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	const requests = 10
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	codes := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			resp := srv.Do(t, http.MethodPost, "/api/users/"+strconv.Itoa(followeeID)+"/follow", token, nil)
+			resp.Body.Close()
+			codes <- resp.StatusCode
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusNoContent {
+			t.Errorf("status %d, want 204", code)
+		}
+	}
+
+	var following []models.Follow
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodGet, "/api/users/following", token, nil), http.StatusOK, &following)
+	if len(following) != 1 || following[0].FolloweeID != followeeID {
+		t.Errorf("following = %+v, want one follow of %d", following, followeeID)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+)
+
+var (
+	ErrInvalidFlagReason = errors.New("invalid flag reason")
+	ErrInvalidFlagAction = errors.New("invalid flag action")
+	ErrFlagComment       = errors.New("flag comment is too long")
+	ErrAlreadyFlagged    = errors.New("game is already flagged by user")
+	ErrFlagClosed        = errors.New("flag is already closed")
+)
+
+// maxFlagComment — предел длины комментария к жалобе в символах
+const maxFlagComment = 1000
+
+// FlagGames правит и удаляет игры при закрытии жалоб, см. GameService
+type FlagGames interface {
+	Patch(ctx context.Context, id, version int, p models.GamePatch) (*models.Game, error)
+	Delete(ctx context.Context, id, requesterID int, force bool) (int, error)
+	ReleaseImage(ctx context.Context, filename string) (bool, error)
+}
+
+// FlagFiles — папка загрузок, из которой удаляется обложка удалённой игры
+type FlagFiles interface {
+	DeleteImage(filename string) error
+}
+
+// FlagService принимает жалобы пользователей на записи каталога и закрывает их
+// по решению модератора. Пока на игру есть открытые жалобы, у неё стоит Flagged
+type FlagService struct {
+	store repository.Store
+	log   *slog.Logger
+	games FlagGames
+	files FlagFiles
+}
+
+func NewFlagService(store repository.Store, log *slog.Logger, games FlagGames, files FlagFiles) *FlagService {
+	return &FlagService{
+		store: store,
+		log:   log,
+		games: games,
+		files: files,
+	}
+}
+
+// Create сохраняет жалобу пользователя на игру. Вторая открытая жалоба того же
+// пользователя на ту же игру — ErrAlreadyFlagged
+func (s *FlagService) Create(ctx context.Context, userID, gameID int, reason models.FlagReason, comment string) (*models.GameFlag, error) {
+	const op = "services.flags.Create"
+
+	if !reason.IsValid() {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidFlagReason)
+	}
+	if utf8.RuneCountInString(comment) > maxFlagComment {
+		return nil, fmt.Errorf("%s: %w", op, ErrFlagComment)
+	}
+
+	now := time.Now()
+	flag := &models.GameFlag{
+		GameID:    gameID,
+		UserID:    userID,
+		Reason:    reason,
+		Comment:   comment,
+		Status:    models.FlagOpen,
+		CreatedAt: &now,
+	}
+
+	err := s.store.WithContext(ctx).Transaction(func(tx repository.Store) error {
+		game, err := tx.Games().GetByID(gameID)
+		if err != nil {
+			return err
+		}
+		flag.GameTitle = game.Title
+
+		exists, err := tx.Flags().HasOpen(userID, gameID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrAlreadyFlagged
+		}
+
+		if err := tx.Flags().Create(flag); err != nil {
+			return err
+		}
+		return tx.Games().SetFlagged(gameID, true)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("game flagged", slog.String("operation", op),
+		slog.Int("game_id", gameID), slog.Int("user_id", userID), slog.String("reason", string(reason)))
+	return flag, nil
+}
+
+// List возвращает страницу жалоб в статусе status, старые первыми
+func (s *FlagService) List(ctx context.Context, status models.FlagStatus, page, pageSize int) ([]models.GameFlag, int, error) {
+	const op = "services.flags.List"
+
+	flags, total, err := s.store.WithContext(ctx).Flags().List(status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return flags, total, nil
+}
+
+// Resolve закрывает жалобу решением модератора. edit применяет patch к игре
+// (nil — игру уже исправили) и закрывает все открытые жалобы на неё, delete
+// удаляет игру у всех пользователей, dismiss отклоняет только эту жалобу
+func (s *FlagService) Resolve(ctx context.Context, id, moderatorID int, action models.FlagAction, version int, patch *models.GamePatch) (*models.GameFlag, error) {
+	const op = "services.flags.Resolve"
+
+	store := s.store.WithContext(ctx)
+
+	flag, err := store.Flags().GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if flag.Status != models.FlagOpen {
+		return nil, fmt.Errorf("%s: %w", op, ErrFlagClosed)
+	}
+
+	switch action {
+	case models.FlagActionDismiss:
+		now := time.Now()
+		flag.Status = models.FlagDismissed
+		flag.Resolution = action
+		flag.ResolvedBy = moderatorID
+		flag.ResolvedAt = &now
+		err = store.Transaction(func(tx repository.Store) error {
+			if err := tx.Flags().Save(flag); err != nil {
+				return err
+			}
+			open, err := tx.Flags().CountOpen(flag.GameID)
+			if err != nil {
+				return err
+			}
+			return tx.Games().SetFlagged(flag.GameID, open > 0)
+		})
+	case models.FlagActionEdit:
+		if patch != nil {
+			if _, err := s.games.Patch(ctx, flag.GameID, version, *patch); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		err = store.Transaction(func(tx repository.Store) error {
+			if _, err := tx.Flags().ResolveOpen(flag.GameID, action, moderatorID, time.Now()); err != nil {
+				return err
+			}
+			return tx.Games().SetFlagged(flag.GameID, false)
+		})
+	case models.FlagActionDelete:
+		var game *models.Game
+		if game, err = store.Games().GetByID(flag.GameID); err != nil {
+			break
+		}
+		// Удаление игры само закрывает её жалобы, см. GameService.Delete
+		if _, err = s.games.Delete(ctx, flag.GameID, moderatorID, true); err == nil && game.Image != "" {
+			s.releaseImage(ctx, game.Image)
+		}
+	default:
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidFlagAction)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("flag resolved", slog.String("operation", op), slog.Int("flag_id", id),
+		slog.Int("game_id", flag.GameID), slog.String("action", string(action)), slog.Int("moderator_id", moderatorID))
+
+	if flag, err = store.Flags().GetByID(id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return flag, nil
+}
+
+// releaseImage снимает ссылку удалённой игры с обложки и удаляет файл, если
+// обложка больше никому не нужна. Ошибки только пишутся в журнал: игра уже удалена
+func (s *FlagService) releaseImage(ctx context.Context, filename string) {
+	const op = "services.flags.releaseImage"
+
+	remove, err := s.games.ReleaseImage(ctx, filename)
+	if err != nil {
+		s.log.Error("failed to release image", slog.String("operation", op), slog.String("filename", filename), slog.String("error", err.Error()))
+		return
+	}
+	if !remove {
+		return
+	}
+	if err := s.files.DeleteImage(filename); err != nil {
+		s.log.Error("failed to delete image", slog.String("operation", op), slog.String("filename", filename), slog.String("error", err.Error()))
+	}
+}
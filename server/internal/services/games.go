@@ -1,472 +1,1700 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"games_webapp/internal/lib/bloom"
 	"games_webapp/internal/models"
-	"games_webapp/internal/storage/mariadb"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+// Ожидаемое количество игр и доля ложных срабатываний для фильтра URL
+const (
+	urlFilterMinSize = 10000
+	urlFilterFPRate  = 0.01
+)
 
-	"gorm.io/gorm"
+var (
+	ErrGameInUse      = errors.New("game is tracked by other users")
+	ErrPriorityStatus = errors.New("priorities are ordered within one status")
 )
 
 type GameService struct {
-	storage *mariadb.Storage
-	log     *slog.Logger
+	store     repository.Store
+	log       *slog.Logger
+	urls      *bloom.Filter
+	notifiers []Notifier
 }
 
-func NewGameService(s *mariadb.Storage, log *slog.Logger) *GameService {
+func NewGameService(store repository.Store, log *slog.Logger) *GameService {
 	return &GameService{
-		storage: s,
-		log:     log,
+		store: store,
+		log:   log,
 	}
 }
 
-func (s *GameService) GetGamesPaginated(userID int, search, sortBy, sortOrder string, page, pageSize int) ([]models.UserGameResponse, int, error) {
-	const op = "services.games.GetAllGames"
-
-	var results []models.UserGameResponse
-	var count int64
-
-	offset := (page - 1) * pageSize
+// UseNotifier подключает ещё один внешний канал уведомлений о событиях библиотеки
+func (s *GameService) UseNotifier(n Notifier) {
+	s.notifiers = append(s.notifiers, n)
+}
 
-	db := s.storage.DB.Table("games").
-		Select("games.*, COALESCE(user_games.priority, 0) as priority, COALESCE(user_games.status, '') as status").
-		Joins("LEFT JOIN user_games ON user_games.game_id = games.id AND user_games.user_id = ?", userID)
+// LoadURLFilter заполняет bloom-фильтр URL всех игр. До успешной загрузки
+// GetGameByURL всегда идёт в базу
+func (s *GameService) LoadURLFilter() error {
+	const op = "services.games.LoadURLFilter"
 
-	if search != "" {
-		db = db.Where("games.title LIKE ?", "%"+search+"%")
+	count, err := s.store.Games().Count()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := db.Count(&count).Error; err != nil {
-		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	size := count * 2
+	if size < urlFilterMinSize {
+		size = urlFilterMinSize
 	}
+	filter := bloom.New(size, urlFilterFPRate)
 
-	allowedSort := map[string]string{
-		"title": "games.title",
-		"year":  "games.year",
+	if err := s.store.Games().EachURL(filter.Add); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	sortField, ok := allowedSort[sortBy]
-	if !ok {
-		sortField = "games.title"
-	}
+	filter.SetReady()
+	s.urls = filter
 
-	if strings.ToLower(sortOrder) != "desc" {
-		sortOrder = "asc"
+	return nil
+}
+
+func (s *GameService) rememberURL(url string) {
+	if s.urls != nil && url != "" {
+		s.urls.Add(url)
 	}
+}
 
-	if err := db.
-		Order(fmt.Sprintf("%s %s", sortField, sortOrder)).
-		Offset(offset).
-		Limit(pageSize).
-		Scan(&results).Error; err != nil {
+func (s *GameService) GetGamesPaginated(ctx context.Context, userID int, search string, filter models.GameFilter, sortBy, sortOrder string, page, pageSize int) ([]models.UserGameResponse, int, error) {
+	const op = "services.games.GetAllGames"
+
+	store := s.store.WithContext(ctx)
+
+	results, count, err := store.Games().Catalog(repository.LibraryQuery{
+		UserID:    userID,
+		Search:    search,
+		Filter:    normalizeFilter(filter),
+		SortBy:    sortBy,
+		SortOrder: sortOrder,
+		Offset:    (page - 1) * pageSize,
+		Limit:     pageSize,
+	})
+	if err != nil {
 		return nil, 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return results, int(count), nil
+	return results, count, nil
 }
 
-func (s *GameService) GetByID(id int) (*models.Game, error) {
+func (s *GameService) GetByID(ctx context.Context, id int) (*models.Game, error) {
 	const op = "services.games.GetByID"
 
-	var g models.Game
+	store := s.store.WithContext(ctx)
 
-	rows := s.storage.DB.First(&g, id)
-	if rows.Error != nil {
-		return nil, fmt.Errorf("%s: %w", op, rows.Error)
+	g, err := store.Games().GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &g, nil
+	return g, nil
 }
 
-func (s *GameService) SearchAllGames(query string) ([]models.Game, error) {
+func (s *GameService) SearchAllGames(ctx context.Context, query string) ([]models.Game, error) {
 	const op = "services.games.SearchAllGames"
 
-	var results []models.Game
-	rows := s.storage.DB.Where("title LIKE ?", "%"+query+"%").Find(&results)
-	if rows.Error != nil {
-		return nil, fmt.Errorf("%s: %w", op, rows.Error)
+	store := s.store.WithContext(ctx)
+
+	results, err := store.Games().Search(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return results, nil
 }
 
-func (s *GameService) GetUserGame(userID, gameID int) (*models.UserGames, error) {
+func (s *GameService) GetUserGame(ctx context.Context, userID, gameID int) (*models.UserGames, error) {
 	const op = "services.games.GetUserGame"
 
-	var g models.UserGames
+	store := s.store.WithContext(ctx)
 
-	rows := s.storage.DB.Where("user_id = ? AND game_id = ?", userID, gameID).First(&g)
-	if rows.Error != nil {
-		return nil, fmt.Errorf("%s: %w", op, rows.Error)
+	g, err := store.UserGames().GetActive(userID, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &g, nil
+	return g, nil
 }
 
-func (s *GameService) GetUserGames(userID int, status *models.GameStatus, search, sortBy, sortOrder string, page, pageSize int) ([]models.UserGameResponse, int, error) {
+func (s *GameService) GetUserGames(ctx context.Context, userID int, status *models.GameStatus, search string, filter models.GameFilter, sortBy, sortOrder string, page, pageSize int) ([]models.UserGameResponse, int, error) {
 	const op = "services.games.GetUserGames"
 
-	var results []models.UserGameResponse
-	var count int64
+	store := s.store.WithContext(ctx)
+
+	results, count, err := store.UserGames().Library(repository.LibraryQuery{
+		UserID:    userID,
+		Status:    status,
+		Search:    search,
+		Filter:    normalizeFilter(filter),
+		SortBy:    sortBy,
+		SortOrder: sortOrder,
+		Offset:    (page - 1) * pageSize,
+		Limit:     pageSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
 
-	offset := (page - 1) * pageSize
+	shown := make([]*models.UserGameResponse, len(results))
+	for i := range results {
+		shown[i] = &results[i]
+	}
+	if err := applyOverrides(store, userID, shown); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
 
-	db := s.storage.DB.
-		Table("games").
-		Select("games.*, user_games.priority, user_games.status").
-		Joins("JOIN user_games ON user_games.game_id = games.id").
-		Where("user_games.user_id = ?", userID)
+	return results, count, nil
+}
 
-	if status != nil {
-		db = db.Where("user_games.status = ?", status)
+// GetLibraryEntries возвращает страницу библиотеки, как GetUserGames, вместе
+// с жанрами игр и сводкой по прохождениям
+func (s *GameService) GetLibraryEntries(ctx context.Context, userID int, status *models.GameStatus, search string, filter models.GameFilter, sortBy, sortOrder string, page, pageSize int) ([]models.LibraryEntry, int, error) {
+	const op = "services.games.GetLibraryEntries"
+
+	store := s.store.WithContext(ctx)
+
+	results, count, err := store.UserGames().LibraryEntries(repository.LibraryQuery{
+		UserID:    userID,
+		Status:    status,
+		Search:    search,
+		Filter:    normalizeFilter(filter),
+		SortBy:    sortBy,
+		SortOrder: sortOrder,
+		Offset:    (page - 1) * pageSize,
+		Limit:     pageSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if search != "" {
-		db = db.Where("games.title LIKE ?", "%"+search+"%")
+	shown := make([]*models.UserGameResponse, len(results))
+	for i := range results {
+		shown[i] = &results[i].UserGameResponse
 	}
-
-	if err := db.Count(&count).Error; err != nil {
+	if err := applyOverrides(store, userID, shown); err != nil {
 		return nil, 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	allowedSort := map[string]string{
-		"title":    "games.title",
-		"year":     "games.year",
-		"priority": "user_games.priority",
-	}
+	return results, count, nil
+}
+
+// normalizeFilter приводит значения фильтра к виду, в котором они хранятся в базе
+func normalizeFilter(f models.GameFilter) models.GameFilter {
+	f.Genre = nameSlug(f.Genre)
+	f.Developer = nameSlug(f.Developer)
+	f.Publisher = nameSlug(f.Publisher)
+	f.Platform = nameSlug(f.Platform)
+	f.PlayedOn = nameSlug(f.PlayedOn)
+	return f
+}
+
+// Create создаёт игру. Если такая игра уже есть (тот же URL или то же
+// нормализованное название и год), новая не создаётся: возвращается
+// существующая и created = false, а пользователь привязывается к ней через CreateUserGame
+func (s *GameService) Create(ctx context.Context, g *models.Game) (game *models.Game, created bool, err error) {
+	const op = "services.games.Create"
 
-	sortField, ok := allowedSort[sortBy]
-	if !ok {
-		sortField = "games.title"
+	if err := validateGame(g, gameFields); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if strings.ToLower(sortOrder) != "desc" {
-		sortOrder = "asc"
+	store := s.store.WithContext(ctx)
+
+	applySteamAppID(g)
+	applyReleaseDate(g)
+
+	existing, err := s.findExisting(store, g)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if existing != nil {
+		return existing, false, nil
 	}
 
-	if err := db.
-		Order(fmt.Sprintf("%s %s", sortField, sortOrder)).
-		Offset(offset).
-		Limit(pageSize).
-		Find(&results).Error; err != nil {
-		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	g.TitleKey = normalizeTitle(g.Title)
+
+	if err := store.Transaction(func(tx repository.Store) error {
+		if err := tx.Games().Create(g); err != nil {
+			return err
+		}
+		return syncCatalog(tx, g)
+	}); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return results, int(count), nil
+	s.rememberURL(g.URL)
+
+	return g, true, nil
 }
 
-func (s *GameService) Create(g *models.Game) (*models.Game, error) {
-	const op = "services.games.Create"
+// findExisting ищет уже сохранённую игру по внешнему ID источника, затем по URL,
+// а затем по нормализованному названию и году
+func (s *GameService) findExisting(store repository.Store, g *models.Game) (*models.Game, error) {
+	const op = "services.games.findExisting"
+
+	if g.ExternalID != "" {
+		byExternal, err := store.Games().GetByExternalID(g.Source, g.ExternalID)
+		if err == nil {
+			return byExternal, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if g.URL != "" && (s.urls == nil || !s.urls.Ready() || s.urls.MightContain(g.URL)) {
+		byURL, err := store.Games().GetByURL(g.URL)
+		if err == nil {
+			return byURL, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
-	err := s.GetGameByURL(g.URL)
+	key := normalizeTitle(g.Title)
+	if key == "" {
+		return nil, nil
+	}
+
+	candidates, err := store.Games().FindByTitleKey(key)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	tx := s.storage.DB.Begin()
-	if tx.Error != nil {
-		return nil, fmt.Errorf("%s: %w", op, tx.Error)
+	for i := range candidates {
+		if yearsMatch(candidates[i].Year, g.Year) {
+			return &candidates[i], nil
+		}
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+	return nil, nil
+}
+
+// BackfillTitleKeys заполняет title_key у игр, созданных до его появления
+func (s *GameService) BackfillTitleKeys() error {
+	const op = "services.games.BackfillTitleKeys"
 
-	if err := tx.Create(g).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("%s: %w", op, tx.Error)
+	games, err := s.store.Games().ListWithoutTitleKey()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("%s: %w", op, tx.Error)
+	for _, g := range games {
+		if err := s.store.Games().SetTitleKey(g.ID, normalizeTitle(g.Title)); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
 	}
 
-	return g, nil
+	return nil
 }
 
-func (s *GameService) Update(g *models.Game) (*models.Game, error) {
-	const op = "services.games.Update"
+// BackfillSources определяет источник игр, созданных до появления поля source, по их URL
+func (s *GameService) BackfillSources() error {
+	const op = "services.games.BackfillSources"
 
-	tx := s.storage.DB.Begin()
-	if tx.Error != nil {
-		return nil, fmt.Errorf("%s: %w", op, tx.Error)
+	games, err := s.store.Games().ListWithoutSource()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	for _, g := range games {
+		if err := s.store.Games().SetSource(g.ID, sourceFromURL(g.URL), ""); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
 		}
-	}()
+	}
 
-	var existing models.Game
-	if err := tx.First(&existing, g.ID).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("%s: %w", op, err)
+	return nil
+}
+
+// sourceFromURL угадывает источник игры по адресу её страницы
+func sourceFromURL(rawURL string) models.GameSource {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return models.SourceManual
 	}
 
-	if err := tx.Model(&models.Game{}).Where("id = ?", g.ID).Updates(g).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("%s: %w", op, err)
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "igdb.com" || strings.HasSuffix(host, ".igdb.com"):
+		return models.SourceIGDB
+	case strings.HasSuffix(host, "steampowered.com") || strings.HasSuffix(host, "steamcommunity.com"):
+		return models.SourceSteam
+	case strings.HasSuffix(host, "wikipedia.org"):
+		return models.SourceWiki
+	case host == "gog.com" || strings.HasSuffix(host, ".gog.com"):
+		return models.SourceGOG
+	case host == "epicgames.com" || strings.HasSuffix(host, ".epicgames.com"):
+		return models.SourceEpic
+	}
+	return models.SourceManual
+}
+
+func (s *GameService) Update(ctx context.Context, g *models.Game) (*models.Game, error) {
+	const op = "services.games.Update"
+
+	store := s.store.WithContext(ctx)
+
+	if g.Title != "" {
+		g.TitleKey = normalizeTitle(g.Title)
 	}
+	// Update сохраняет только непустые поля, так что source и external_id не трогаем
+	g.SteamAppID = steamAppID(g.URL)
 
-	if err := tx.Commit().Error; err != nil {
+	if err := store.Transaction(func(tx repository.Store) error {
+		existing, err := tx.Games().GetByID(g.ID)
+		if err != nil {
+			return err
+		}
+		if g.Version != 0 && g.Version != existing.Version {
+			return storage.ErrConflict
+		}
+		if err := validateGame(g, changedFields(g, existing, true)); err != nil {
+			return err
+		}
+		// Клиент обычно присылает год обратно без изменений: тогда точную дату не
+		// заменяем на 1 января
+		if g.ReleaseDate != nil || g.Year != existing.Year {
+			applyReleaseDate(g)
+		}
+		if err := tx.Games().Update(g); err != nil {
+			return err
+		}
+		if g.Version == 0 {
+			g.Version = existing.Version + 1
+		}
+		// Пустые поля Update не меняет, поэтому и связи для них не трогаем
+		if g.Genre != "" {
+			if err := syncGenres(tx, g); err != nil {
+				return err
+			}
+		}
+		if g.Developer != "" {
+			if err := syncDevelopers(tx, g); err != nil {
+				return err
+			}
+		}
+		if g.Publisher != "" {
+			if err := syncPublishers(tx, g); err != nil {
+				return err
+			}
+		}
+		if g.Platforms != "" {
+			return syncPlatforms(tx, g)
+		}
+		return nil
+	}); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	s.rememberURL(g.URL)
+
 	return g, nil
 }
 
-func (s *GameService) Delete(id int) error {
-	const op = "services.games.Delete"
+// Patch меняет у игры только поля, заданные в p. version — версия игры, которую
+// видел клиент; если с тех пор игру изменили, возвращается storage.ErrConflict
+func (s *GameService) Patch(ctx context.Context, id, version int, p models.GamePatch) (*models.Game, error) {
+	const op = "services.games.Patch"
 
-	tx := s.storage.DB.Begin()
-	if tx.Error != nil {
-		return fmt.Errorf("%s: %w", op, tx.Error)
-	}
+	store := s.store.WithContext(ctx)
+
+	var g models.Game
+	if err := store.Transaction(func(tx repository.Store) error {
+		existing, err := tx.Games().GetByID(id)
+		if err != nil {
+			return err
+		}
+		if existing.Version != version {
+			return storage.ErrConflict
+		}
+
+		g = *existing
+		for field, value := range map[*string]*string{
+			&g.Title:     p.Title,
+			&g.Preambula: p.Preambula,
+			&g.TitleEn:   p.TitleEn,
+			&g.SummaryEn: p.SummaryEn,
+			&g.Developer: p.Developer,
+			&g.Publisher: p.Publisher,
+			&g.Genre:     p.Genre,
+			&g.Platforms: p.Platforms,
+			&g.URL:       p.URL,
+		} {
+			if value != nil {
+				*field = *value
+			}
+		}
+		g.TitleKey = normalizeTitle(g.Title)
+		g.SteamAppID = steamAppID(g.URL)
+
+		// Дата выхода важнее года; новый год без даты заменяет прежнюю дату.
+		// Year выводится из даты, поэтому вместе с датой очищается и он
+		switch {
+		case p.ReleaseDate != nil:
+			g.ReleaseDate, g.ReleasePrecision = p.ReleaseDate, ""
+		case p.ClearReleaseDate || p.Year != nil:
+			g.Year, g.ReleaseDate, g.ReleasePrecision = "", nil, ""
+			if p.Year != nil {
+				g.Year = *p.Year
+			}
+		}
+		applyReleaseDate(&g)
 
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+		if err := validateGame(&g, changedFields(&g, existing, false)); err != nil {
+			return err
+		}
+		if err := tx.Games().Replace(&g); err != nil {
+			return err
 		}
-	}()
 
-	if err := tx.Delete(&models.Game{}, id).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("%s: %w", op, tx.Error)
+		if p.Genre != nil {
+			if err := syncGenres(tx, &g); err != nil {
+				return err
+			}
+		}
+		if p.Developer != nil {
+			if err := syncDevelopers(tx, &g); err != nil {
+				return err
+			}
+		}
+		if p.Publisher != nil {
+			if err := syncPublishers(tx, &g); err != nil {
+				return err
+			}
+		}
+		if p.Platforms != nil {
+			return syncPlatforms(tx, &g)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("%s: %w", op, tx.Error)
+	s.rememberURL(g.URL)
+
+	return &g, nil
+}
+
+// Delete удаляет игру вместе со всеми записями user_games всех пользователей.
+// Если игру, кроме requesterID, отслеживают другие пользователи и force = false,
+// ничего не удаляется: возвращается ErrGameInUse и количество таких пользователей
+func (s *GameService) Delete(ctx context.Context, id, requesterID int, force bool) (int, error) {
+	const op = "services.games.Delete"
+
+	store := s.store.WithContext(ctx)
+
+	var others int
+	err := store.Transaction(func(tx repository.Store) error {
+		var err error
+		if others, err = tx.UserGames().CountOtherUsers(id, requesterID); err != nil {
+			return err
+		}
+
+		if others > 0 && !force {
+			return ErrGameInUse
+		}
+
+		return removeGame(tx, id, requesterID)
+	})
+	if err != nil {
+		return others, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return others, nil
 }
 
-func (s *GameService) GetGameByURL(url string) error {
+func (s *GameService) GetGameByURL(ctx context.Context, url string) error {
 	const op = "services.games.GetGameByURL"
 
-	fmt.Printf("url: %s \n op: %s\n", url, op)
+	store := s.store.WithContext(ctx)
 
 	if url == "" {
 		return fmt.Errorf("%s: url is empty", op)
 	}
 
-	rows := s.storage.DB.Where("url = ?", url).First(&models.Game{})
-	fmt.Println(rows)
-	if rows.Error != nil && !errors.Is(rows.Error, gorm.ErrRecordNotFound) {
+	// Фильтр не даёт ложноотрицательных ответов, так что при промахе в базу можно не ходить
+	if s.urls != nil && s.urls.Ready() && !s.urls.MightContain(url) {
 		return nil
 	}
-	if rows.Error == nil {
+
+	_, err := store.Games().GetByURL(url)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err == nil {
 		return fmt.Errorf("%s: %w", op, errors.New("game already exists"))
 	}
 
 	return nil
 }
 
-func (s *GameService) CreateUserGame(ug *models.UserGames) error {
+func (s *GameService) CreateUserGame(ctx context.Context, ug *models.UserGames) error {
 	const op = "services.games.CreateUserGame"
 
-	var existing models.UserGames
-	err := s.storage.DB.Where(
-		"user_id = ? AND game_id = ?",
-		ug.UserID,
-		ug.GameID,
-	).First(&existing).Error
-	fmt.Println("ТУТАЧКИ")
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		if err := s.storage.DB.Create(ug).Error; err != nil {
-			return fmt.Errorf("%s: %w", op, err)
+	if err := s.createUserGame(s.store.WithContext(ctx), ug); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// CreateWithUserGame создаёт игру (или находит существующую, как Create) и
+// привязывает её к пользователю в одной транзакции, чтобы при ошибке привязки
+// в базе не оставалось игры без владельца. GameID у ug проставляется автоматически
+func (s *GameService) CreateWithUserGame(ctx context.Context, g *models.Game, ug *models.UserGames) (game *models.Game, created bool, err error) {
+	const op = "services.games.CreateWithUserGame"
+
+	if err := validateGame(g, gameFields); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := ValidatePriority(ug.Priority); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	store := s.store.WithContext(ctx)
+
+	applySteamAppID(g)
+	applyReleaseDate(g)
+
+	existing, err := s.findExisting(store, g)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	game = existing
+	if err := store.Transaction(func(tx repository.Store) error {
+		if game == nil {
+			g.TitleKey = normalizeTitle(g.Title)
+			if err := tx.Games().Create(g); err != nil {
+				return err
+			}
+			if err := syncCatalog(tx, g); err != nil {
+				return err
+			}
+			game = g
 		}
-		fmt.Println("ВСЁ НОРМ")
+
+		ug.GameID = game.ID
+		return s.createUserGame(tx, ug)
+	}); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if existing == nil {
+		s.rememberURL(game.URL)
+	}
+
+	return game, existing == nil, nil
+}
+
+// createUserGame добавляет игру в библиотеку, если её там ещё нет. store может быть транзакцией
+func (s *GameService) createUserGame(store repository.Store, ug *models.UserGames) error {
+	if err := ValidatePriority(ug.Priority); err != nil {
+		return err
+	}
+
+	exists, err := store.UserGames().Exists(ug.UserID, ug.GameID)
+	if err != nil {
+		return err
+	}
+	if exists {
 		return nil
+	}
 
-	} else if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if err := store.UserGames().Create(ug); err != nil {
+		return err
+	}
+	recordEvent(store.Events(), s.log, ug.UserID, ug.GameID, models.EventGameAdded, string(ug.Status))
+	queueWebhook(store, s.log, models.WebhookGameCreated, ug, "")
+	if ug.Status == models.StatusFinished {
+		recordEvent(store.Events(), s.log, ug.UserID, ug.GameID, models.EventGameFinished, "")
+		queueWebhook(store, s.log, models.WebhookGameFinished, ug, "")
+		for _, n := range s.notifiers {
+			n.GameFinished(ug.UserID, ug.GameID)
+		}
 	}
 	return nil
 }
 
-func (s *GameService) UpdateUserGame(ug *models.UserGames) error {
+func (s *GameService) UpdateUserGame(ctx context.Context, ug *models.UserGames) error {
 	const op = "services.games.UpdateUserGame"
-	fmt.Println("ОБНОВЛЕНИЕ")
-
-	var existing models.UserGames
-
-	fmt.Printf("%v", ug)
-	err := s.storage.DB.
-		Table("user_games").
-		Where("user_id = ? AND game_id = ?", ug.UserID, ug.GameID).
-		First(&existing).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		fmt.Println("СОЗДАНИЕ")
-		return s.CreateUserGame(ug)
-	} else if err != nil {
+
+	if err := s.updateUserGame(s.store.WithContext(ctx), ug); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	return nil
+}
 
-	existing.Priority = ug.Priority
-	existing.Status = ug.Status
+// UpdatePriority ставит игре приоритет в списке её статуса (ug.Status). Занятые
+// ячейки не дублируются: игры с тем же и идущими подряд меньшими приоритетами
+// сдвигаются на единицу вниз
+func (s *GameService) UpdatePriority(ctx context.Context, ug *models.UserGames) error {
+	const op = "services.games.UpdatePriority"
+
+	store := s.store.WithContext(ctx)
 
-	if err := s.storage.DB.Table("user_games").Save(&existing).Error; err != nil {
-		fmt.Println("НУ Я ТУТ")
+	if err := store.Transaction(func(tx repository.Store) error {
+		if err := shiftPriorities(tx, ug.UserID, ug.GameID, ug.Status, ug.Priority); err != nil {
+			return err
+		}
+		return s.updateUserGame(tx, ug)
+	}); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	fmt.Printf("%v", existing)
-	fmt.Println("ВСЁ ЧЕТЕНЬКО")
 	return nil
 }
 
-func (s *GameService) DeleteUserGame(userID, gameID int) error {
-	const op = "services.games.DeleteUserGame"
+// ReorderPriorities переписывает приоритеты в списке статуса status по порядку
+// gameIDs: первая игра получает высший приоритет. Остальные игры в этом статусе
+// остаются без приоритета, списки других статусов не меняются. Пустой status
+// берётся у перечисленных игр; все они должны быть в одном статусе
+func (s *GameService) ReorderPriorities(ctx context.Context, userID int, status models.GameStatus, gameIDs []int) error {
+	const op = "services.games.ReorderPriorities"
+
+	store := s.store.WithContext(ctx)
+
+	if err := store.Transaction(func(tx repository.Store) error {
+		entries := make([]*models.UserGames, len(gameIDs))
+		for i, gameID := range gameIDs {
+			ug, err := tx.UserGames().GetActive(userID, gameID)
+			if err != nil {
+				return err
+			}
+			if status == "" {
+				status = ug.Status
+			}
+			if ug.Status != status {
+				return ErrPriorityStatus
+			}
+			entries[i] = ug
+		}
+		if status == "" {
+			return ErrPriorityStatus
+		}
+
+		if err := tx.UserGames().ResetPriorities(userID, status); err != nil {
+			return err
+		}
 
-	if err := s.storage.DB.Where("user_id = ? AND game_id = ?", userID, gameID).Delete(&models.UserGames{}).Error; err != nil {
+		for i, ug := range entries {
+			if err := tx.UserGames().SetPriority(ug.ID, models.MaxPriority-i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-
 	return nil
 }
 
-func (s *GameService) GetFinishedGames(userID int) (int, error) {
-	const op = "services.games.GetFinishedGames"
-
-	var count int64
-	if err := s.storage.DB.
-		Model(&models.UserGames{}).
-		Where("user_id = ?", userID).
-		Where("status = ?", "finished").
-		Count(&count).Error; err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+// BulkUpdateStatus меняет статус нескольких игр из библиотеки в одной транзакции.
+// Игры, которых нет в библиотеке, пропускаются и отмечаются в результате
+func (s *GameService) BulkUpdateStatus(ctx context.Context, userID int, gameIDs []int, status models.GameStatus) ([]models.BulkItem, error) {
+	const op = "services.games.BulkUpdateStatus"
+
+	store := s.store.WithContext(ctx)
+
+	results := make([]models.BulkItem, 0, len(gameIDs))
+	if err := store.Transaction(func(tx repository.Store) error {
+		results = results[:0]
+		seen := make(map[int]bool, len(gameIDs))
+
+		for _, gameID := range gameIDs {
+			if seen[gameID] {
+				continue
+			}
+			seen[gameID] = true
+
+			existing, err := tx.UserGames().GetActive(userID, gameID)
+			if errors.Is(err, storage.ErrNotFound) {
+				results = append(results, models.BulkItem{GameID: gameID, Result: models.BulkNotFound})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			ug := &models.UserGames{UserID: userID, GameID: gameID, Priority: existing.Priority, Status: status}
+			if err := s.updateUserGame(tx, ug); err != nil {
+				return err
+			}
+			results = append(results, models.BulkItem{GameID: gameID, Result: models.BulkUpdated})
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return int(count), nil
+	return results, nil
 }
 
-func (s *GameService) GetPlayingGames(userID int) (int, error) {
-	const op = "services.games.GetPlayingGames"
-
-	var count int64
-	if err := s.storage.DB.
-		Model(&models.UserGames{}).
-		Where("user_id = ?", userID).
-		Where("status = ?", "playing").
-		Count(&count).Error; err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+// BulkDeleteUserGames убирает несколько игр из библиотеки в одной транзакции.
+// С deleteOwned игры, созданные пользователем и больше никем не отслеживаемые,
+// удаляются из каталога целиком — они возвращаются в removed, чтобы вызывающий
+// мог освободить их картинки
+func (s *GameService) BulkDeleteUserGames(ctx context.Context, userID int, gameIDs []int, deleteOwned bool) (results []models.BulkItem, removed []models.Game, err error) {
+	const op = "services.games.BulkDeleteUserGames"
+
+	store := s.store.WithContext(ctx)
+
+	if err := store.Transaction(func(tx repository.Store) error {
+		results, removed = results[:0], removed[:0]
+		seen := make(map[int]bool, len(gameIDs))
+
+		for _, gameID := range gameIDs {
+			if seen[gameID] {
+				continue
+			}
+			seen[gameID] = true
+
+			exists, err := tx.UserGames().Exists(userID, gameID)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				results = append(results, models.BulkItem{GameID: gameID, Result: models.BulkNotFound})
+				continue
+			}
+
+			if deleteOwned {
+				game, deleted, err := deleteOwnedGame(tx, userID, gameID)
+				if err != nil {
+					return err
+				}
+				if deleted {
+					removed = append(removed, *game)
+					results = append(results, models.BulkItem{GameID: gameID, Result: models.BulkGameDeleted})
+					continue
+				}
+			}
+
+			if err := tx.UserGames().Delete(userID, gameID); err != nil {
+				return err
+			}
+			results = append(results, models.BulkItem{GameID: gameID, Result: models.BulkRemoved})
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return int(count), nil
+	return results, removed, nil
 }
 
-func (s *GameService) GetPlannedGames(userID int) (int, error) {
-	const op = "services.games.GetPlannedGames"
+// deleteOwnedGame удаляет игру вместе с записями о ней, если её создал userID
+// и никто другой её не отслеживает
+func deleteOwnedGame(store repository.Store, userID, gameID int) (*models.Game, bool, error) {
+	game, err := store.Games().GetByID(gameID)
+	if err != nil {
+		return nil, false, err
+	}
+	if game.Creator != userID {
+		return game, false, nil
+	}
 
-	var count int64
-	if err := s.storage.DB.
-		Model(&models.UserGames{}).
-		Where("user_id = ?", userID).
-		Where("status = ?", "planned").
-		Count(&count).Error; err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+	others, err := store.UserGames().CountOtherUsers(gameID, userID)
+	if err != nil || others > 0 {
+		return game, false, err
 	}
 
-	return int(count), nil
+	if err := removeGame(store, gameID, userID); err != nil {
+		return nil, false, err
+	}
+	return game, true, nil
 }
 
-func (s *GameService) GetDroppedGames(userID int) (int, error) {
-	const op = "services.games.GetDroppedGames"
-
-	var count int64
-	if err := s.storage.DB.
-		Model(&models.UserGames{}).
-		Where("user_id = ?", userID).
-		Where("status = ?", "dropped").
-		Count(&count).Error; err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+// removeGame удаляет игру из каталога вместе с записями о ней. Открытые жалобы
+// на игру закрываются от имени by и остаются в истории модерации
+func removeGame(store repository.Store, gameID, by int) error {
+	if err := store.UserGames().DeleteByGame(gameID); err != nil {
+		return err
 	}
-
-	return int(count), nil
+	if err := store.Overrides().DeleteByGame(gameID); err != nil {
+		return err
+	}
+	if _, err := store.Flags().ResolveOpen(gameID, models.FlagActionDelete, by, time.Now()); err != nil {
+		return err
+	}
+	return store.Games().Delete(gameID)
 }
 
-func (s *GameService) GetFlex(
-	userID int,
-	fields []string,
-	where []models.WhereQuery,
-	order []models.Sort,
-	limit int,
-	offset int,
-) ([]models.UserGameResponse, error) {
-	const op = "services.games.GetFlex"
+// shiftPriorities освобождает ячейку priority в списке статуса status: записи,
+// занимающие её и идущие за ней без пропусков, сдвигаются на единицу вниз.
+// Приоритет 0 не уникален
+func shiftPriorities(store repository.Store, userID, gameID int, status models.GameStatus, priority int) error {
+	if priority <= 0 {
+		return nil
+	}
+
+	ranked, err := store.UserGames().ListRanked(userID, status, priority)
+	if err != nil {
+		return err
+	}
 
-	db := s.storage.DB.Model(&models.Game{})
-	if userID != 0 {
-		if userID <= 0 {
-			return nil, fmt.Errorf("%s: userID is required", op)
+	var ids []int
+	next := priority
+	for _, ug := range ranked {
+		if ug.GameID == gameID {
+			continue
 		}
+		if ug.Priority < next {
+			break
+		}
+		ids = append(ids, ug.ID)
+		next = ug.Priority - 1
+	}
 
-		db = db.Select("games.*, user_games.priority, user_games.status").
-			Joins("JOIN user_games ON user_games.game_id = games.id and user_games.user_id = ?", userID)
+	return store.UserGames().ShiftDown(ids)
+}
+
+// movePriority переносит запись existing в список приоритетов нового статуса
+// ug.Status. Место в старом списке в новом ничего не значит, поэтому прежний
+// приоритет сбрасывается в 0; если вызывающий задал другой приоритет, ему
+// освобождается ячейка в новом списке
+func movePriority(store repository.Store, ug, existing *models.UserGames) error {
+	if ug.Priority == existing.Priority {
+		ug.Priority = 0
+		return nil
 	}
+	return shiftPriorities(store, ug.UserID, ug.GameID, ug.Status, ug.Priority)
+}
 
-	if len(fields) > 0 {
-		if userID != 0 {
-			db = db.Select(append(fields, "user_games.priority", "user_games.status"))
-		} else {
-			db = db.Select(fields)
-		}
+// BackfillPriorityLists разводит совпадающие приоритеты внутри списков статусов.
+// Они остались с тех пор, как приоритет был общим на всю библиотеку и менялся
+// формой игры без сдвига соседей. Порядок сохраняется: из записей с одним
+// приоритетом выше остаётся изменённая последней, остальные сдвигаются вниз
+func (s *GameService) BackfillPriorityLists() error {
+	const op = "services.games.BackfillPriorityLists"
+
+	scopes, err := s.store.UserGames().ListPriorityClashes()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	for _, wq := range where {
-		if wq.Field == "" {
-			continue
+	for _, scope := range scopes {
+		if err := s.store.Transaction(func(tx repository.Store) error {
+			ranked, err := tx.UserGames().ListRanked(scope.UserID, scope.Status, models.MaxPriority)
+			if err != nil {
+				return err
+			}
+
+			next := models.MaxPriority
+			for _, ug := range ranked {
+				priority := min(ug.Priority, next)
+				if priority != ug.Priority {
+					if err := tx.UserGames().SetPriority(ug.ID, priority); err != nil {
+						return err
+					}
+				}
+				next = max(priority-1, 0)
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
 		}
+	}
 
-		condition := map[string]string{
-			"gt":  ">",
-			"lt":  "<",
-			"gte": ">=",
-			"lte": "<=",
-			"eq":  "=",
-			"neq": "!=",
-		}[strings.ToLower(wq.Condition)]
+	return nil
+}
 
-		if condition == "" {
-			continue
-		}
+// updateUserGame обновляет приоритет и статус активной записи или создаёт её.
+// store может быть транзакцией
+func (s *GameService) updateUserGame(store repository.Store, ug *models.UserGames) error {
+	if err := ValidatePriority(ug.Priority); err != nil {
+		return err
+	}
 
-		db = db.Where(fmt.Sprintf("%s %s ?", wq.Field, condition), wq.Value)
+	existing, err := store.UserGames().GetActive(ug.UserID, ug.GameID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return s.createUserGame(store, ug)
+	} else if err != nil {
+		return err
 	}
 
-	for _, s := range order {
-		if s.Field == "" {
-			continue
+	previous := existing.Status
+	statusChanged := previous != ug.Status
+	if statusChanged {
+		if err := movePriority(store, ug, existing); err != nil {
+			return err
 		}
+	}
 
-		dir := "ASC"
+	existing.Priority = ug.Priority
+	existing.Status = ug.Status
+	// Пользователь тронул запись — она больше не считается залежавшейся
+	existing.Stale = false
+	existing.AgedAt = nil
 
-		if strings.ToLower(s.Direction) == "desc" {
-			dir = "DESC"
-		}
+	if err := store.UserGames().Save(existing); err != nil {
+		return err
+	}
 
-		db = db.Order(fmt.Sprintf("%s %s", s.Field, dir))
+	if statusChanged {
+		recordEvent(store.Events(), s.log, existing.UserID, existing.GameID, models.EventStatusChanged, string(existing.Status))
+		queueWebhook(store, s.log, models.WebhookStatusChanged, existing, previous)
+		s.notifyStatusChanged(existing, previous)
+	}
+	if statusChanged && existing.Status == models.StatusFinished {
+		recordEvent(store.Events(), s.log, existing.UserID, existing.GameID, models.EventGameFinished, "")
+		queueWebhook(store, s.log, models.WebhookGameFinished, existing, previous)
 	}
+	return nil
+}
+
+// UpdateNotes меняет заметки активного прохождения игры пользователя
+func (s *GameService) UpdateNotes(ctx context.Context, userID, gameID int, notes string) (*models.UserGames, error) {
+	const op = "services.games.UpdateNotes"
+
+	store := s.store.WithContext(ctx)
 
-	if limit > 0 {
-		db = db.Limit(int(limit))
+	ug, err := store.UserGames().GetActive(userID, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if offset > 0 {
-		db = db.Offset(int(offset))
+	if err := store.UserGames().SetNotes(ug.ID, notes); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	var res []models.UserGameResponse
-	if err := db.Scan(&res).Error; err != nil {
+	ug.Notes = notes
+	return ug, nil
+}
+
+// ToggleFavorite переключает отметку «избранное» у игры из библиотеки пользователя
+func (s *GameService) ToggleFavorite(ctx context.Context, userID, gameID int) (*models.UserGames, error) {
+	const op = "services.games.ToggleFavorite"
+
+	store := s.store.WithContext(ctx)
+
+	ug, err := store.UserGames().GetActive(userID, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ug.IsFavorite = !ug.IsFavorite
+	if err := store.UserGames().SetFavorite(ug.ID, ug.IsFavorite); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ug, nil
+}
+
+func (s *GameService) DeleteUserGame(ctx context.Context, userID, gameID int) error {
+	const op = "services.games.DeleteUserGame"
+
+	store := s.store.WithContext(ctx)
+
+	if err := store.UserGames().Delete(userID, gameID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetStatusCounts возвращает количество игр пользователя по статусам, избранных и
+// средний процент прохождения (округлённый до десятых) одним запросом
+func (s *GameService) GetStatusCounts(ctx context.Context, userID int) (*models.StatusCounts, error) {
+	const op = "services.games.GetStatusCounts"
+
+	store := s.store.WithContext(ctx)
+
+	counts, err := store.UserGames().StatusCounts(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	counts.AverageCompletion = math.Round(counts.AverageCompletion*10) / 10
+
+	return counts, nil
+}
+
+func (s *GameService) GetFlex(
+	ctx context.Context,
+	userID int,
+	joins []string,
+	fields []string,
+	where []models.WhereQuery,
+	order []models.Sort,
+	limit int,
+	offset int,
+) ([]models.UserGameResponse, error) {
+	const op = "services.games.GetFlex"
+
+	store := s.store.WithContext(ctx)
+
+	if userID < 0 {
+		return nil, fmt.Errorf("%s: userID is required", op)
+	}
+
+	res, err := store.Games().Flex(repository.FlexQuery{
+		UserID: userID,
+		Joins:  joins,
+		Fields: fields,
+		Where:  where,
+		Order:  order,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return res, nil
 }
+
+func (s *GameService) GetFlexAggregate(
+	ctx context.Context,
+	userID int,
+	joins []string,
+	where []models.WhereQuery,
+	aggregate models.FlexAggregation,
+	order []models.Sort,
+	limit int,
+	offset int,
+) ([]map[string]interface{}, error) {
+	const op = "services.games.GetFlexAggregate"
+
+	store := s.store.WithContext(ctx)
+
+	if userID < 0 {
+		return nil, fmt.Errorf("%s: userID is required", op)
+	}
+
+	res, err := store.Games().FlexAggregate(repository.FlexQuery{
+		UserID:    userID,
+		Joins:     joins,
+		Where:     where,
+		Order:     order,
+		Limit:     limit,
+		Offset:    offset,
+		Aggregate: &aggregate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+func (s *GameService) GetPlaythroughs(ctx context.Context, userID, gameID int) ([]models.UserGames, error) {
+	const op = "services.games.GetPlaythroughs"
+
+	store := s.store.WithContext(ctx)
+
+	playthroughs, err := store.UserGames().ListPlaythroughs(userID, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return playthroughs, nil
+}
+
+// StartPlaythrough делает текущее прохождение неактивным и создаёт новое активное
+func (s *GameService) StartPlaythrough(ctx context.Context, ug *models.UserGames) error {
+	const op = "services.games.StartPlaythrough"
+
+	if err := ValidatePriority(ug.Priority); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	store := s.store.WithContext(ctx)
+
+	if err := store.Transaction(func(tx repository.Store) error {
+		if err := tx.UserGames().DeactivateAll(ug.UserID, ug.GameID); err != nil {
+			return err
+		}
+
+		ug.ID = 0
+		ug.IsActive = true
+		stampPlaythrough(ug, "")
+		return tx.UserGames().Create(ug)
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	recordEvent(store.Events(), s.log, ug.UserID, ug.GameID, models.EventGameAdded, ug.Label)
+	if ug.Rating > 0 {
+		recordEvent(store.Events(), s.log, ug.UserID, ug.GameID, models.EventGameRated, strconv.Itoa(ug.Rating))
+	}
+	return nil
+}
+
+func (s *GameService) ActivatePlaythrough(ctx context.Context, userID, gameID, playthroughID int) error {
+	const op = "services.games.ActivatePlaythrough"
+
+	store := s.store.WithContext(ctx)
+
+	if err := store.Transaction(func(tx repository.Store) error {
+		target, err := tx.UserGames().GetPlaythrough(playthroughID, userID, gameID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.UserGames().DeactivateAll(userID, gameID); err != nil {
+			return err
+		}
+
+		return tx.UserGames().Activate(target.ID)
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UpdatePlaythrough обновляет прохождение. notes == nil оставляет заметки как есть
+func (s *GameService) UpdatePlaythrough(ctx context.Context, ug *models.UserGames, notes, platform *string) error {
+	const op = "services.games.UpdatePlaythrough"
+
+	if err := ValidatePriority(ug.Priority); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	store := s.store.WithContext(ctx)
+
+	existing, err := store.UserGames().GetPlaythrough(ug.ID, ug.UserID, ug.GameID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	statusChanged := existing.Status != ug.Status
+
+	// Не переданные в запросе даты и заметки остаются прежними
+	if ug.StartedAt == nil {
+		ug.StartedAt = existing.StartedAt
+	}
+	if ug.FinishedAt == nil {
+		ug.FinishedAt = existing.FinishedAt
+	}
+	ug.Notes = existing.Notes
+	if notes != nil {
+		ug.Notes = *notes
+	}
+	if platform == nil {
+		ug.Platform = existing.Platform
+	} else {
+		ug.Platform = *platform
+	}
+	if statusChanged {
+		stampPlaythrough(ug, existing.Status)
+	}
+
+	if err := store.Transaction(func(tx repository.Store) error {
+		// Неактивные прохождения в списках приоритетов не участвуют
+		if statusChanged && existing.IsActive {
+			if err := movePriority(tx, ug, existing); err != nil {
+				return err
+			}
+		}
+		return tx.UserGames().UpdateProgress(ug)
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if statusChanged {
+		recordEvent(store.Events(), s.log, ug.UserID, ug.GameID, models.EventStatusChanged, string(ug.Status))
+		queueWebhook(store, s.log, models.WebhookStatusChanged, ug, existing.Status)
+		s.notifyStatusChanged(ug, existing.Status)
+	}
+	if statusChanged && ug.Status == models.StatusFinished {
+		recordEvent(store.Events(), s.log, ug.UserID, ug.GameID, models.EventGameFinished, ug.Label)
+		queueWebhook(store, s.log, models.WebhookGameFinished, ug, existing.Status)
+	}
+	if ug.Rating > 0 && ug.Rating != existing.Rating {
+		recordEvent(store.Events(), s.log, ug.UserID, ug.GameID, models.EventGameRated, strconv.Itoa(ug.Rating))
+	}
+
+	return nil
+}
+
+// DeletePlaythrough удаляет прохождение. Если оно было активным, активным
+// становится последнее из оставшихся
+func (s *GameService) DeletePlaythrough(ctx context.Context, userID, gameID, playthroughID int) error {
+	const op = "services.games.DeletePlaythrough"
+
+	store := s.store.WithContext(ctx)
+
+	if err := store.Transaction(func(tx repository.Store) error {
+		target, err := tx.UserGames().GetPlaythrough(playthroughID, userID, gameID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.UserGames().DeletePlaythrough(target.ID); err != nil {
+			return err
+		}
+
+		if !target.IsActive {
+			return nil
+		}
+
+		rest, err := tx.UserGames().ListPlaythroughs(userID, gameID)
+		if err != nil {
+			return err
+		}
+		if len(rest) == 0 {
+			return nil
+		}
+		return tx.UserGames().Activate(rest[len(rest)-1].ID)
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// notifyStatusChanged сообщает каналам уведомлений о смене статуса, а о переходе
+// в «пройдено» — ещё и отдельным событием
+func (s *GameService) notifyStatusChanged(ug *models.UserGames, previous models.GameStatus) {
+	for _, n := range s.notifiers {
+		n.StatusChanged(ug.UserID, ug.GameID, previous, ug.Status)
+		if ug.Status == models.StatusFinished {
+			n.GameFinished(ug.UserID, ug.GameID)
+		}
+	}
+}
+
+// stampPlaythrough проставляет даты начала и окончания, если клиент их не
+// передал, а статус перешёл в «играю» или «пройдено»
+func stampPlaythrough(ug *models.UserGames, previous models.GameStatus) {
+	now := time.Now()
+
+	if ug.StartedAt == nil && ug.Status != models.StatusPlanned && previous != ug.Status {
+		ug.StartedAt = &now
+	}
+	if ug.FinishedAt == nil && ug.Status == models.StatusFinished && previous != ug.Status {
+		ug.FinishedAt = &now
+	}
+}
+
+func (s *GameService) RecordImportRun(ctx context.Context, run *models.ImportRun) error {
+	const op = "services.games.RecordImportRun"
+
+	store := s.store.WithContext(ctx)
+
+	if err := store.ImportRuns().Create(run); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for _, n := range s.notifiers {
+		n.ImportFinished(run)
+	}
+
+	return nil
+}
+
+// RecordImportRetry записывает повтор неудачных названий запуска retriedID.
+// Названия, которые снова не удались, переходят к run, у старого запуска
+// повторять больше нечего
+func (s *GameService) RecordImportRetry(ctx context.Context, retriedID int, run *models.ImportRun) error {
+	const op = "services.games.RecordImportRetry"
+
+	store := s.store.WithContext(ctx)
+
+	if err := store.Transaction(func(tx repository.Store) error {
+		if err := tx.ImportRuns().Create(run); err != nil {
+			return err
+		}
+		return tx.ImportRuns().DeleteItems(retriedID)
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for _, n := range s.notifiers {
+		n.ImportFinished(run)
+	}
+
+	return nil
+}
+
+// GetImportRun возвращает запуск импорта пользователя с неудачными названиями
+func (s *GameService) GetImportRun(ctx context.Context, id, userID int) (*models.ImportRun, error) {
+	const op = "services.games.GetImportRun"
+
+	run, err := s.store.WithContext(ctx).ImportRuns().Get(id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return run, nil
+}
+
+// GetLibraryProfile собирает самые частые жанры и разработчиков среди
+// пройденных и текущих игр пользователя, а также уже добавленные игры
+func (s *GameService) GetLibraryProfile(ctx context.Context, userID int, limit int) (*models.LibraryProfile, error) {
+	const op = "services.games.GetLibraryProfile"
+
+	store := s.store.WithContext(ctx)
+
+	games, err := store.UserGames().ListLibrary(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	profile := &models.LibraryProfile{
+		OwnedURLs:   make(map[string]bool),
+		OwnedTitles: make(map[string]bool),
+	}
+
+	genres := make(map[string]int)
+	developers := make(map[string]int)
+
+	for _, g := range games {
+		if g.URL != "" {
+			profile.OwnedURLs[g.URL] = true
+		}
+		profile.OwnedTitles[strings.ToLower(strings.TrimSpace(g.Title))] = true
+
+		if g.Status != models.StatusFinished && g.Status != models.StatusPlaying {
+			continue
+		}
+		for _, genre := range splitList(g.Genre) {
+			genres[genre]++
+		}
+		for _, dev := range splitList(g.Developer) {
+			developers[dev]++
+		}
+	}
+
+	profile.TopGenres = topKeys(genres, limit)
+	profile.TopDevelopers = topKeys(developers, limit)
+
+	return profile, nil
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			res = append(res, part)
+		}
+	}
+	return res
+}
+
+func topKeys(counts map[string]int, limit int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+func (s *GameService) FindDuplicates(ctx context.Context) ([]models.DuplicateGroup, error) {
+	const op = "services.games.FindDuplicates"
+
+	store := s.store.WithContext(ctx)
+
+	games, err := store.Games().List()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return groupDuplicates(games), nil
+}
+
+// groupDuplicates группирует игры с одинаковым нормализованным названием и совпадающим годом
+func groupDuplicates(games []models.Game) []models.DuplicateGroup {
+	byTitle := make(map[string][]models.Game)
+	var order []string
+	for _, g := range games {
+		key := normalizeTitle(g.Title)
+		if key == "" {
+			continue
+		}
+		if _, ok := byTitle[key]; !ok {
+			order = append(order, key)
+		}
+		byTitle[key] = append(byTitle[key], g)
+	}
+
+	var groups []models.DuplicateGroup
+	for _, key := range order {
+		candidates := byTitle[key]
+		if len(candidates) < 2 {
+			continue
+		}
+
+		// Игры с одинаковым названием, но разными годами — скорее всего ремейки,
+		// поэтому группируем только тех, чьи годы совпадают с первой игрой группы
+		used := make([]bool, len(candidates))
+		for i := range candidates {
+			if used[i] {
+				continue
+			}
+			group := []models.Game{candidates[i]}
+			for j := i + 1; j < len(candidates); j++ {
+				if !used[j] && yearsMatch(candidates[i].Year, candidates[j].Year) {
+					group = append(group, candidates[j])
+					used[j] = true
+				}
+			}
+			if len(group) > 1 {
+				groups = append(groups, models.DuplicateGroup{NormalizedTitle: key, Games: group})
+			}
+		}
+	}
+
+	return groups
+}
+
+// MergeGames переносит все связи дубликата на оставшуюся игру и удаляет дубликат.
+// Возвращает имя картинки дубликата, которая больше не используется (если есть)
+func (s *GameService) MergeGames(ctx context.Context, survivorID, duplicateID int) (*models.Game, string, error) {
+	const op = "services.games.MergeGames"
+
+	store := s.store.WithContext(ctx)
+
+	if survivorID == duplicateID {
+		return nil, "", fmt.Errorf("%s: cannot merge game into itself", op)
+	}
+
+	var survivor *models.Game
+	var orphanImage string
+
+	err := store.Transaction(func(tx repository.Store) error {
+		var err error
+		if survivor, err = tx.Games().GetByID(survivorID); err != nil {
+			return err
+		}
+		duplicate, err := tx.Games().GetByID(duplicateID)
+		if err != nil {
+			return err
+		}
+
+		// Пользователи, у которых уже есть оставшаяся игра, сохраняют записи дубликата
+		// как неактивные прохождения, остальным просто меняем game_id
+		survivorUsers, err := tx.UserGames().UsersOf(survivorID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.UserGames().Reassign(duplicateID, survivorID, survivorUsers); err != nil {
+			return err
+		}
+
+		if err := tx.Events().Reassign(duplicateID, survivorID); err != nil {
+			return err
+		}
+
+		if err := tx.Overrides().Reassign(duplicateID, survivorID); err != nil {
+			return err
+		}
+
+		if err := tx.Flags().Reassign(duplicateID, survivorID); err != nil {
+			return err
+		}
+		survivor.Flagged = survivor.Flagged || duplicate.Flagged
+
+		// Заполняем пустые поля оставшейся игры данными дубликата
+		orphanImage = duplicate.Image
+		if survivor.Image == "" && duplicate.Image != "" {
+			survivor.Image = duplicate.Image
+			orphanImage = ""
+		}
+		if survivor.Preambula == "" {
+			survivor.Preambula = duplicate.Preambula
+		}
+		if survivor.TitleEn == "" {
+			survivor.TitleEn = duplicate.TitleEn
+		}
+		if survivor.SummaryEn == "" {
+			survivor.SummaryEn = duplicate.SummaryEn
+		}
+		if survivor.Developer == "" {
+			survivor.Developer = duplicate.Developer
+		}
+		if survivor.Publisher == "" {
+			survivor.Publisher = duplicate.Publisher
+		}
+		if survivor.ReleaseDate == nil && duplicate.ReleaseDate != nil {
+			survivor.ReleaseDate = duplicate.ReleaseDate
+			survivor.ReleasePrecision = duplicate.ReleasePrecision
+			survivor.Year = duplicate.Year
+		}
+		if survivor.Year == "" {
+			survivor.Year = duplicate.Year
+		}
+		if survivor.Genre == "" {
+			survivor.Genre = duplicate.Genre
+		}
+		if survivor.Platforms == "" {
+			survivor.Platforms = duplicate.Platforms
+		}
+		if survivor.URL == "" {
+			survivor.URL = duplicate.URL
+		}
+		if survivor.SteamAppID == 0 {
+			survivor.SteamAppID = duplicate.SteamAppID
+		}
+		if survivor.ExternalID == "" && duplicate.ExternalID != "" {
+			survivor.Source = duplicate.Source
+			survivor.ExternalID = duplicate.ExternalID
+			survivor.LastSyncedAt = duplicate.LastSyncedAt
+		}
+
+		if err := tx.Games().Delete(duplicateID); err != nil {
+			return err
+		}
+
+		if err := tx.Games().Save(survivor); err != nil {
+			return err
+		}
+		return syncCatalog(tx, survivor)
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.rememberURL(survivor.URL)
+
+	return survivor, orphanImage, nil
+}
+
+const (
+	AgingModeDecay = "decay"
+	AgingModeFlag  = "flag"
+)
+
+// AgeBacklog понижает приоритет (или помечает stale) активных запланированных игр,
+// которые не трогали с olderThan, у пользователей с включённой настройкой.
+// Повторно одна и та же запись обрабатывается не раньше чем через тот же срок.
+// Каждому затронутому пользователю в ленту пишется итог — EventBacklogAged
+func (s *GameService) AgeBacklog(ctx context.Context, olderThan time.Time, mode string) (int, error) {
+	const op = "services.games.AgeBacklog"
+
+	store := s.store.WithContext(ctx)
+
+	decay := mode != AgingModeFlag
+	var aged []models.AgedBacklog
+	if err := store.Transaction(func(tx repository.Store) error {
+		var err error
+		if aged, err = tx.UserGames().Age(olderThan, decay); err != nil {
+			return err
+		}
+		for _, a := range aged {
+			recordEvent(tx.Events(), s.log, a.UserID, 0, models.EventBacklogAged, strconv.Itoa(a.Games))
+		}
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	total := 0
+	for _, a := range aged {
+		total += a.Games
+	}
+
+	// Пониженная игра могла занять ячейку свежей: свежая остаётся выше
+	if decay && total > 0 {
+		if err := s.BackfillPriorityLists(); err != nil {
+			return total, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return total, nil
+}
+
+func (s *GameService) GetStaleGames(ctx context.Context, userID int, olderThan time.Time) ([]models.UserGameResponse, error) {
+	const op = "services.games.GetStaleGames"
+
+	store := s.store.WithContext(ctx)
+
+	results, err := store.UserGames().ListStale(userID, olderThan)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, nil
+}
+
+func (s *GameService) GetUpcomingGames(ctx context.Context, userID int, from, to time.Time) ([]models.UserGameResponse, error) {
+	const op = "services.games.GetUpcomingGames"
+
+	store := s.store.WithContext(ctx)
+
+	results, err := store.UserGames().ListUpcoming(userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, nil
+}
+
+// GetTriage собирает всё, что стоит почистить в библиотеке пользователя:
+// залежавшиеся запланированные игры, дубликаты, игры без описания и без обложки
+func (s *GameService) GetTriage(ctx context.Context, userID int, staleBefore time.Time) (*models.Triage, error) {
+	const op = "services.games.GetTriage"
+
+	store := s.store.WithContext(ctx)
+
+	stale, err := s.GetStaleGames(ctx, userID, staleBefore)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	library, err := store.UserGames().ListLibrary(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	triage := &models.Triage{
+		Stale:           stale,
+		Duplicates:      []models.DuplicateGroup{},
+		MissingMetadata: []models.UserGameResponse{},
+		MissingCover:    []models.UserGameResponse{},
+	}
+
+	games := make([]models.Game, 0, len(library))
+	for _, ug := range library {
+		games = append(games, ug.Game)
+
+		if ug.Image == "" {
+			triage.MissingCover = append(triage.MissingCover, ug)
+		}
+		if ug.Preambula == "" || ug.Developer == "" || ug.Year == "" || ug.Genre == "" {
+			triage.MissingMetadata = append(triage.MissingMetadata, ug)
+		}
+	}
+
+	if groups := groupDuplicates(games); groups != nil {
+		triage.Duplicates = groups
+	}
+	if triage.Stale == nil {
+		triage.Stale = []models.UserGameResponse{}
+	}
+
+	return triage, nil
+}
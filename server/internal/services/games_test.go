@@ -0,0 +1,65 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"games_webapp/internal/controllers"
+	"games_webapp/internal/models"
+	"games_webapp/internal/testutil"
+)
+
+func pixelPNG(t *testing.T) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestBulkDeleteResolvesFlags(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	ownerID, owner := srv.NewUser(t, "owner@example.com", false)
+	_, reporter := srv.NewUser(t, "reporter@example.com", false)
+
+	var created controllers.CreatedGame
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodPost, "/api/games", owner, map[string]interface{}{
+		"title":    "Flagged",
+		"year":     "2020",
+		"priority": 1,
+		"image":    pixelPNG(t),
+	}), http.StatusOK, &created)
+
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodPost, "/api/games/"+strconv.Itoa(created.ID)+"/flags", reporter,
+		controllers.CreateFlagRequest{Reason: models.FlagWrongMetadata}), http.StatusCreated, nil)
+
+	var results []models.BulkItem
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodDelete, "/api/games/user?delete_games=true", owner,
+		[]int{created.ID}), http.StatusOK, &results)
+	if len(results) != 1 || results[0].Result != models.BulkGameDeleted {
+		t.Fatalf("results = %+v, want the game deleted", results)
+	}
+
+	// Жалоба закрыта удалением от имени владельца, а не висит у модераторов
+	open, total, err := srv.Store().Flags().List(models.FlagOpen, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 0 || len(open) != 0 {
+		t.Errorf("got %d open flags, want none", total)
+	}
+	resolved, _, err := srv.Store().Flags().List(models.FlagResolved, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 1 || resolved[0].Resolution != models.FlagActionDelete || resolved[0].ResolvedBy != ownerID {
+		t.Errorf("resolved = %+v, want one deleted by %d", resolved, ownerID)
+	}
+}
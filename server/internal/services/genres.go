@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+)
+
+// nameSlug нормализует название для справочников: "Action", " action " и "ACTION" — одно и то же
+func nameSlug(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+type catalogName struct {
+	name string
+	slug string
+}
+
+// splitNames разбивает список через запятую на названия без повторов (с точностью до slug)
+func splitNames(list string) []catalogName {
+	seen := make(map[string]bool)
+	var names []catalogName
+
+	for _, name := range splitList(list) {
+		slug := nameSlug(name)
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		names = append(names, catalogName{name: name, slug: slug})
+	}
+	return names
+}
+
+// syncGenres приводит связи игры со справочником жанров в соответствие со строкой
+// Genre, создавая недостающие жанры. store может быть транзакцией
+func syncGenres(store repository.Store, g *models.Game) error {
+	var ids []int
+	for _, n := range splitNames(g.Genre) {
+		genre, err := store.Genres().GetOrCreate(n.name, n.slug)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, genre.ID)
+	}
+	return store.Genres().SetForGame(g.ID, ids)
+}
+
+// syncPlatforms приводит связи игры со справочником платформ в соответствие
+// со строкой Platforms. store может быть транзакцией
+func syncPlatforms(store repository.Store, g *models.Game) error {
+	var ids []int
+	for _, n := range splitNames(g.Platforms) {
+		platform, err := store.Platforms().GetOrCreate(n.name, n.slug)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, platform.ID)
+	}
+	return store.Platforms().SetForGame(g.ID, ids)
+}
+
+// syncCatalog обновляет связи игры со всеми справочниками: жанрами, компаниями и платформами
+func syncCatalog(store repository.Store, g *models.Game) error {
+	if err := syncGenres(store, g); err != nil {
+		return err
+	}
+	if err := syncDevelopers(store, g); err != nil {
+		return err
+	}
+	if err := syncPublishers(store, g); err != nil {
+		return err
+	}
+	return syncPlatforms(store, g)
+}
+
+// BackfillGenres раскладывает по справочнику жанры игр, созданных до его появления
+func (s *GameService) BackfillGenres() error {
+	const op = "services.games.BackfillGenres"
+
+	games, err := s.store.Games().ListWithoutGenres()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range games {
+		if err := s.store.Transaction(func(tx repository.Store) error {
+			return syncGenres(tx, &games[i])
+		}); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
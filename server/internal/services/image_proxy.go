@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"games_webapp/internal/httpx"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrProxyURL      = errors.New("invalid image url")
+	ErrProxyHost     = errors.New("image host is not allowed")
+	ErrProxyTooLarge = errors.New("image is too large")
+	ErrProxyNotImage = errors.New("not an image")
+	ErrProxyUpstream = errors.New("image source failed")
+)
+
+// ImageProxyPolicy — откуда и какие картинки отдаёт ImageProxyService
+type ImageProxyPolicy struct {
+	// AllowedHosts — хосты, с которых можно брать картинки, вместе с поддоменами
+	AllowedHosts []string
+	MaxSize      int64
+	// CacheTTL — сколько картинка считается свежей. Устаревшая отдаётся, если
+	// источник не ответил
+	CacheTTL time.Duration
+}
+
+// ImageProxyService скачивает внешние обложки и хранит их в папке кэша, чтобы
+// отдавать через наш домен: браузер не блокирует http-картинки на https-странице,
+// а источник не видит чужой Referer
+type ImageProxyService struct {
+	dir    string
+	log    *slog.Logger
+	http   *http.Client
+	policy ImageProxyPolicy
+
+	// Одну картинку, которую просят одновременно, скачиваем один раз
+	group singleflight.Group
+}
+
+func NewImageProxyService(log *slog.Logger, dir string, policy ImageProxyPolicy) (*ImageProxyService, error) {
+	const op = "services.image_proxy.New"
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s := &ImageProxyService{dir: dir, log: log, policy: policy, http: httpx.Client(0)}
+	// Перенаправление не должно увести на хост не из списка
+	s.http.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		if !s.allowedHost(req.URL.Hostname()) {
+			return ErrProxyHost
+		}
+		return nil
+	}
+	return s, nil
+}
+
+// Open возвращает файл картинки rawURL из кэша, скачивая её, если в кэше её нет
+// или она устарела
+func (s *ImageProxyService) Open(ctx context.Context, rawURL string) (*os.File, error) {
+	const op = "services.image_proxy.Open"
+
+	u, err := s.parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sum := sha256.Sum256([]byte(u.String()))
+	key := hex.EncodeToString(sum[:])
+	path := filepath.Join(s.dir, key)
+
+	info, statErr := os.Stat(path)
+	if statErr == nil && time.Since(info.ModTime()) < s.policy.CacheTTL {
+		return os.Open(path)
+	}
+
+	// Скачивание не прерывается, если первый запросивший ушёл: картинку ждут и другие
+	_, err, _ = s.group.Do(key, func() (interface{}, error) {
+		return nil, s.fetch(context.WithoutCancel(ctx), u, key)
+	})
+	if err != nil {
+		if statErr == nil {
+			s.log.Warn("serving stale image", slog.String("operation", op), slog.String("url", u.String()), slog.String("error", err.Error()))
+			return os.Open(path)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return os.Open(path)
+}
+
+// parse проверяет ссылку: http или https на разрешённом хосте. Ссылки без
+// схемы, как у IGDB, считаются https
+func (s *ImageProxyService) parse(rawURL string) (*url.URL, error) {
+	if strings.HasPrefix(rawURL, "//") {
+		rawURL = "https:" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return nil, ErrProxyURL
+	}
+	if !s.allowedHost(u.Hostname()) {
+		return nil, ErrProxyHost
+	}
+	u.Fragment = ""
+	return u, nil
+}
+
+func (s *ImageProxyService) allowedHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range s.policy.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch скачивает картинку и атомарно кладёт её в кэш под именем key
+func (s *ImageProxyService) fetch(ctx context.Context, u *url.URL, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return ErrProxyURL
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrProxyHost) {
+			return ErrProxyHost
+		}
+		return fmt.Errorf("%w: %s", ErrProxyUpstream, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", ErrProxyUpstream, resp.StatusCode)
+	}
+	if resp.ContentLength > s.policy.MaxSize {
+		return ErrProxyTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.policy.MaxSize+1))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrProxyUpstream, err)
+	}
+	if int64(len(data)) > s.policy.MaxSize {
+		return ErrProxyTooLarge
+	}
+	// Тип определяется по содержимому: SVG и HTML под видом картинки не пройдут
+	if !strings.HasPrefix(http.DetectContentType(data), "image/") {
+		return ErrProxyNotImage
+	}
+
+	tmp, err := os.CreateTemp(s.dir, key+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, key))
+}
+
+// Cleanup удаляет из кэша картинки, устаревшие больше чем на CacheTTL, и
+// брошенные временные файлы. Возвращает число удалённых файлов
+func (s *ImageProxyService) Cleanup() (int, error) {
+	const op = "services.image_proxy.Cleanup"
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	removed := 0
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return removed, fmt.Errorf("%s: %w", op, err)
+		}
+		if time.Since(info.ModTime()) < 2*s.policy.CacheTTL {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, e.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, fmt.Errorf("%s: %w", op, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+// AcquireImage регистрирует ссылку на картинку с данным хэшем содержимого.
+// Если такая картинка уже есть, возвращает её имя файла и isNew == false —
+// тогда файл записывать не нужно. Иначе картинка заводится под filename и
+// засчитывается в квоту загрузок uploadedBy (0 — ничью)
+func (s *GameService) AcquireImage(ctx context.Context, hash, filename string, size int64, uploadedBy int) (name string, isNew bool, err error) {
+	const op = "services.images.AcquireImage"
+
+	store := s.store.WithContext(ctx)
+
+	err = store.Transaction(func(tx repository.Store) error {
+		img, err := tx.Images().GetByHash(hash)
+		if err == nil {
+			name = img.Filename
+			return tx.Images().AddRefs(img.ID, 1)
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+
+		now := time.Now()
+		name, isNew = filename, true
+		return tx.Images().Create(&models.Image{
+			Hash:       hash,
+			Filename:   filename,
+			Refs:       1,
+			CreatedAt:  &now,
+			Size:       size,
+			UploadedBy: uploadedBy,
+		})
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return name, isNew, nil
+}
+
+// ReleaseImage снимает одну ссылку с картинки. Возвращает true, если ссылок
+// не осталось и файл можно удалить. Файлы, загруженные до учёта ссылок,
+// в таблице не записаны и удаляются сразу, как раньше
+func (s *GameService) ReleaseImage(ctx context.Context, filename string) (bool, error) {
+	const op = "services.images.ReleaseImage"
+
+	store := s.store.WithContext(ctx)
+
+	var remove bool
+	err := store.Transaction(func(tx repository.Store) error {
+		img, err := tx.Images().GetByFilename(filename)
+		if errors.Is(err, storage.ErrNotFound) {
+			remove = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if img.Refs > 1 {
+			return tx.Images().AddRefs(img.ID, -1)
+		}
+
+		remove = true
+		return tx.Images().Delete(img.ID)
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return remove, nil
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+)
+
+var ErrLoginLocked = errors.New("login temporarily locked")
+
+// LoginPolicy — сколько неудачных входов прощается и как растёт задержка после них
+type LoginPolicy struct {
+	// FreeAttempts неудач подряд не вызывают задержки
+	FreeAttempts int
+	// BaseDelay — задержка после первой неудачи сверх бесплатных, дальше она удваивается
+	BaseDelay time.Duration
+	// MaxDelay ограничивает удвоение
+	MaxDelay time.Duration
+	// После LockoutAfter неудач ключ блокируется на LockoutDuration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	// Window — через сколько без неудач счётчик сбрасывается
+	Window time.Duration
+}
+
+// delay возвращает, на сколько закрыть вход после failures неудач подряд
+func (p LoginPolicy) delay(failures int) time.Duration {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	d := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// LoginGuardService защищает вход от перебора паролей. Неудачи считаются
+// отдельно по email и по IP-адресу: первый счётчик защищает аккаунт от
+// распределённого перебора, второй — все аккаунты от одного адреса
+type LoginGuardService struct {
+	store repository.Store
+	log   *slog.Logger
+
+	mu     sync.RWMutex
+	policy LoginPolicy
+}
+
+func NewLoginGuardService(store repository.Store, log *slog.Logger, policy LoginPolicy) *LoginGuardService {
+	return &LoginGuardService{
+		store:  store,
+		log:    log,
+		policy: policy,
+	}
+}
+
+// SetPolicy меняет правила при перечитывании конфига. Уже выставленные
+// блокировки не пересчитываются
+func (s *LoginGuardService) SetPolicy(p LoginPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policy = p
+}
+
+func (s *LoginGuardService) currentPolicy() LoginPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.policy
+}
+
+// Check возвращает ErrLoginLocked и время снятия блокировки, если вход для
+// email или ip сейчас закрыт
+func (s *LoginGuardService) Check(email, ip string) (time.Time, error) {
+	const op = "services.login_guard.Check"
+
+	attempts, err := s.store.LoginAttempts().Get(loginKeys(email, ip))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	var until time.Time
+	for _, a := range attempts {
+		if a.LockedUntil != nil && a.LockedUntil.After(now) && a.LockedUntil.After(until) {
+			until = *a.LockedUntil
+		}
+	}
+	if !until.IsZero() {
+		return until, fmt.Errorf("%s: %w", op, ErrLoginLocked)
+	}
+
+	return time.Time{}, nil
+}
+
+// Fail учитывает неудачный вход и продлевает задержку по обоим ключам.
+// Счётчик увеличивается в базе, поэтому параллельные неудачи не теряются
+func (s *LoginGuardService) Fail(email, ip string) error {
+	const op = "services.login_guard.Fail"
+
+	policy := s.currentPolicy()
+	now := time.Now()
+	if err := s.store.Transaction(func(tx repository.Store) error {
+		for _, key := range loginKeys(email, ip) {
+			failures, err := tx.LoginAttempts().Increment(key, now, now.Add(-policy.Window))
+			if err != nil {
+				return err
+			}
+
+			d := policy.delay(failures)
+			if d <= 0 {
+				continue
+			}
+			until := now.Add(d)
+			if err := tx.LoginAttempts().Lock(key, until); err != nil {
+				return err
+			}
+			if failures == policy.LockoutAfter {
+				s.log.Warn("login locked", slog.String("operation", op), slog.String("key", key), slog.Time("until", until))
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Succeed сбрасывает счётчик email. Счётчик IP остаётся: иначе с одного адреса
+// можно было бы обнулять его входом в свой аккаунт между попытками подбора
+func (s *LoginGuardService) Succeed(email string) error {
+	const op = "services.login_guard.Succeed"
+
+	if _, err := s.store.LoginAttempts().Delete([]string{emailLoginKey(email)}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListLocked возвращает ключи, вход по которым сейчас закрыт
+func (s *LoginGuardService) ListLocked() ([]models.LoginAttempt, error) {
+	const op = "services.login_guard.ListLocked"
+
+	attempts, err := s.store.LoginAttempts().ListLocked(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return attempts, nil
+}
+
+// Clear снимает блокировку и сбрасывает счётчики для email и/или ip.
+// Возвращает, сколько счётчиков удалено
+func (s *LoginGuardService) Clear(email, ip string) (int, error) {
+	const op = "services.login_guard.Clear"
+
+	var keys []string
+	if email != "" {
+		keys = append(keys, emailLoginKey(email))
+	}
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	cleared, err := s.store.LoginAttempts().Delete(keys)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cleared, nil
+}
+
+// Cleanup удаляет счётчики, которые уже сбросились бы по окну
+func (s *LoginGuardService) Cleanup() error {
+	const op = "services.login_guard.Cleanup"
+
+	now := time.Now()
+	if _, err := s.store.LoginAttempts().DeleteStale(now.Add(-s.currentPolicy().Window), now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func loginKeys(email, ip string) []string {
+	return []string{emailLoginKey(email), "ip:" + ip}
+}
+
+func emailLoginKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,74 @@
+package services_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"games_webapp/internal/services"
+	"games_webapp/internal/testutil"
+)
+
+func TestLoginGuardCountsConcurrentFailures(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+
+	const attempts = 50
+	guard := services.NewLoginGuardService(srv.Store(), slog.New(slog.NewTextHandler(io.Discard, nil)), services.LoginPolicy{
+		FreeAttempts:    attempts, // задержек до блокировки нет, закрыть вход может только она
+		LockoutAfter:    attempts,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	})
+
+	// Все попытки проходят Check до того, как записана хоть одна неудача
+	if _, err := guard.Check("player@example.com", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+
+	// На одном ядре горутины шли бы по очереди и гонку не показали бы
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- guard.Fail("player@example.com", "203.0.113.7")
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counters, err := srv.Store().LoginAttempts().Get([]string{"email:player@example.com", "ip:203.0.113.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counters) != 2 {
+		t.Fatalf("got %d counters, want 2", len(counters))
+	}
+	for _, c := range counters {
+		if c.Failures != attempts {
+			t.Errorf("%s: failures = %d, want %d", c.Key, c.Failures, attempts)
+		}
+	}
+
+	if _, err := guard.Check("player@example.com", "198.51.100.1"); !errors.Is(err, services.ErrLoginLocked) {
+		t.Errorf("email check err = %v, want %v", err, services.ErrLoginLocked)
+	}
+	if _, err := guard.Check("other@example.com", "203.0.113.7"); !errors.Is(err, services.ErrLoginLocked) {
+		t.Errorf("ip check err = %v, want %v", err, services.ErrLoginLocked)
+	}
+}
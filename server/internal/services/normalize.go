@@ -0,0 +1,77 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var titleStopWords = map[string]bool{
+	"the": true,
+	"a":   true,
+	"an":  true,
+}
+
+var romanNumerals = map[string]string{
+	"ii":   "2",
+	"iii":  "3",
+	"iv":   "4",
+	"v":    "5",
+	"vi":   "6",
+	"vii":  "7",
+	"viii": "8",
+	"ix":   "9",
+	"x":    "10",
+}
+
+// normalizeTitle приводит название к виду, в котором сравниваются возможные дубликаты:
+// нижний регистр, без пунктуации, артиклей и с арабскими цифрами вместо римских
+func normalizeTitle(title string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			return unicode.ToLower(r)
+		default:
+			return ' '
+		}
+	}, title)
+
+	var words []string
+	for _, w := range strings.Fields(cleaned) {
+		if titleStopWords[w] {
+			continue
+		}
+		if n, ok := romanNumerals[w]; ok {
+			w = n
+		}
+		words = append(words, w)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// TitleKey — название в том виде, в котором его сравнивает SameTitle
+func TitleKey(title string) string {
+	return normalizeTitle(title)
+}
+
+// SameTitle сообщает, совпадают ли названия после нормализации
+func SameTitle(a, b string) bool {
+	return normalizeTitle(a) == normalizeTitle(b)
+}
+
+// yearsMatch считает годы совпадающими, если один из них неизвестен
+// или они отличаются не больше чем на год (релизы на разных платформах)
+func yearsMatch(a, b string) bool {
+	ya, errA := strconv.Atoi(strings.TrimSpace(a))
+	yb, errB := strconv.Atoi(strings.TrimSpace(b))
+	if errA != nil || errB != nil {
+		return true
+	}
+
+	diff := ya - yb
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= 1
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"text/template"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+const (
+	defaultFinishedTemplate = `🎮 Пользователь #{{.UserID}} прошёл «{{.Game}}»`
+	defaultImportTemplate   = `📥 Пользователь #{{.UserID}} импортировал игры из {{.Provider}}: добавлено {{.Succeeded}} из {{.Requested}}` +
+		`{{if .Failed}}, ошибок {{.Failed}}{{end}}{{if .Review}}, ждут выбора {{.Review}}{{end}}`
+
+	// notifyTimeout ограничивает одну отправку уведомления вместе с запросами к базе
+	notifyTimeout = 30 * time.Second
+)
+
+// statusNames — названия статусов для текстов уведомлений
+var statusNames = map[models.GameStatus]string{
+	models.StatusPlanned:  "в планах",
+	models.StatusPlaying:  "играю",
+	models.StatusFinished: "пройдено",
+	models.StatusDropped:  "брошено",
+}
+
+// Notifier получает события библиотеки, о которых стоит сообщить во внешний канал.
+// Методы не должны блокировать запрос и не возвращают ошибок. Переход в «пройдено»
+// приходит и как StatusChanged, и как GameFinished — канал выбирает, что слать
+type Notifier interface {
+	StatusChanged(userID, gameID int, previous, status models.GameStatus)
+	GameFinished(userID, gameID int)
+	ImportFinished(run *models.ImportRun)
+}
+
+type DiscordSender interface {
+	Send(ctx context.Context, content string) error
+}
+
+// Tracker позволяет дождаться фоновых отправок при остановке
+type Tracker interface {
+	Track() (done func(), ok bool)
+}
+
+// FinishedMessage — данные шаблона сообщения о пройденной игре
+type FinishedMessage struct {
+	UserID int
+	GameID int
+	Game   string
+}
+
+// ImportMessage — данные шаблона сообщения о завершённом импорте
+type ImportMessage struct {
+	UserID    int
+	Provider  string
+	Requested int
+	Succeeded int
+	Failed    int
+	Review    int
+}
+
+// DiscordNotifier публикует в Discord сообщения о пройденных играх и импортах
+// пользователей, включивших это в настройках. Отправка идёт в фоне
+type DiscordNotifier struct {
+	store    repository.Store
+	log      *slog.Logger
+	client   DiscordSender
+	tracker  Tracker
+	finished *template.Template
+	imported *template.Template
+}
+
+// NewDiscordNotifier разбирает шаблоны сообщений. Пустой шаблон заменяется стандартным
+func NewDiscordNotifier(store repository.Store, log *slog.Logger, client DiscordSender, tracker Tracker, finishedTmpl, importTmpl string) (*DiscordNotifier, error) {
+	const op = "services.notifications.NewDiscordNotifier"
+
+	if finishedTmpl == "" {
+		finishedTmpl = defaultFinishedTemplate
+	}
+	if importTmpl == "" {
+		importTmpl = defaultImportTemplate
+	}
+
+	finished, err := template.New("finished").Parse(finishedTmpl)
+	if err != nil {
+		return nil, fmt.Errorf("%s: finished template: %w", op, err)
+	}
+	imported, err := template.New("import").Parse(importTmpl)
+	if err != nil {
+		return nil, fmt.Errorf("%s: import template: %w", op, err)
+	}
+
+	return &DiscordNotifier{
+		store:    store,
+		log:      log,
+		client:   client,
+		tracker:  tracker,
+		finished: finished,
+		imported: imported,
+	}, nil
+}
+
+// StatusChanged не публикуется: в общий канал идут только пройденные игры
+func (n *DiscordNotifier) StatusChanged(userID, gameID int, previous, status models.GameStatus) {}
+
+func (n *DiscordNotifier) GameFinished(userID, gameID int) {
+	n.send("services.notifications.GameFinished", userID, func() (*template.Template, interface{}, error) {
+		game, err := n.store.Games().GetByID(gameID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return n.finished, FinishedMessage{UserID: userID, GameID: gameID, Game: game.Title}, nil
+	})
+}
+
+func (n *DiscordNotifier) ImportFinished(run *models.ImportRun) {
+	msg := ImportMessage{
+		UserID:    run.UserID,
+		Provider:  run.Provider,
+		Requested: run.Requested,
+		Succeeded: run.Succeeded,
+		Failed:    run.Failed,
+		Review:    run.Review,
+	}
+	n.send("services.notifications.ImportFinished", run.UserID, func() (*template.Template, interface{}, error) {
+		return n.imported, msg, nil
+	})
+}
+
+// send в фоне проверяет, включены ли уведомления у пользователя, собирает
+// сообщение и отправляет его. Если игра к этому моменту не найдена (например,
+// транзакция откатилась), сообщение не отправляется
+func (n *DiscordNotifier) send(op string, userID int, build func() (*template.Template, interface{}, error)) {
+	runNotification(n.tracker, n.log, op, func(ctx context.Context) error {
+		settings, err := n.store.Settings().Get(userID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !settings.DiscordNotify {
+			return nil
+		}
+
+		tmpl, data, err := build()
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var content bytes.Buffer
+		if err := tmpl.Execute(&content, data); err != nil {
+			return err
+		}
+
+		return n.client.Send(ctx, content.String())
+	})
+}
+
+// runNotification выполняет отправку в фоне, чтобы не задерживать запрос.
+// Остановка приложения дожидается начатых отправок; после её начала новые
+// не запускаются
+func runNotification(tracker Tracker, log *slog.Logger, op string, fn func(ctx context.Context) error) {
+	done, ok := tracker.Track()
+	if !ok {
+		return
+	}
+
+	go func() {
+		defer done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+
+		if err := fn(ctx); err != nil {
+			log.Error("failed to send notification", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}()
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+// GetOverride возвращает личные правки пользователя к игре. Если их нет — storage.ErrNotFound
+func (s *GameService) GetOverride(ctx context.Context, userID, gameID int) (*models.GameOverride, error) {
+	const op = "services.overrides.GetOverride"
+
+	store := s.store.WithContext(ctx)
+
+	o, err := store.Overrides().Get(userID, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return o, nil
+}
+
+// SetOverride заменяет название и заметки в правках пользователя. image == nil
+// оставляет прежнюю обложку, пустая строка убирает её. Правка без полей удаляется.
+// Возвращает обложку, которая больше не используется, чтобы снять с неё ссылку
+func (s *GameService) SetOverride(ctx context.Context, userID, gameID int, title, notes string, image *string) (o *models.GameOverride, oldImage string, err error) {
+	const op = "services.overrides.SetOverride"
+
+	store := s.store.WithContext(ctx)
+
+	err = store.Transaction(func(tx repository.Store) error {
+		if _, err := tx.Games().GetByID(gameID); err != nil {
+			return err
+		}
+
+		existing, err := tx.Overrides().Get(userID, gameID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+
+		now := time.Now()
+		o = &models.GameOverride{UserID: userID, GameID: gameID, Title: title, Notes: notes, UpdatedAt: &now}
+		if existing != nil {
+			o.Image = existing.Image
+		}
+		if image != nil && *image != o.Image {
+			oldImage, o.Image = o.Image, *image
+		}
+
+		if o.IsEmpty() {
+			return tx.Overrides().Delete(userID, gameID)
+		}
+		return tx.Overrides().Save(o)
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if o.IsEmpty() {
+		return nil, oldImage, nil
+	}
+	return o, oldImage, nil
+}
+
+// DeleteOverride удаляет правки пользователя к игре и возвращает их обложку
+func (s *GameService) DeleteOverride(ctx context.Context, userID, gameID int) (string, error) {
+	const op = "services.overrides.DeleteOverride"
+
+	store := s.store.WithContext(ctx)
+
+	var image string
+	err := store.Transaction(func(tx repository.Store) error {
+		o, err := tx.Overrides().Get(userID, gameID)
+		if err != nil {
+			return err
+		}
+		image = o.Image
+		return tx.Overrides().Delete(userID, gameID)
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return image, nil
+}
+
+// applyOverrides подставляет в игры библиотеки личные название и обложку
+// пользователя. Правки всей страницы загружаются одним запросом
+func applyOverrides(store repository.Store, userID int, games []*models.UserGameResponse) error {
+	if len(games) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(games))
+	for i, g := range games {
+		ids[i] = g.ID
+	}
+
+	overrides, err := store.Overrides().ListForGames(userID, ids)
+	if err != nil {
+		return err
+	}
+
+	byGame := make(map[int]*models.GameOverride, len(overrides))
+	for i := range overrides {
+		byGame[overrides[i].GameID] = &overrides[i]
+	}
+
+	for _, g := range games {
+		o, ok := byGame[g.ID]
+		if !ok {
+			continue
+		}
+		g.Override = o
+		if o.Title != "" {
+			g.Title = o.Title
+		}
+		if o.Image != "" {
+			g.Image = o.Image
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+// publicEventTypes — события, которые видны на публичной странице профиля
+var publicEventTypes = []models.EventType{models.EventGameAdded, models.EventGameFinished}
+
+// PublicService отдаёт данные открытых профилей пользователям без авторизации
+type PublicService struct {
+	store repository.Store
+	log   *slog.Logger
+}
+
+func NewPublicService(store repository.Store, log *slog.Logger) *PublicService {
+	return &PublicService{
+		store: store,
+		log:   log,
+	}
+}
+
+// GetPublicActivity возвращает последние добавления и прохождения пользователя
+// с открытым профилем. Закрытый и несуществующий профиль одинаково дают storage.ErrNotFound
+func (s *PublicService) GetPublicActivity(slug string, limit int) ([]models.FeedItem, error) {
+	const op = "services.public.GetPublicActivity"
+
+	settings, err := s.store.Settings().GetByPublicSlug(slug)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !settings.IsPublic() {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	items, err := s.store.Events().ListRecentForUser(settings.UserID, publicEventTypes, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+var ErrQuotaExceeded = errors.New("upload quota exceeded")
+
+// QuotaError — файл не поместился в квоту загрузок пользователя
+type QuotaError struct {
+	Used  int64
+	Size  int64
+	Limit int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: used %d of %d bytes, file is %d bytes", ErrQuotaExceeded, e.Used, e.Limit, e.Size)
+}
+
+func (e *QuotaError) Unwrap() error { return ErrQuotaExceeded }
+
+// QuotaService считает место, которое пользователь занимает в загрузках:
+// обложки, которые он загрузил первым, и его фото. Обложки, скачанные из
+// внешних источников, ни на кого не записываются
+type QuotaService struct {
+	store repository.Store
+	log   *slog.Logger
+	// limit — квота в байтах, 0 — без ограничения. Меняется при перечитывании конфига
+	limit atomic.Int64
+}
+
+func NewQuotaService(store repository.Store, log *slog.Logger, limit int64) *QuotaService {
+	s := &QuotaService{store: store, log: log}
+	s.limit.Store(limit)
+	return s
+}
+
+// SetLimit меняет квоту; 0 отключает ограничение
+func (s *QuotaService) SetLimit(limit int64) {
+	s.limit.Store(limit)
+}
+
+// Usage возвращает занятое пользователем место и текущую квоту
+func (s *QuotaService) Usage(ctx context.Context, userID int) (models.UploadUsage, error) {
+	const op = "services.quota.Usage"
+
+	store := s.store.WithContext(ctx)
+
+	usage := models.UploadUsage{Limit: s.limit.Load()}
+	covers, err := store.Images().SizeByUploader(userID)
+	if err != nil {
+		return usage, fmt.Errorf("%s: %w", op, err)
+	}
+	usage.Covers = covers
+
+	photo, err := store.Photos().Get(userID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return usage, fmt.Errorf("%s: %w", op, err)
+	}
+	if photo != nil {
+		usage.Photo = photo.Size
+	}
+
+	usage.Used = usage.Covers + usage.Photo
+	return usage, nil
+}
+
+// CheckCover проверяет, что новая обложка размером size поместится в квоту
+func (s *QuotaService) CheckCover(ctx context.Context, userID int, size int64) error {
+	const op = "services.quota.CheckCover"
+
+	if err := s.check(ctx, userID, size, false); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// CheckPhoto проверяет, что фото размером size поместится в квоту вместо
+// текущего фото пользователя
+func (s *QuotaService) CheckPhoto(ctx context.Context, userID int, size int64) error {
+	const op = "services.quota.CheckPhoto"
+
+	if err := s.check(ctx, userID, size, true); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *QuotaService) check(ctx context.Context, userID int, size int64, replacesPhoto bool) error {
+	if s.limit.Load() <= 0 {
+		return nil
+	}
+
+	usage, err := s.Usage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	used := usage.Used
+	if replacesPhoto {
+		used -= usage.Photo
+	}
+	if used+size > usage.Limit {
+		return &QuotaError{Used: usage.Used, Size: size, Limit: usage.Limit}
+	}
+	return nil
+}
+
+// SetPhoto запоминает размер нового фото пользователя вместо прежнего
+func (s *QuotaService) SetPhoto(ctx context.Context, userID int, filename string, size int64) error {
+	const op = "services.quota.SetPhoto"
+
+	now := time.Now()
+	if err := s.store.WithContext(ctx).Photos().Save(&models.UserPhoto{
+		UserID:    userID,
+		Filename:  filename,
+		Size:      size,
+		UpdatedAt: &now,
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
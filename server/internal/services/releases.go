@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"games_webapp/internal/models"
+)
+
+// releaseLayouts — форматы полной даты, которые встречаются в старых значениях year
+var releaseLayouts = []string{
+	"2006-01-02",
+	"02.01.2006",
+	"2.1.2006",
+	"2006/01/02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+}
+
+var yearPattern = regexp.MustCompile(`\b(1[89]\d{2}|2\d{3})\b`)
+
+// parseRelease по возможности разбирает дату выхода из произвольной строки: сначала
+// как полную дату, затем ищет в ней год. Для строк вроде "199?" возвращает ok = false
+func parseRelease(s string) (time.Time, models.DatePrecision, bool) {
+	s = strings.TrimSpace(s)
+
+	for _, layout := range releaseLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, models.PrecisionDay, true
+		}
+	}
+
+	if m := yearPattern.FindString(s); m != "" {
+		year, _ := strconv.Atoi(m)
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), models.PrecisionYear, true
+	}
+
+	return time.Time{}, "", false
+}
+
+// applyReleaseDate приводит дату выхода и год к согласованному виду: release_date
+// разбирается из year, если её нет, а year всегда вычисляется из release_date
+func applyReleaseDate(g *models.Game) {
+	if g.ReleaseDate == nil && g.Year != "" {
+		if t, precision, ok := parseRelease(g.Year); ok {
+			g.ReleaseDate = &t
+			g.ReleasePrecision = precision
+		}
+	}
+
+	if g.ReleaseDate != nil {
+		if g.ReleasePrecision == "" {
+			g.ReleasePrecision = models.PrecisionDay
+		}
+		g.Year = strconv.Itoa(g.ReleaseDate.Year())
+	}
+}
+
+// BackfillReleaseDates заполняет release_date у игр, для которых был известен только
+// строковый year. Значения, из которых не удалось достать год, остаются как есть
+func (s *GameService) BackfillReleaseDates() error {
+	const op = "services.games.BackfillReleaseDates"
+
+	games, err := s.store.Games().ListWithoutReleaseDate()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range games {
+		g := &games[i]
+		applyReleaseDate(g)
+		if g.ReleaseDate == nil {
+			continue
+		}
+		if err := s.store.Games().SetReleaseDate(g.ID, *g.ReleaseDate, g.ReleasePrecision, g.Year); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
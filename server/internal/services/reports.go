@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/storage"
+
+	ssov1 "github.com/Nergous/sso_protos/gen/go/sso"
+	"github.com/xuri/excelize/v2"
+)
+
+// ReportWriter принимает отчёт построчно, чтобы не собирать его целиком в памяти
+type ReportWriter interface {
+	WriteRow(row ...string) error
+	Close() error
+}
+
+type csvReportWriter struct {
+	w *csv.Writer
+}
+
+func NewCSVReportWriter(w io.Writer) ReportWriter {
+	return &csvReportWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvReportWriter) WriteRow(row ...string) error {
+	if err := c.w.Write(row); err != nil {
+		return err
+	}
+	// Сбрасываем буфер после каждой строки, чтобы клиент получал данные по мере генерации
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvReportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type xlsxReportWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func NewXLSXReportWriter(w io.Writer) (ReportWriter, error) {
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxReportWriter{out: w, file: f, stream: sw, row: 1}, nil
+}
+
+func (x *xlsxReportWriter) WriteRow(row ...string) error {
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(row))
+	for i, v := range row {
+		values[i] = v
+	}
+
+	x.row++
+	return x.stream.SetRow(cell, values)
+}
+
+func (x *xlsxReportWriter) Close() error {
+	defer x.file.Close()
+
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.out)
+}
+
+type SSOUsersProvider interface {
+	GetUsersForApp(ctx context.Context, appID uint32) (*ssov1.GetAllUsersForAppResponse, error)
+}
+
+type StorageUsager interface {
+	Usage(from, to time.Time) (total int64, added int64, err error)
+}
+
+type ReportService struct {
+	storage storage.Storage
+	log     *slog.Logger
+	users   SSOUsersProvider
+	uploads StorageUsager
+}
+
+func NewReportService(s storage.Storage, log *slog.Logger, users SSOUsersProvider, uploads StorageUsager) *ReportService {
+	return &ReportService{
+		storage: s,
+		log:     log,
+		users:   users,
+		uploads: uploads,
+	}
+}
+
+// WriteMonthly пишет отчёт за месяц, в который попадает month
+func (s *ReportService) WriteMonthly(ctx context.Context, month time.Time, w ReportWriter) error {
+	const op = "services.reports.WriteMonthly"
+
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	to := from.AddDate(0, 1, 0)
+
+	write := func(row ...string) error {
+		if err := w.WriteRow(row...); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
+
+	if err := write("section", "metric", "value"); err != nil {
+		return err
+	}
+	if err := write("period", "from", from.Format("2006-01-02")); err != nil {
+		return err
+	}
+	if err := write("period", "to", to.Format("2006-01-02")); err != nil {
+		return err
+	}
+
+	// SSO не отдаёт дату регистрации, поэтому новых пользователей считаем по
+	// первому событию в библиотеке, а общее количество берём из SSO
+	usersTotal := "n/a"
+	if resp, err := s.users.GetUsersForApp(ctx, 1); err != nil {
+		s.log.Error("failed to get users from sso", slog.String("operation", op), slog.String("error", err.Error()))
+	} else {
+		usersTotal = strconv.Itoa(len(resp.GetUsers()))
+	}
+	if err := write("users", "total", usersTotal); err != nil {
+		return err
+	}
+
+	var newUsers int64
+	if err := s.storage.DB().
+		Table("(?) as firsts", s.storage.DB().
+			Model(&models.Event{}).
+			Select("user_id, MIN(created_at) as first_at").
+			Group("user_id")).
+		Where("first_at >= ? AND first_at < ?", from, to).
+		Count(&newUsers).Error; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := write("users", "new", strconv.FormatInt(newUsers, 10)); err != nil {
+		return err
+	}
+
+	var gamesAdded int64
+	if err := s.storage.DB().
+		Model(&models.Game{}).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Count(&gamesAdded).Error; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := write("games", "added", strconv.FormatInt(gamesAdded, 10)); err != nil {
+		return err
+	}
+
+	var imports struct {
+		Runs      int64
+		Requested int64
+		Succeeded int64
+		Failed    int64
+	}
+	if err := s.storage.DB().
+		Model(&models.ImportRun{}).
+		Select("COUNT(*) as runs, COALESCE(SUM(requested), 0) as requested, COALESCE(SUM(succeeded), 0) as succeeded, COALESCE(SUM(failed), 0) as failed").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Scan(&imports).Error; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for _, m := range []struct {
+		name  string
+		value int64
+	}{
+		{"runs", imports.Runs},
+		{"requested", imports.Requested},
+		{"succeeded", imports.Succeeded},
+		{"failed", imports.Failed},
+	} {
+		if err := write("imports", m.name, strconv.FormatInt(m.value, 10)); err != nil {
+			return err
+		}
+	}
+
+	rows, err := s.storage.DB().
+		Model(&models.ImportRun{}).
+		Select("provider, SUM(failed) as failed").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("provider").
+		Having("SUM(failed) > 0").
+		Order("failed desc").
+		Limit(10).
+		Rows()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var provider string
+		var failed int64
+		if err := rows.Scan(&provider, &failed); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := write("failing_providers", provider, strconv.FormatInt(failed, 10)); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	total, added, err := s.uploads.Usage(from, to)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := write("storage", "total_bytes", strconv.FormatInt(total, 10)); err != nil {
+		return err
+	}
+	if err := write("storage", "added_bytes", strconv.FormatInt(added, 10)); err != nil {
+		return err
+	}
+
+	return nil
+}
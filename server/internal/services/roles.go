@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+// ErrInvalidRole — роль, которую нельзя назначить в приложении (admin выдаётся в SSO)
+var ErrInvalidRole = errors.New("invalid role")
+
+type RoleService struct {
+	store repository.Store
+	log   *slog.Logger
+}
+
+func NewRoleService(store repository.Store, log *slog.Logger) *RoleService {
+	return &RoleService{
+		store: store,
+		log:   log,
+	}
+}
+
+// GetRole возвращает роль, назначенную пользователю в приложении, или user
+func (s *RoleService) GetRole(userID int) (models.Role, error) {
+	const op = "services.roles.GetRole"
+
+	role, err := s.store.Roles().Get(userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.RoleUser, nil
+	}
+	if err != nil {
+		return models.RoleUser, fmt.Errorf("%s: %w", op, err)
+	}
+	return role.Role, nil
+}
+
+// List возвращает пользователей с назначенными ролями
+func (s *RoleService) List() ([]models.UserRole, error) {
+	const op = "services.roles.List"
+
+	roles, err := s.store.Roles().List()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return roles, nil
+}
+
+// SetRole назначает пользователю роль. Роль user снимает назначенную раньше
+func (s *RoleService) SetRole(userID int, role models.Role, grantedBy int) error {
+	const op = "services.roles.SetRole"
+
+	switch role {
+	case models.RoleUser:
+		if err := s.store.Roles().Delete(userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	case models.RoleModerator:
+	default:
+		return fmt.Errorf("%s: %w", op, ErrInvalidRole)
+	}
+
+	now := time.Now()
+	if err := s.store.Roles().Save(&models.UserRole{
+		UserID:    userID,
+		Role:      role,
+		GrantedBy: grantedBy,
+		UpdatedAt: &now,
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("role changed", slog.String("operation", op),
+		slog.Int("user_id", userID), slog.String("role", string(role)), slog.Int("granted_by", grantedBy))
+	return nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+var ErrSessionRevoked = errors.New("session revoked")
+
+// maxUserAgentLength — длина колонки user_agent
+const maxUserAgentLength = 255
+
+// SessionService ведёт реестр входов. SSO хранит refresh-токены у себя и не
+// умеет их перечислять, поэтому каждая сессия отслеживается по хешу токена,
+// который выдали через нас. Отзыв срабатывает при следующем обновлении токена:
+// access-токен доживает свой короткий срок
+type SessionService struct {
+	store repository.Store
+	log   *slog.Logger
+}
+
+func NewSessionService(store repository.Store, log *slog.Logger) *SessionService {
+	return &SessionService{
+		store: store,
+		log:   log,
+	}
+}
+
+// Start записывает новую сессию после входа
+func (s *SessionService) Start(userID int, refreshToken, userAgent, ip string) error {
+	const op = "services.sessions.Start"
+
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UserID:     userID,
+		TokenHash:  hashRefreshToken(refreshToken),
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  &now,
+		LastUsedAt: &now,
+	}
+	if err := s.store.Sessions().Create(session); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// CheckRefresh возвращает ErrSessionRevoked, если сессию с этим токеном отозвали.
+// Неизвестный токен (выданный до появления реестра) пропускается
+func (s *SessionService) CheckRefresh(refreshToken string) error {
+	const op = "services.sessions.CheckRefresh"
+
+	session, err := s.store.Sessions().GetByTokenHash(hashRefreshToken(refreshToken))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if session.RevokedAt != nil {
+		return fmt.Errorf("%s: %w", op, ErrSessionRevoked)
+	}
+
+	return nil
+}
+
+// Rotate переводит сессию со старого refresh-токена на новый. Если старый
+// токен неизвестен, сессия заводится заново
+func (s *SessionService) Rotate(userID int, oldToken, newToken, userAgent, ip string) error {
+	const op = "services.sessions.Rotate"
+
+	session, err := s.store.Sessions().GetByTokenHash(hashRefreshToken(oldToken))
+	if errors.Is(err, storage.ErrNotFound) {
+		return s.Start(userID, newToken, userAgent, ip)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.store.Sessions().Rotate(session.ID, hashRefreshToken(newToken), time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// List возвращает активные сессии пользователя и отмечает ту, к которой
+// относится currentToken
+func (s *SessionService) List(userID int, currentToken string) ([]models.Session, error) {
+	const op = "services.sessions.List"
+
+	sessions, err := s.store.Sessions().ListActive(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if currentToken != "" {
+		current := hashRefreshToken(currentToken)
+		for i := range sessions {
+			sessions[i].Current = sessions[i].TokenHash == current
+		}
+	}
+
+	return sessions, nil
+}
+
+func (s *SessionService) Revoke(userID, sessionID int) error {
+	const op = "services.sessions.Revoke"
+
+	if err := s.store.Sessions().Revoke(sessionID, userID, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// End удаляет сессию при выходе
+func (s *SessionService) End(refreshToken string) error {
+	const op = "services.sessions.End"
+
+	session, err := s.store.Sessions().GetByTokenHash(hashRefreshToken(refreshToken))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.store.Sessions().Delete(session.ID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Cleanup удаляет сессии, которые не обновлялись дольше maxAge: их
+// refresh-токены к этому времени уже истекли
+func (s *SessionService) Cleanup(maxAge time.Duration) error {
+	const op = "services.sessions.Cleanup"
+
+	deleted, err := s.store.Sessions().DeleteUnusedSince(time.Now().Add(-maxAge))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if deleted > 0 {
+		s.log.Info("expired sessions removed", slog.String("operation", op), slog.Int("count", deleted))
+	}
+
+	return nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+var ErrSlugTaken = errors.New("public slug is taken")
+
+type SettingsService struct {
+	store repository.Store
+	log   *slog.Logger
+}
+
+func NewSettingsService(store repository.Store, log *slog.Logger) *SettingsService {
+	return &SettingsService{
+		store: store,
+		log:   log,
+	}
+}
+
+// GetSettings возвращает настройки пользователя или умолчания, если он их не менял
+func (s *SettingsService) GetSettings(userID int) (*models.UserSettings, error) {
+	const op = "services.settings.GetSettings"
+
+	settings, err := s.store.Settings().Get(userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.DefaultSettings(userID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return settings, nil
+}
+
+// UpdateSettings применяет изменения и возвращает итоговые настройки
+func (s *SettingsService) UpdateSettings(userID int, patch *models.SettingsPatch) (*models.UserSettings, error) {
+	const op = "services.settings.UpdateSettings"
+
+	var settings *models.UserSettings
+	if err := s.store.Transaction(func(tx repository.Store) error {
+		if patch.PublicSlug != nil && *patch.PublicSlug != "" {
+			owner, err := tx.Settings().GetByPublicSlug(*patch.PublicSlug)
+			if err == nil && owner.UserID != userID {
+				return ErrSlugTaken
+			}
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return err
+			}
+		}
+
+		// Запись создаётся заранее: два первых сохранения подряд не должны
+		// упираться в уникальный user_id
+		if err := tx.Settings().CreateDefaults(userID); err != nil {
+			return err
+		}
+
+		var err error
+		settings, err = tx.Settings().Get(userID)
+		if err != nil {
+			return err
+		}
+
+		patch.Apply(settings)
+		return tx.Settings().SavePreferences(settings)
+	}); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"games_webapp/internal/models"
+)
+
+// statsTopGenres — сколько самых частых жанров попадает в статистику
+const statsTopGenres = 5
+
+// GetLibraryStats собирает расширенную статистику библиотеки. Ряд прохождений
+// по месяцам строится за months месяцев, последний из которых — месяц now
+func (s *GameService) GetLibraryStats(ctx context.Context, userID, months int, now time.Time) (*models.LibraryStats, error) {
+	const op = "services.games.GetLibraryStats"
+
+	store := s.store.WithContext(ctx)
+
+	counts, err := s.GetStatusCounts(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	stats := &models.LibraryStats{StatusCounts: *counts}
+
+	// Даты группируются по месяцам здесь, а не в SQL: форматирование дат у каждой СУБД своё
+	start := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, now.Location())
+	dates, err := store.UserGames().FinishedSince(userID, start)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	stats.FinishedPerMonth = monthSeries(start, months, dates)
+
+	spans, err := store.UserGames().FinishSpans(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	stats.AvgDaysToFinish = averageDays(spans)
+
+	if stats.TopGenres, err = store.Genres().TopForUser(userID, statsTopGenres); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if stats.TopGenres == nil {
+		stats.TopGenres = []models.GenreCount{}
+	}
+
+	if counts.Total > 0 {
+		stats.FinishedRatio = float64(counts.Finished) / float64(counts.Total)
+		stats.DroppedRatio = float64(counts.Dropped) / float64(counts.Total)
+	}
+	if decided := counts.Finished + counts.Dropped; decided > 0 {
+		stats.CompletionRate = float64(counts.Finished) / float64(decided)
+	}
+
+	return stats, nil
+}
+
+// monthSeries раскладывает даты по months месяцам начиная с start. Месяцы без дат
+// тоже попадают в ряд, чтобы клиенту не приходилось достраивать ось
+func monthSeries(start time.Time, months int, dates []time.Time) []models.MonthCount {
+	series := make([]models.MonthCount, months)
+	index := make(map[string]int, months)
+	for i := range series {
+		month := start.AddDate(0, i, 0).Format("2006-01")
+		series[i].Month = month
+		index[month] = i
+	}
+
+	for _, d := range dates {
+		if i, ok := index[d.In(start.Location()).Format("2006-01")]; ok {
+			series[i].Count++
+		}
+	}
+	return series
+}
+
+// averageDays возвращает среднее число дней от добавления до прохождения. Прохождения,
+// отмеченные раньше добавления (даты указаны вручную), не учитываются
+func averageDays(spans []models.FinishSpan) float64 {
+	var total time.Duration
+	var n int
+	for _, span := range spans {
+		if d := span.FinishedAt.Sub(span.AddedAt); d >= 0 {
+			total += d
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return total.Hours() / 24 / float64(n)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"games_webapp/internal/models"
+)
+
+// steamAppID достаёт appid из ссылки на страницу игры в Steam
+// (store.steampowered.com/app/<appid>/... или steamcommunity.com/app/<appid>).
+// Для остальных ссылок возвращает 0
+func steamAppID(rawURL string) int {
+	if sourceFromURL(rawURL) != models.SourceSteam {
+		return 0
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "app" {
+		return 0
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil || id <= 0 {
+		return 0
+	}
+	return id
+}
+
+// applySteamAppID заполняет appid по ссылке на Steam. Игра, добавленная вручную
+// по ссылке на Steam, считается игрой из Steam с appid в качестве внешнего ID
+func applySteamAppID(g *models.Game) {
+	appID := steamAppID(g.URL)
+	if appID == 0 {
+		return
+	}
+
+	g.SteamAppID = appID
+	if (g.Source == "" || g.Source == models.SourceManual) && g.ExternalID == "" {
+		g.Source = models.SourceSteam
+		g.ExternalID = strconv.Itoa(appID)
+	}
+}
+
+// BackfillSteamAppIDs заполняет appid у игр, чья ссылка ведёт в Steam, и
+// возвращает количество обновлённых игр
+func (s *GameService) BackfillSteamAppIDs(ctx context.Context) (int, error) {
+	const op = "services.steam.BackfillSteamAppIDs"
+
+	store := s.store.WithContext(ctx)
+
+	games, err := store.Games().ListWithoutSteamAppID()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	updated := 0
+	for _, g := range games {
+		appID := steamAppID(g.URL)
+		if appID == 0 {
+			continue
+		}
+
+		if err := store.Games().SetSteamAppID(g.ID, appID); err != nil {
+			return updated, fmt.Errorf("%s: %w", op, err)
+		}
+		if g.Source == models.SourceSteam && g.ExternalID == "" {
+			if err := store.Games().SetSource(g.ID, models.SourceSteam, strconv.Itoa(appID)); err != nil {
+				return updated, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		updated++
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"games_webapp/internal/clients/telegram"
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+// telegramLinkTTL — сколько действует код привязки чата
+const telegramLinkTTL = 15 * time.Minute
+
+type TelegramClient interface {
+	SendMessage(ctx context.Context, chatID int64, text string) error
+	GetUpdates(ctx context.Context, offset int64) ([]telegram.Update, error)
+}
+
+// TelegramLink — данные для привязки чата: пользователь открывает URL
+// или отправляет боту команду /start <code>
+type TelegramLink struct {
+	Code      string    `json:"code"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TelegramService привязывает чаты Telegram к пользователям и отправляет туда
+// смены статусов и итоги импорта
+type TelegramService struct {
+	store   repository.Store
+	log     *slog.Logger
+	client  TelegramClient
+	tracker Tracker
+	botName string
+
+	// offset — id следующего непрочитанного обновления. Обновления читает одна задача,
+	// поэтому без блокировок
+	offset int64
+}
+
+func NewTelegramService(store repository.Store, log *slog.Logger, client TelegramClient, tracker Tracker, botName string) *TelegramService {
+	return &TelegramService{
+		store:   store,
+		log:     log,
+		client:  client,
+		tracker: tracker,
+		botName: botName,
+	}
+}
+
+// CreateLink выпускает новый код привязки. Предыдущий код перестаёт действовать
+func (s *TelegramService) CreateLink(userID int) (*TelegramLink, error) {
+	const op = "services.telegram.CreateLink"
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	code := hex.EncodeToString(raw)
+	expires := time.Now().Add(telegramLinkTTL)
+
+	if err := s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Settings().CreateDefaults(userID); err != nil {
+			return err
+		}
+		return tx.Settings().SetTelegramLinkCode(userID, code, expires)
+	}); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &TelegramLink{
+		Code:      code,
+		URL:       fmt.Sprintf("https://t.me/%s?start=%s", s.botName, code),
+		ExpiresAt: expires,
+	}, nil
+}
+
+// IsLinked сообщает, привязан ли к пользователю чат
+func (s *TelegramService) IsLinked(userID int) (bool, error) {
+	const op = "services.telegram.IsLinked"
+
+	settings, err := s.store.Settings().Get(userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return settings.TelegramChatID != 0, nil
+}
+
+func (s *TelegramService) Unlink(userID int) error {
+	const op = "services.telegram.Unlink"
+
+	if err := s.store.Settings().SetTelegramChat(userID, 0); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PollUpdates читает новые сообщения боту и привязывает чаты по командам /start <code>
+func (s *TelegramService) PollUpdates(ctx context.Context) error {
+	const op = "services.telegram.PollUpdates"
+
+	updates, err := s.client.GetUpdates(ctx, s.offset)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, u := range updates {
+		s.offset = u.UpdateID + 1
+		if u.Message == nil {
+			continue
+		}
+
+		reply, err := s.handleMessage(u.Message)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if reply == "" {
+			continue
+		}
+		if err := s.client.SendMessage(ctx, u.Message.Chat.ID, reply); err != nil {
+			s.log.Error("failed to reply in telegram", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// handleMessage возвращает ответ боту на сообщение. Всё, кроме /start, игнорируется
+func (s *TelegramService) handleMessage(msg *telegram.Message) (string, error) {
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 || fields[0] != "/start" {
+		return "", nil
+	}
+	if len(fields) < 2 {
+		return "Чтобы получать уведомления, откройте ссылку привязки из настроек приложения", nil
+	}
+
+	settings, err := s.store.Settings().GetByTelegramLinkCode(fields[1])
+	if errors.Is(err, storage.ErrNotFound) {
+		return "Код привязки не найден. Получите новую ссылку в настройках приложения", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if settings.TelegramLinkExpires == nil || time.Now().After(*settings.TelegramLinkExpires) {
+		return "Срок действия кода истёк. Получите новую ссылку в настройках приложения", nil
+	}
+
+	if err := s.store.Settings().SetTelegramChat(settings.UserID, msg.Chat.ID); err != nil {
+		return "", err
+	}
+
+	return "Готово! Сюда будут приходить смены статусов игр и итоги импорта", nil
+}
+
+func (s *TelegramService) StatusChanged(userID, gameID int, previous, status models.GameStatus) {
+	s.send("services.telegram.StatusChanged", userID, func() (string, error) {
+		game, err := s.store.Games().GetByID(gameID)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("«%s»: %s → %s", game.Title, statusNames[previous], statusNames[status]), nil
+	})
+}
+
+// GameFinished не отправляется отдельно: переход в «пройдено» уже пришёл как StatusChanged
+func (s *TelegramService) GameFinished(userID, gameID int) {}
+
+func (s *TelegramService) ImportFinished(run *models.ImportRun) {
+	text := fmt.Sprintf("Импорт из %s завершён: добавлено %d из %d", run.Provider, run.Succeeded, run.Requested)
+	if run.Failed > 0 {
+		text += fmt.Sprintf(", ошибок %d", run.Failed)
+	}
+	if run.Review > 0 {
+		text += fmt.Sprintf(", ждут выбора %d", run.Review)
+	}
+
+	s.send("services.telegram.ImportFinished", run.UserID, func() (string, error) {
+		return text, nil
+	})
+}
+
+// send отправляет сообщение в привязанный чат пользователя, если он есть
+func (s *TelegramService) send(op string, userID int, build func() (string, error)) {
+	runNotification(s.tracker, s.log, op, func(ctx context.Context) error {
+		settings, err := s.store.Settings().Get(userID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if settings.TelegramChatID == 0 {
+			return nil
+		}
+
+		text, err := build()
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return s.client.SendMessage(ctx, settings.TelegramChatID, text)
+	})
+}
@@ -0,0 +1,60 @@
+package services_test
+
+import (
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"testing"
+
+	"games_webapp/internal/services"
+	"games_webapp/internal/testutil"
+)
+
+func TestTelegramCreateLinkConcurrent(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	userID, _ := srv.NewUser(t, "player@example.com", false)
+
+	telegram := services.NewTelegramService(srv.Store(), slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, "games_bot")
+
+	// Первые запросы пользователя без настроек не должны упираться в уникальный user_id
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	const requests = 10
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	codes := make(chan string, requests)
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			link, err := telegram.CreateLink(userID)
+			if err != nil {
+				errs <- err
+				return
+			}
+			codes <- link.Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	close(codes)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	settings, err := srv.Store().Settings().Get(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := make(map[string]bool, requests)
+	for code := range codes {
+		issued[code] = true
+	}
+	if !issued[settings.TelegramLinkCode] {
+		t.Errorf("stored code %q is not one of the issued codes", settings.TelegramLinkCode)
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+var (
+	ErrInvalidAPIToken = errors.New("invalid api token")
+	ErrTooManyTokens   = errors.New("too many api tokens")
+)
+
+const (
+	// apiTokenPrefix отличает наши токены от случайных строк и упрощает их поиск в утечках
+	apiTokenPrefix = "gw_"
+	// MaxAPITokens — сколько токенов может быть у одного пользователя
+	MaxAPITokens = 20
+	// tokenTouchInterval — не чаще этого обновляется время последнего использования
+	tokenTouchInterval = time.Minute
+)
+
+type APITokenService struct {
+	store repository.Store
+	log   *slog.Logger
+}
+
+func NewAPITokenService(store repository.Store, log *slog.Logger) *APITokenService {
+	return &APITokenService{
+		store: store,
+		log:   log,
+	}
+}
+
+// Create выпускает токен и возвращает его вместе с записью. Сам токен больше
+// нигде не сохраняется, поэтому показать его можно только сейчас
+func (s *APITokenService) Create(userID int, name string, scope models.TokenScope) (string, *models.APIToken, error) {
+	const op = "services.tokens.Create"
+
+	count, err := s.store.APITokens().CountByUser(userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if count >= MaxAPITokens {
+		return "", nil, fmt.Errorf("%s: %w", op, ErrTooManyTokens)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+	raw := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now()
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:len(apiTokenPrefix)+6],
+		Hash:      hashAPIToken(raw),
+		Scope:     scope,
+		CreatedAt: &now,
+	}
+	if err := s.store.APITokens().Create(token); err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return raw, token, nil
+}
+
+func (s *APITokenService) List(userID int) ([]models.APIToken, error) {
+	const op = "services.tokens.List"
+
+	tokens, err := s.store.APITokens().ListByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+func (s *APITokenService) Revoke(userID, tokenID int) error {
+	const op = "services.tokens.Revoke"
+
+	if err := s.store.APITokens().Delete(tokenID, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ValidateAPIToken возвращает владельца и права токена. Для неизвестного токена
+// возвращает ErrInvalidAPIToken
+func (s *APITokenService) ValidateAPIToken(raw string) (int, models.TokenScope, error) {
+	const op = "services.tokens.ValidateAPIToken"
+
+	if !strings.HasPrefix(raw, apiTokenPrefix) {
+		return 0, "", fmt.Errorf("%s: %w", op, ErrInvalidAPIToken)
+	}
+
+	token, err := s.store.APITokens().GetByHash(hashAPIToken(raw))
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, "", fmt.Errorf("%s: %w", op, ErrInvalidAPIToken)
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > tokenTouchInterval {
+		if err := s.store.APITokens().Touch(token.ID, now); err != nil {
+			s.log.Error("failed to update token last use", slog.String("operation", op), slog.String("error", err.Error()))
+		}
+	}
+
+	return token.UserID, token.Scope, nil
+}
+
+// hashAPIToken возвращает SHA-256 токена. У токена 256 бит случайности,
+// поэтому медленный хеш для паролей здесь не нужен
+func hashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
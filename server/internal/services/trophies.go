@@ -0,0 +1,184 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"games_webapp/internal/models"
+)
+
+var (
+	// ErrTrophyNoTitle — в CSV не нашлось колонки с названием игры
+	ErrTrophyNoTitle = errors.New("no game title column")
+	// ErrTrophyNoProgress — в CSV нет ни процента прохождения, ни пары «получено/всего»
+	ErrTrophyNoProgress = errors.New("no completion columns")
+)
+
+// TrophyMapping — какие колонки CSV читать. Пустое поле — искать колонку по
+// известным названиям из экспортов PSNProfiles, TrueAchievements, Exophase и т. п.
+type TrophyMapping struct {
+	Title    string
+	Earned   string
+	Total    string
+	Progress string
+}
+
+// Известные названия колонок, в нижнем регистре
+var (
+	trophyTitleColumns    = []string{"game", "title", "name", "game title", "game name"}
+	trophyEarnedColumns   = []string{"earned", "unlocked", "trophies earned", "achievements won", "won", "achieved"}
+	trophyTotalColumns    = []string{"total", "trophies", "trophies total", "achievements", "total achievements", "max achievements"}
+	trophyProgressColumns = []string{"progress", "completion", "completion %", "percent", "%"}
+)
+
+// ParseTrophyCSV читает экспорт трофеев или достижений: по строке на игру.
+// Строки без названия пропускаются, строки с непонятным прогрессом попадают в
+// результат с Err, чтобы пользователь увидел их в отчёте
+func ParseTrophyCSV(r io.Reader, mapping TrophyMapping, maxRows int) ([]models.TrophyRow, error) {
+	const op = "services.trophies.ParseTrophyCSV"
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel добавляет BOM в начало файла
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	title := trophyColumn(columns, mapping.Title, trophyTitleColumns)
+	earned := trophyColumn(columns, mapping.Earned, trophyEarnedColumns)
+	total := trophyColumn(columns, mapping.Total, trophyTotalColumns)
+	progress := trophyColumn(columns, mapping.Progress, trophyProgressColumns)
+
+	if title < 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrTrophyNoTitle)
+	}
+	if progress < 0 && (earned < 0 || total < 0) {
+		return nil, fmt.Errorf("%s: %w", op, ErrTrophyNoProgress)
+	}
+
+	var rows []models.TrophyRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		name := cleanTrophyTitle(cell(record, title))
+		if name == "" {
+			continue
+		}
+		if len(rows) == maxRows {
+			return nil, fmt.Errorf("%s: more than %d rows", op, maxRows)
+		}
+
+		row := models.TrophyRow{Line: line, Title: name}
+		percent, err := trophyProgress(cell(record, progress), cell(record, earned), cell(record, total))
+		if err != nil {
+			row.Err = err.Error()
+		} else {
+			row.Progress = percent
+			row.Status = TrophyStatus(percent)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// TrophyStatus переводит процент полученных трофеев в статус: 100 — пройдена,
+// больше нуля — в процессе, ноль — в планах
+func TrophyStatus(percent int) models.GameStatus {
+	switch {
+	case percent >= 100:
+		return models.StatusFinished
+	case percent > 0:
+		return models.StatusPlaying
+	default:
+		return models.StatusPlanned
+	}
+}
+
+// trophyColumn возвращает номер колонки: указанной явно или первой из известных
+func trophyColumn(columns map[string]int, explicit string, known []string) int {
+	if explicit != "" {
+		if i, ok := columns[strings.ToLower(strings.TrimSpace(explicit))]; ok {
+			return i
+		}
+		return -1
+	}
+	for _, name := range known {
+		if i, ok := columns[name]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+func cell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// trophyProgress считает процент по колонке прогресса («45%», «45», «0.45») или,
+// если её нет, по паре «получено/всего». Получено может быть и «12/40»
+func trophyProgress(progress, earned, total string) (int, error) {
+	if progress != "" {
+		value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(progress, "%")), 64)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid progress %q", progress)
+		}
+		if value <= 1 && !strings.HasSuffix(progress, "%") && strings.Contains(progress, ".") {
+			value *= 100
+		}
+		if value > 100 {
+			value = 100
+		}
+		return int(value), nil
+	}
+
+	if got, all, ok := strings.Cut(earned, "/"); ok {
+		earned, total = strings.TrimSpace(got), strings.TrimSpace(all)
+	}
+	got, errGot := strconv.Atoi(earned)
+	all, errAll := strconv.Atoi(total)
+	if errGot != nil || errAll != nil || got < 0 || all <= 0 {
+		return 0, fmt.Errorf("invalid earned/total %q/%q", earned, total)
+	}
+	if got >= all {
+		return 100, nil
+	}
+	return got * 100 / all, nil
+}
+
+var (
+	trademarks       = strings.NewReplacer("™", "", "®", "", "©", "")
+	platformSuffix   = regexp.MustCompile(`(?i)\s*[\(\[](ps[3-5]|ps vita|psvr2?|xbox( one| 360| series x\|s)?|pc|windows)[\)\]]\s*$`)
+	trophyListSuffix = regexp.MustCompile(`(?i)\s+(trophies|achievements)$`)
+)
+
+// cleanTrophyTitle убирает из названия знаки ™ и ®, платформу в скобках и
+// хвост «Trophies», которые добавляют сервисы трофеев
+func cleanTrophyTitle(title string) string {
+	title = trademarks.Replace(title)
+	title = platformSuffix.ReplaceAllString(title, "")
+	title = trophyListSuffix.ReplaceAllString(title, "")
+	return strings.Join(strings.Fields(title), " ")
+}
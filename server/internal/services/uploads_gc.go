@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+	"games_webapp/internal/storage/uploads"
+)
+
+type UploadsLister interface {
+	List() ([]uploads.File, error)
+	DeleteImage(filename string) error
+}
+
+// UploadsGCService удаляет из папки загрузок файлы, на которые ничего не ссылается
+type UploadsGCService struct {
+	store   repository.Store
+	log     *slog.Logger
+	users   SSOUsersProvider
+	uploads UploadsLister
+	minAge  time.Duration
+}
+
+// NewUploadsGCService создаёт сборщик. Файлы моложе minAge не трогаются:
+// картинка может быть уже записана, а игра с ней ещё не создана
+func NewUploadsGCService(store repository.Store, log *slog.Logger, users SSOUsersProvider, uploads UploadsLister, minAge time.Duration) *UploadsGCService {
+	return &UploadsGCService{
+		store:   store,
+		log:     log,
+		users:   users,
+		uploads: uploads,
+		minAge:  minAge,
+	}
+}
+
+// Collect находит файлы без ссылок и, если dryRun == false, удаляет их.
+// Если список фото пользователей получить не удалось, ничего не удаляется
+func (s *UploadsGCService) Collect(ctx context.Context, dryRun bool) (*models.UploadsGCReport, error) {
+	const op = "services.uploads_gc.Collect"
+
+	referenced, err := s.referenced(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	files, err := s.uploads.List()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	report := &models.UploadsGCReport{
+		DryRun:   dryRun,
+		Scanned:  len(files),
+		Orphaned: []string{},
+	}
+
+	cutoff := time.Now().Add(-s.minAge)
+	for _, f := range files {
+		if _, ok := referenced[f.Name]; ok || f.ModTime.After(cutoff) {
+			continue
+		}
+
+		report.Orphaned = append(report.Orphaned, f.Name)
+		if dryRun {
+			report.Bytes += f.Size
+			continue
+		}
+
+		if err := s.uploads.DeleteImage(f.Name); err != nil {
+			s.log.Error("failed to delete orphaned upload", slog.String("operation", op), slog.String("filename", f.Name), slog.String("error", err.Error()))
+			continue
+		}
+		report.Deleted++
+		report.Bytes += f.Size
+
+		if err := s.forgetImage(f.Name); err != nil {
+			s.log.Error("failed to delete image record", slog.String("operation", op), slog.String("filename", f.Name), slog.String("error", err.Error()))
+		}
+	}
+
+	return report, nil
+}
+
+// referenced собирает имена файлов, на которые ссылаются игры, правки игр и фото пользователей
+func (s *UploadsGCService) referenced(ctx context.Context) (map[string]struct{}, error) {
+	images, err := s.store.Games().ListImages()
+	if err != nil {
+		return nil, err
+	}
+
+	covers, err := s.store.Overrides().ListImages()
+	if err != nil {
+		return nil, err
+	}
+	images = append(images, covers...)
+
+	resp, err := s.users.GetUsersForApp(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]struct{}, len(images)+len(resp.GetUsers()))
+	for _, img := range images {
+		referenced[filepath.Base(img)] = struct{}{}
+	}
+	for _, u := range resp.GetUsers() {
+		if photo := u.GetPathToPhoto(); photo != "" {
+			referenced[filepath.Base(photo)] = struct{}{}
+		}
+	}
+
+	return referenced, nil
+}
+
+// forgetImage удаляет запись о картинке, если файл был учтён в таблице images
+func (s *UploadsGCService) forgetImage(filename string) error {
+	img, err := s.store.Images().GetByFilename(filename)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.store.Images().Delete(img.ID)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"games_webapp/internal/models"
+)
+
+var ErrInvalidGame = errors.New("invalid game")
+
+// Ошибки отдельных полей в ValidationError.Fields
+var (
+	ErrTitleRequired   = errors.New("title is required")
+	ErrTitleTooLong    = errors.New("title is too long")
+	ErrYearInvalid     = errors.New("year has no recognizable year")
+	ErrYearOutOfRange  = errors.New("year is out of range")
+	ErrURLInvalid      = errors.New("url must be an absolute http(s) link")
+	ErrPriorityInvalid = errors.New("priority is out of range")
+	ErrGenreTooLong    = errors.New("genre is too long")
+)
+
+const (
+	maxTitleLength = 255
+	// minGameYear — раньше компьютерных игр не было; вперёд год допускается
+	// с запасом для анонсов
+	minGameYear     = 1950
+	maxYearsAhead   = 10
+	maxGenreLength  = 100 // как у genres.name
+	maxGenresLength = 1000
+)
+
+// ValidationError — игра или запись библиотеки не прошли проверку. Fields —
+// ошибка по каждому полю (ErrTitleRequired и т. п.), ключи как в JSON
+type ValidationError struct {
+	Fields map[string]error
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field, err := range e.Fields {
+		fields = append(fields, field+": "+err.Error())
+	}
+	sort.Strings(fields)
+	return ErrInvalidGame.Error() + ": " + strings.Join(fields, "; ")
+}
+
+func (e *ValidationError) Unwrap() error { return ErrInvalidGame }
+
+// gameRules — проверки полей игры по их именам в JSON
+var gameRules = map[string]func(g *models.Game) error{
+	"title": func(g *models.Game) error {
+		title := strings.TrimSpace(g.Title)
+		if title == "" {
+			return ErrTitleRequired
+		}
+		if utf8.RuneCountInString(title) > maxTitleLength {
+			return ErrTitleTooLong
+		}
+		return nil
+	},
+	"year": func(g *models.Game) error {
+		year := strings.TrimSpace(g.Year)
+		if year == "" {
+			return nil
+		}
+		// Число вне шаблона parseRelease (например, 3000) — год, но недопустимый
+		if n, err := strconv.Atoi(year); err == nil {
+			return checkYear(n)
+		}
+		t, _, ok := parseRelease(year)
+		if !ok {
+			return ErrYearInvalid
+		}
+		return checkYear(t.Year())
+	},
+	"release_date": func(g *models.Game) error {
+		if g.ReleaseDate == nil {
+			return nil
+		}
+		return checkYear(g.ReleaseDate.Year())
+	},
+	"url": func(g *models.Game) error {
+		if g.URL == "" {
+			return nil
+		}
+		u, err := url.Parse(g.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrURLInvalid
+		}
+		return nil
+	},
+	"genre": func(g *models.Game) error {
+		if utf8.RuneCountInString(g.Genre) > maxGenresLength {
+			return ErrGenreTooLong
+		}
+		for _, name := range splitList(g.Genre) {
+			if utf8.RuneCountInString(name) > maxGenreLength {
+				return ErrGenreTooLong
+			}
+		}
+		return nil
+	},
+}
+
+func checkYear(year int) error {
+	if year < minGameYear || year > time.Now().Year()+maxYearsAhead {
+		return ErrYearOutOfRange
+	}
+	return nil
+}
+
+// gameFields — все проверяемые поля, для новой игры
+var gameFields = []string{"title", "year", "release_date", "url", "genre"}
+
+// validateGame проверяет у игры поля fields
+func validateGame(g *models.Game, fields []string) error {
+	invalid := make(map[string]error)
+	for _, field := range fields {
+		if rule, ok := gameRules[field]; ok {
+			if err := rule(g); err != nil {
+				invalid[field] = err
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		return &ValidationError{Fields: invalid}
+	}
+	return nil
+}
+
+// changedFields — проверяемые поля, значения которых в g отличаются от existing.
+// Старые значения, сохранённые до появления проверок, не мешают править другие
+// поля. skipEmpty — пустое значение в g означает «не менять», как в Update
+func changedFields(g, existing *models.Game, skipEmpty bool) []string {
+	var fields []string
+	for field, values := range map[string][2]string{
+		"title": {g.Title, existing.Title},
+		"year":  {g.Year, existing.Year},
+		"url":   {g.URL, existing.URL},
+		"genre": {g.Genre, existing.Genre},
+	} {
+		if values[0] != values[1] && (values[0] != "" || !skipEmpty) {
+			fields = append(fields, field)
+		}
+	}
+	if g.ReleaseDate != nil && (existing.ReleaseDate == nil || !g.ReleaseDate.Equal(*existing.ReleaseDate)) {
+		fields = append(fields, "release_date")
+	}
+	return fields
+}
+
+// ValidatePriority проверяет приоритет записи библиотеки: 0 — не задан. Нужна
+// контроллерам, которые должны отклонить запрос до сохранения игры
+func ValidatePriority(priority int) error {
+	if priority < 0 || priority > models.MaxPriority {
+		return &ValidationError{Fields: map[string]error{"priority": ErrPriorityInvalid}}
+	}
+	return nil
+}
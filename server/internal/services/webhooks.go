@@ -0,0 +1,361 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"games_webapp/internal/httpx"
+	"games_webapp/internal/models"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/storage"
+)
+
+var (
+	ErrInvalidWebhookURL = errors.New("invalid webhook url")
+	ErrPrivateWebhookURL = errors.New("webhook url is not public")
+	ErrTooManyWebhooks   = errors.New("too many webhooks")
+
+	errUnexpectedStatus = errors.New("unexpected status")
+)
+
+const (
+	// MaxWebhooks — сколько вебхуков может быть у одного пользователя
+	MaxWebhooks = 10
+	// webhookMaxAttempts — после стольких неудачных попыток отправка помечается failed
+	webhookMaxAttempts = 6
+	// webhookRetryBase — задержка перед второй попыткой, дальше растёт в 4 раза:
+	// 30s, 2m, 8m, 32m, ~2ч
+	webhookRetryBase = 30 * time.Second
+	webhookBatchSize = 50
+	// webhookRetention — сколько хранится журнал завершённых отправок
+	webhookRetention = 30 * 24 * time.Hour
+
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+type WebhookService struct {
+	store  repository.Store
+	log    *slog.Logger
+	client *http.Client
+}
+
+// NewWebhookService создаёт сервис вебхуков. Адреса задают пользователи,
+// поэтому отправки идут только на публичные адреса
+func NewWebhookService(store repository.Store, log *slog.Logger, timeout time.Duration) *WebhookService {
+	return &WebhookService{
+		store:  store,
+		log:    log,
+		client: httpx.PublicClient(timeout),
+	}
+}
+
+// Create регистрирует вебхук и возвращает его вместе с секретом подписи.
+// Секрет показывается только сейчас
+func (s *WebhookService) Create(userID int, rawURL string, events []models.WebhookEvent) (string, *models.Webhook, error) {
+	const op = "services.webhooks.Create"
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", nil, fmt.Errorf("%s: %w", op, ErrInvalidWebhookURL)
+	}
+	if err := httpx.CheckPublicHost(u.Hostname()); err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, ErrPrivateWebhookURL)
+	}
+
+	count, err := s.store.Webhooks().CountByUser(userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if count >= MaxWebhooks {
+		return "", nil, fmt.Errorf("%s: %w", op, ErrTooManyWebhooks)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	names := make([]string, 0, len(events))
+	for _, e := range events {
+		names = append(names, string(e))
+	}
+
+	now := time.Now()
+	hook := &models.Webhook{
+		UserID:    userID,
+		URL:       u.String(),
+		Events:    strings.Join(names, ","),
+		Secret:    hex.EncodeToString(secret),
+		CreatedAt: &now,
+	}
+	if err := s.store.Webhooks().Create(hook); err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+	hook.EventList = events
+
+	return hook.Secret, hook, nil
+}
+
+func (s *WebhookService) List(userID int) ([]models.Webhook, error) {
+	const op = "services.webhooks.List"
+
+	hooks, err := s.store.Webhooks().ListByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for i := range hooks {
+		hooks[i].EventList = webhookEvents(hooks[i].Events)
+	}
+
+	return hooks, nil
+}
+
+func (s *WebhookService) Delete(userID, webhookID int) error {
+	const op = "services.webhooks.Delete"
+
+	if err := s.store.Transaction(func(tx repository.Store) error {
+		return tx.Webhooks().Delete(webhookID, userID)
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListDeliveries возвращает журнал отправок вебхука пользователя
+func (s *WebhookService) ListDeliveries(userID, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	const op = "services.webhooks.ListDeliveries"
+
+	if _, err := s.store.Webhooks().Get(webhookID, userID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries, err := s.store.Webhooks().ListDeliveries(webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// Dispatch отправляет наступившие отправки и чистит старый журнал.
+// Возвращает число успешных и окончательно неудачных отправок
+func (s *WebhookService) Dispatch(ctx context.Context) (delivered, failed int, err error) {
+	const op = "services.webhooks.Dispatch"
+
+	now := time.Now()
+	due, err := s.store.Webhooks().ListDue(now, webhookBatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hooks := make(map[int]*models.Webhook)
+	for i := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		d := &due[i]
+
+		hook, ok := hooks[d.WebhookID]
+		if !ok {
+			hook, err = s.store.Webhooks().GetByID(d.WebhookID)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return delivered, failed, fmt.Errorf("%s: %w", op, err)
+			}
+			hooks[d.WebhookID] = hook
+		}
+
+		if hook == nil {
+			d.Status = models.DeliveryFailed
+			d.Error = "webhook deleted"
+			d.NextAttemptAt = nil
+		} else {
+			s.deliver(ctx, hook, d)
+		}
+
+		switch d.Status {
+		case models.DeliveryDelivered:
+			delivered++
+		case models.DeliveryFailed:
+			failed++
+		}
+
+		if err := s.store.Webhooks().SaveDelivery(d); err != nil {
+			return delivered, failed, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if _, err := s.store.Webhooks().DeleteDeliveriesBefore(now.Add(-webhookRetention)); err != nil {
+		return delivered, failed, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return delivered, failed, nil
+}
+
+// deliver делает одну попытку отправки и обновляет d: при неудаче назначает
+// следующую попытку или помечает отправку failed
+func (s *WebhookService) deliver(ctx context.Context, hook *models.Webhook, d *models.WebhookDelivery) {
+	d.Attempts++
+	d.ResponseCode = 0
+	d.Error = ""
+
+	code, err := s.post(ctx, hook, d)
+	d.ResponseCode = code
+
+	now := time.Now()
+	if err == nil {
+		d.Status = models.DeliveryDelivered
+		d.DeliveredAt = &now
+		d.NextAttemptAt = nil
+		return
+	}
+
+	d.Error = deliveryError(err)
+
+	if d.Attempts >= webhookMaxAttempts {
+		d.Status = models.DeliveryFailed
+		d.NextAttemptAt = nil
+		return
+	}
+
+	next := now.Add(webhookRetryBase << (2 * (d.Attempts - 1)))
+	d.NextAttemptAt = &next
+}
+
+func (s *WebhookService) post(ctx context.Context, hook *models.Webhook, d *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader([]byte(d.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "games_webapp-webhooks")
+	req.Header.Set(WebhookEventHeader, string(d.Event))
+	req.Header.Set(WebhookDeliveryHeader, strconv.Itoa(d.ID))
+	req.Header.Set(WebhookSignatureHeader, "sha256="+signWebhook(hook.Secret, []byte(d.Payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%w %d", errUnexpectedStatus, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// deliveryError сводит ошибку отправки к общей категории. Текст ошибки
+// соединения не сохраняется: по нему можно было бы изучать сеть за сервером
+func deliveryError(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, httpx.ErrPrivateAddress):
+		return "address is not public"
+	case errors.Is(err, errUnexpectedStatus):
+		return "unexpected status"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "connection failed"
+	}
+}
+
+// signWebhook возвращает HMAC-SHA256 тела запроса в hex
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookEvents(events string) []models.WebhookEvent {
+	list := []models.WebhookEvent{}
+	for _, name := range strings.Split(events, ",") {
+		if name != "" {
+			list = append(list, models.WebhookEvent(name))
+		}
+	}
+	return list
+}
+
+// queueWebhook ставит событие в очередь отправки всем подписанным вебхукам
+// пользователя. Как и recordEvent, ошибки только логирует: вебхуки не должны
+// ломать изменение библиотеки. store может быть транзакцией — тогда отправки
+// появятся только вместе с самим изменением
+func queueWebhook(store repository.Store, log *slog.Logger, event models.WebhookEvent, ug *models.UserGames, previous models.GameStatus) {
+	const op = "services.webhooks.queueWebhook"
+
+	logErr := func(err error) {
+		log.Error(
+			"failed to queue webhook",
+			slog.String("operation", op),
+			slog.String("event", string(event)),
+			slog.String("error", err.Error()))
+	}
+
+	hooks, err := store.Webhooks().ListByUser(ug.UserID)
+	if err != nil {
+		logErr(err)
+		return
+	}
+
+	var subscribed []models.Webhook
+	for _, h := range hooks {
+		if h.Subscribed(event) {
+			subscribed = append(subscribed, h)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	now := time.Now()
+	payload := models.WebhookPayload{
+		Event:     event,
+		CreatedAt: now.UTC(),
+		Data: models.WebhookData{
+			UserID:         ug.UserID,
+			GameID:         ug.GameID,
+			Status:         ug.Status,
+			PreviousStatus: previous,
+		},
+	}
+	if game, err := store.Games().GetByID(ug.GameID); err == nil {
+		payload.Data.GameTitle = game.Title
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logErr(err)
+		return
+	}
+
+	for _, h := range subscribed {
+		if err := store.Webhooks().CreateDelivery(&models.WebhookDelivery{
+			WebhookID:     h.ID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     &now,
+		}); err != nil {
+			logErr(err)
+		}
+	}
+}
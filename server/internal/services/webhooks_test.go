@@ -0,0 +1,87 @@
+package services_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"games_webapp/internal/models"
+	"games_webapp/internal/services"
+	"games_webapp/internal/testutil"
+)
+
+func TestWebhookRejectsLoopbackURL(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	_, token := srv.NewUser(t, "player@example.com", false)
+
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+		"http://169.254.169.254/latest/meta-data",
+	} {
+		resp := srv.Do(t, http.MethodPost, "/api/webhooks", token, map[string]interface{}{
+			"url":    url,
+			"events": []string{string(models.WebhookGameCreated)},
+		})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", url, resp.StatusCode)
+		}
+	}
+}
+
+func TestWebhookDeliveryRefusesLoopback(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	userID, _ := srv.NewUser(t, "player@example.com", false)
+
+	var hits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer target.Close()
+
+	// Вебхук записан в обход Create, как если бы адрес сменил свой DNS после проверки
+	store := srv.Store()
+	hook := &models.Webhook{UserID: userID, URL: target.URL, Events: string(models.WebhookGameCreated), Secret: "secret"}
+	if err := store.Webhooks().Create(hook); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Add(-time.Second)
+	d := &models.WebhookDelivery{
+		WebhookID:     hook.ID,
+		Event:         models.WebhookGameCreated,
+		Payload:       "{}",
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     &now,
+	}
+	if err := store.Webhooks().CreateDelivery(d); err != nil {
+		t.Fatal(err)
+	}
+
+	webhooks := services.NewWebhookService(store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Second)
+	if _, _, err := webhooks.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := hits.Load(); n != 0 {
+		t.Errorf("loopback server got %d requests, want none", n)
+	}
+	deliveries, err := webhooks.ListDeliveries(userID, hook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	if got := deliveries[0]; got.Status != models.DeliveryPending || got.ResponseCode != 0 || got.Error != "address is not public" {
+		t.Errorf("delivery = %s %d %q, want pending 0 %q", got.Status, got.ResponseCode, got.Error, "address is not public")
+	}
+}
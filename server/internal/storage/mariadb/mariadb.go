@@ -1,18 +1,30 @@
 package mariadb
 
 import (
+	"database/sql"
+	"embed"
+	"errors"
 	"fmt"
 
 	"games_webapp/internal/config"
 	"games_webapp/internal/models"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
+// Версионные миграции для изменений схемы, которые AutoMigrate сделать не может
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
 type Storage struct {
-	DB *gorm.DB
+	db  *gorm.DB
+	dsn string
 }
 
 func New(cfg config.Database) (*Storage, error) {
@@ -23,12 +35,16 @@ func New(cfg config.Database) (*Storage, error) {
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return &Storage{DB: db}, nil
+	return &Storage{db: db, dsn: dsn}, nil
+}
+
+func (s *Storage) DB() *gorm.DB {
+	return s.db
 }
 
 func (s *Storage) Close() error {
 	const op = "storage.mariadb.Close"
-	db, err := s.DB.DB()
+	db, err := s.db.DB()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -38,9 +54,58 @@ func (s *Storage) Close() error {
 
 func (s *Storage) Migrate() error {
 	const op = "storage.mariadb.Migrate"
-	err := s.DB.AutoMigrate(&models.Game{}, &models.UserGames{})
+	err := s.db.AutoMigrate(models.All()...)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+
+	if err := s.migrateVersioned(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// migrateVersioned применяет SQL-миграции из migrations поверх таблиц, созданных AutoMigrate
+func (s *Storage) migrateVersioned() error {
+	// Миграции могут содержать несколько запросов, поэтому нужно отдельное подключение
+	db, err := sql.Open("mysql", s.dsn+"&multiStatements=true")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	driver, err := migratemysql.WithInstance(db, &migratemysql.Config{})
+	if err != nil {
+		return err
+	}
+
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "mysql", driver)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
 	return nil
 }
+
+// OpenReplica подключается к реплике для чтения. Версия сервера не запрашивается,
+// чтобы недоступная при старте реплика не мешала запуску
+func OpenReplica(dsn string) (*sql.DB, gorm.Dialector, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, Dialector(db), nil
+}
+
+// Dialector оборачивает готовое подключение в диалект GORM
+func Dialector(db *sql.DB) gorm.Dialector {
+	return mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true})
+}
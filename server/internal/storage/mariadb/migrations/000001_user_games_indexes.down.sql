@@ -0,0 +1,3 @@
+DROP INDEX idx_user_games_active ON user_games;
+
+ALTER TABLE user_games DROP COLUMN active_game_id;
@@ -0,0 +1,18 @@
+-- У пользователя может быть несколько прохождений одной игры, но активно только одно.
+-- Перед созданием уникального индекса оставляем активным самое новое прохождение
+UPDATE user_games ug
+JOIN (
+    SELECT user_id, game_id, MAX(id) AS keep_id
+    FROM user_games
+    WHERE is_active = 1
+    GROUP BY user_id, game_id
+    HAVING COUNT(*) > 1
+) d ON ug.user_id = d.user_id AND ug.game_id = d.game_id
+SET ug.is_active = 0
+WHERE ug.is_active = 1 AND ug.id <> d.keep_id;
+
+-- NULL у неактивных прохождений не участвует в уникальности
+ALTER TABLE user_games
+    ADD COLUMN active_game_id BIGINT AS (IF(is_active, game_id, NULL)) VIRTUAL;
+
+CREATE UNIQUE INDEX idx_user_games_active ON user_games (user_id, active_game_id);
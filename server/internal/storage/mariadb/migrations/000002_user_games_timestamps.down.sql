@@ -0,0 +1 @@
+UPDATE user_games SET created_at = NULL;
@@ -0,0 +1,17 @@
+-- У пользователя может быть несколько прохождений одной игры, но активно только одно.
+-- Перед созданием уникального индекса оставляем активным самое новое прохождение
+UPDATE user_games ug
+SET is_active = false
+FROM (
+    SELECT user_id, game_id, MAX(id) AS keep_id
+    FROM user_games
+    WHERE is_active
+    GROUP BY user_id, game_id
+    HAVING COUNT(*) > 1
+) d
+WHERE ug.user_id = d.user_id
+  AND ug.game_id = d.game_id
+  AND ug.is_active
+  AND ug.id <> d.keep_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_games_active ON user_games (user_id, game_id) WHERE is_active;
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/models"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratepgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Версионные миграции для изменений схемы, которые AutoMigrate сделать не может
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+type Storage struct {
+	db  *gorm.DB
+	dsn string
+}
+
+func New(cfg config.Database) (*Storage, error) {
+	const op = "storage.postgres.New"
+
+	dsn := cfg.GetDSN()
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &Storage{db: db, dsn: dsn}, nil
+}
+
+func (s *Storage) DB() *gorm.DB {
+	return s.db
+}
+
+func (s *Storage) Close() error {
+	const op = "storage.postgres.Close"
+	db, err := s.db.DB()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	db.Close()
+	return nil
+}
+
+func (s *Storage) Migrate() error {
+	const op = "storage.postgres.Migrate"
+	err := s.db.AutoMigrate(models.All()...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.migrateVersioned(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// migrateVersioned применяет SQL-миграции из migrations поверх таблиц, созданных AutoMigrate
+func (s *Storage) migrateVersioned() error {
+	db, err := sql.Open("pgx", s.dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	driver, err := migratepgx.WithInstance(db, &migratepgx.Config{})
+	if err != nil {
+		return err
+	}
+
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "pgx", driver)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// OpenReplica подключается к реплике для чтения. Соединение открывается лениво,
+// так что недоступная при старте реплика не мешает запуску
+func OpenReplica(dsn string) (*sql.DB, gorm.Dialector, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, Dialector(db), nil
+}
+
+// Dialector оборачивает готовое подключение в диалект GORM
+func Dialector(db *sql.DB) gorm.Dialector {
+	return postgres.New(postgres.Config{Conn: db})
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/storage/mariadb"
+	"games_webapp/internal/storage/postgres"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// ReplicaResolver — имя резолвера реплик. Запрос уходит на реплику, только если
+// явно помечен dbresolver.Use(ReplicaResolver); остальные чтения и все записи
+// выполняются на основной базе
+const ReplicaResolver = "replicas"
+
+// replicaPingTimeout — сколько ждать ответа реплики при проверке
+const replicaPingTimeout = 2 * time.Second
+
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// Replicas выбирает реплику для чтения среди доступных. Если ни одна не отвечает,
+// запросы идут на основную базу
+type Replicas struct {
+	primary  gorm.ConnPool
+	replicas []*replica
+}
+
+// UseReplicas подключает реплики из cfg.Replicas к db. Без реплик возвращает nil.
+// Реплики считаются доступными сразу; их состояние обновляет Check
+func UseReplicas(db *gorm.DB, cfg config.Database) (*Replicas, error) {
+	const op = "storage.UseReplicas"
+
+	if len(cfg.Replicas) == 0 {
+		return nil, nil
+	}
+
+	primary, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	open := func(dsn string) (*sql.DB, gorm.Dialector, error) {
+		switch cfg.Driver {
+		case config.DriverMariaDB, "":
+			return mariadb.OpenReplica(dsn)
+		case config.DriverPostgres:
+			return postgres.OpenReplica(dsn)
+		default:
+			return nil, nil, fmt.Errorf("driver %q does not support replicas", cfg.Driver)
+		}
+	}
+
+	r := &Replicas{primary: primary}
+	dialectors := make([]gorm.Dialector, 0, len(cfg.Replicas)+1)
+	for _, dsn := range cfg.Replicas {
+		conn, dialector, err := open(dsn)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		rep := &replica{db: conn}
+		rep.healthy.Store(true)
+		r.replicas = append(r.replicas, rep)
+		dialectors = append(dialectors, dialector)
+	}
+
+	// Основная база — последний кандидат: dbresolver не зовёт политику, когда кандидат
+	// один, а без политики некуда было бы уйти с упавшей реплики
+	dialectors = append(dialectors, primaryDialector(cfg.Driver, primary))
+
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   r,
+	}, ReplicaResolver)); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+func primaryDialector(driver string, db *sql.DB) gorm.Dialector {
+	if driver == config.DriverPostgres {
+		return postgres.Dialector(db)
+	}
+	return mariadb.Dialector(db)
+}
+
+// Resolve реализует dbresolver.Policy: случайная доступная реплика или основная база
+func (r *Replicas) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]gorm.ConnPool, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		if rep.healthy.Load() {
+			healthy = append(healthy, rep.db)
+		}
+	}
+	if len(healthy) == 0 {
+		return r.primary
+	}
+	return healthy[rand.Intn(len(healthy))]
+}
+
+// Check пингует реплики и отмечает недоступные, чтобы чтение с них ушло на основную базу.
+// Возвращает число доступных реплик
+func (r *Replicas) Check(ctx context.Context) int {
+	up := 0
+	for _, rep := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+		err := rep.db.PingContext(pingCtx)
+		cancel()
+
+		rep.healthy.Store(err == nil)
+		if err == nil {
+			up++
+		}
+	}
+	return up
+}
+
+// Len возвращает число настроенных реплик
+func (r *Replicas) Len() int {
+	return len(r.replicas)
+}
+
+func (r *Replicas) Close() error {
+	for _, rep := range r.replicas {
+		rep.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+-- У пользователя может быть несколько прохождений одной игры, но активно только одно.
+-- Перед созданием уникального индекса оставляем активным самое новое прохождение
+UPDATE user_games
+SET is_active = 0
+WHERE is_active
+  AND id NOT IN (
+    SELECT MAX(id)
+    FROM user_games
+    WHERE is_active
+    GROUP BY user_id, game_id
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_games_active ON user_games (user_id, game_id) WHERE is_active;
@@ -0,0 +1,9 @@
+-- created_at появился позже самих записей. Точная дата добавления неизвестна,
+-- поэтому берём самую раннюю из известных дат записи
+UPDATE user_games
+SET created_at = COALESCE(started_at, finished_at, updated_at, CURRENT_TIMESTAMP)
+WHERE created_at IS NULL;
+
+UPDATE user_games
+SET updated_at = created_at
+WHERE updated_at IS NULL;
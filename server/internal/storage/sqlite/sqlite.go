@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/models"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Версионные миграции для изменений схемы, которые AutoMigrate сделать не может
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Storage хранит данные в файле SQLite. Нужен для локальной разработки и тестов,
+// когда поднимать отдельный сервер БД не хочется. Драйвер использует cgo
+type Storage struct {
+	db  *gorm.DB
+	dsn string
+}
+
+func New(cfg config.Database) (*Storage, error) {
+	const op = "storage.sqlite.New"
+
+	dsn := cfg.GetDSN()
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &Storage{db: db, dsn: dsn}, nil
+}
+
+func (s *Storage) DB() *gorm.DB {
+	return s.db
+}
+
+func (s *Storage) Close() error {
+	const op = "storage.sqlite.Close"
+	db, err := s.db.DB()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	db.Close()
+	return nil
+}
+
+func (s *Storage) Migrate() error {
+	const op = "storage.sqlite.Migrate"
+	err := s.db.AutoMigrate(models.All()...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.migrateVersioned(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// migrateVersioned применяет SQL-миграции из migrations поверх таблиц, созданных AutoMigrate
+func (s *Storage) migrateVersioned() error {
+	db, err := sql.Open("sqlite3", s.dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		return err
+	}
+
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite3", driver)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
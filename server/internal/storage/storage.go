@@ -1,6 +1,16 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/storage/mariadb"
+	"games_webapp/internal/storage/postgres"
+	"games_webapp/internal/storage/sqlite"
+
+	"gorm.io/gorm"
+)
 
 var (
 	ErrNotFound     = errors.New("not found")
@@ -8,4 +18,40 @@ var (
 	ErrCreateFailed = errors.New("failed to create")
 	ErrUpdateFailed = errors.New("failed to update")
 	ErrDeleteFailed = errors.New("failed to delete")
+	// ErrConflict — запись изменилась после того, как клиент её прочитал
+	ErrConflict = errors.New("modified concurrently")
 )
+
+// FieldError — поле запроса, которое нельзя использовать: неизвестное,
+// недоступное без join или с неверным значением
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// Storage — подключение к базе. Сервисы работают с ним через GORM,
+// поэтому конкретная СУБД выбирается только в конфиге (database.driver)
+type Storage interface {
+	DB() *gorm.DB
+	Migrate() error
+	Close() error
+}
+
+func New(cfg config.Database) (Storage, error) {
+	const op = "storage.New"
+
+	switch cfg.Driver {
+	case config.DriverMariaDB, "":
+		return mariadb.New(cfg)
+	case config.DriverPostgres:
+		return postgres.New(cfg)
+	case config.DriverSQLite:
+		return sqlite.New(cfg)
+	default:
+		return nil, fmt.Errorf("%s: unknown driver %q", op, cfg.Driver)
+	}
+}
@@ -0,0 +1,71 @@
+package uploads
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrInfected   = errors.New("file is infected")
+	ErrScanFailed = errors.New("antivirus scan failed")
+)
+
+// Scanner проверяет содержимое файла антивирусом, например clamav.Client.
+// Для заражённого файла возвращает имя сигнатуры, для чистого — пустую строку
+type Scanner interface {
+	Scan(data []byte) (string, error)
+}
+
+// ScanPolicy — что делать с результатами проверки
+type ScanPolicy struct {
+	// FailOpen сохраняет файл, если антивирус не ответил. Иначе файл отклоняется
+	FailOpen bool
+	// Audit получает запись о каждой проверке
+	Audit *slog.Logger
+}
+
+// UseScanner включает проверку файлов перед записью в SaveImage и ReplaceImage
+func (u *Uploads) UseScanner(s Scanner, policy ScanPolicy) {
+	u.scanner = s
+	u.scanPolicy = policy
+}
+
+// scan проверяет файл, если сканер подключён, и пишет результат в журнал аудита
+func (u *Uploads) scan(data []byte, filename string) error {
+	if u.scanner == nil {
+		return nil
+	}
+
+	start := time.Now()
+	signature, err := u.scanner.Scan(data)
+	attrs := []any{
+		slog.String("folder", u.folderPath),
+		slog.String("filename", filename),
+		slog.Int("size", len(data)),
+		slog.Duration("duration", time.Since(start)),
+	}
+
+	audit := u.scanPolicy.Audit
+	switch {
+	case err != nil:
+		if audit != nil {
+			audit.Error("upload scan failed", append(attrs, slog.Bool("fail_open", u.scanPolicy.FailOpen), slog.String("error", err.Error()))...)
+		}
+		if u.scanPolicy.FailOpen {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrScanFailed, err)
+	case signature != "":
+		if audit != nil {
+			audit.Warn("upload rejected: infected", append(attrs, slog.String("signature", signature))...)
+		}
+		return fmt.Errorf("%w: %s", ErrInfected, signature)
+	}
+
+	if audit != nil {
+		audit.Info("upload scanned: clean", attrs...)
+	}
+	return nil
+}
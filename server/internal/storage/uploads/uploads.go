@@ -3,9 +3,12 @@ package uploads
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -19,21 +22,36 @@ type IUploads interface {
 	SaveImage(image []byte, filename string) error
 	DeleteImage(filename string) error
 	ReplaceImage(image []byte, oldFilename, newFilename string) error
+	ReadImage(src io.Reader) ([]byte, string, error)
 }
 
 type Uploads struct {
 	folderPath string
+	limits     Limits
 	mu         sync.RWMutex
+
+	scanner    Scanner
+	scanPolicy ScanPolicy
 }
 
-func NewUploads(folderPath string) (*Uploads, error) {
+func NewUploads(folderPath string, limits Limits) (*Uploads, error) {
 	if folderPath == "" {
 		return nil, errors.New("folder path is empty")
 	}
 
 	folderPath = filepath.Clean(folderPath) + string(filepath.Separator)
 
-	u := &Uploads{folderPath: folderPath}
+	if limits.MaxSize <= 0 {
+		limits.MaxSize = DefaultMaxImageSize
+	}
+	if len(limits.AllowedTypes) == 0 {
+		limits.AllowedTypes = DefaultAllowedTypes
+	}
+	if limits.JPEGQuality <= 0 || limits.JPEGQuality > 100 {
+		limits.JPEGQuality = DefaultJPEGQuality
+	}
+
+	u := &Uploads{folderPath: folderPath, limits: limits}
 
 	if err := u.ensureFolderExists(); err != nil {
 		return nil, err
@@ -42,6 +60,19 @@ func NewUploads(folderPath string) (*Uploads, error) {
 	return u, nil
 }
 
+// CheckWritable проверяет, что в папку можно записать файл
+func (u *Uploads) CheckWritable() error {
+	f, err := os.CreateTemp(u.folderPath, ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
 func (u *Uploads) ensureFolderExists() error {
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -63,6 +94,14 @@ func (u *Uploads) SaveImage(image []byte, filename string) error {
 		return ErrInvalidFileName
 	}
 
+	if _, err := decode(image); err != nil {
+		return err
+	}
+
+	if err := u.scan(image, filename); err != nil {
+		return err
+	}
+
 	fullPath := filepath.Join(u.folderPath, filename)
 
 	u.mu.Lock()
@@ -102,6 +141,88 @@ func (u *Uploads) DeleteImage(filename string) error {
 	return os.Remove(fullPath)
 }
 
+// Open открывает файл из папки загрузок для чтения. Имя не может содержать путь
+func (u *Uploads) Open(filename string) (*os.File, error) {
+	if filename == "" || filename != filepath.Base(filename) || strings.HasPrefix(filename, ".") {
+		return nil, ErrInvalidFileName
+	}
+
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	f, err := os.Open(filepath.Join(u.folderPath, filename))
+	if os.IsNotExist(err) {
+		return nil, ErrFileNotExists
+	}
+	return f, err
+}
+
+// File — файл в папке загрузок
+type File struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// List возвращает файлы из корня папки загрузок. Служебные файлы (скрытые и
+// временные .tmp, которые пишет ReplaceImage) не возвращаются
+func (u *Uploads) List() ([]File, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	entries, err := os.ReadDir(u.folderPath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || strings.HasSuffix(e.Name(), ".tmp") {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		files = append(files, File{Name: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+
+	return files, nil
+}
+
+// Usage возвращает общий размер папки и размер файлов, изменённых в промежутке [from, to)
+func (u *Uploads) Usage(from, to time.Time) (total int64, added int64, err error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	err = filepath.WalkDir(u.folderPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		total += info.Size()
+		if !info.ModTime().Before(from) && info.ModTime().Before(to) {
+			added += info.Size()
+		}
+		return nil
+	})
+
+	return total, added, err
+}
+
 func (u *Uploads) ReplaceImage(image []byte, oldFilename, newFilename string) error {
 	if len(image) == 0 {
 		return ErrInvalidImage
@@ -111,6 +232,14 @@ func (u *Uploads) ReplaceImage(image []byte, oldFilename, newFilename string) er
 		return ErrInvalidFileName
 	}
 
+	if _, err := decode(image); err != nil {
+		return err
+	}
+
+	if err := u.scan(image, newFilename); err != nil {
+		return err
+	}
+
 	oldPath := filepath.Join(u.folderPath, oldFilename)
 	newPath := filepath.Join(u.folderPath, newFilename)
 
@@ -0,0 +1,98 @@
+package uploads
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+var (
+	ErrImageTooLarge   = errors.New("image is too large")
+	ErrUnsupportedType = errors.New("unsupported image type")
+)
+
+const DefaultMaxImageSize = 5 << 20
+
+var DefaultAllowedTypes = []string{"image/jpeg", "image/png", "image/webp", "image/gif"}
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// Limits ограничивает принимаемые картинки. Тип определяется по первым байтам
+// файла, а не по расширению или заголовку Content-Type от клиента
+type Limits struct {
+	MaxSize      int64
+	AllowedTypes []string
+	JPEGQuality  int
+}
+
+// ImageError описывает, почему картинка не прошла проверку
+type ImageError struct {
+	Err          error
+	ContentType  string
+	Size         int64
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+func (e *ImageError) Error() string {
+	if errors.Is(e.Err, ErrImageTooLarge) {
+		return fmt.Sprintf("%s: more than %d bytes", e.Err, e.MaxSize)
+	}
+	return fmt.Sprintf("%s: %s", e.Err, e.ContentType)
+}
+
+func (e *ImageError) Unwrap() error {
+	return e.Err
+}
+
+// ReadImage читает не больше MaxSize байт из src, проверяет тип содержимого
+// и приводит картинку к CanonicalType. Возвращает данные и их MIME-тип
+func (u *Uploads) ReadImage(src io.Reader) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(src, u.limits.MaxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if int64(len(data)) > u.limits.MaxSize {
+		return nil, "", &ImageError{
+			Err:     ErrImageTooLarge,
+			Size:    int64(len(data)),
+			MaxSize: u.limits.MaxSize,
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, "", ErrInvalidImage
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(u.limits.AllowedTypes, contentType) {
+		return nil, "", &ImageError{
+			Err:          ErrUnsupportedType,
+			ContentType:  contentType,
+			AllowedTypes: u.limits.AllowedTypes,
+		}
+	}
+
+	data, err = normalize(data, u.limits.JPEGQuality)
+	if err != nil {
+		return nil, "", &ImageError{Err: err, ContentType: contentType}
+	}
+
+	return data, CanonicalType, nil
+}
+
+// Extension возвращает расширение файла для MIME-типа картинки
+func Extension(contentType string) string {
+	if ext, ok := extensions[contentType]; ok {
+		return ext
+	}
+	return ".jpg"
+}
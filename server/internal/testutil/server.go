@@ -0,0 +1,265 @@
+// Package testutil поднимает сервер целиком для интеграционных тестов: роутер
+// со всеми middleware, сервисы и настоящую базу, а вместо SSO — fake.Server на
+// локальном gRPC-порту. Так тест проходит тот же путь, что и запрос в проде
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"games_webapp/internal/config"
+	"games_webapp/internal/lifecycle"
+	"games_webapp/internal/middleware"
+	"games_webapp/internal/repository"
+	"games_webapp/internal/routes"
+	dbstorage "games_webapp/internal/storage"
+	"games_webapp/internal/storage/uploads"
+
+	"games_webapp/internal/clients/sso/fake"
+	ssogrpc "games_webapp/internal/clients/sso/grpc"
+)
+
+// Options меняют окружение тестового сервера
+type Options struct {
+	// Database — база вместо SQLite во временной папке, например MariaDB,
+	// запущенная в testcontainers. Таблицы создаются миграциями, но не
+	// очищаются: отдельную базу на тест выбирает вызывающий
+	Database *config.Database
+	// Configure правит конфиг перед сборкой роутера
+	Configure func(cfg *config.Config)
+	// LogLevel — уровень логов сервера в выводе теста. nil — warn
+	LogLevel slog.Leveler
+}
+
+// Server — запущенный тестовый сервер. URL и Client берутся из httptest.Server
+type Server struct {
+	*httptest.Server
+
+	Config     *config.Config
+	ConfigPath string // файл конфига: после его правки Reloader.Reload применяет изменения
+	Reloader   *config.Reloader
+	Storage    dbstorage.Storage
+	Uploads    *uploads.Uploads
+	Photos     *uploads.Uploads
+	Lifecycle  *lifecycle.Manager
+	SSO        *fake.Server
+	Log        *slog.Logger
+}
+
+// New собирает сервер так же, как cmd/games, и останавливает его по окончании
+// теста. Внешние источники метаданных выключены, чтобы тесты не ходили в сеть
+func New(t testing.TB, opts Options) *Server {
+	t.Helper()
+
+	var level slog.Leveler = slog.LevelWarn
+	if opts.LogLevel != nil {
+		level = opts.LogLevel
+	}
+	log := slog.New(slog.NewTextHandler(testWriter{t}, &slog.HandlerOptions{Level: level}))
+
+	sso := fake.New(fake.Options{})
+	if err := sso.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sso.Stop)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(baseConfig(dir, sso.Addr())), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatalf("testutil: load config: %v", err)
+	}
+	// Переменные окружения важнее файла, поэтому то, что изолирует тест, ставим явно
+	cfg.UploadsPath = filepath.Join(dir, "uploads")
+	cfg.Photos.Path = filepath.Join(dir, "photos")
+	cfg.ImageProxy.CacheDir = filepath.Join(dir, "image_cache")
+	cfg.Clients.SSO.Address = sso.Addr()
+	cfg.Database = config.Database{Driver: config.DriverSQLite, Path: filepath.Join(dir, "games.db")}
+	if opts.Database != nil {
+		cfg.Database = *opts.Database
+	}
+	if opts.Configure != nil {
+		opts.Configure(cfg)
+	}
+
+	s := &Server{Config: cfg, ConfigPath: configPath, SSO: sso, Log: log}
+	s.Reloader = config.NewReloader(configPath, cfg, log)
+
+	ssoClient, err := ssogrpc.New(context.Background(), log, cfg.Clients.SSO.Address, cfg.Clients.SSO.Timeout, cfg.Clients.SSO.RetriesCount)
+	if err != nil {
+		t.Fatalf("testutil: sso client: %v", err)
+	}
+
+	s.Storage, err = dbstorage.New(cfg.Database)
+	if err != nil {
+		t.Fatalf("testutil: open database: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Storage.Close() })
+	if err := s.Storage.Migrate(); err != nil {
+		t.Fatalf("testutil: migrate: %v", err)
+	}
+
+	limits := uploads.Limits{
+		MaxSize:      cfg.Images.MaxSize,
+		AllowedTypes: cfg.Images.AllowedTypes,
+		JPEGQuality:  cfg.Images.JPEGQuality,
+	}
+	if s.Uploads, err = uploads.NewUploads(cfg.UploadsPath, limits); err != nil {
+		t.Fatalf("testutil: uploads: %v", err)
+	}
+	if s.Photos, err = uploads.NewUploads(cfg.Photos.Path, limits); err != nil {
+		t.Fatalf("testutil: photos: %v", err)
+	}
+
+	s.Lifecycle = lifecycle.New(log)
+	t.Cleanup(func() {
+		if err := s.Lifecycle.Shutdown(5 * time.Second); err != nil {
+			t.Errorf("testutil: shutdown: %v", err)
+		}
+	})
+
+	router := routes.SetupRouter(log, s.Storage, s.Uploads, s.Photos,
+		middleware.NewAuthMiddleware(ssoClient), ssoClient, s.Lifecycle, cfg, s.Reloader)
+	s.Server = httptest.NewServer(router)
+	t.Cleanup(s.Server.Close)
+
+	return s
+}
+
+// Store — репозиторий поверх базы сервера, чтобы готовить данные и проверять
+// результат в обход API
+func (s *Server) Store() repository.Store {
+	return repository.New(s.Storage.DB())
+}
+
+// NewUser заводит пользователя в SSO и возвращает его id и access-токен
+func (s *Server) NewUser(t testing.TB, email string, admin bool) (int, string) {
+	t.Helper()
+
+	id, err := s.SSO.AddUser(email, "password", admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(id), s.SSO.Token(id)
+}
+
+// Do отправляет запрос с Bearer-токеном. body кодируется в JSON; io.Reader
+// отправляется как есть, а Content-Type тогда задаётся через headers
+func (s *Server) Do(t testing.TB, method, path, token string, body interface{}, headers ...string) *http.Response {
+	t.Helper()
+
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		r = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(data)
+		headers = append([]string{"Content-Type", "application/json"}, headers...)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, s.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// DecodeJSON проверяет код ответа и разбирает тело в v
+func DecodeJSON(t testing.TB, resp *http.Response, wantStatus int, v interface{}) {
+	t.Helper()
+
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: status %d, want %d: %s", resp.Request.Method, resp.Request.URL.Path,
+			resp.StatusCode, wantStatus, strings.TrimSpace(string(data)))
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s %s: decode response: %v", resp.Request.Method, resp.Request.URL.Path, err)
+	}
+}
+
+// baseConfig — конфиг тестового сервера: без кэша токенов и размыкания SSO,
+// без задержек входа и без внешних источников метаданных
+func baseConfig(dir, ssoAddr string) string {
+	return fmt.Sprintf(`env: local
+uploads_path: %q
+app_secret: test-app-secret
+database:
+  driver: sqlite
+  path: %q
+photos:
+  path: %q
+  secret: test-photos-secret
+clients:
+  sso:
+    address: %q
+    timeout: 5s
+    retries_count: 1
+    cache_ttl: 0s
+    breaker_threshold: 0
+login_guard:
+  enabled: false
+webhooks:
+  enabled: false
+metadata:
+  providers:
+    - name: steam
+      enabled: false
+    - name: wiki
+      enabled: false
+    - name: igdb
+      enabled: false
+    - name: gog
+      enabled: false
+    - name: epic
+      enabled: false
+`, filepath.Join(dir, "uploads"), filepath.Join(dir, "games.db"), filepath.Join(dir, "photos"), ssoAddr)
+}
+
+// testWriter пишет логи сервера в вывод теста
+type testWriter struct {
+	t testing.TB
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
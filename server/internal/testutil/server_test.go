@@ -0,0 +1,55 @@
+package testutil_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"net/http"
+	"testing"
+
+	"games_webapp/internal/controllers"
+	"games_webapp/internal/models"
+	"games_webapp/internal/testutil"
+)
+
+func TestServerGamesRoundTrip(t *testing.T) {
+	srv := testutil.New(t, testutil.Options{})
+	userID, token := srv.NewUser(t, "player@example.com", false)
+
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodGet, "/api/games", "", nil), http.StatusUnauthorized, nil)
+
+	var cover bytes.Buffer
+	if err := png.Encode(&cover, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	resp := srv.Do(t, http.MethodPost, "/api/games", token, map[string]interface{}{
+		"title":  "Hades",
+		"year":   "2020",
+		"status": models.StatusPlaying,
+		"image":  base64.StdEncoding.EncodeToString(cover.Bytes()),
+	})
+	var created controllers.CreatedGame
+	testutil.DecodeJSON(t, resp, http.StatusOK, &created)
+	if created.Creator != userID {
+		t.Errorf("creator = %d, want %d", created.Creator, userID)
+	}
+
+	var list controllers.PaginationResponse
+	testutil.DecodeJSON(t, srv.Do(t, http.MethodGet, "/api/games", token, nil), http.StatusOK, &list)
+	if list.Total != 1 || len(list.Data) != 1 {
+		t.Fatalf("got %d games (total %d), want 1", len(list.Data), list.Total)
+	}
+	if got := list.Data[0]; got.ID != created.ID || got.Title != "Hades" {
+		t.Errorf("listed game = %d %q, want %d %q", got.ID, got.Title, created.ID, "Hades")
+	}
+
+	// Запись библиотеки видна и в обход API
+	ug, err := srv.Store().UserGames().GetActive(userID, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ug.Status != models.StatusPlaying {
+		t.Errorf("status = %q, want %q", ug.Status, models.StatusPlaying)
+	}
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey — где callback'и GORM хранят спан запроса между «до» и «после»
+const spanKey = "tracing:span"
+
+type gormSpan struct {
+	span trace.Span
+	op   string
+}
+
+// gormPlugin открывает спан на каждый запрос GORM. В спан попадает SQL с
+// плейсхолдерами, но не значения параметров
+type gormPlugin struct{}
+
+// GormPlugin — плагин для gorm.DB.Use. Спаны становятся дочерними для контекста,
+// переданного через WithContext
+func GormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", startSpan("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", endSpan),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", startSpan("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", endSpan),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", startSpan("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", endSpan),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", startSpan("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", endSpan),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", startSpan("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", endSpan),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", startSpan("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", endSpan),
+	)
+}
+
+func startSpan(op string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx, span := tracer().Start(tx.Statement.Context, "db."+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", tx.Dialector.Name())),
+		)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(spanKey, gormSpan{span: span, op: op})
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	v, _ := tx.InstanceGet(spanKey)
+	s, ok := v.(gormSpan)
+	if !ok {
+		return
+	}
+	span := s.span
+	defer span.End()
+
+	if table := tx.Statement.Table; table != "" {
+		span.SetName("db." + s.op + " " + table)
+		span.SetAttributes(attribute.String("db.sql.table", table))
+	}
+	span.SetAttributes(
+		attribute.String("db.statement", tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(otelcodes.Error, tx.Error.Error())
+	}
+}
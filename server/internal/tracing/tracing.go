@@ -0,0 +1,121 @@
+// Package tracing включает трассировку OpenTelemetry: спаны входящих запросов,
+// вызовов SSO по gRPC, запросов к базе через GORM и исходящих HTTP-запросов.
+// Пока Setup не вызван, все обёртки пакета работают с пустым провайдером и
+// ничего не записывают
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"games_webapp/internal/config"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName — имя, под которым пакет создаёт спаны
+const instrumentationName = "games_webapp/internal/tracing"
+
+// Setup ставит глобальный провайдер спанов с отправкой по OTLP и распространение
+// контекста трассы в заголовках W3C traceparent. Возвращает функцию, которая
+// отправляет накопленные спаны при остановке
+func Setup(cfg config.Tracing, env string, log *slog.Logger) func(ctx context.Context) error {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		attribute.String("deployment.environment", env),
+	)
+
+	// Экспортёр ходит в коллектор своим клиентом без otelhttp, иначе каждая
+	// отправка порождала бы новые спаны
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(tracesURL(cfg.Endpoint)),
+		otlptracehttp.WithHeaders(cfg.Headers),
+		otlptracehttp.WithTimeout(cfg.Timeout),
+	)
+	if err != nil {
+		log.Error("failed to create trace exporter, tracing disabled", slog.String("error", err.Error()))
+		return func(context.Context) error { return nil }
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warn("tracing error", slog.String("error", err.Error()))
+	}))
+
+	return tp.Shutdown
+}
+
+// tracesURL дописывает к адресу коллектора путь /v1/traces, если его там нет
+func tracesURL(endpoint string) string {
+	url := strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	return url
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Middleware открывает спан на каждый входящий запрос, кроме проверок живости, и
+// продолжает трассу из traceparent. Спан называется по шаблону маршрута chi,
+// например GET /api/games/{id}, поэтому ставится на корневой роутер
+func Middleware(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		rctx := chi.RouteContext(r.Context())
+		if rctx == nil || rctx.RoutePattern() == "" {
+			return
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + rctx.RoutePattern())
+		span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+	})
+
+	return otelhttp.NewHandler(named, "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return r.Method }),
+		otelhttp.WithFilter(func(r *http.Request) bool { return !strings.HasPrefix(r.URL.Path, "/api/health") }),
+	)
+}
+
+// Transport оборачивает base (nil — http.DefaultTransport) так, что исходящие
+// запросы получают спан и заголовок traceparent
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// TraceID — id трассы запроса для логов. Пусто, если запрос не трассируется
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsSampled() {
+		return ""
+	}
+	return sc.TraceID().String()
+}